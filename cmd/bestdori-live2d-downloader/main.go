@@ -3,21 +3,41 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/api"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/downloader"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/history"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/matcher"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/pack"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/rpc"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/settings"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/stats"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/tui"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/version"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -29,8 +49,21 @@ const (
 	// StateInput 表示输入状态.
 	StateInput = "input"
 
-	// ErrDownloadCancelled 表示下载已取消的错误.
-	ErrDownloadCancelled = "下载已取消"
+	// searchCacheFileName 是角色搜索缓存在角色信息缓存目录中的文件名.
+	searchCacheFileName = "chara_search.json"
+
+	// importSelectionCommand 是导入选择文件的子命令，也可以直接在输入框中输入.
+	importSelectionCommand = "import-selection"
+
+	// displayLocale 是角色与乐队显示名称优先使用的服务器，没有时回退到其他服务器.
+	displayLocale = model.LocaleCN
+
+	// listFormatJSON 与 listFormatCSV 是 -list-all 支持的输出格式.
+	listFormatJSON = "json"
+	listFormatCSV  = "csv"
+
+	// exitAllFailed 是不使用 TUI 时没有任何模型下载成功的退出码，部分模型失败时退出码为 1.
+	exitAllFailed = 2
 )
 
 // SuggestionError 表示建议类型的错误.
@@ -50,30 +83,302 @@ func IsSuggestionError(err error) bool {
 	return ok
 }
 
+// cliOptions 表示命令行选项.
+type cliOptions struct {
+	failFast   bool                 // 批量下载时是否在第一个失败后中止
+	refresh    bool                 // 是否忽略下载清单重新下载所有文件
+	dumpDB     string               // 角色-模型数据库的导出路径，非空时只导出数据库
+	listAll    bool                 // 是否只输出全部角色的服装列表
+	format     string               // 服装列表的输出格式，json 或 csv
+	offline    bool                 // 是否只使用缓存数据运行
+	cleanTemp  bool                 // 是否只清理遗留的临时文件
+	clearCache bool                 // 是否只清空角色信息缓存
+	listFile   string               // 模型列表文件路径，非空时启动后直接下载列表中的模型
+	taskFile   string               // 任务文件路径，非空时启动后按任务文件下载
+	models     []string             // 命令行指定的模型名称，非空时不使用 TUI 直接下载后退出
+	server     string               // 使用的服务器，为 all 时合并查询所有服务器
+	selection  string               // 选择文件路径，非空时启动后导入其中的服装
+	yes        bool                 // 导入选择文件后是否不经确认直接下载
+	pack       string               // 需要打包为分发包的模型目录
+	unpack     string               // 需要解包的分发包路径
+	verify     string               // 需要校验完整性的分发包路径
+	out        string               // 打包或解包的输出路径，为空时与输入同名
+	exclude    model.Live2dPatterns // 通配符匹配、模型列表和任务文件中排除的模型
+	history    bool                 // 是否只显示下载历史
+	rpc        bool                 // 是否通过标准输入输出的 JSON 行协议接收命令
+	printCfg   bool                 // 是否只打印生效的配置
+	version    bool                 // 是否只显示版本号
+	config     *configFlags         // 覆盖配置文件的命令行参数
+}
+
+// configFlags 表示覆盖配置的命令行参数
+// 只有显式指定的参数才会覆盖 bestdori.yaml 与默认配置中的值.
+type configFlags struct {
+	savePath        string          // Live2D 模型保存路径
+	cachePath       string          // 角色信息缓存路径
+	logPath         string          // 日志文件保存路径
+	maxDownloads    int             // 单个模型的最大并发文件下载数
+	maxModels       int             // 最大并发模型下载数
+	noCache         bool            // 是否不使用角色信息缓存
+	cacheTTL        time.Duration   // 缓存过期时间
+	verifyIntegrity bool            // 复用已存在的文件前是否校验 SHA-256
+	outputZip       bool            // 是否在模型下载完成后打包为 ZIP
+	zipPath         string          // ZIP 的保存目录
+	noTUI           bool            // 是否不使用 TUI，将进度输出到标准输出
+	set             map[string]bool // 显式指定的参数名称
+}
+
+// configFlagKeys 是覆盖配置的命令行参数对应的配置项名称.
+var configFlagKeys = map[string]string{
+	"save-path":        "live2d_save_path",
+	"cache-path":       "chara_cache_path",
+	"log-path":         "log_path",
+	"max-downloads":    "max_concurrent_downloads",
+	"max-models":       "max_concurrent_models",
+	"no-cache":         "use_chara_cache",
+	"cache-ttl":        "cache_duration",
+	"verify-integrity": "verify_file_integrity",
+	"output-zip":       "zip_output",
+	"zip-path":         "zip_path",
+	"no-tui":           "no_tui",
+}
+
+// registerConfigFlags 在命令行参数集中注册覆盖配置的参数.
+func registerConfigFlags(fs *flag.FlagSet) *configFlags {
+	defaults := config.DefaultConfig()
+	f := &configFlags{}
+	fs.StringVar(&f.savePath, "save-path", defaults.Live2dSavePath, "Live2D 模型保存路径")
+	fs.StringVar(&f.cachePath, "cache-path", defaults.CharaCachePath, "角色信息缓存路径")
+	fs.StringVar(&f.logPath, "log-path", defaults.LogPath, "日志文件保存路径")
+	fs.IntVar(&f.maxDownloads, "max-downloads", defaults.MaxConcurrentDownloads, "单个模型下载时的最大并发文件下载数")
+	fs.IntVar(&f.maxModels, "max-models", defaults.MaxConcurrentModels, "最大并发模型下载数")
+	fs.BoolVar(&f.noCache, "no-cache", !defaults.UseCharaCache, "不使用角色信息缓存")
+	fs.DurationVar(&f.cacheTTL, "cache-ttl", defaults.CacheDuration, "缓存过期时间，例如 12h")
+	fs.BoolVar(&f.verifyIntegrity, "verify-integrity", defaults.VerifyFileIntegrity,
+		"复用已存在的文件前按下载清单校验 SHA-256")
+	fs.BoolVar(&f.outputZip, "output-zip", defaults.ZipOutput, "模型下载完成后将模型目录打包为 ZIP")
+	fs.StringVar(&f.zipPath, "zip-path", defaults.ZipPath, "ZIP 的保存目录，默认保存在模型目录旁")
+	fs.BoolVar(&f.noTUI, "no-tui", defaults.NoTUI, "不使用 TUI，按 -model、-list、-task 或导入的选择文件下载后退出，进度输出到标准输出")
+	return f
+}
+
+// validate 记录显式指定的参数并检查取值.
+func (f *configFlags) validate(fs *flag.FlagSet) error {
+	f.set = make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) { f.set[fl.Name] = true })
+
+	switch {
+	case f.set["max-downloads"] && f.maxDownloads < 1:
+		return fmt.Errorf("-max-downloads 必须大于 0: %d", f.maxDownloads)
+	case f.set["max-models"] && f.maxModels < 1:
+		return fmt.Errorf("-max-models 必须大于 0: %d", f.maxModels)
+	case f.set["cache-ttl"] && f.cacheTTL < 0:
+		return fmt.Errorf("-cache-ttl 不能为负数: %s", f.cacheTTL)
+	}
+	return nil
+}
+
+// apply 将显式指定的参数覆盖到配置上，f 为 nil 时不做修改.
+func (f *configFlags) apply(cfg *config.Config) {
+	if f == nil {
+		return
+	}
+	if f.set["save-path"] {
+		cfg.Live2dSavePath = f.savePath
+	}
+	if f.set["cache-path"] {
+		cfg.CharaCachePath = f.cachePath
+	}
+	if f.set["log-path"] {
+		cfg.LogPath = f.logPath
+	}
+	if f.set["max-downloads"] {
+		cfg.MaxConcurrentDownloads = f.maxDownloads
+	}
+	if f.set["max-models"] {
+		cfg.MaxConcurrentModels = f.maxModels
+	}
+	if f.set["no-cache"] {
+		cfg.UseCharaCache = !f.noCache
+	}
+	if f.set["cache-ttl"] {
+		cfg.CacheDuration = f.cacheTTL
+	}
+	if f.set["verify-integrity"] {
+		cfg.VerifyFileIntegrity = f.verifyIntegrity
+	}
+	if f.set["output-zip"] {
+		cfg.ZipOutput = f.outputZip
+	}
+	if f.set["zip-path"] {
+		cfg.ZipPath = f.zipPath
+	}
+	if f.set["no-tui"] {
+		cfg.NoTUI = f.noTUI
+	}
+}
+
+// configSources 返回各配置项生效值的来源，命令行参数优先于配置文件.
+func (o cliOptions) configSources(fileKeys map[string]bool) map[string]config.Source {
+	sources := make(map[string]config.Source, len(fileKeys))
+	for key := range fileKeys {
+		sources[key] = config.SourceFile
+	}
+	if o.config != nil {
+		for name := range o.config.set {
+			sources[configFlagKeys[name]] = config.SourceFlag
+		}
+	}
+	if o.failFast {
+		sources["batch_fail_fast"] = config.SourceFlag
+	}
+	if o.offline {
+		sources["offline"] = config.SourceFlag
+	}
+	if o.server != "" {
+		sources["server"] = config.SourceFlag
+	}
+	return sources
+}
+
+// applyConfig 将不属于 configFlags 的命令行选项覆盖到配置上.
+func (o cliOptions) applyConfig(cfg *config.Config) {
+	if o.failFast {
+		cfg.BatchFailFast = true
+	}
+	if o.offline {
+		cfg.Offline = true
+	}
+	if o.server != "" {
+		cfg.Server = o.server
+	}
+}
+
+// initConfig 初始化全局配置并用命令行参数覆盖配置文件中的值.
+func initConfig(flags *configFlags) error {
+	if err := config.Init(); err != nil {
+		return err //nolint:wrapcheck // 已包含配置文件路径
+	}
+	flags.apply(config.Get())
+	return nil
+}
+
+// parseOptions 解析命令行选项.
+func parseOptions(args []string) (cliOptions, error) {
+	var opts cliOptions
+	fs := flag.NewFlagSet("bestdori-live2d-downloader", flag.ContinueOnError)
+	fs.BoolVar(&opts.failFast, "fail-fast", false, "批量下载时在第一个模型失败后中止整个批次")
+	fs.BoolVar(&opts.refresh, "force-refresh", false, "忽略模型目录中的下载清单，重新下载所有文件")
+	fs.StringVar(&opts.dumpDB, "dump-db", "", "导出角色-模型映射数据库到指定的 JSON 文件")
+	fs.BoolVar(&opts.listAll, "list-all", false, "将全部角色的服装列表输出到标准输出后退出")
+	fs.StringVar(&opts.format, "format", listFormatJSON, "-list-all 的输出格式（json、csv）")
+	fs.BoolVar(&opts.offline, "offline", false, "离线模式，只使用缓存的角色和服装数据，不发送网络请求")
+	fs.BoolVar(&opts.cleanTemp, "clean-temp", false, "清理下载目录中遗留的全部临时文件及已删除模型的使用统计后退出")
+	fs.BoolVar(&opts.clearCache, "clear-cache", false, "清空角色信息缓存目录中的缓存文件后退出")
+	fs.StringVar(&opts.listFile, "list", "", "从文件读取要下载的模型列表，每行一个，可用 \"模型名 => 输出路径\" 单独指定保存路径")
+	fs.BoolVar(&opts.history, "history", false, "显示下载历史后退出")
+	fs.BoolVar(&opts.rpc, "rpc", false, "不使用 TUI，从标准输入读取 JSON 命令并将进度以 JSON 事件输出到标准输出，供其他程序驱动下载")
+	fs.BoolVar(&opts.version, "version", false, "显示版本号后退出")
+	fs.BoolVar(&opts.printCfg, "print-config", false, "打印合并默认值、配置文件、环境变量与命令行参数后生效的配置及其来源后退出")
+	fs.StringVar(&opts.taskFile, "task", "", "从任务文件读取服务器、模型列表和下载选项并直接开始下载")
+	fs.Func("model", "不使用 TUI 直接下载指定的模型后退出，多个模型用逗号分隔，可重复指定，例如 -model 037_casual-2023", func(value string) error {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if model.IsLive2dPattern(name) {
+				return fmt.Errorf("不支持通配符，请在 TUI 中输入或使用 -list: %s", name)
+			}
+			opts.models = append(opts.models, name)
+		}
+		return nil
+	})
+	fs.StringVar(&opts.pack, "pack", "", "将模型目录打包为 .l2dpack 分发包后退出")
+	fs.StringVar(&opts.unpack, "unpack", "", "校验并解包 .l2dpack 分发包后退出")
+	fs.StringVar(&opts.verify, "verify", "", "校验 .l2dpack 分发包的完整性后退出")
+	fs.StringVar(&opts.out, "out", "", "-pack 生成的分发包路径或 -unpack 的解包目录，默认与输入同名")
+	fs.Func("exclude", "排除与模式匹配的模型，多个模式用逗号分隔，可重复指定，例如 -exclude \"*_live_*\"", func(value string) error {
+		patterns, err := model.ParseLive2dPatterns(strings.Split(value, ","))
+		if err != nil {
+			return err //nolint:wrapcheck // 由 flag 包输出错误信息
+		}
+		opts.exclude = append(opts.exclude, patterns...)
+		return nil
+	})
+	fs.StringVar(&opts.server, "server", "", "使用的服务器（jp、en、tw、cn、kr），为 all 时合并查询所有服务器的服装")
+	opts.config = registerConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return opts, fmt.Errorf("解析命令行参数失败: %w", err)
+	}
+	if err := opts.config.validate(fs); err != nil {
+		return opts, fmt.Errorf("解析命令行参数失败: %w", err)
+	}
+	if opts.server != "" {
+		if err := config.ValidateServer(opts.server); err != nil {
+			return opts, fmt.Errorf("解析命令行参数失败: %w", err)
+		}
+	}
+	if opts.format != listFormatJSON && opts.format != listFormatCSV {
+		return opts, fmt.Errorf("解析命令行参数失败: -format 只能是 %s 或 %s: %s", listFormatJSON, listFormatCSV, opts.format)
+	}
+	return opts, nil
+}
+
+// batchProgress 接收批量下载的进度
+// 由 TUI 模型或不使用 TUI 时的 downloader.NullProgressSink 实现.
+type batchProgress interface {
+	downloader.ProgressSink
+	SetTotalModels(total int)
+	UpdateTotalProgress()
+	SetItemGroup(name, group, dir string)
+	SetItemTitle(name, title string)
+	SendSpaceEstimate(text string, insufficient bool)
+}
+
 // App 表示应用程序的主要结构.
 type App struct {
 	ctx       context.Context
 	cancel    context.CancelFunc
+	opts      cliOptions
 	apiClient *api.Client
 	dl        *downloader.Downloader
-	tuiModel  *tui.Model
-	program   *tea.Program
+	tuiModel  *tui.Model    // TUI 模型，不使用 TUI 时为 nil
+	program   *tea.Program  // TUI 程序，不使用 TUI 时为 nil
+	progress  batchProgress // 批量下载的进度接收者，使用 TUI 时即为 tuiModel
+	rpcServer *rpc.Server   // RPC 模式的命令服务，不使用 RPC 模式时为 nil
+	exitCode  int
+	succeeded int // 不使用 TUI 时本次运行下载成功的模型数，用于区分部分失败与全部失败
+
+	conflictMu sync.Mutex // 保证同一时间只处理一个服务器冲突，使记住的选择对后续模型生效
+
+	outputPaths    map[string]string           // 模型列表中单独指定的保存路径，key 为模型名称
+	buildData      map[string]*model.BuildData // 估算所需空间时获取的构建数据，下载时直接使用，key 为模型名称
+	listChara      string                      // 当前服装列表所属的角色名称，通配符匹配的列表为空
+	blocklist      model.Live2dPatterns        // 屏蔽的服装
+	blockedConfirm string                      // 等待再次确认下载的屏蔽服装输入
+	searchCache    *matcher.Cache              // 角色搜索结果缓存
 }
 
 // NewApp 创建新的应用程序实例.
-func NewApp() *App {
+func NewApp(opts cliOptions) *App {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &App{
 		ctx:    ctx,
 		cancel: cancel,
+		opts:   opts,
 	}
 }
 
 // initialize 初始化应用程序.
 func (a *App) initialize() {
-	// 初始化配置
-	config.Init()
+	// 初始化配置，命令行参数优先于配置文件
+	if err := initConfig(a.opts.config); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
 	cfg := config.Get()
+	a.opts.applyConfig(cfg)
 
 	// 初始化日志
 	if _, err := log.New(cfg.LogPath); err != nil {
@@ -81,15 +386,64 @@ func (a *App) initialize() {
 		os.Exit(1)
 	}
 
-	// 创建 TUI 模型
-	model := tui.NewModel()
-	a.tuiModel = &model
-	a.program = tea.NewProgram(a.tuiModel, tea.WithAltScreen())
-	a.tuiModel.SetProgram(a.program)
+	// 清理上次异常退出遗留的临时文件
+	if cfg.TempFileMaxAge > 0 {
+		_, _ = cleanTempFiles(cfg.Live2dSavePath, cfg.TempFileMaxAge) // 错误已记录到日志，不影响启动
+	}
 
-	// 创建 API 客户端和下载器
+	// 创建 TUI 模型，不使用 TUI 或命令行指定了模型时将进度输出到标准输出，RPC 模式时以 JSON 事件输出
 	a.apiClient = api.NewClient()
-	a.dl = downloader.NewDownloader(a.apiClient, a.tuiModel, a.program)
+	if cfg.UseCharaCache {
+		if _, err := a.apiClient.PruneExpiredCache(); err != nil {
+			log.DefaultLogger.Warn().Str("path", cfg.CharaCachePath).Err(err).Msg("删除过期的缓存文件失败")
+		}
+	}
+	switch {
+	case a.opts.rpc:
+		a.rpcServer = rpc.NewServer(a.ctx, os.Stdout)
+		a.progress = a.rpcServer.Sink()
+	case cfg.NoTUI || len(a.opts.models) > 0:
+		a.progress = downloader.NewNullProgressSink(a.ctx, os.Stdout)
+	default:
+		model := tui.NewModel()
+		a.tuiModel = &model
+		a.tuiModel.FailFast = cfg.BatchFailFast
+		a.tuiModel.NarrowWidth = cfg.NarrowWidth
+		a.tuiModel.SetCompactView(cfg.CompactDownloadView)
+		a.program = tea.NewProgram(a.tuiModel, tea.WithAltScreen())
+		a.tuiModel.SetProgram(a.program)
+		a.progress = a.tuiModel
+		go a.forwardLogLines()
+
+		a.apiClient.SetFetchProgress(a.tuiModel.UpdateLoadingProgress)
+		a.tuiModel.Server = a.apiClient.Server()
+	}
+
+	// 创建下载器
+	a.dl = downloader.NewDownloader(a.apiClient, a.progress, a.program)
+	a.dl.ForceRefresh = a.opts.refresh
+
+	// 角色搜索缓存与角色列表缓存使用相同的有效期，不使用角色信息缓存时只缓存在内存中
+	searchCachePath := ""
+	if cfg.UseCharaCache {
+		searchCachePath = filepath.Join(cfg.CharaCachePath, searchCacheFileName)
+	}
+	a.searchCache = matcher.LoadCache(searchCachePath, cfg.CacheDuration)
+
+	blocklist, err := downloader.LoadBlocklist(cfg.BlocklistPath, cfg.Blocklist)
+	if err != nil {
+		log.DefaultLogger.Warn().Str("path", cfg.BlocklistPath).Err(err).Msg("读取屏蔽列表失败，不屏蔽任何服装")
+	}
+	a.blocklist = blocklist
+}
+
+// savePath 返回模型的保存根目录
+// 模型列表中为模型单独指定的输出路径优先于全局保存路径.
+func (a *App) savePath(live2dName string) string {
+	if output, ok := a.outputPaths[live2dName]; ok {
+		return output
+	}
+	return config.Get().Live2dSavePath
 }
 
 // getLive2dPath 根据 Live2D 名称获取保存路径.
@@ -97,7 +451,14 @@ func (a *App) getLive2dPath(live2dName string) (string, error) {
 	parts := strings.SplitN(live2dName, "_", SplitPartsCount)
 	if len(parts) != SplitPartsCount {
 		log.DefaultLogger.Error().Str("live2dName", live2dName).Msg("无效的Live2D名称格式")
-		return "", errors.New("无效的Live2D名称格式")
+		return "", errs.ErrInvalidLive2dName
+	}
+
+	savePath := a.savePath(live2dName)
+
+	// 平铺布局直接使用服装资源名作为目录名，不需要查询角色信息
+	if config.Get().DirectoryLayout == model.DirectoryLayoutFlat {
+		return filepath.Join(savePath, live2dName), nil
 	}
 
 	charaID, err := strconv.Atoi(parts[0])
@@ -106,93 +467,293 @@ func (a *App) getLive2dPath(live2dName string) (string, error) {
 		return "", fmt.Errorf("无效的角色ID: %w", err)
 	}
 
+	// 优先使用用户自定义的目录名
+	if dirName, ok := config.Get().CharaDirOverrides[charaID]; ok {
+		dirName = strings.TrimSpace(dirName)
+		if filepath.IsLocal(dirName) {
+			path := filepath.Join(savePath, dirName, parts[1])
+			log.DefaultLogger.Info().Int("charaID", charaID).Str("path", path).Msg("使用自定义角色目录名")
+			return path, nil
+		}
+		log.DefaultLogger.Warn().Int("charaID", charaID).Str("dirName", dirName).Msg("无效的自定义角色目录名，使用自动解析的目录名")
+	}
+
 	// 尝试获取角色信息
 	chara, err := a.apiClient.GetChara(a.ctx, charaID)
 	if err != nil {
 		// 如果获取角色信息失败，使用角色ID作为目录名
 		log.DefaultLogger.Warn().Int("charaID", charaID).Err(err).Msg("获取角色信息失败，使用角色ID作为目录名")
-		path := filepath.Join(config.Get().Live2dSavePath, fmt.Sprintf("chara_%03d", charaID), parts[1])
+		path := filepath.Join(savePath, fmt.Sprintf("chara_%03d", charaID), parts[1])
 		log.DefaultLogger.Info().Str("path", path).Msg("获取Live2D路径成功")
 		return path, nil
 	}
 
-	// 如果成功获取角色信息，使用角色名作为目录名
-	firstName, ok := chara["firstName"].([]any)[1].(string)
-	if !ok {
+	// 如果成功获取角色信息，使用国际服的角色名作为目录名
+	// 不回退到其他服务器的名称，避免同一角色因数据缺失而使用不同的目录
+	firstName := chara.FirstName.At(model.LocaleEN)
+	if firstName == "" {
 		// 如果无法获取角色名，使用角色ID作为目录名
 		log.DefaultLogger.Warn().Int("charaID", charaID).Msg("无效的角色名字格式，使用角色ID作为目录名")
-		path := filepath.Join(config.Get().Live2dSavePath, fmt.Sprintf("chara_%03d", charaID), parts[1])
+		path := filepath.Join(savePath, fmt.Sprintf("chara_%03d", charaID), parts[1])
 		log.DefaultLogger.Info().Str("path", path).Msg("获取Live2D路径成功")
 		return path, nil
 	}
 
-	path := filepath.Join(config.Get().Live2dSavePath, strings.ToLower(firstName), parts[1])
+	cfg := config.Get()
+	path := filepath.Join(savePath, model.CharaDirName(firstName, charaID, cfg.DirNameStyle, cfg.DirNameAppendID), parts[1])
 	log.DefaultLogger.Info().Str("path", path).Msg("获取Live2D路径成功")
 	return path, nil
 }
 
-// downloadLive2d 下载指定的 Live2D 模型.
-func (a *App) downloadLive2d(live2dName string) error {
+// downloadLive2d 下载指定的 Live2D 模型，返回模型的保存路径.
+func (a *App) downloadLive2d(ctx context.Context, live2dName string) (string, error) {
 	log.DefaultLogger.Info().Str("live2dName", live2dName).Msg("开始下载Live2D")
 
-	data, err := a.apiClient.GetLive2dData(a.ctx, live2dName)
-	if err != nil {
-		log.DefaultLogger.Error().Str("live2dName", live2dName).Err(err).Msg("获取Live2D数据失败")
-		return fmt.Errorf("获取Live2D数据失败: %w", err)
+	data, ok := a.buildData[live2dName]
+	if !ok {
+		var err error
+		if data, err = a.apiClient.GetLive2dData(ctx, live2dName); err != nil {
+			log.DefaultLogger.Error().Str("live2dName", live2dName).Err(err).Msg("获取Live2D数据失败")
+			return "", fmt.Errorf("获取Live2D数据失败: %w", err)
+		}
 	}
 
 	path, err := a.getLive2dPath(live2dName)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	// 切换目录布局后，按原布局下载过的模型只提示而不重复下载
+	if _, statErr := os.Stat(path); errors.Is(statErr, fs.ErrNotExist) {
+		layout := config.Get().DirectoryLayout
+		if other := downloader.FindModelInOtherLayout(a.savePath(live2dName), live2dName, layout); other != "" {
+			log.DefaultLogger.Warn().Str("live2dName", live2dName).Str("path", other).Msg("模型已按另一种目录布局下载，跳过")
+			return "", fmt.Errorf("%w: %s 已存在于 %s", errs.ErrOtherDirectoryLayout, live2dName, other)
+		}
+	}
+
+	// 避免同一角色目录混入不同服务器的模型，构建数据可能来自回退服务器
+	server := data.Server
+	path, err = downloader.ResolveServerFolder(path, server, func(folderServer string) (model.ServerConflictDecision, error) {
+		return a.decideServerConflict(ctx, filepath.Base(filepath.Dir(path)), folderServer, server)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// 按角色目录对下载列表分组，分组标题中显示角色所属乐队
+	charaDir := filepath.Dir(path)
+	group := filepath.Base(charaDir)
+	byCharacter := config.Get().DirectoryLayout != model.DirectoryLayoutFlat
+	if charaID, isChara := model.Live2dCharaID(live2dName); isChara && byCharacter {
+		group = model.BandTitle(a.charaBandName(ctx, charaID), group)
+	}
+	a.progress.SetItemGroup(live2dName, group, charaDir)
+	if title, ok := a.apiClient.CostumeTitle(live2dName); ok {
+		a.progress.SetItemTitle(live2dName, title)
 	}
 
 	builder := downloader.NewLive2dBuilder(path, data, a.dl, live2dName)
-	if constructErr := builder.Construct(); constructErr != nil {
+	if constructErr := builder.ConstructWithContext(ctx); constructErr != nil {
 		log.DefaultLogger.Error().Str("live2dName", live2dName).Err(constructErr).Msg("构建Live2D模型失败")
-		return fmt.Errorf("构建Live2D模型失败: %w", constructErr)
+		return "", fmt.Errorf("构建Live2D模型失败: %w", constructErr)
+	}
+
+	if skipped := builder.SkippedFiles(); len(skipped) > 0 {
+		log.DefaultLogger.Warn().Str("live2dName", live2dName).Strs("files", skipped).Msg("部分可选文件下载超时，已跳过")
+		a.progress.SetError(fmt.Sprintf("%s: 已跳过 %d 个下载超时的文件: %s", live2dName, len(skipped), strings.Join(skipped, ", ")))
+	}
+	if missing := builder.MissingFiles(); len(missing) > 0 {
+		log.DefaultLogger.Warn().Str("live2dName", live2dName).Strs("files", missing).Msg("部分允许缺失的文件不存在，已跳过")
+		a.progress.SetError(fmt.Sprintf("%s: 已跳过 %d 个不存在的文件: %s", live2dName, len(missing), strings.Join(missing, ", ")))
+	}
+
+	if markerErr := downloader.EnsureFolderMarker(charaDir, server); markerErr != nil {
+		log.DefaultLogger.Warn().Str("path", charaDir).Err(markerErr).Msg("写入目录标记失败")
 	}
 
 	log.DefaultLogger.Info().Str("live2dName", live2dName).Str("path", path).Msg("Live2D下载完成")
-	return nil
+	return path, nil
 }
 
-// findChara 根据名称搜索角色.
-func (a *App) findChara(name string) (*model.MatchChara, error) {
-	log.DefaultLogger.Info().Str("name", name).Msg("开始搜索角色")
+// decideServerConflict 决定角色目录服务器冲突的处理方式
+// 优先使用配置中记住的选择，否则询问用户；选择记住时写入配置文件的 server_conflict_policy.
+func (a *App) decideServerConflict(
+	ctx context.Context,
+	chara, folderServer, server string,
+) (model.ServerConflictDecision, error) {
+	a.conflictMu.Lock()
+	defer a.conflictMu.Unlock()
+
+	cfg := config.Get()
+	if decision, ok := cfg.ServerConflictPolicy[chara]; ok && decision != model.ServerConflictAsk {
+		log.DefaultLogger.Info().Str("chara", chara).Str("decision", string(decision)).Msg("使用已记住的服务器冲突处理方式")
+		return decision, nil
+	}
+
+	// 不使用 TUI 时无法询问，中止下载该模型，保持角色目录不变
+	if a.tuiModel == nil {
+		log.DefaultLogger.Warn().Str("chara", chara).Msg("不使用 TUI 时无法询问服务器冲突的处理方式，请在 server_conflict_policy 中配置")
+		return model.ServerConflictAbort, nil
+	}
+
+	answer, err := a.tuiModel.AskServerConflict(ctx, chara, folderServer, server)
+	if err != nil {
+		return "", err
+	}
+	if answer.Remember {
+		if cfg.ServerConflictPolicy == nil {
+			cfg.ServerConflictPolicy = make(map[string]model.ServerConflictDecision)
+		}
+		cfg.ServerConflictPolicy[chara] = answer.Decision
+		// 写回配置文件，下次运行时仍然使用该选择
+		if err := config.SaveField(config.FileName, "server_conflict_policy", cfg.ServerConflictPolicy); err != nil {
+			log.DefaultLogger.Warn().Str("chara", chara).Err(err).Msg("保存服务器冲突处理方式失败，只在本次运行中生效")
+		}
+	}
+	return answer.Decision, nil
+}
+
+// matchChara 在候选角色中查找最佳匹配，优先使用搜索缓存.
+func (a *App) matchChara(name string, candidates map[string][]string) (string, string, float64) {
+	fingerprint := matcher.Fingerprint(candidates)
+	if cached, ok := a.searchCache.Lookup(name, fingerprint); ok {
+		log.DefaultLogger.Debug().Str("name", name).Str("bestMatch", cached.Name).Msg("使用角色搜索缓存")
+		return cached.ID, cached.Name, cached.Similarity
+	}
+
+	bestID, bestMatch, similarity := matcher.FindBestMatch(name, candidates)
+	match := matcher.CachedMatch{ID: bestID, Name: bestMatch, Similarity: similarity, CreatedAt: time.Now()}
+	if err := a.searchCache.Store(name, fingerprint, match); err != nil {
+		log.DefaultLogger.Warn().Err(err).Msg("保存角色搜索缓存失败")
+	}
+	return bestID, bestMatch, similarity
+}
 
+// charaCandidates 返回角色搜索的候选名称，key 为角色ID.
+func (a *App) charaCandidates() (map[string][]string, error) {
 	characterRoster, err := a.apiClient.GetCharaRoster(a.ctx)
 	if err != nil {
-		log.DefaultLogger.Error().Str("name", name).Err(err).Msg("获取角色列表失败")
 		return nil, fmt.Errorf("获取角色列表失败: %w", err)
 	}
 
 	candidates := make(map[string][]string)
 	for charaID, info := range characterRoster {
 		charaIDNum, parseErr := strconv.Atoi(charaID)
-		if parseErr != nil || charaIDNum > 1000 {
+		if parseErr != nil || !config.Get().CharaIDAllowed(charaIDNum) {
 			continue
 		}
 
-		charaInfo, ok := info.(map[string]any)
-		if !ok {
+		if len(info.CharacterName) == 0 {
 			continue
 		}
-		characterNames, ok := charaInfo["characterName"].([]any)
-		if !ok {
-			continue
+		candidates[charaID] = info.CharacterName
+	}
+	return candidates, nil
+}
+
+// findBand 根据名称搜索乐队
+// 只有乐队名称足够相似且比任何角色名称都更相似时才视为乐队，避免角色搜索被乐队抢先匹配；
+// 乐队信息获取失败时按没有匹配的乐队处理.
+func (a *App) findBand(name string) (int, string, bool) {
+	bands, err := a.apiClient.GetBands(a.ctx)
+	if err != nil {
+		log.DefaultLogger.Warn().Str("name", name).Err(err).Msg("获取乐队信息失败，只搜索角色")
+		return 0, "", false
+	}
+
+	candidates := make(map[string][]string, len(bands))
+	for id, band := range bands {
+		candidates[strconv.Itoa(id)] = band.BandName
+	}
+	bestID, bestMatch, similarity := matcher.FindBestMatch(name, candidates)
+	// 乐队名称与角色名称容易部分重合，阈值高于角色搜索
+	const similarityThreshold = 0.8
+	if similarity < similarityThreshold {
+		return 0, "", false
+	}
+	if charas, charaErr := a.charaCandidates(); charaErr == nil {
+		if _, _, charaSimilarity := a.matchChara(name, charas); charaSimilarity >= similarity {
+			return 0, "", false
 		}
-		names := make([]string, len(characterNames))
-		for i := range characterNames {
-			characterName, nameOk := characterNames[i].(string)
-			if !nameOk {
-				continue
-			}
-			names[i] = characterName
+	}
+
+	id, _ := strconv.Atoi(bestID)
+	log.DefaultLogger.Info().Str("name", name).Str("bestMatch", bestMatch).Float64("similarity", similarity).Msg("找到乐队")
+	return id, bands[id].LocalizedName(displayLocale), true
+}
+
+// handleBandSearch 列出乐队成员，由用户选择角色后进入服装列表.
+func (a *App) handleBandSearch(bandID int, bandName string) bool {
+	members, err := a.apiClient.GetBandMembers(a.ctx, bandID)
+	if err != nil {
+		log.DefaultLogger.Error().Int("bandID", bandID).Err(err).Msg("获取乐队成员失败")
+		a.tuiModel.SetError(fmt.Sprintf("获取乐队成员失败: %v", err))
+		a.tuiModel.State = StateInput
+		return true
+	}
+	if len(members) == 0 {
+		log.DefaultLogger.Warn().Int("bandID", bandID).Msg("乐队没有成员")
+		a.tuiModel.SetError(fmt.Sprintf("乐队「%s」没有可选择的角色", bandName))
+		a.tuiModel.State = StateInput
+		return true
+	}
+
+	items := make([]tui.BandMember, 0, len(members))
+	for _, member := range members {
+		name := member.Chara.LocalizedName(displayLocale)
+		if name == "" {
+			name = fmt.Sprintf("角色%d", member.ID)
+		}
+		items = append(items, tui.BandMember{ID: member.ID, Name: name})
+	}
+	a.program.Send(tui.UpdateBandMembersMsg{Band: bandName, Members: items})
+	return true
+}
+
+// handleBrowseCharas 按乐队分组列出全部角色，由用户选择角色后进入服装列表.
+func (a *App) handleBrowseCharas() bool {
+	charas, err := a.apiClient.GetAllCharas(a.ctx)
+	if err != nil {
+		log.DefaultLogger.Error().Err(err).Msg("获取角色列表失败")
+		a.tuiModel.SetError(fmt.Sprintf("获取角色列表失败: %v", err))
+		a.tuiModel.State = StateInput
+		return true
+	}
+
+	// 角色已按乐队排序，相同乐队的角色相邻
+	var groups []tui.CharaGroup
+	for _, chara := range charas {
+		band := chara.BandName.Get(displayLocale)
+		if band == "" {
+			band = "其他"
 		}
-		candidates[charaID] = names
+		if len(groups) == 0 || groups[len(groups)-1].Band != band {
+			groups = append(groups, tui.CharaGroup{Band: band})
+		}
+		name := chara.Name.Get(displayLocale)
+		if name == "" {
+			name = fmt.Sprintf("角色%d", chara.ID)
+		}
+		group := &groups[len(groups)-1]
+		group.Charas = append(group.Charas, tui.BandMember{ID: chara.ID, Name: name})
+	}
+	log.DefaultLogger.Info().Int("charaCount", len(charas)).Int("bandCount", len(groups)).Msg("浏览全部角色")
+	a.program.Send(tui.UpdateCharaBrowserMsg{Groups: groups})
+	return true
+}
+
+// findChara 根据名称搜索角色.
+func (a *App) findChara(name string) (*model.MatchChara, error) {
+	log.DefaultLogger.Info().Str("name", name).Msg("开始搜索角色")
+
+	candidates, err := a.charaCandidates()
+	if err != nil {
+		log.DefaultLogger.Error().Str("name", name).Err(err).Msg("获取角色列表失败")
+		return nil, err
 	}
 
-	bestID, bestMatch, maxSimilarity := matcher.FindBestMatch(name, candidates)
+	bestID, bestMatch, maxSimilarity := a.matchChara(name, candidates)
 	// 设置相似度阈值，用于判断是否为高置信度匹配
 	const similarityThreshold = 0.6
 
@@ -225,16 +786,16 @@ func (a *App) findChara(name string) (*model.MatchChara, error) {
 
 // updateCharaCostumes 更新角色服装列表.
 func (a *App) updateCharaCostumes(id int, firstName string, displayName string) bool {
-	// 获取角色服装列表
-	costumes, err := a.apiClient.GetCharaCostumes(a.ctx, id)
+	// 获取角色服装列表及服装描述
+	infos, err := a.apiClient.GetCharaCostumeInfos(a.ctx, id)
 	if err != nil {
 		log.DefaultLogger.Error().Int("charaID", id).Err(err).Msg("获取角色服装列表失败")
-		a.tuiModel.SetError(fmt.Sprintf("获取角色服装列表失败: %v", err))
+		a.tuiModel.SetError(a.withLayoutHint(fmt.Sprintf("获取角色服装列表失败: %v", err)))
 		a.tuiModel.State = StateInput
 		return true
 	}
 
-	if len(costumes) == 0 {
+	if len(infos) == 0 {
 		log.DefaultLogger.Warn().Int("charaID", id).Msg("未找到该角色的 Live2D 模型")
 		a.tuiModel.SetError("未找到该角色的 Live2D 模型")
 		a.tuiModel.State = StateInput
@@ -245,7 +806,9 @@ func (a *App) updateCharaCostumes(id int, firstName string, displayName string)
 	a.tuiModel.ClearError()
 
 	// 更新列表
+	a.listChara = firstName
 	a.tuiModel.CurrentCharaName = firstName
+	a.tuiModel.CurrentBandName = a.charaBandName(a.ctx, id)
 	if displayName != firstName {
 		a.tuiModel.ExtraCharaName = displayName
 	} else {
@@ -253,13 +816,47 @@ func (a *App) updateCharaCostumes(id int, firstName string, displayName string)
 	}
 	log.DefaultLogger.Info().
 		Str("charaName", firstName).
-		Int("costumesCount", len(costumes)).
+		Int("costumesCount", len(infos)).
 		Msg("找到角色服装列表")
-	a.program.Send(tui.UpdateListMsg{Items: costumes})
+	costumes := make([]string, 0, len(infos))
+	labels := make(map[string]string, len(infos))
+	for _, info := range infos {
+		costumes = append(costumes, info.Name)
+		labels[info.Name] = info.Label()
+	}
+	notes, summary := a.usageNotes(costumes)
+	_, blocked := a.blocklist.Split(costumes)
+	a.program.Send(tui.UpdateListMsg{Items: costumes, Notes: notes, Summary: summary, Labels: labels, Blocked: blocked})
 
 	return true
 }
 
+// usageNotes 返回服装列表的使用统计说明及角色的使用统计汇总.
+func (a *App) usageNotes(costumes []string) (map[string]string, string) {
+	cfg := config.Get()
+	if !cfg.UsageStats {
+		return nil, ""
+	}
+	state, err := stats.Load(cfg.StatePath)
+	if err != nil {
+		log.DefaultLogger.Warn().Err(err).Msg("读取使用统计失败")
+		return nil, ""
+	}
+
+	notes := make(map[string]string)
+	chara := ""
+	for _, costume := range costumes {
+		if usage, ok := state.Usage[costume]; ok {
+			notes[costume] = usage.Label()
+			chara = usage.Chara
+		}
+	}
+	if chara == "" {
+		return notes, ""
+	}
+	return notes, state.CharaUsage(chara).Label()
+}
+
 // handleCharaIDSearch 处理角色编号搜索请求.
 func (a *App) handleCharaIDSearch(charaID string) bool {
 	id, err := strconv.Atoi(charaID)
@@ -284,28 +881,32 @@ func (a *App) getCharaNames(id int) (string, string) {
 		return defaultName, defaultName
 	}
 
-	// 检查角色信息格式
-	characterNames, ok := chara["characterName"].([]any)
-	if !ok || len(characterNames) < 4 {
+	firstName := chara.LocalizedName(model.LocaleJP)
+	if firstName == "" {
 		log.DefaultLogger.Error().Int("charaID", id).Msg("无效的角色名字格式")
 		defaultName := fmt.Sprintf("角色%d", id)
 		return defaultName, defaultName
 	}
 
-	// 检查每个元素是否为字符串
-	firstName, ok := characterNames[0].(string)
-	if !ok {
-		log.DefaultLogger.Error().Int("charaID", id).Msg("角色名字格式错误")
-		defaultName := fmt.Sprintf("角色%d", id)
-		return defaultName, defaultName
-	}
+	// 显示国服的名称，没有时回退到其他服务器的名称
+	displayName := chara.LocalizedName(displayLocale)
+	return firstName, displayName
+}
 
-	displayName, ok := characterNames[3].(string)
-	if !ok || displayName == "" {
-		displayName = firstName
+// charaBandName 获取角色所属乐队的名称，与角色显示名称使用相同的服务器优先顺序
+// 乐队信息只用于显示，获取失败时只记录日志并返回空字符串.
+func (a *App) charaBandName(ctx context.Context, id int) string {
+	chara, err := a.apiClient.GetChara(ctx, id)
+	if err != nil {
+		log.DefaultLogger.Debug().Int("charaID", id).Err(err).Msg("获取角色信息失败，不显示乐队名称")
+		return ""
 	}
-
-	return firstName, displayName
+	band, err := a.apiClient.GetCharaBandName(ctx, chara, displayLocale)
+	if err != nil {
+		log.DefaultLogger.Warn().Int("charaID", id).Err(err).Msg("获取乐队信息失败，不显示乐队名称")
+		return ""
+	}
+	return band
 }
 
 // handleCharaSearch 处理角色搜索请求.
@@ -332,177 +933,610 @@ func (a *App) handleCharaSearch(input string) bool {
 		return true
 	}
 
-	// 使用与 main.go 相同的名称逻辑
-	displayName := matchChara.Names[3]
-	if displayName == "" {
-		displayName = matchChara.Names[0]
-	}
+	// 角色名称列表按服务器排列，可能不完整，缺少国服名称时回退到其他服务器的名称
+	displayName := model.LocalizedStrings(matchChara.Names).Get(displayLocale)
 
 	return a.updateCharaCostumes(matchChara.ID, matchChara.Name, displayName)
 }
 
+// handlePatternSearch 在全部模型中按通配符模式查找，列出匹配的模型供用户确认.
+func (a *App) handlePatternSearch(input string) bool {
+	pattern, err := model.NormalizeLive2dPattern(input)
+	if err != nil {
+		log.DefaultLogger.Error().Str("input", input).Err(err).Msg("无效的通配符模式")
+		a.tuiModel.SetError(err.Error())
+		a.tuiModel.State = StateInput
+		return true
+	}
+
+	matches, err := a.apiClient.MatchLive2dModels(a.ctx, pattern)
+	if err != nil {
+		log.DefaultLogger.Error().Str("pattern", pattern).Err(err).Msg("匹配模型失败")
+		a.tuiModel.SetError(a.withLayoutHint(fmt.Sprintf("匹配模型失败: %v", err)))
+		a.tuiModel.State = StateInput
+		return true
+	}
+
+	matches, excluded := a.opts.exclude.Split(matches)
+	if len(excluded) > 0 {
+		log.DefaultLogger.Info().Str("pattern", pattern).Strs("excluded", excluded).Msg("已排除匹配的模型")
+	}
+
+	limit := config.Get().MaxPatternMatches
+	switch {
+	case len(matches) == 0:
+		log.DefaultLogger.Warn().Str("pattern", pattern).Msg("没有匹配的模型")
+		a.tuiModel.SetError(fmt.Sprintf("没有与 %s 匹配的模型", pattern))
+		a.tuiModel.State = StateInput
+		return true
+	case limit > 0 && len(matches) > limit:
+		log.DefaultLogger.Warn().Str("pattern", pattern).Int("matches", len(matches)).Msg("匹配的模型过多")
+		a.tuiModel.SetError(fmt.Sprintf("%s 匹配到 %d 个模型，超过上限 %d 个，请缩小范围", pattern, len(matches), limit))
+		a.tuiModel.State = StateInput
+		return true
+	}
+
+	a.tuiModel.ClearError()
+	a.listChara = ""
+	a.tuiModel.CurrentCharaName = pattern
+	a.tuiModel.CurrentBandName = ""
+	a.tuiModel.ExtraCharaName = fmt.Sprintf("匹配 %d 个", len(matches))
+	log.DefaultLogger.Info().Str("pattern", pattern).Int("matches", len(matches)).Msg("找到匹配的模型")
+	notes, _ := a.usageNotes(matches)
+	_, blocked := a.blocklist.Split(matches)
+	a.program.Send(tui.UpdateListMsg{Items: matches, Notes: notes, Blocked: blocked})
+	return true
+}
+
 // handleDirectDownload 处理直接下载请求.
 func (a *App) handleDirectDownload(input string) bool {
+	// 直接模型名模式下也支持通配符
+	if model.IsLive2dPattern(input) {
+		return a.handlePatternSearch(input)
+	}
+
 	log.DefaultLogger.Info().Str("input", input).Msg("开始直接下载Live2D")
 
 	// 分割输入字符串，支持空格、中文逗号和英文逗号作为分隔符
 	inputs := strings.FieldsFunc(input, func(r rune) bool {
-		return r == ' ' || r == ',' || r == '，'
+		return unicode.IsSpace(r) || r == ',' || r == '，'
 	})
 
-	// 移除每个模型名可能存在的 _rip 后缀
+	// 规范化每个模型名，去掉路径前缀、_rip 后缀等常见的多余部分
 	modelNames := make([]string, 0, len(inputs))
 	for _, name := range inputs {
-		name = strings.TrimSpace(name)
-		if name == "" {
-			continue
+		normalized, err := model.NormalizeLive2dName(name)
+		if err != nil {
+			log.DefaultLogger.Error().Str("input", name).Err(err).Msg("无效的模型名称")
+			a.fail(err.Error())
+			return true
 		}
-		modelNames = append(modelNames, strings.TrimSuffix(name, "_rip"))
-	}
-
-	if len(modelNames) == 0 {
-		log.DefaultLogger.Error().Str("input", input).Msg("没有有效的模型名称")
-		a.tuiModel.SetError("没有有效的模型名称")
-		a.tuiModel.State = StateInput
-		return true
+		modelNames = append(modelNames, normalized)
 	}
 
-	// 验证所有模型是否存在
-	var invalidModels []string
-	for _, name := range modelNames {
-		exists, err := a.apiClient.ValidateLive2dModel(a.ctx, name)
-		if err != nil {
-			log.DefaultLogger.Error().Str("model", name).Err(err).Msg("验证模型失败")
-			a.tuiModel.SetError(fmt.Sprintf("验证模型失败: %v", err))
-			a.tuiModel.State = StateInput
+	// 直接下载屏蔽的服装时先提示，再次输入相同内容时确认下载；不使用 TUI 时视为已确认
+	if _, blocked := a.blocklist.Split(modelNames); len(blocked) > 0 && a.blockedConfirm != input {
+		log.DefaultLogger.Warn().Strs("models", blocked).Msg("要下载的服装在屏蔽列表中")
+		if a.tuiModel != nil {
+			a.blockedConfirm = input
+			a.fail(fmt.Sprintf("%s 在屏蔽列表中，可能无法下载，再次按 Enter 确认下载", strings.Join(blocked, ", ")))
 			return true
 		}
-		if !exists {
-			invalidModels = append(invalidModels, name)
-		}
 	}
+	a.blockedConfirm = ""
 
-	// 如果有无效的模型，显示错误信息
-	if len(invalidModels) > 0 {
-		errorMsg := fmt.Sprintf("以下模型不存在: %s", strings.Join(invalidModels, ", "))
-		log.DefaultLogger.Error().Strs("invalidModels", invalidModels).Msg("发现无效的模型名称")
-		a.tuiModel.SetError(errorMsg)
-		a.tuiModel.State = StateInput
+	return a.startDownload(modelNames)
+}
+
+// handleListFile 下载模型列表文件中的模型.
+func (a *App) handleListFile(listFile string) bool {
+	log.DefaultLogger.Info().Str("listFile", listFile).Msg("开始下载模型列表")
+
+	file, err := os.Open(listFile)
+	if err != nil {
+		log.DefaultLogger.Error().Str("listFile", listFile).Err(err).Msg("打开模型列表失败")
+		a.fail(fmt.Sprintf("打开模型列表失败: %v", err))
 		return true
 	}
+	defer file.Close()
 
-	a.tuiModel.State = "downloading"
-	a.tuiModel.DownloadList.Title = "下载进度"
+	entries, err := downloader.ParseModelList(file)
+	if err != nil {
+		log.DefaultLogger.Error().Str("listFile", listFile).Err(err).Msg("解析模型列表失败")
+		a.fail(fmt.Sprintf("解析模型列表失败: %v", err))
+		return true
+	}
 
-	// 使用批量下载功能处理多个模型
-	return a.handleBatchDownload(modelNames)
+	return a.startDownload(a.applyEntries(entries))
+}
+
+// applyEntries 记录模型列表中单独指定的保存路径并返回模型名称列表
+// 与排除模式匹配的模型会被去掉，屏蔽的服装视为用户明确指定，只记录警告.
+func (a *App) applyEntries(entries []downloader.ModelListEntry) []string {
+	modelNames := make([]string, 0, len(entries))
+	a.outputPaths = make(map[string]string)
+	for _, entry := range entries {
+		if a.opts.exclude.Match(entry.Name) {
+			log.DefaultLogger.Info().Str("model", entry.Name).Msg("已排除模型")
+			continue
+		}
+		if a.blocklist.Match(entry.Name) {
+			log.DefaultLogger.Warn().Str("model", entry.Name).Msg("模型列表中的服装在屏蔽列表中，仍然下载")
+		}
+		modelNames = append(modelNames, entry.Name)
+		if entry.OutputPath != "" {
+			a.outputPaths[entry.Name] = entry.OutputPath
+		}
+	}
+	return modelNames
+}
+
+// handleTaskFile 按任务文件中的服务器、模型列表和下载选项开始下载.
+func (a *App) handleTaskFile(taskFile string) bool {
+	log.DefaultLogger.Info().Str("taskFile", taskFile).Msg("开始执行任务文件")
+
+	task, err := downloader.ReadTaskFile(taskFile)
+	if err != nil {
+		log.DefaultLogger.Error().Str("taskFile", taskFile).Err(err).Msg("读取任务文件失败")
+		a.fail(fmt.Sprintf("读取任务文件失败: %v", err))
+		return true
+	}
+
+	cfg := config.Get()
+	if applyErr := task.Apply(cfg); applyErr != nil {
+		log.DefaultLogger.Error().Str("taskFile", taskFile).Err(applyErr).Msg("应用任务文件失败")
+		a.fail(fmt.Sprintf("应用任务文件失败: %v", applyErr))
+		return true
+	}
+	if a.tuiModel != nil {
+		a.tuiModel.FailFast = task.Options.FailFast
+	}
+
+	log.DefaultLogger.Info().
+		Str("server", task.Server).
+		Int("formatVersion", task.FormatVersion).
+		Str("appVersion", task.AppVersion).
+		Int("models", len(task.Models)).
+		Msg("已加载任务文件")
+	return a.startDownload(a.applyEntries(task.Models))
+}
+
+// exportSelection 将选中的服装导出为可分享的选择文件.
+func (a *App) exportSelection(selectedItems []string) {
+	cfg := config.Get()
+	selection := downloader.NewSelectionFile(cfg.AssetsServer(), a.listChara, selectedItems)
+	if err := downloader.WriteSelectionFile(cfg.SelectionPath, selection); err != nil {
+		log.DefaultLogger.Error().Str("selectionFile", cfg.SelectionPath).Err(err).Msg("导出选择文件失败")
+		a.tuiModel.SetError(fmt.Sprintf("导出选择文件失败: %v", err))
+		return
+	}
+	log.DefaultLogger.Info().Str("selectionFile", cfg.SelectionPath).Int("costumes", len(selectedItems)).Msg("已导出选择文件")
+	a.tuiModel.SetError(fmt.Sprintf("已将 %d 个服装导出到 %s，可使用 %s %s 导入",
+		len(selectedItems), cfg.SelectionPath, importSelectionCommand, cfg.SelectionPath))
+}
+
+// handleImportSelection 导入选择文件
+// 已不存在的服装会报告给用户，其余服装在列表中预先选中，yes 为 true 时直接开始下载.
+func (a *App) handleImportSelection(selectionFile string, yes bool) bool {
+	log.DefaultLogger.Info().Str("selectionFile", selectionFile).Msg("开始导入选择文件")
+
+	selection, err := downloader.ReadSelectionFile(selectionFile)
+	if err != nil {
+		log.DefaultLogger.Error().Str("selectionFile", selectionFile).Err(err).Msg("读取选择文件失败")
+		a.fail(fmt.Sprintf("读取选择文件失败: %v", err))
+		return true
+	}
+	log.DefaultLogger.Info().
+		Str("server", selection.Server).
+		Int("formatVersion", selection.FormatVersion).
+		Str("appVersion", selection.AppVersion).
+		Int("costumes", len(selection.Costumes)).
+		Msg("已加载选择文件")
+
+	found, unknown, err := selection.Resolve(func(costume string) (bool, error) {
+		return a.apiClient.ValidateLive2dModel(a.ctx, costume)
+	})
+	if err != nil {
+		log.DefaultLogger.Error().Str("selectionFile", selectionFile).Err(err).Msg("验证选择文件中的服装失败")
+		a.fail(a.withLayoutHint(fmt.Sprintf("验证选择文件中的服装失败: %v", err)))
+		return true
+	}
+
+	var notice string
+	if len(unknown) > 0 {
+		log.DefaultLogger.Warn().Strs("costumes", unknown).Msg("选择文件中的部分服装已不存在")
+		notice = fmt.Sprintf("选择文件中有 %d 个服装已不存在或已改名: %s", len(unknown), strings.Join(unknown, ", "))
+	}
+	if len(found) == 0 {
+		a.fail(notice)
+		return true
+	}
+
+	if yes {
+		if notice != "" {
+			a.progress.SetError(notice)
+		}
+		a.outputPaths = nil
+		return a.startDownload(found)
+	}
+
+	a.tuiModel.ClearError()
+	if notice != "" {
+		a.tuiModel.SetError(notice)
+	}
+	a.listChara = ""
+	a.tuiModel.CurrentCharaName = filepath.Base(selectionFile)
+	a.tuiModel.CurrentBandName = ""
+	a.tuiModel.ExtraCharaName = fmt.Sprintf("已选择 %d 个", len(found))
+	notes, _ := a.usageNotes(found)
+	a.program.Send(tui.UpdateListMsg{Items: found, Notes: notes, Selected: found})
+	return true
+}
+
+// exportTask 将选中的模型及当前下载选项导出为任务文件.
+func (a *App) exportTask(selectedItems []string) {
+	cfg := config.Get()
+	entries := make([]downloader.ModelListEntry, 0, len(selectedItems))
+	for _, name := range selectedItems {
+		entries = append(entries, downloader.ModelListEntry{Name: name, OutputPath: a.outputPaths[name]})
+	}
+
+	task := downloader.NewTaskFile(cfg, entries)
+	task.Options.FailFast = a.tuiModel.FailFast
+	if err := downloader.WriteTaskFile(cfg.TaskExportPath, task); err != nil {
+		log.DefaultLogger.Error().Str("taskFile", cfg.TaskExportPath).Err(err).Msg("导出任务文件失败")
+		a.tuiModel.SetError(fmt.Sprintf("导出任务文件失败: %v", err))
+		return
+	}
+	log.DefaultLogger.Info().Str("taskFile", cfg.TaskExportPath).Int("models", len(entries)).Msg("已导出任务文件")
+	a.tuiModel.SetError(fmt.Sprintf("已将 %d 个模型导出到 %s，可使用 -task %s 执行", len(entries), cfg.TaskExportPath, cfg.TaskExportPath))
+}
+
+// startDownload 验证模型是否存在并开始批量下载.
+func (a *App) startDownload(modelNames []string) bool {
+	if len(modelNames) == 0 {
+		log.DefaultLogger.Error().Msg("没有有效的模型名称")
+		a.fail("没有有效的模型名称")
+		return true
+	}
+
+	// 验证所有模型是否存在
+	var invalidModels []string
+	for _, name := range modelNames {
+		exists, err := a.apiClient.ValidateLive2dModel(a.ctx, name)
+		if err != nil {
+			log.DefaultLogger.Error().Str("model", name).Err(err).Msg("验证模型失败")
+			a.fail(a.withLayoutHint(fmt.Sprintf("验证模型失败: %v", err)))
+			return true
+		}
+		if !exists {
+			invalidModels = append(invalidModels, name)
+		}
+	}
+
+	// 如果有无效的模型，显示错误信息
+	if len(invalidModels) > 0 {
+		errorMsg := fmt.Sprintf("以下%s: %s", errs.ErrModelNotFound, strings.Join(invalidModels, ", "))
+		log.DefaultLogger.Error().Strs("invalidModels", invalidModels).Msg("发现无效的模型名称")
+		a.fail(errorMsg)
+		return true
+	}
+
+	a.notifyPreviousDownloads(modelNames)
+
+	if a.tuiModel != nil {
+		a.tuiModel.State = "downloading"
+		a.tuiModel.DownloadList.Title = "下载进度"
+	}
+
+	// 使用批量下载功能处理多个模型
+	return a.handleBatchDownload(modelNames)
+}
+
+// fail 报告使本次操作无法继续的错误并回到输入状态
+// 不使用 TUI 时没有可以重新输入的界面，将退出码设为 1.
+func (a *App) fail(message string) {
+	a.progress.SetError(message)
+	if a.tuiModel == nil {
+		a.exitCode = 1
+		return
+	}
+	a.tuiModel.State = StateInput
+}
+
+// failFast 返回批量下载是否在第一个模型失败后中止，使用 TUI 时以界面中的开关为准.
+func (a *App) failFast() bool {
+	if a.tuiModel != nil {
+		return a.tuiModel.FailFast
+	}
+	return config.Get().BatchFailFast
+}
+
+// forwardLogLines 将最近的日志行转发到 TUI 的日志面板.
+func (a *App) forwardLogLines() {
+	tail := log.DefaultLogger.Tail()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-tail.Notify():
+			a.tuiModel.SendLogLines(tail.Lines())
+		}
+	}
+}
+
+// historyPath 返回下载历史文件路径.
+func historyPath() string {
+	return filepath.Join(config.Get().CharaCachePath, history.FileName)
+}
+
+// notifyPreviousDownloads 提示用户哪些模型之前已经下载过.
+func (a *App) notifyPreviousDownloads(modelNames []string) {
+	if config.Get().HistoryMaxEntries <= 0 {
+		return
+	}
+	h, err := history.Load(historyPath())
+	if err != nil {
+		log.DefaultLogger.Warn().Err(err).Msg("读取下载历史失败")
+		return
+	}
+
+	var notes []string
+	for _, name := range modelNames {
+		if entry, ok := h.LastSuccess(name); ok {
+			notes = append(notes, fmt.Sprintf("你在 %s 从 %s 服下载过 %s",
+				entry.Time.Local().Format(time.DateTime), entry.Server, name))
+		}
+	}
+	if len(notes) > 0 {
+		a.progress.SetError(strings.Join(notes, "\n"))
+	}
+}
+
+// recordHistory 将模型的下载结果记录到下载历史.
+func (a *App) recordHistory(live2dName string, downloadErr error) {
+	cfg := config.Get()
+	if cfg.HistoryMaxEntries <= 0 {
+		return
+	}
+	entry := history.Entry{
+		Model:   live2dName,
+		Server:  cfg.AssetsServer(),
+		Success: downloadErr == nil,
+		Time:    time.Now(),
+	}
+	if downloadErr != nil {
+		entry.Error = downloadErr.Error()
+	}
+	if err := history.Append(historyPath(), entry, cfg.HistoryMaxEntries); err != nil {
+		log.DefaultLogger.Warn().Str("live2dName", live2dName).Err(err).Msg("记录下载历史失败")
+	}
 }
 
 // handleDownload 处理下载请求.
 func (a *App) handleDownload(input string) bool {
+	// 交互输入的下载不使用模型列表中单独指定的保存路径
+	a.outputPaths = nil
+
+	if file, ok := strings.CutPrefix(input, importSelectionCommand+" "); ok {
+		return a.handleImportSelection(strings.TrimSpace(file), false)
+	}
+
+	// 输入为空时浏览全部角色
+	if input == "" {
+		return a.handleBrowseCharas()
+	}
+
 	// 检查是否为纯数字
 	if _, err := strconv.Atoi(input); err == nil {
 		// 如果是纯数字，直接搜索该编号的角色
 		return a.handleCharaIDSearch(input)
 	}
 
+	// 包含通配符时在全部模型中匹配
+	if model.IsLive2dPattern(input) {
+		return a.handlePatternSearch(input)
+	}
+
 	// 先尝试作为 Live2D 模型名称处理
-	parts := strings.SplitN(input, "_", SplitPartsCount)
-	if len(parts) >= 2 {
-		if _, err := strconv.Atoi(parts[0]); err == nil {
-			return a.handleDirectDownload(input)
-		}
+	if model.LooksLikeLive2dName(input) {
+		return a.handleDirectDownload(input)
+	}
+
+	// 乐队名称比角色名称更匹配时列出乐队成员
+	if bandID, bandName, ok := a.findBand(input); ok {
+		return a.handleBandSearch(bandID, bandName)
 	}
 
 	// 如果不是模型名称，则尝试角色搜索
 	return a.handleCharaSearch(input)
 }
 
-// downloadModel 下载单个模型.
-func (a *App) downloadModel(
-	costume string,
-	errChan chan error,
-	completed map[string]bool,
-	progressUpdated chan struct{},
-) {
-	if err := a.downloadLive2d(costume); err != nil {
-		if err.Error() == ErrDownloadCancelled {
-			errChan <- err
-			return
-		}
-		log.DefaultLogger.Error().Str("model", costume).Err(err).Msg("下载失败")
-	} else {
-		completed[costume] = true
-	}
-	// 无论成功还是失败，都更新总体进度
-	a.tuiModel.UpdateTotalProgress()
-	// 通知进度已更新
-	select {
-	case progressUpdated <- struct{}{}:
-	default:
-	}
-}
-
 // handleBatchDownload 处理批量下载请求.
 func (a *App) handleBatchDownload(selectedItems []string) bool {
 	if len(selectedItems) == 0 {
 		return true
 	}
 
-	log.DefaultLogger.Info().Int("selectedCount", len(selectedItems)).Msg("开始批量下载Live2D")
+	// 下载模型文件必须联网，离线时只能浏览和准备下载清单
+	if config.Get().Offline {
+		log.DefaultLogger.Warn().Strs("models", selectedItems).Msg("离线模式下无法下载模型")
+		a.fail(fmt.Sprintf("离线模式下无法下载模型，请去掉 -offline 后重试: %s", strings.Join(selectedItems, ", ")))
+		return true
+	}
 
-	// 设置总体进度
-	a.tuiModel.SetTotalModels(len(selectedItems))
+	failFast := a.failFast()
+	log.DefaultLogger.Info().
+		Int("selectedCount", len(selectedItems)).
+		Bool("failFast", failFast).
+		Msg("开始批量下载Live2D")
+
+	// 设置总体进度，并在文件总数确定前先登记各模型的下载项
+	a.progress.SetTotalModels(len(selectedItems))
+	for _, name := range selectedItems {
+		a.dl.EnsureItem(name, 0)
+	}
 
-	errChan := make(chan error, 1)
-	completed := make(map[string]bool)
-	modelSem := make(chan struct{}, config.Get().MaxConcurrentModels)
-	progressUpdated := make(chan struct{}, 1) // 用于通知进度已更新
+	// 估算所需空间，避免下载到一半磁盘已满
+	a.prefetchBuildData(selectedItems)
+	a.estimateSpace(selectedItems)
+
+	// 按配置的调度方式安排开始顺序，避免小模型排在大模型之后长时间等待
+	order := downloader.ScheduleModels(selectedItems, a.buildData, config.Get().ModelScheduling)
+
+	// 预热连接，减少批量开始时的突发建连
+	a.dl.WarmupConnections(a.ctx)
+
+	// 下载期间监控剩余空间，空间不足时暂停
+	monitorCtx, stopMonitor := context.WithCancel(a.ctx)
+	defer stopMonitor()
+	go a.dl.MonitorDiskSpace(monitorCtx)
+
+	result, err := downloader.RunBatch(
+		a.ctx,
+		order,
+		config.Get().MaxConcurrentModels,
+		failFast,
+		func(ctx context.Context, costume string) error {
+			modelPath, downloadErr := a.downloadLive2d(ctx, costume)
+			if downloadErr == nil && a.dl.FinalizationHook != nil {
+				downloadErr = a.dl.FinalizationHook(modelPath)
+			}
+			if downloadErr != nil && errs.IsCancelled(downloadErr) {
+				return downloadErr
+			}
+			a.recordHistory(costume, downloadErr)
+			if downloadErr != nil {
+				log.DefaultLogger.Error().Str("model", costume).Err(downloadErr).Msg("下载失败")
+			}
+			// 无论成功还是失败，都更新总体进度
+			a.progress.UpdateTotalProgress()
+			return downloadErr
+		},
+	)
+
+	for _, name := range result.Skipped {
+		log.DefaultLogger.Warn().Str("model", name).Msg("已跳过（快速失败）")
+		a.progress.SendError(name, errs.ErrSkippedFailFast)
+	}
+	a.succeeded += len(result.Completed)
 
-	for _, costume := range selectedItems {
-		select {
-		case <-a.ctx.Done():
-			a.handleCancelledDownloads(selectedItems, completed)
-			return false
-		case err := <-errChan:
-			if err.Error() == ErrDownloadCancelled {
-				a.handleCancelledDownloads(selectedItems, completed)
-				return false
+	switch {
+	case errs.IsCancelled(err):
+		for _, item := range selectedItems {
+			if !slices.Contains(result.Completed, item) {
+				log.DefaultLogger.Error().Str("model", item).Msg("下载已取消")
 			}
-			log.DefaultLogger.Error().Err(err).Msg("下载失败")
-			continue
-		default:
-			modelSem <- struct{}{}
-			go func(costume string) {
-				defer func() { <-modelSem }()
-				a.downloadModel(costume, errChan, completed, progressUpdated)
-			}(costume)
 		}
+		return false
+	case errors.Is(err, errs.ErrBatchAborted):
+		a.exitCode = 1
+		log.DefaultLogger.Error().Err(err).Int("skipped", len(result.Skipped)).Msg("批量下载已中止")
+		a.progress.SetError(fmt.Sprintf("%v（已跳过 %d 个模型）", err, len(result.Skipped)))
+		return true
 	}
 
-	for range cap(modelSem) {
-		modelSem <- struct{}{}
+	log.DefaultLogger.Info().
+		Int("completed", len(result.Completed)).
+		Int("failed", len(result.Failed)).
+		Msg("批量下载完成")
+	if len(result.Failed) > 0 && a.tuiModel == nil {
+		a.exitCode = 1
+	}
+	if layoutErr := a.apiClient.LayoutChange(); layoutErr != nil {
+		a.progress.SetError(layoutErr.Error())
 	}
-	log.DefaultLogger.Info().Msg("批量下载完成")
 	return true
 }
 
-// handleCancelledDownloads 处理已取消的下载.
-func (a *App) handleCancelledDownloads(selectedItems []string, completed map[string]bool) {
-	for _, item := range selectedItems {
-		if !completed[item] {
-			log.DefaultLogger.Error().Str("model", item).Msg("下载已取消")
-			// 注意：总体进度已经在downloadModel中更新，这里不需要重复更新
+// prefetchBuildData 在批量下载开始前获取构建数据，用于估算所需空间与安排下载顺序
+// 获取到的构建数据会保留给本次批量下载使用，获取失败的模型留到下载时重新获取并报告错误.
+func (a *App) prefetchBuildData(selectedItems []string) {
+	a.buildData = make(map[string]*model.BuildData, len(selectedItems))
+	cfg := config.Get()
+	if !cfg.EstimateDiskSpace && cfg.ModelScheduling != model.ModelSchedulingFewestFiles {
+		return
+	}
+	if cfg.EstimateDiskSpace {
+		a.progress.SendSpaceEstimate("正在估算所需空间...", false)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(cfg.MaxConcurrentModels, 1))
+	for _, name := range selectedItems {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			data, err := a.apiClient.GetLive2dData(a.ctx, name)
+			if err != nil {
+				log.DefaultLogger.Warn().Str("live2dName", name).Err(err).Msg("预先获取Live2D数据失败，下载时重新获取")
+				return
+			}
+			mu.Lock()
+			a.buildData[name] = data
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+// estimateSpace 根据预先获取的构建数据估算所需空间并显示在下载界面.
+func (a *App) estimateSpace(selectedItems []string) {
+	cfg := config.Get()
+	if !cfg.EstimateDiskSpace {
+		a.progress.SendSpaceEstimate("", false)
+		return
+	}
+
+	models := make([]*model.BuildData, 0, len(a.buildData))
+	for _, name := range selectedItems {
+		if data, ok := a.buildData[name]; ok {
+			models = append(models, data)
 		}
 	}
+	if len(models) == 0 {
+		a.progress.SendSpaceEstimate("", false)
+		return
+	}
+	estimate := a.dl.EstimateSpace(a.ctx, models)
+	insufficient := !estimate.Sufficient(cfg.DiskSafetyMargin)
+	log.DefaultLogger.Info().
+		Int("files", estimate.Files).
+		Int("sizedFiles", estimate.SizedFiles).
+		Uint64("bytes", estimate.Bytes).
+		Uint64("free", estimate.Free).
+		Bool("insufficient", insufficient).
+		Msg("已估算所需空间")
+	a.progress.SendSpaceEstimate(estimate.String(), insufficient)
+}
+
+// withLayoutHint 在检测到 Bestdori 资源结构可能变化时为错误信息附加提示
+// 帮助用户区分上游变动与自身问题.
+func (a *App) withLayoutHint(msg string) string {
+	if err := a.apiClient.LayoutChange(); err != nil {
+		return msg + "\n" + err.Error()
+	}
+	return msg
 }
 
-// Run 运行应用程序.
-func (a *App) Run() {
+// Run 运行应用程序
+// 返回程序的退出码.
+func (a *App) Run() int {
 	a.initialize()
 	log.DefaultLogger.Info().Msg("程序启动")
 	defer a.cancel()
 
+	if a.rpcServer != nil {
+		return a.runRPC()
+	}
+	if a.tuiModel == nil {
+		return a.runHeadless()
+	}
+
 	// 启动 TUI
 	go func() {
 		if _, err := a.program.Run(); err != nil {
@@ -511,34 +1545,666 @@ func (a *App) Run() {
 		}
 	}()
 
+	// 启动时指定了模型列表则直接开始下载
+	if a.opts.listFile != "" && !a.handleListFile(a.opts.listFile) {
+		return a.exitCode
+	}
+
+	// 启动时指定了任务文件则按任务文件下载
+	if a.opts.taskFile != "" && !a.handleTaskFile(a.opts.taskFile) {
+		return a.exitCode
+	}
+
+	// 启动时指定了选择文件则导入其中的服装
+	if a.opts.selection != "" && !a.handleImportSelection(a.opts.selection, a.opts.yes) {
+		return a.exitCode
+	}
+
 	// 处理用户输入和下载
 	for {
 		select {
 		case <-a.ctx.Done():
 			log.DefaultLogger.Info().Msg("程序正常退出")
-			return
+			return a.exitCode
 		case <-a.tuiModel.GetCancelChan():
+			log.DefaultLogger.Info().Err(context.Cause(a.tuiModel.Ctx)).Msg("程序已退出")
 			a.cancel()
-			return
+			return a.exitCode
 		case input := <-a.tuiModel.GetSearchChan():
 			if input == "q" {
 				a.cancel()
-				return
+				return a.exitCode
 			}
 
 			if !a.handleDownload(input) {
-				return
+				return a.exitCode
+			}
+		case input := <-a.tuiModel.GetModelChan():
+			// 直接模型名模式跳过角色搜索
+			a.outputPaths = nil
+			if !a.handleDirectDownload(input) {
+				return a.exitCode
 			}
 		case selectedItems := <-a.tuiModel.GetSelectChan():
 			if !a.handleBatchDownload(selectedItems) {
-				return
+				return a.exitCode
 			}
+		case selectedItems := <-a.tuiModel.GetExportChan():
+			a.exportTask(selectedItems)
+		case selectedItems := <-a.tuiModel.GetShareChan():
+			a.exportSelection(selectedItems)
 		}
 	}
 }
 
+// runCommand 处理命令行子命令
+// 返回退出码以及是否已处理该子命令.
+func runCommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+
+	switch args[0] {
+	case "provenance":
+		return runProvenance(args[1:]), true
+	case "export-settings":
+		return runExportSettings(args[1:]), true
+	case "import-settings":
+		return runImportSettings(args[1:]), true
+	case importSelectionCommand:
+		return runImportSelection(args[1:]), true
+	default:
+		return 0, false
+	}
+}
+
+// runImportSelection 启动程序并导入选择文件.
+func runImportSelection(args []string) int {
+	fs := flag.NewFlagSet(importSelectionCommand, flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "不经确认直接下载选择文件中的服装")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "用法: bestdori-live2d-downloader import-selection [-yes] <选择文件>")
+		return 1
+	}
+	return NewApp(cliOptions{selection: fs.Arg(0), yes: *yes}).Run()
+}
+
+// runHeadless 在不使用 TUI 时依次下载命令行、模型列表、任务文件与选择文件中的模型后退出
+// 选择文件中的服装不经确认直接下载，没有指定任何要下载的内容时返回错误
+// 全部成功时退出码为 0，部分失败时为 1，没有任何模型下载成功时为 2.
+func (a *App) runHeadless() int {
+	if len(a.opts.models) == 0 && a.opts.listFile == "" && a.opts.taskFile == "" && a.opts.selection == "" {
+		fmt.Fprintln(os.Stderr, "不使用 TUI 时需要通过 -model、-list、-task 或 import-selection 指定要下载的模型")
+		return 1
+	}
+
+	// 没有 TUI 处理按键，收到中断信号时取消下载
+	signalCtx, stop := signal.NotifyContext(a.ctx, os.Interrupt)
+	defer stop()
+	context.AfterFunc(signalCtx, a.cancel)
+
+	ok := len(a.opts.models) == 0 || a.handleDirectDownload(strings.Join(a.opts.models, ","))
+	ok = ok && (a.opts.listFile == "" || a.handleListFile(a.opts.listFile))
+	ok = ok && (a.opts.taskFile == "" || a.handleTaskFile(a.opts.taskFile))
+	if ok && a.opts.selection != "" {
+		a.handleImportSelection(a.opts.selection, true)
+	}
+	switch {
+	case a.ctx.Err() != nil:
+		a.exitCode = max(a.exitCode, 1)
+	case a.exitCode != 0 && a.succeeded == 0:
+		a.exitCode = exitAllFailed
+	}
+	log.DefaultLogger.Info().Int("exitCode", a.exitCode).Msg("程序已退出")
+	return a.exitCode
+}
+
+// runRPC 从标准输入读取命令并执行，输入结束或收到中断信号后退出
+// 命令与事件的格式见 rpc 包，读取输入失败时退出码为 1.
+func (a *App) runRPC() int {
+	// 没有 TUI 处理按键，收到中断信号时取消所有命令
+	signalCtx, stop := signal.NotifyContext(a.ctx, os.Interrupt)
+	defer stop()
+	context.AfterFunc(signalCtx, a.cancel)
+
+	if err := a.rpcServer.Serve(a.ctx, os.Stdin, rpcHandler{app: a}); err != nil {
+		log.DefaultLogger.Error().Err(err).Msg("读取命令失败")
+		a.exitCode = 1
+	}
+	log.DefaultLogger.Info().Int("exitCode", a.exitCode).Msg("程序已退出")
+	return a.exitCode
+}
+
+// rpcHandler 在 RPC 模式下执行下载与搜索命令.
+type rpcHandler struct {
+	app *App
+}
+
+// Download 下载命令中的模型，单个模型失败不影响其他模型
+// 模型名称按直接下载的规则规范化，屏蔽列表中的服装不经确认直接下载.
+func (h rpcHandler) Download(ctx context.Context, models []string, report func(name string, err error)) error {
+	a := h.app
+	names := make([]string, 0, len(models))
+	for _, name := range models {
+		normalized, err := model.NormalizeLive2dName(name)
+		if err != nil {
+			log.DefaultLogger.Error().Str("input", name).Err(err).Msg("无效的模型名称")
+			report(name, err)
+			continue
+		}
+		names = append(names, normalized)
+	}
+
+	_, err := downloader.RunBatch(ctx, names, config.Get().MaxConcurrentModels, false,
+		func(ctx context.Context, costume string) error {
+			modelPath, downloadErr := a.downloadLive2d(ctx, costume)
+			if downloadErr == nil && a.dl.FinalizationHook != nil {
+				downloadErr = a.dl.FinalizationHook(modelPath)
+			}
+			if downloadErr != nil && errs.IsCancelled(downloadErr) {
+				return downloadErr
+			}
+			a.recordHistory(costume, downloadErr)
+			if downloadErr != nil {
+				log.DefaultLogger.Error().Str("model", costume).Err(downloadErr).Msg("下载失败")
+			}
+			report(costume, downloadErr)
+			return downloadErr
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("批量下载失败: %w", err)
+	}
+	return nil
+}
+
+// Search 按名称搜索角色，返回最匹配的角色及其服装
+// 相似度不足时返回包含建议名称的错误.
+func (h rpcHandler) Search(ctx context.Context, query string) ([]rpc.SearchResult, error) {
+	a := h.app
+	matchChara, err := a.findChara(query)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := a.apiClient.GetCharaCostumeInfos(ctx, matchChara.ID)
+	if err != nil {
+		log.DefaultLogger.Error().Int("charaID", matchChara.ID).Err(err).Msg("获取角色服装列表失败")
+		return nil, fmt.Errorf("获取角色服装列表失败: %w", err)
+	}
+	costumes := make([]rpc.Costume, 0, len(infos))
+	for _, info := range infos {
+		costumes = append(costumes, rpc.Costume{Name: info.Name, Title: info.Description})
+	}
+
+	name := model.LocalizedStrings(matchChara.Names).Get(displayLocale)
+	if name == "" {
+		name = matchChara.Name
+	}
+	return []rpc.SearchResult{{CharaID: matchChara.ID, Name: name, Costumes: costumes}}, nil
+}
+
+// runProvenance 打印模型目录中指定文件的来源信息.
+func runProvenance(args []string) int {
+	if len(args) != SplitPartsCount {
+		fmt.Fprintln(os.Stderr, "用法: bestdori-live2d-downloader provenance <模型目录> <文件>")
+		return 1
+	}
+
+	modelPath, file := args[0], args[1]
+	manifest, err := downloader.LoadManifest(modelPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	// 同时支持相对于模型目录的路径和包含模型目录的路径
+	relPath := file
+	if rel, relErr := filepath.Rel(modelPath, file); relErr == nil && !strings.HasPrefix(rel, "..") {
+		relPath = rel
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	entry, ok := manifest.Files[relPath]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "下载清单中没有文件: %s\n", relPath)
+		return 1
+	}
+
+	provenance := entry.Provenance
+	fmt.Fprintf(os.Stdout, "文件: %s\n", relPath)
+	fmt.Fprintf(os.Stdout, "来源: %s\n", provenance.Source)
+	if provenance.Host != "" {
+		fmt.Fprintf(os.Stdout, "主机: %s\n", provenance.Host)
+	}
+	if provenance.URL != "" {
+		fmt.Fprintf(os.Stdout, "URL: %s\n", provenance.URL)
+	}
+	if provenance.Recovery != "" {
+		fmt.Fprintf(os.Stdout, "校验失败后的下载方式: %s\n", provenance.Recovery)
+	}
+	if !provenance.DownloadedAt.IsZero() {
+		fmt.Fprintf(os.Stdout, "下载时间: %s\n", provenance.DownloadedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(os.Stdout, "大小: %d 字节\n", provenance.Size)
+	return 0
+}
+
+// settingsItems 返回可以在机器之间迁移的设置内容
+// 下载历史保存在缓存目录中，但不属于缓存，不带缓存导出时也需要迁移；
+// 下载历史排在缓存目录之前，导入时先备份其中的文件，再备份整个目录.
+func settingsItems(withCache bool) []settings.Item {
+	items := []settings.Item{
+		{Name: "config", Path: config.FileName},
+		{Name: "state", Path: config.Get().StatePath},
+		{Name: "history", Path: historyPath()},
+	}
+	if withCache {
+		items = append(items, settings.Item{Name: "chara_cache", Path: config.Get().CharaCachePath})
+	}
+	return items
+}
+
+// runExportSettings 导出设置包.
+func runExportSettings(args []string) int {
+	fs := flag.NewFlagSet("export-settings", flag.ContinueOnError)
+	withCache := fs.Bool("with-cache", false, "同时导出角色信息缓存")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "用法: bestdori-live2d-downloader export-settings [-with-cache] <文件.tar.gz>")
+		return 1
+	}
+
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	manifest, err := settings.Export(fs.Arg(0), settingsItems(*withCache), version.GetVersion())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导出设置失败: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "已导出设置到 %s，包含: %s\n", fs.Arg(0), strings.Join(manifest.Items, ", "))
+	return 0
+}
+
+// runImportSettings 导入设置包，覆盖前会请求确认并备份本地内容.
+func runImportSettings(args []string) int {
+	fs := flag.NewFlagSet("import-settings", flag.ContinueOnError)
+	yes := fs.Bool("y", false, "不询问直接导入")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "用法: bestdori-live2d-downloader import-settings [-y] <文件.tar.gz>")
+		return 1
+	}
+
+	if err := config.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	bundlePath := fs.Arg(0)
+	manifest, err := settings.ReadManifest(bundlePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取设置包失败: %v\n", err)
+		return 1
+	}
+
+	items := settingsItems(slices.Contains(manifest.Items, "chara_cache"))
+	fmt.Fprintf(os.Stdout, "设置包版本 %d，导出自 %s（%s）\n",
+		manifest.FormatVersion, manifest.AppVersion, manifest.CreatedAt.Format(time.RFC3339))
+	for _, item := range settings.Conflicts(manifest, items) {
+		fmt.Fprintf(os.Stdout, "将覆盖 %s（会先备份）\n", item.Path)
+	}
+
+	if !*yes {
+		fmt.Fprint(os.Stdout, "确认导入？[y/N] ")
+		reply, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if !strings.EqualFold(strings.TrimSpace(reply), "y") {
+			fmt.Fprintln(os.Stdout, "已取消导入")
+			return 1
+		}
+	}
+
+	backups, err := settings.Import(bundlePath, items)
+	for _, backup := range backups {
+		fmt.Fprintf(os.Stdout, "已备份到 %s\n", backup)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导入设置失败: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(os.Stdout, "导入完成")
+	return 0
+}
+
+// runPack 将模型目录打包为分发包.
+func runPack(modelPath, packPath string) int {
+	if packPath == "" {
+		packPath = filepath.Clean(modelPath) + pack.Extension
+	}
+	meta, err := pack.Create(context.Background(), modelPath, packPath, version.GetVersion())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打包模型失败: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "已将模型 %s 打包到 %s\n", meta.Model, packPath)
+	return 0
+}
+
+// runUnpack 校验并解包分发包.
+func runUnpack(packPath, destDir string) int {
+	if destDir == "" {
+		destDir = strings.TrimSuffix(packPath, pack.Extension)
+		if destDir == packPath {
+			destDir += "_unpacked"
+		}
+	}
+	meta, err := pack.Unpack(context.Background(), packPath, destDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解包失败: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "已将模型 %s 解包到 %s\n", meta.Model, destDir)
+	return 0
+}
+
+// runVerify 校验分发包的完整性并打印校验结果.
+func runVerify(packPath string) int {
+	report, err := pack.Verify(context.Background(), packPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "校验模型包失败: %v\n", err)
+		return 1
+	}
+
+	meta := report.Meta
+	fmt.Fprintf(os.Stdout, "模型: %s\n", meta.Model)
+	if meta.Server != "" {
+		fmt.Fprintf(os.Stdout, "服务器: %s\n", meta.Server)
+	}
+	fmt.Fprintf(os.Stdout, "模型包版本 %d，打包自 %s（%s）\n",
+		meta.FormatVersion, meta.AppVersion, meta.CreatedAt.Format(time.RFC3339))
+	for _, name := range report.Missing {
+		fmt.Fprintf(os.Stdout, "缺失: %s\n", name)
+	}
+	for _, name := range report.Mismatched {
+		fmt.Fprintf(os.Stdout, "已损坏: %s\n", name)
+	}
+	for _, name := range report.Unexpected {
+		fmt.Fprintf(os.Stdout, "未记录: %s\n", name)
+	}
+	if !report.OK() {
+		fmt.Fprintln(os.Stdout, "校验失败，模型包不完整")
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "校验通过，共 %d 个文件\n", report.Files)
+	return 0
+}
+
+// cleanTempFiles 清理下载目录中遗留的临时文件，返回删除的文件数量.
+func cleanTempFiles(root string, minAge time.Duration) (int, error) {
+	removed, err := fsutil.CleanTempFiles(root, minAge)
+	for _, path := range removed {
+		log.DefaultLogger.Info().Str("path", path).Msg("已删除遗留的临时文件")
+	}
+	if err != nil {
+		log.DefaultLogger.Warn().Str("root", root).Err(err).Msg("清理临时文件失败")
+	}
+	return len(removed), err
+}
+
+// runCleanTemp 清理下载目录中遗留的全部临时文件及已删除模型的使用统计.
+func runCleanTemp(flags *configFlags) int {
+	if err := initConfig(flags); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	cfg := config.Get()
+	if _, err := log.New(cfg.LogPath); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+
+	count, err := cleanTempFiles(cfg.Live2dSavePath, 0)
+	fmt.Fprintf(os.Stdout, "已删除 %d 个临时文件\n", count)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	// 同时清理已删除模型的使用统计，避免状态文件越来越大
+	if cfg.UsageStats {
+		pruned, pruneErr := stats.Prune(cfg.StatePath)
+		if pruneErr != nil {
+			fmt.Fprintf(os.Stderr, "清理使用统计失败: %v\n", pruneErr)
+			return 1
+		}
+		fmt.Fprintf(os.Stdout, "已删除 %d 条已删除模型的使用统计\n", len(pruned))
+	}
+	return 0
+}
+
+// runClearCache 清空角色信息缓存目录中的缓存文件.
+func runClearCache(flags *configFlags) int {
+	if err := initConfig(flags); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	cfg := config.Get()
+	if _, err := log.New(cfg.LogPath); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+
+	removed, err := api.NewClient().ClearCache()
+	fmt.Fprintf(os.Stdout, "已删除 %d 个缓存文件\n", len(removed))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runHistory 显示下载历史.
+func runHistory(flags *configFlags) int {
+	if err := initConfig(flags); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	h, err := history.Load(historyPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	if len(h.Entries) == 0 {
+		fmt.Fprintln(os.Stdout, "暂无下载历史")
+		return 0
+	}
+	// 服装标题来自缓存，不发送网络请求
+	client := api.NewClient()
+	for _, entry := range h.Entries {
+		fmt.Fprintln(os.Stdout, entry.Format(client.CostumeLabel(entry.Model)))
+	}
+	fmt.Fprintf(os.Stdout, "共 %d 条记录\n", len(h.Entries))
+	return 0
+}
+
+// runDumpDB 导出角色-模型映射数据库.
+func runDumpDB(outPath string, offline bool, flags *configFlags) int {
+	if err := initConfig(flags); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	config.Get().Offline = offline
+	if _, err := log.New(config.Get().LogPath); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	records, err := api.NewClient().BuildCharaDatabase(ctx)
+	if err != nil {
+		log.DefaultLogger.Error().Err(err).Msg("导出角色-模型数据库失败")
+		fmt.Fprintf(os.Stderr, "导出角色-模型数据库失败: %v\n", err)
+		return 1
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "序列化数据库失败: %v\n", err)
+		return 1
+	}
+	if writeErr := os.WriteFile(outPath, data, 0600); writeErr != nil {
+		fmt.Fprintf(os.Stderr, "写入数据库失败: %v\n", writeErr)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stdout, "已导出 %d 个角色到 %s\n", len(records), outPath)
+	return 0
+}
+
+// costumeListEntry 表示 -list-all 输出的一个角色的服装列表.
+type costumeListEntry struct {
+	CharaID   int      `json:"chara_id"`   // 角色ID
+	CharaName string   `json:"chara_name"` // 角色名称，无法获取时为空
+	Costumes  []string `json:"costumes"`   // Live2D 服装列表
+}
+
+// runListAll 将全部角色的服装列表按指定格式输出到标准输出.
+func runListAll(opts cliOptions) int {
+	if err := initConfig(opts.config); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	opts.applyConfig(config.Get())
+	if _, err := log.New(config.Get().LogPath); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client := api.NewClient()
+	costumes, err := client.GetAllCostumes(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取服装列表失败: %v\n", err)
+		return 1
+	}
+	charaIDs := slices.Sorted(maps.Keys(costumes))
+	charas, err := client.ResolveCharas(ctx, charaIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	entries := make([]costumeListEntry, 0, len(charaIDs))
+	for _, charaID := range charaIDs {
+		entry := costumeListEntry{CharaID: charaID, Costumes: costumes[charaID]}
+		if chara := charas[charaID]; chara != nil {
+			entry.CharaName = chara.LocalizedName(displayLocale)
+		}
+		entries = append(entries, entry)
+	}
+	if err = writeCostumeList(os.Stdout, opts.format, entries); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeCostumeList 按指定格式写入服装列表，CSV 格式每行一个服装.
+func writeCostumeList(w io.Writer, format string, entries []costumeListEntry) error {
+	if format == listFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(entries); err != nil {
+			return fmt.Errorf("输出服装列表失败: %w", err)
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"chara_id", "chara_name", "costume_name"}) // 错误由 Flush 后的 Error 返回
+	for _, entry := range entries {
+		for _, costume := range entry.Costumes {
+			_ = writer.Write([]string{strconv.Itoa(entry.CharaID), entry.CharaName, costume})
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("输出服装列表失败: %w", err)
+	}
+	return nil
+}
+
+// runPrintConfig 打印生效的配置及其来源.
+func runPrintConfig(opts cliOptions) int {
+	if err := initConfig(opts.config); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	cfg := config.Get()
+	opts.applyConfig(cfg)
+
+	fileKeys, err := config.FileKeys(config.FileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	if err = config.PrintEntries(os.Stdout, cfg.Entries(opts.configSources(fileKeys))); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
 // main 函数是程序的入口点.
 func main() {
-	app := NewApp()
-	app.Run()
+	if code, handled := runCommand(os.Args[1:]); handled {
+		os.Exit(code)
+	}
+
+	opts, err := parseOptions(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if opts.version {
+		fmt.Fprintln(os.Stdout, version.GetVersion())
+		os.Exit(0)
+	}
+	if opts.printCfg {
+		os.Exit(runPrintConfig(opts))
+	}
+	if opts.dumpDB != "" {
+		os.Exit(runDumpDB(opts.dumpDB, opts.offline, opts.config))
+	}
+	if opts.listAll {
+		os.Exit(runListAll(opts))
+	}
+	if opts.cleanTemp {
+		os.Exit(runCleanTemp(opts.config))
+	}
+	if opts.clearCache {
+		os.Exit(runClearCache(opts.config))
+	}
+	if opts.history {
+		os.Exit(runHistory(opts.config))
+	}
+	if opts.pack != "" {
+		os.Exit(runPack(opts.pack, opts.out))
+	}
+	if opts.unpack != "" {
+		os.Exit(runUnpack(opts.unpack, opts.out))
+	}
+	if opts.verify != "" {
+		os.Exit(runVerify(opts.verify))
+	}
+
+	app := NewApp(opts)
+	os.Exit(app.Run())
 }
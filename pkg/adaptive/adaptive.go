@@ -0,0 +1,188 @@
+// Package adaptive 提供了根据下载结果自适应调整并发数的控制器
+// 思路类似 TCP 拥塞控制：成功率正常时逐步增加并发，失败增多时成倍减少
+package adaptive
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// throughputTolerance 是判断吞吐量变化的相对容差，变化在容差内时视为持平.
+const throughputTolerance = 0.02
+
+// Options 表示控制器的参数.
+type Options struct {
+	Min            int     // 并发数下限，至少为 1
+	Max            int     // 并发数上限，小于下限时使用下限
+	Window         int     // 每统计多少个结果调整一次并发数，至少为 1
+	MinSuccessRate float64 // 窗口内成功率低于该值时将并发数减半
+}
+
+// Sample 表示一次下载的结果.
+type Sample struct {
+	Success  bool          // 是否下载成功
+	Bytes    int64         // 下载的字节数
+	Duration time.Duration // 下载耗时
+}
+
+// Controller 根据最近窗口内的成功率和平均速度动态调整允许同时进行的下载数
+// 窗口内成功率低于阈值时并发数减半；否则按估算的吞吐量（平均速度 × 并发数）逐个调整：
+// 吞吐量上升时沿原方向继续调整，下降时反向调整，持平时尝试增加并发.
+// nil 控制器不限制并发，便于在关闭自适应时直接调用.
+type Controller struct {
+	opts Options
+
+	mu             sync.Mutex
+	limit          int           // 当前允许的并发数
+	active         int           // 正在进行的下载数
+	wake           chan struct{} // 并发数空出或上调时关闭，唤醒等待中的 Acquire
+	successes      int           // 窗口内成功的下载数
+	failures       int           // 窗口内失败的下载数
+	bytes          int64         // 窗口内成功下载的字节数
+	duration       time.Duration // 窗口内成功下载的总耗时
+	lastThroughput float64       // 上一个窗口估算的吞吐量（字节/秒）
+	step           int           // 上一次调整的方向，1 为增加，-1 为减少
+}
+
+// New 创建自适应并发控制器，初始并发数取上下限的中间值
+// 参数:
+//   - opts: 控制器参数
+//
+// 返回:
+//   - *Controller: 控制器实例
+func New(opts Options) *Controller {
+	opts.Min = max(opts.Min, 1)
+	opts.Max = max(opts.Max, opts.Min)
+	opts.Window = max(opts.Window, 1)
+	return &Controller{
+		opts:  opts,
+		limit: (opts.Min + opts.Max) / 2,
+		step:  1,
+		wake:  make(chan struct{}),
+	}
+}
+
+// Limit 返回当前允许的并发数.
+func (c *Controller) Limit() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit
+}
+
+// Acquire 等待空出的并发名额
+// 参数:
+//   - ctx: 上下文，取消时停止等待
+//
+// 返回:
+//   - error: 上下文被取消时返回上下文的错误
+func (c *Controller) Acquire(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	for {
+		c.mu.Lock()
+		if c.active < c.limit {
+			c.active++
+			c.mu.Unlock()
+			return nil
+		}
+		wake := c.wake
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err() //nolint:wrapcheck // 由调用方包装错误
+		case <-wake:
+		}
+	}
+}
+
+// Release 归还并发名额
+// 参数:
+//   - sample: 本次下载的结果，为 nil 时（例如下载被取消）不计入统计
+//
+// 返回:
+//   - int: 调整后的并发数，没有调整时为 0
+func (c *Controller) Release(sample *Sample) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.active--
+	changed := 0
+	if sample != nil {
+		c.record(*sample)
+		if c.successes+c.failures >= c.opts.Window {
+			changed = c.adjust()
+		}
+	}
+	close(c.wake)
+	c.wake = make(chan struct{})
+	return changed
+}
+
+// record 将下载结果计入当前窗口.
+func (c *Controller) record(sample Sample) {
+	if !sample.Success {
+		c.failures++
+		return
+	}
+	c.successes++
+	c.bytes += sample.Bytes
+	c.duration += sample.Duration
+}
+
+// adjust 根据当前窗口的统计调整并发数并开始新的窗口
+// 返回:
+//   - int: 调整后的并发数，没有调整时为 0
+func (c *Controller) adjust() int {
+	defer c.resetWindow()
+
+	old := c.limit
+	rate := float64(c.successes) / float64(c.successes+c.failures)
+	if rate < c.opts.MinSuccessRate {
+		c.limit = max(c.opts.Min, c.limit/2)
+		c.lastThroughput = 0
+		c.step = 1
+		return changedLimit(old, c.limit)
+	}
+
+	throughput := 0.0
+	if c.duration > 0 {
+		throughput = float64(c.bytes) / c.duration.Seconds() / float64(c.successes) * float64(c.limit)
+	}
+	switch {
+	case c.lastThroughput == 0:
+	case throughput < c.lastThroughput*(1-throughputTolerance):
+		// 上一次调整降低了吞吐量，反向调整
+		c.step = -c.step
+	case throughput <= c.lastThroughput*(1+throughputTolerance):
+		// 吞吐量持平时继续尝试更高的并发，由失败率决定何时回退
+		c.step = 1
+	}
+	c.limit = min(c.opts.Max, max(c.opts.Min, c.limit+c.step))
+	c.lastThroughput = throughput
+	return changedLimit(old, c.limit)
+}
+
+// resetWindow 清空窗口内的统计.
+func (c *Controller) resetWindow() {
+	c.successes = 0
+	c.failures = 0
+	c.bytes = 0
+	c.duration = 0
+}
+
+// changedLimit 在并发数发生变化时返回新的并发数，否则返回 0.
+func changedLimit(old, limit int) int {
+	if old == limit {
+		return 0
+	}
+	return limit
+}
@@ -0,0 +1,148 @@
+package adaptive_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/adaptive"
+)
+
+// fileSize 是模拟下载的文件大小.
+const fileSize = 256 * 1024
+
+// network 模拟一个网络环境，返回在 n 个并发下单个文件的下载结果.
+type network func(n, i int) adaptive.Sample
+
+// congested 模拟带宽为 capacity 字节/秒的网络：并发数超过 threshold 后，超出部分的请求会超时失败.
+func congested(capacity float64, threshold int) network {
+	return func(n, i int) adaptive.Sample {
+		if n > threshold && i%n < n-threshold {
+			return adaptive.Sample{Success: false}
+		}
+		speed := capacity / float64(max(n, threshold))
+		return adaptive.Sample{Success: true, Bytes: fileSize, Duration: time.Duration(fileSize / speed * float64(time.Second))}
+	}
+}
+
+// thrashing 模拟不会失败但并发数超过 threshold 后总吞吐量下降的网络.
+func thrashing(capacity float64, threshold int) network {
+	return func(n, _ int) adaptive.Sample {
+		speed := capacity / float64(n)
+		if n > threshold {
+			speed *= float64(threshold) / float64(n)
+		}
+		return adaptive.Sample{Success: true, Bytes: fileSize, Duration: time.Duration(fileSize / speed * float64(time.Second))}
+	}
+}
+
+// simulate 按当前并发数依次完成 windows 个窗口的下载，返回每个窗口结束后的并发数.
+func simulate(t *testing.T, c *adaptive.Controller, net network, window, windows int) []int {
+	t.Helper()
+
+	ctx := context.Background()
+	limits := make([]int, 0, windows)
+	for range windows {
+		n := c.Limit()
+		for i := range window {
+			require.NoError(t, c.Acquire(ctx))
+			sample := net(n, i)
+			c.Release(&sample)
+		}
+		limits = append(limits, c.Limit())
+	}
+	return limits
+}
+
+func TestControllerSimulation(t *testing.T) {
+	tests := []struct {
+		name    string
+		net     network
+		wantMin int
+		wantMax int
+	}{
+		{name: "网络良好时增加到上限", net: congested(1<<30, 100), wantMin: 40, wantMax: 40},
+		{name: "超时增多时在拥塞点附近波动", net: congested(4<<20, 10), wantMin: 2, wantMax: 12},
+		{name: "吞吐量下降时撤回增加", net: thrashing(4<<20, 10), wantMin: 2, wantMax: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := adaptive.New(adaptive.Options{Min: 2, Max: 40, Window: 20, MinSuccessRate: 0.9})
+			limits := simulate(t, c, tt.net, 20, 100)
+			// 前 30 个窗口用于收敛
+			for _, limit := range limits[30:] {
+				assert.GreaterOrEqual(t, limit, tt.wantMin)
+				assert.LessOrEqual(t, limit, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestControllerBackoff(t *testing.T) {
+	c := adaptive.New(adaptive.Options{Min: 2, Max: 20, Window: 4, MinSuccessRate: 0.9})
+	assert.Equal(t, 11, c.Limit())
+
+	ctx := context.Background()
+	for i := range 4 {
+		require.NoError(t, c.Acquire(ctx))
+		changed := c.Release(&adaptive.Sample{Success: i%2 == 0, Bytes: fileSize, Duration: time.Second})
+		if i < 3 {
+			assert.Zero(t, changed)
+		} else {
+			assert.Equal(t, 5, changed)
+		}
+	}
+	assert.Equal(t, 5, c.Limit())
+
+	// 被取消的下载不计入统计
+	for range 10 {
+		require.NoError(t, c.Acquire(ctx))
+		assert.Zero(t, c.Release(nil))
+	}
+	assert.Equal(t, 5, c.Limit())
+}
+
+func TestControllerAcquire(t *testing.T) {
+	c := adaptive.New(adaptive.Options{Min: 1, Max: 1, Window: 1})
+	require.NoError(t, c.Acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, c.Acquire(ctx), context.DeadlineExceeded)
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- c.Acquire(context.Background()) }()
+	c.Release(nil)
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Acquire should return after Release")
+	}
+}
+
+func TestNilController(t *testing.T) {
+	var c *adaptive.Controller
+	require.NoError(t, c.Acquire(context.Background()))
+	assert.Zero(t, c.Release(&adaptive.Sample{Success: true}))
+	assert.Zero(t, c.Limit())
+}
+
+func BenchmarkController(b *testing.B) {
+	c := adaptive.New(adaptive.Options{Min: 2, Max: 60, Window: 20, MinSuccessRate: 0.9})
+	ctx := context.Background()
+	sample := adaptive.Sample{Success: true, Bytes: fileSize, Duration: time.Second}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := c.Acquire(ctx); err != nil {
+				b.Fatal(err)
+			}
+			c.Release(&sample)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// GetBands 获取所有乐队信息
+// 只在第一次调用时请求，之后直接返回第一次的结果，获取失败时也不再重试，避免每个模型都等待一次失败的请求
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - map[int]*model.BandInfo: 乐队信息，key 为乐队ID
+//   - error: 错误信息
+func (c *Client) GetBands(ctx context.Context) (map[int]*model.BandInfo, error) {
+	c.bandsMu.Lock()
+	defer c.bandsMu.Unlock()
+	if c.bandsFetched {
+		return c.bands, c.bandsErr
+	}
+	bands, err := c.fetchBands(ctx)
+	if ctx.Err() != nil {
+		// 取消导致的失败不记录，下次调用时重新获取
+		return nil, err
+	}
+	c.bands, c.bandsErr, c.bandsFetched = bands, err, true
+	return bands, err
+}
+
+// fetchBands 请求并解析乐队信息.
+func (c *Client) fetchBands(ctx context.Context) (map[int]*model.BandInfo, error) {
+	data, err := c.FetchData(ctx, c.bandsURL, bandsCache)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析乐队信息失败: %w", err)
+	}
+	return model.ParseBands(raw) //nolint:wrapcheck // 已包含上下文
+}
+
+// GetCharaBandName 获取角色所属乐队的名称
+// 参数:
+//   - ctx: 上下文
+//   - chara: 角色信息
+//   - localeIndex: 服务器在多语言数组中的位置，与角色名称使用相同的位置
+//
+// 返回:
+//   - string: 乐队名称，角色不属于任何乐队或乐队信息中没有该乐队时为空字符串
+//   - error: 获取乐队信息失败时返回错误
+func (c *Client) GetCharaBandName(ctx context.Context, chara *model.CharaInfo, localeIndex int) (string, error) {
+	if chara == nil || chara.BandID <= 0 {
+		return "", nil
+	}
+	bands, err := c.GetBands(ctx)
+	if err != nil {
+		return "", err
+	}
+	return bands[chara.BandID].LocalizedName(localeIndex), nil
+}
+
+// GetBandMembers 从角色列表中筛选指定乐队的成员
+// 参数:
+//   - ctx: 上下文
+//   - bandID: 乐队ID
+//
+// 返回:
+//   - []model.BandMember: 乐队成员，按角色ID排序
+//   - error: 错误信息
+func (c *Client) GetBandMembers(ctx context.Context, bandID int) ([]model.BandMember, error) {
+	roster, err := c.GetCharaRoster(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []model.BandMember
+	for id, chara := range roster {
+		charaID, parseErr := strconv.Atoi(id)
+		if parseErr != nil || !config.Get().CharaIDAllowed(charaID) || chara.BandID != bandID {
+			continue
+		}
+		members = append(members, model.BandMember{ID: charaID, Chara: chara})
+	}
+	slices.SortFunc(members, func(a, b model.BandMember) int { return a.ID - b.ID })
+	return members, nil
+}
+
+// GetAllCharas 获取可以浏览的全部角色
+// 乐队信息获取失败时只记录日志，角色的乐队名称为空
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - []model.CharaSummary: 角色列表，按乐队ID排序，不属于任何乐队的角色排在最后，同一乐队内按角色ID排序
+//   - error: 获取角色列表失败时返回错误
+func (c *Client) GetAllCharas(ctx context.Context) ([]model.CharaSummary, error) {
+	roster, err := c.GetCharaRoster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bands, bandsErr := c.GetBands(ctx)
+	if bandsErr != nil {
+		log.DefaultLogger.Warn().Err(bandsErr).Msg("获取乐队信息失败，角色列表不显示乐队")
+	}
+
+	charas := make([]model.CharaSummary, 0, len(roster))
+	for id, chara := range roster {
+		charaID, parseErr := strconv.Atoi(id)
+		if parseErr != nil || !config.Get().CharaIDAllowed(charaID) || len(chara.CharacterName) == 0 {
+			continue
+		}
+		summary := model.CharaSummary{ID: charaID, Name: chara.CharacterName, BandID: chara.BandID}
+		if band := bands[chara.BandID]; band != nil {
+			summary.BandName = band.BandName
+		}
+		charas = append(charas, summary)
+	}
+	slices.SortFunc(charas, func(a, b model.CharaSummary) int {
+		if a.BandID != b.BandID {
+			return bandOrder(a.BandID) - bandOrder(b.BandID)
+		}
+		return a.ID - b.ID
+	})
+	return charas, nil
+}
+
+// bandOrder 返回乐队的排序键，不属于任何乐队的角色排在所有乐队之后.
+func bandOrder(bandID int) int {
+	if bandID <= 0 {
+		return math.MaxInt32
+	}
+	return bandID
+}
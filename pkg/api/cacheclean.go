@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+)
+
+// 客户端写入缓存目录的缓存文件名
+// 缓存目录中还保存着下载历史与角色搜索缓存等其他数据，清理缓存时只能删除这里列出的文件.
+const (
+	cacheFileExt            = ".json"
+	charaRosterCache        = "chara_roster.json" // 角色列表
+	charaCachePrefix        = "chara_"            // 单个角色的信息，文件名为 chara_<角色ID>.json
+	assetsIndexCache        = "assets_info.json"  // 当前服务器的资源索引
+	serverAssetsIndexPrefix = "assets_info_"      // 其他服务器的资源索引，文件名为 assets_info_<服务器>.json
+	bandsCache              = "bands.json"        // 乐队信息
+	costumesCache           = "costumes.json"     // 服装信息
+)
+
+// isCacheFileName 判断文件名是否为客户端写入的缓存文件或其遗留的临时文件.
+func isCacheFileName(name string) bool {
+	if target, ok := fsutil.TempTarget(name); ok {
+		name = target
+	}
+	switch name {
+	case charaRosterCache, assetsIndexCache, bandsCache, costumesCache, NegativeCacheFileName, CostumeTitlesFileName:
+		return true
+	}
+	stem, ok := strings.CutSuffix(name, cacheFileExt)
+	if !ok {
+		return false
+	}
+	if id, isChara := strings.CutPrefix(stem, charaCachePrefix); isChara {
+		_, err := strconv.Atoi(id)
+		return err == nil
+	}
+	if server, isIndex := strings.CutPrefix(stem, serverAssetsIndexPrefix); isIndex {
+		return config.ValidateServer(server) == nil
+	}
+	return false
+}
+
+// cacheFiles 返回缓存目录中可以删除的缓存文件
+// 只返回客户端写入的缓存文件与写入时遗留的临时文件，下载历史、角色搜索缓存等其他文件不会被删除；
+// 不进入子目录，也不处理符号链接，缓存目录被配置为其他目录时不会误删无关的文件.
+func (c *Client) cacheFiles() ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(c.charaCachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取缓存目录失败: %w", err)
+	}
+
+	var files []os.FileInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !filepath.IsLocal(name) {
+			continue
+		}
+		if !isCacheFileName(name) {
+			continue
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			continue // 文件在读取目录后被删除
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// removeCacheFiles 删除缓存目录中满足条件的缓存文件，单个文件删除失败时继续删除其余文件.
+func (c *Client) removeCacheFiles(match func(os.FileInfo) bool) ([]string, error) {
+	files, err := c.cacheFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	var errList []error
+	for _, info := range files {
+		if !match(info) {
+			continue
+		}
+		path := filepath.Join(c.charaCachePath, info.Name())
+		if removeErr := os.Remove(path); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			log.DefaultLogger.Warn().Str("path", path).Err(removeErr).Msg("删除缓存文件失败")
+			errList = append(errList, removeErr)
+			continue
+		}
+		log.DefaultLogger.Debug().Str("path", path).Msg("已删除缓存文件")
+		removed = append(removed, path)
+	}
+	if len(errList) > 0 {
+		return removed, fmt.Errorf("删除缓存文件失败: %w", errors.Join(errList...))
+	}
+	return removed, nil
+}
+
+// ClearCache 删除缓存目录中的全部缓存文件，同时清空内存中的负缓存与服装标题
+// 只删除客户端写入的缓存文件与遗留的临时文件，下载历史、角色搜索缓存、子目录与其他文件保持不变
+// 返回:
+//   - []string: 已删除的文件路径
+//   - error: 错误信息
+func (c *Client) ClearCache() ([]string, error) {
+	removed, err := c.removeCacheFiles(func(os.FileInfo) bool { return true })
+	c.notFound = newNegativeCache(filepath.Join(c.charaCachePath, NegativeCacheFileName), c.notFound.ttl)
+	c.titles = newTitleCache(filepath.Join(c.charaCachePath, CostumeTitlesFileName), c.titles.ttl)
+	log.DefaultLogger.Info().Str("path", c.charaCachePath).Int("removed", len(removed)).Msg("已清空缓存")
+	return removed, err
+}
+
+// PruneExpiredCache 删除修改时间超过缓存过期时间 CachePruneFactor 倍的缓存文件
+// 这些文件已过期很久，只在离线模式下还可能被读取，离线模式下不删除；缓存过期时间或倍数不大于 0 时不删除
+// 返回:
+//   - []string: 已删除的文件路径
+//   - error: 错误信息
+func (c *Client) PruneExpiredCache() ([]string, error) {
+	if c.offline || c.cachePruneFactor <= 0 || c.cacheDuration <= 0 {
+		return nil, nil
+	}
+	maxAge := c.cacheDuration * time.Duration(c.cachePruneFactor)
+	removed, err := c.removeCacheFiles(func(info os.FileInfo) bool {
+		return time.Since(info.ModTime()) > maxAge
+	})
+	if len(removed) > 0 {
+		log.DefaultLogger.Info().Str("path", c.charaCachePath).Int("removed", len(removed)).Dur("maxAge", maxAge).Msg("已删除过期的缓存文件")
+	}
+	return removed, err
+}
@@ -3,33 +3,68 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+
+	"golang.org/x/sync/singleflight"
 )
 
 // Client 表示 API 客户端
 // 负责处理与 Bestdori API 的所有交互.
 type Client struct {
-	useCharaCache  bool          // 是否使用角色信息缓存
-	charaCachePath string        // 角色信息缓存路径
-	cacheDuration  time.Duration // 缓存过期时间
-	baseAssetsURL  string        // Bestdori 资源基础 URL
-	charaRosterURL string        // 角色信息 API URL
-	assetsIndexURL string        // 资源索引 API URL
-	httpClient     *http.Client  // HTTP 客户端
+	useCharaCache    bool              // 是否使用角色信息缓存
+	charaCachePath   string            // 角色信息缓存路径
+	cacheDuration    time.Duration     // 缓存过期时间
+	cachePruneFactor int               // 删除修改时间超过缓存过期时间该倍数的缓存文件，为 0 时不删除
+	offline          bool              // 是否为离线模式
+	baseAssetsURL    string            // Bestdori 资源基础 URL
+	charaRosterURL   string            // 角色信息 API URL
+	costumesURL      string            // 服装信息 API URL
+	bandsURL         string            // 乐队信息 API URL
+	assetsIndexURL   string            // 资源索引 API URL
+	serverFallback   []string          // 构建数据不存在时依次尝试的服务器
+	server           string            // 配置中指定的服务器
+	servers          []string          // 合并查询资源索引的服务器
+	costumeSort      model.CostumeSort // 服装列表的排序方式
+	httpClient       *http.Client      // HTTP 客户端
+	maxRetries       int               // 确认构建数据是否存在时遇到网络错误或异常状态码的最大重试次数
+	retryBaseDelay   time.Duration     // 第一次重试前的等待时间，之后每次重试翻倍
+
+	fetchProgress FetchProgressFunc // 获取数据时的默认进度回调
+
+	serversMu    sync.RWMutex            // 保护 modelSources
+	modelSources map[string]*modelSource // 合并资源索引后各模型的来源，key 为模型统一名称
+
+	bandsMu      sync.Mutex              // 保护 bands 与 bandsErr
+	bandsFetched bool                    // 是否已经尝试获取乐队信息
+	bands        map[int]*model.BandInfo // 已获取的乐队信息，key 为乐队ID
+	bandsErr     error                   // 获取乐队信息的错误
+
+	layout layoutMonitor // 资源结构变化检测
+
+	notFound *negativeCache // 最近返回 404 的 URL
+	titles   *titleCache    // 资源包名称到服装标题的映射
+
+	staleWhileRevalidate bool               // 缓存过期时是否先返回旧数据并在后台刷新
+	revalidating         singleflight.Group // 合并对同一缓存文件的后台刷新，key 为缓存文件名
 }
 
 // NewClient 创建新的 API 客户端实例
@@ -37,19 +72,48 @@ type Client struct {
 //   - *Client: 新的 API 客户端实例
 func NewClient() *Client {
 	cfg := config.Get()
+	if err := cfg.ApplyServer(); err != nil {
+		log.DefaultLogger.Warn().Str("server", cfg.Server).Err(err).Msg("切换服务器失败，沿用资源 URL 中的服务器")
+	}
 	return &Client{
-		useCharaCache:  cfg.UseCharaCache,
-		charaCachePath: cfg.CharaCachePath,
-		cacheDuration:  cfg.CacheDuration,
-		baseAssetsURL:  cfg.BaseAssetsURL,
-		charaRosterURL: cfg.CharaRosterURL,
-		assetsIndexURL: cfg.AssetsIndexURL,
+		useCharaCache:        cfg.UseCharaCache,
+		staleWhileRevalidate: cfg.StaleWhileRevalidate,
+		charaCachePath:       cfg.CharaCachePath,
+		cacheDuration:        cfg.CacheDuration,
+		cachePruneFactor:     cfg.CachePruneFactor,
+		offline:              cfg.Offline,
+		baseAssetsURL:        cfg.BaseAssetsURL,
+		charaRosterURL:       cfg.CharaRosterURL,
+		costumesURL:          cfg.CostumesURL,
+		bandsURL:             cfg.BandsURL,
+		assetsIndexURL:       cfg.AssetsIndexURL,
+		serverFallback:       cfg.ServerFallback,
+		server:               cfg.Server,
+		servers:              configServers(cfg),
+		costumeSort:          cfg.CostumeSort,
+		maxRetries:           cfg.MaxRetries,
+		retryBaseDelay:       cfg.RetryBaseDelay,
+		notFound:             newNegativeCache(filepath.Join(cfg.CharaCachePath, NegativeCacheFileName), cfg.NegativeCacheDuration),
+		titles:               newTitleCache(filepath.Join(cfg.CharaCachePath, CostumeTitlesFileName), cfg.CacheDuration),
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: newTransport(cfg),
 		},
 	}
 }
 
+// newTransport 创建 API 请求使用的 HTTP 传输层，按配置设置代理
+// 代理 URL 已在初始化配置时校验，运行期间被改为无效值时沿用环境变量中的代理.
+func newTransport(cfg *config.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // 标准库的默认传输层类型固定
+	if proxy, err := cfg.Proxy(); err != nil {
+		log.DefaultLogger.Error().Err(err).Msg("代理配置无效，使用环境变量中的代理")
+	} else {
+		transport.Proxy = proxy
+	}
+	return transport
+}
+
 // readCacheData 从缓存文件读取数据
 // 参数:
 //   - cacheFile: 缓存文件路径
@@ -64,16 +128,59 @@ func (c *Client) readCacheData(cacheFile string) (map[string]any, error) {
 		return nil, fmt.Errorf("读取缓存数据失败: %w", readErr)
 	}
 
+	if isHTMLContent(cacheData) {
+		log.DefaultLogger.Error().Str("cacheFile", cacheFile).Msg("缓存数据为HTML页面")
+		return nil, fmt.Errorf("解析缓存数据失败: %w", errs.ErrFileUnavailable)
+	}
+
 	var result map[string]any
 	if unmarshalErr := json.Unmarshal(cacheData, &result); unmarshalErr != nil {
 		log.DefaultLogger.Error().Str("cacheFile", cacheFile).Err(unmarshalErr).Msg("解析缓存数据失败")
 		return nil, fmt.Errorf("解析缓存数据失败: %w", unmarshalErr)
 	}
+	if result == nil {
+		log.DefaultLogger.Error().Str("cacheFile", cacheFile).Msg("缓存数据不是JSON对象")
+		return nil, fmt.Errorf("解析缓存数据失败: %w", errs.ErrFileUnavailable)
+	}
 
 	return result, nil
 }
 
+// writeCacheFile 先写入临时文件再替换缓存文件，读取缓存时不会读到写了一半的内容.
+func writeCacheFile(cacheFile string, data []byte) error {
+	file, err := fsutil.CreateTemp(cacheFile)
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	if _, writeErr := file.Write(data); writeErr != nil {
+		fsutil.DiscardTemp(file)
+		return fmt.Errorf("写入临时文件失败: %w", writeErr)
+	}
+	if commitErr := fsutil.CommitTemp(file, cacheFile); commitErr != nil {
+		return fmt.Errorf("替换缓存文件失败: %w", commitErr)
+	}
+	return nil
+}
+
+// isHTMLContent 判断数据是否为 HTML 页面
+// 用于识别 Cloudflare 验证页等错误页面
+// 参数:
+//   - data: 待检查的数据
+//
+// 返回:
+//   - bool: 是否为 HTML 页面
+func isHTMLContent(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '<' {
+		return false
+	}
+	return strings.HasPrefix(http.DetectContentType(trimmed), "text/html") ||
+		bytes.HasPrefix(bytes.ToLower(trimmed), []byte("<!doctype html"))
+}
+
 // FetchData 从指定 URL 获取数据，支持缓存功能
+// 使用 SetFetchProgress 设置的进度回调报告下载进度
+// 返回 404 的 URL 会记录到负缓存中，有效期内再次请求时直接返回 errs.ErrNotFound
 // 参数:
 //   - ctx: 上下文
 //   - url: 请求的 URL
@@ -83,18 +190,97 @@ func (c *Client) readCacheData(cacheFile string) (map[string]any, error) {
 //   - map[string]any: 获取的数据
 //   - error: 错误信息
 func (c *Client) FetchData(ctx context.Context, url string, cache string) (map[string]any, error) {
+	return c.FetchDataWithProgress(ctx, url, cache, c.fetchProgress)
+}
+
+// FetchDataWithProgress 从指定 URL 获取数据，并报告下载进度
+// 参数:
+//   - ctx: 上下文
+//   - url: 请求的 URL
+//   - cache: 缓存文件名（为空则不使用缓存）
+//   - onProgress: 进度回调，为 nil 时不报告进度
+//
+// 返回:
+//   - map[string]any: 获取的数据
+//   - error: 错误信息
+func (c *Client) FetchDataWithProgress(
+	ctx context.Context,
+	url string,
+	cache string,
+	onProgress FetchProgressFunc,
+) (map[string]any, error) {
+	if c.offline {
+		return c.fetchOffline(url, cache)
+	}
+	if c.notFound.contains(url) {
+		log.DefaultLogger.Info().Str("url", url).Msg("负缓存中记录该资源不存在，跳过请求")
+		return nil, fmt.Errorf("%w: 最近已确认不存在", errs.ErrNotFound)
+	}
+
 	if c.useCharaCache && cache != "" {
 		cacheFile := filepath.Join(c.charaCachePath, cache)
 		if fileInfo, err := os.Stat(cacheFile); err == nil {
 			// 检查文件修改时间是否在缓存期限内
-			if time.Since(fileInfo.ModTime()) < c.cacheDuration {
+			fresh := time.Since(fileInfo.ModTime()) < c.cacheDuration
+			switch {
+			case fresh:
 				log.DefaultLogger.Info().Str("cacheFile", cacheFile).Msg("使用缓存数据")
-				return c.readCacheData(cacheFile)
+			case c.staleWhileRevalidate:
+				log.DefaultLogger.Info().Str("cacheFile", cacheFile).Msg("缓存已过期，先使用旧数据并在后台刷新")
+			default:
+				log.DefaultLogger.Info().Str("cacheFile", cacheFile).Msg("缓存已过期")
+			}
+			if fresh || c.staleWhileRevalidate {
+				result, readErr := c.readCacheData(cacheFile)
+				if readErr == nil {
+					if !fresh {
+						c.revalidate(ctx, url, cache)
+					}
+					return result, nil
+				}
+				// 缓存文件损坏时删除并重新获取
+				log.DefaultLogger.Warn().Str("cacheFile", cacheFile).Err(readErr).Msg("缓存数据无效，删除后重新获取")
+				if removeErr := os.Remove(cacheFile); removeErr != nil && !os.IsNotExist(removeErr) {
+					log.DefaultLogger.Warn().Str("cacheFile", cacheFile).Err(removeErr).Msg("删除无效缓存失败")
+				}
 			}
-			log.DefaultLogger.Info().Str("cacheFile", cacheFile).Msg("缓存已过期")
 		}
 	}
 
+	return c.fetchRemote(ctx, url, cache, onProgress)
+}
+
+// revalidate 在后台重新获取数据并更新缓存文件，下次使用缓存时即为新数据
+// 同一缓存文件同时只刷新一次；刷新随 ctx 取消，失败只记录日志.
+func (c *Client) revalidate(ctx context.Context, url string, cache string) {
+	go func() {
+		_, _, _ = c.revalidating.Do(cache, func() (any, error) {
+			if _, err := c.fetchRemote(ctx, url, cache, nil); err != nil {
+				log.DefaultLogger.Warn().Str("url", url).Err(err).Msg("后台刷新缓存失败，继续使用旧数据")
+				return nil, err
+			}
+			log.DefaultLogger.Info().Str("url", url).Msg("后台刷新缓存完成")
+			return nil, nil
+		})
+	}()
+}
+
+// fetchRemote 通过网络获取数据，成功时写入缓存文件
+// 参数:
+//   - ctx: 上下文
+//   - url: 请求的 URL
+//   - cache: 缓存文件名（为空则不写入缓存）
+//   - onProgress: 进度回调，为 nil 时不报告进度
+//
+// 返回:
+//   - map[string]any: 获取的数据
+//   - error: 错误信息
+func (c *Client) fetchRemote(
+	ctx context.Context,
+	url string,
+	cache string,
+	onProgress FetchProgressFunc,
+) (map[string]any, error) {
 	log.DefaultLogger.Info().Str("url", url).Msg("开始获取数据")
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -111,13 +297,39 @@ func (c *Client) FetchData(ctx context.Context, url string, cache string) (map[s
 
 	if resp.StatusCode != http.StatusOK {
 		log.DefaultLogger.Error().Str("url", url).Int("statusCode", resp.StatusCode).Msg("HTTP错误")
+		if resp.StatusCode == http.StatusNotFound {
+			c.notFound.add(url)
+			return nil, fmt.Errorf("%w: HTTP错误: %d", errs.ErrNotFound, resp.StatusCode)
+		}
 		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode)
 	}
 
+	var bodyReader io.Reader = resp.Body
+	if onProgress != nil {
+		bodyReader = &progressReader{
+			reader:     resp.Body,
+			endpoint:   c.endpointName(url),
+			total:      resp.ContentLength,
+			onProgress: onProgress,
+		}
+	}
+	body, err := io.ReadAll(bodyReader)
+	if err != nil {
+		log.DefaultLogger.Error().Str("url", url).Err(err).Msg("读取响应失败")
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	// 检查是否为HTML错误页面，避免将其写入缓存
+	contentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "text/html") || isHTMLContent(body) {
+		log.DefaultLogger.Error().Str("url", url).Str("contentType", contentType).Msg("响应为HTML页面")
+		return nil, fmt.Errorf("获取数据失败: %w", errs.ErrFileUnavailable)
+	}
+
 	var result map[string]any
-	if decodeErr := json.NewDecoder(resp.Body).Decode(&result); decodeErr != nil {
-		log.DefaultLogger.Error().Str("url", url).Err(decodeErr).Msg("解析JSON失败")
-		return nil, fmt.Errorf("解析JSON失败: %w", decodeErr)
+	if unmarshalErr := json.Unmarshal(body, &result); unmarshalErr != nil {
+		log.DefaultLogger.Error().Str("url", url).Err(unmarshalErr).Msg("解析JSON失败")
+		return nil, fmt.Errorf("解析JSON失败: %w", unmarshalErr)
 	}
 
 	if c.useCharaCache && cache != "" {
@@ -127,7 +339,8 @@ func (c *Client) FetchData(ctx context.Context, url string, cache string) (map[s
 		}
 		if jsonData, marshalErr := json.Marshal(result); marshalErr == nil {
 			cacheFilePath := filepath.Join(c.charaCachePath, cache)
-			if writeErr := os.WriteFile(cacheFilePath, jsonData, 0600); writeErr != nil {
+			// 后台刷新时其他请求可能正在读取缓存，先写入临时文件再替换，避免读到写了一半的文件
+			if writeErr := writeCacheFile(cacheFilePath, jsonData); writeErr != nil {
 				log.DefaultLogger.Error().Str("cacheFile", cacheFilePath).Err(writeErr).Msg("写入缓存文件失败")
 				return nil, fmt.Errorf("写入缓存文件失败: %w", writeErr)
 			}
@@ -139,16 +352,56 @@ func (c *Client) FetchData(ctx context.Context, url string, cache string) (map[s
 	return result, nil
 }
 
+// fetchOffline 在离线模式下从缓存读取数据
+// 离线时无法刷新缓存，因此已过期的缓存也会被使用
+// 参数:
+//   - url: 请求的 URL，仅用于日志
+//   - cache: 缓存文件名
+//
+// 返回:
+//   - map[string]any: 缓存数据
+//   - error: 没有可用缓存时返回 errs.ErrOffline
+func (c *Client) fetchOffline(url string, cache string) (map[string]any, error) {
+	if !c.useCharaCache || cache == "" {
+		log.DefaultLogger.Warn().Str("url", url).Msg("离线模式下该数据不支持缓存")
+		return nil, fmt.Errorf("获取 %s 失败: %w", c.endpointName(url), errs.ErrOffline)
+	}
+
+	cacheFile := filepath.Join(c.charaCachePath, cache)
+	result, err := c.readCacheData(cacheFile)
+	if err != nil {
+		log.DefaultLogger.Warn().Str("url", url).Str("cacheFile", cacheFile).Err(err).Msg("离线模式下没有可用的缓存")
+		return nil, fmt.Errorf("获取 %s 失败: %w", c.endpointName(url), errs.ErrOffline)
+	}
+	log.DefaultLogger.Info().Str("cacheFile", cacheFile).Msg("离线模式，使用缓存数据")
+	return result, nil
+}
+
 // GetCharaRoster 获取所有角色信息列表
+// 无法解析的角色会被跳过
 // 参数:
 //   - ctx: 上下文
 //
 // 返回:
-//   - map[string]any: 角色信息列表
+//   - map[string]*model.CharaInfo: 角色信息列表，key 为角色ID
 //   - error: 错误信息
-func (c *Client) GetCharaRoster(ctx context.Context) (map[string]any, error) {
+func (c *Client) GetCharaRoster(ctx context.Context) (map[string]*model.CharaInfo, error) {
 	url := fmt.Sprintf("%s/all.2.json", c.charaRosterURL)
-	return c.FetchData(ctx, url, "chara_roster.json")
+	data, err := c.FetchData(ctx, url, charaRosterCache)
+	if err != nil {
+		return nil, err
+	}
+
+	roster := make(map[string]*model.CharaInfo, len(data))
+	for id, value := range data {
+		info, decodeErr := decodeCharaInfo(value)
+		if decodeErr != nil {
+			log.DefaultLogger.Debug().Str("charaID", id).Err(decodeErr).Msg("跳过无法解析的角色信息")
+			continue
+		}
+		roster[id] = info
+	}
+	return roster, nil
 }
 
 // GetChara 获取指定角色的详细信息
@@ -157,56 +410,79 @@ func (c *Client) GetCharaRoster(ctx context.Context) (map[string]any, error) {
 //   - charaID: 角色ID
 //
 // 返回:
-//   - map[string]any: 角色详细信息
+//   - *model.CharaInfo: 角色详细信息
 //   - error: 错误信息
-func (c *Client) GetChara(ctx context.Context, charaID int) (map[string]any, error) {
+func (c *Client) GetChara(ctx context.Context, charaID int) (*model.CharaInfo, error) {
 	url := fmt.Sprintf("%s/%d.json", c.charaRosterURL, charaID)
-	return c.FetchData(ctx, url, fmt.Sprintf("chara_%d.json", charaID))
+	data, err := c.FetchData(ctx, url, charaCachePrefix+strconv.Itoa(charaID)+cacheFileExt)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCharaInfo(data)
+}
+
+// decodeCharaInfo 将通用 JSON 数据转换为角色信息.
+func decodeCharaInfo(value any) (*model.CharaInfo, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("解析角色信息失败: %w", err)
+	}
+	return model.ParseCharaInfo(data) //nolint:wrapcheck // 已包含上下文
 }
 
-// getLive2dAssets 获取 Live2D 资源映射
+// GetCharaCostumes 获取指定角色的所有 Live2D 服装列表
+// 合并查询配置的所有服务器，同名服装只出现一次
 // 参数:
 //   - ctx: 上下文
+//   - charaID: 角色ID
 //
 // 返回:
-//   - map[string]any: Live2D 资源映射
+//   - []string: 服装列表（按特定规则排序）
 //   - error: 错误信息
-func (c *Client) getLive2dAssets(ctx context.Context) (map[string]any, error) {
-	assetsInfo, err := c.FetchData(ctx, c.assetsIndexURL, "assets_info.json")
+func (c *Client) GetCharaCostumes(ctx context.Context, charaID int) ([]string, error) {
+	live2dAssets, err := c.getLive2dAssets(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	live2dAssets, ok := assetsInfo["live2d"].(map[string]any)["chara"].(map[string]any)
-	if !ok {
-		return nil, errors.New("无效的资源索引格式")
+	var costumes []string
+	for live2d := range live2dAssets {
+		if id, ok := model.Live2dCharaID(live2d); ok && id == charaID {
+			costumes = append(costumes, live2d)
+		}
 	}
 
-	return live2dAssets, nil
+	sortCostumes(costumes)
+	return costumes, nil
 }
 
-// GetCharaCostumes 获取指定角色的所有 Live2D 服装列表
+// MatchLive2dModels 在资源索引的全部 Live2D 模型中按通配符模式查找模型
+// 与 GetCharaCostumes 一样排除各角色共用的 general 资源
 // 参数:
 //   - ctx: 上下文
-//   - charaID: 角色ID
+//   - pattern: 规范化后的模式，参见 model.MatchLive2dNames
 //
 // 返回:
-//   - []string: 服装列表（按特定规则排序）
+//   - []string: 按名称排序的匹配结果
 //   - error: 错误信息
-func (c *Client) GetCharaCostumes(ctx context.Context, charaID int) ([]string, error) {
+func (c *Client) MatchLive2dModels(ctx context.Context, pattern string) ([]string, error) {
 	live2dAssets, err := c.getLive2dAssets(ctx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("获取资源索引失败: %w", err)
 	}
 
-	var costumes []string
+	names := make([]string, 0, len(live2dAssets))
 	for live2d := range live2dAssets {
-		if live2d[:3] == fmt.Sprintf("%03d", charaID) && !strings.HasSuffix(live2d, "general") {
-			costumes = append(costumes, live2d)
+		if !strings.HasSuffix(live2d, "general") {
+			names = append(names, live2d)
 		}
 	}
+	return model.MatchLive2dNames(names, pattern), nil
+}
 
-	// 对服装列表进行排序
+// sortCostumes 对服装列表进行排序
+// live_event 服装排在最后，其余按服装ID排序.
+func sortCostumes(costumes []string) {
 	sort.Slice(costumes, func(i, j int) bool {
 		// 提取服装ID（模型名称中的数字部分）
 		iParts := strings.Split(costumes[i], "_")
@@ -232,8 +508,6 @@ func (c *Client) GetCharaCostumes(ctx context.Context, charaID int) ([]string, e
 		// 如果无法比较ID，则按字符串排序
 		return costumes[i] < costumes[j]
 	})
-
-	return costumes, nil
 }
 
 // GetLive2dData 获取指定 Live2D 模型的构建数据
@@ -245,12 +519,15 @@ func (c *Client) GetCharaCostumes(ctx context.Context, charaID int) ([]string, e
 //   - *model.BuildData: Live2D 构建数据
 //   - error: 错误信息
 func (c *Client) GetLive2dData(ctx context.Context, live2dName string) (*model.BuildData, error) {
-	// 构建资源包 URL
-	url := fmt.Sprintf("%s/live2d/chara/%s_rip/buildData.asset", c.baseAssetsURL, live2dName)
-	log.DefaultLogger.Info().Str("live2dName", live2dName).Str("url", url).Msg("开始获取Live2D构建数据")
+	buildData, err := c.getLive2dData(ctx, live2dName)
+	c.layout.recordBuildData(err)
+	return buildData, err
+}
 
+// getLive2dData 获取并处理指定 Live2D 模型的构建数据.
+func (c *Client) getLive2dData(ctx context.Context, live2dName string) (*model.BuildData, error) {
 	// 获取构建数据
-	data, err := c.FetchData(ctx, url, "")
+	data, server, err := c.fetchBuildData(ctx, live2dName)
 	if err != nil {
 		log.DefaultLogger.Error().Str("live2dName", live2dName).Err(err).Msg("获取构建数据失败")
 		return nil, fmt.Errorf("获取构建数据失败: %w", err)
@@ -260,7 +537,7 @@ func (c *Client) GetLive2dData(ctx context.Context, live2dName string) (*model.B
 	baseData, ok := data["Base"].(map[string]any)
 	if !ok {
 		log.DefaultLogger.Error().Str("live2dName", live2dName).Msg("构建数据格式错误: 缺少 Base 字段")
-		return nil, errors.New("构建数据格式错误: 缺少 Base 字段")
+		return nil, fmt.Errorf("%w: 缺少 Base 字段", errs.ErrInvalidBuildData)
 	}
 
 	// 序列化基础数据
@@ -288,27 +565,192 @@ func (c *Client) GetLive2dData(ctx context.Context, live2dName string) (*model.B
 		buildData.Textures[i].EnsurePngSuffix()
 	}
 
-	log.DefaultLogger.Info().Str("live2dName", live2dName).Msg("Live2D构建数据处理完成")
+	buildData.Server = server
+	log.DefaultLogger.Info().Str("live2dName", live2dName).Str("server", server).Msg("Live2D构建数据处理完成")
 	return &buildData, nil
 }
 
-// ValidateLive2dModel 验证指定的 Live2D 模型是否存在
+// isMissingResource 判断错误是否表示资源在该服务器上不存在
+// 只有 404 与 HTML 错误页会触发换服，网络错误等其他问题直接返回，避免被回退掩盖.
+func isMissingResource(err error) bool {
+	return errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrFileUnavailable)
+}
+
+// fetchBuildData 按服务器回退顺序获取构建数据
 // 参数:
 //   - ctx: 上下文
 //   - live2dName: Live2D 模型名称
 //
 // 返回:
-//   - bool: 模型是否存在
-//   - error: 错误信息
+//   - map[string]any: 构建数据
+//   - string: 实际获取到构建数据的服务器
+//   - error: 所有服务器都不存在该模型时返回最后一个错误
+func (c *Client) fetchBuildData(ctx context.Context, live2dName string) (map[string]any, string, error) {
+	var lastErr error
+	for _, server := range c.fallbackServers(live2dName) {
+		baseURL, err := config.ServerAssetsURL(c.baseAssetsURL, server)
+		if err != nil {
+			log.DefaultLogger.Warn().Str("server", server).Err(err).Msg("跳过无效的回退服务器")
+			continue
+		}
+
+		url := buildDataURL(baseURL, c.bundleName(live2dName, server))
+		log.DefaultLogger.Info().Str("live2dName", live2dName).Str("server", server).Str("url", url).Msg("开始获取Live2D构建数据")
+
+		data, fetchErr := c.FetchData(ctx, url, "")
+		if fetchErr == nil {
+			return data, server, nil
+		}
+		if !isMissingResource(fetchErr) {
+			return nil, "", fetchErr
+		}
+		log.DefaultLogger.Warn().Str("live2dName", live2dName).Str("server", server).Msg("该服务器不存在构建数据，尝试下一个服务器")
+		lastErr = fetchErr
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("%w: 没有可用的服务器", errs.ErrNotFound)
+	}
+	return nil, "", lastErr
+}
+
+// buildDataURL 返回模型构建数据的 URL.
+func buildDataURL(baseURL, live2dName string) string {
+	return fmt.Sprintf("%s/live2d/chara/%s_rip/buildData.asset", baseURL, live2dName)
+}
+
+// maxProbeRetryDelay 是确认构建数据是否存在时两次重试之间的最长等待时间.
+const maxProbeRetryDelay = 30 * time.Second
+
+// probeBuildData 确认构建数据是否存在，不下载构建数据
+// 网络错误与异常状态码按配置的重试次数指数退避重试，避免一次短暂的失败中止整个下载
+// 参数:
+//   - ctx: 上下文
+//   - url: 构建数据 URL
+//
+// 返回:
+//   - bool: 构建数据是否存在，404 与 HTML 错误页视为不存在
+//   - error: 重试后仍然遇到网络错误或其他异常状态码时返回错误
+func (c *Client) probeBuildData(ctx context.Context, url string) (bool, error) {
+	if c.offline {
+		return false, fmt.Errorf("确认构建数据是否存在失败: %w", errs.ErrOffline)
+	}
+
+	delay := min(c.retryBaseDelay, maxProbeRetryDelay)
+	for attempt := 0; ; attempt++ {
+		exists, err := c.probeBuildDataOnce(ctx, url)
+		if err == nil || attempt >= c.maxRetries || ctx.Err() != nil {
+			return exists, err
+		}
+		log.DefaultLogger.Warn().
+			Str("url", url).
+			Int("attempt", attempt+1).
+			Int("maxRetries", c.maxRetries).
+			Dur("delay", delay).
+			Err(err).
+			Msg("确认构建数据是否存在失败，稍后重试")
+		select {
+		case <-ctx.Done():
+			return false, fmt.Errorf("确认构建数据是否存在失败: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxProbeRetryDelay)
+	}
+}
+
+// probeBuildDataOnce 发送一次确认构建数据是否存在的请求
+// 优先发送 HEAD 请求，服务器不支持 HEAD 时改为只请求第一个字节的 GET 请求.
+func (c *Client) probeBuildDataOnce(ctx context.Context, url string) (bool, error) {
+	status, contentType, err := c.probe(ctx, http.MethodHead, url)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, contentType, err = c.probe(ctx, http.MethodGet, url)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case status == http.StatusNotFound, strings.HasPrefix(contentType, "text/html"):
+		return false, nil
+	case status == http.StatusOK, status == http.StatusPartialContent:
+		return true, nil
+	default:
+		return false, fmt.Errorf("确认构建数据是否存在失败: HTTP错误: %d", status)
+	}
+}
+
+// probe 发送不读取响应内容的请求，GET 请求只请求第一个字节
+// 返回:
+//   - int: 响应状态码
+//   - string: 响应的 Content-Type
+//   - error: 网络错误
+func (c *Client) probe(ctx context.Context, method, url string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("创建请求失败: %w", err)
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.DefaultLogger.Error().Str("url", url).Str("method", method).Err(err).Msg("确认构建数据是否存在失败")
+		return 0, "", fmt.Errorf("确认构建数据是否存在失败: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("Content-Type"), nil
+}
+
+// ValidateLive2dModel 验证指定的 Live2D 模型是否存在
+// 当前服务器的资源索引中没有该模型时，按服务器回退顺序通过 HEAD 请求确认其他服务器是否存在构建数据；
+// 资源索引不可用时不阻止校验，直接按服务器回退顺序确认构建数据
+// 参数:
+//   - ctx: 上下文
+//   - live2dName: Live2D 模型名称，可以带有 _rip 后缀
+//
+// 返回:
+//   - bool: 模型是否存在，名称为空时返回 false
+//   - error: 离线且没有资源索引缓存或确认构建数据时遇到网络错误时返回错误
 func (c *Client) ValidateLive2dModel(ctx context.Context, live2dName string) (bool, error) {
+	live2dName = strings.TrimSuffix(strings.TrimSpace(live2dName), "_rip")
+	if live2dName == "" {
+		return false, nil
+	}
+
 	live2dAssets, err := c.getLive2dAssets(ctx)
-	if err != nil {
+	switch {
+	case err == nil:
+		// 检查模型名是否存在于live2dAssets中
+		if _, exists := live2dAssets[live2dName]; exists {
+			return true, nil
+		}
+		if len(c.fallbackServers(live2dName)) <= 1 {
+			return false, nil
+		}
+		// 资源索引只包含当前服务器的模型，其他服务器独有的模型通过构建数据确认
+	case c.offline:
 		return false, fmt.Errorf("获取资源索引失败: %w", err)
+	default:
+		log.DefaultLogger.Warn().Str("live2dName", live2dName).Err(err).Msg("获取资源索引失败，直接确认构建数据是否存在")
 	}
 
-	// 检查模型名是否存在于live2dAssets中
-	_, exists := live2dAssets[live2dName]
-	return exists, nil
+	for _, server := range c.fallbackServers(live2dName) {
+		baseURL, urlErr := config.ServerAssetsURL(c.baseAssetsURL, server)
+		if urlErr != nil {
+			log.DefaultLogger.Warn().Str("server", server).Err(urlErr).Msg("跳过无效的回退服务器")
+			continue
+		}
+		exists, probeErr := c.probeBuildData(ctx, buildDataURL(baseURL, c.bundleName(live2dName, server)))
+		if probeErr != nil {
+			return false, probeErr
+		}
+		if exists {
+			log.DefaultLogger.Info().Str("live2dName", live2dName).Str("server", server).Msg("模型只存在于其他服务器")
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // SetCharaCachePath 设置角色信息缓存路径
@@ -316,6 +758,16 @@ func (c *Client) ValidateLive2dModel(ctx context.Context, live2dName string) (bo
 //   - path: 缓存路径
 func (c *Client) SetCharaCachePath(path string) {
 	c.charaCachePath = path
+	c.notFound = newNegativeCache(filepath.Join(path, NegativeCacheFileName), c.notFound.ttl)
+	c.titles = newTitleCache(filepath.Join(path, CostumeTitlesFileName), c.titles.ttl)
+}
+
+// SetOffline 设置是否为离线模式
+// 离线模式下只读取缓存，不发送网络请求
+// 参数:
+//   - offline: 是否为离线模式
+func (c *Client) SetOffline(offline bool) {
+	c.offline = offline
 }
 
 // SetUseCharaCache 设置是否使用角色信息缓存
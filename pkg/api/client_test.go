@@ -3,13 +3,24 @@ package api_test
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/api"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/history"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,9 +30,11 @@ func setupTest(t *testing.T) {
 	tempDir := t.TempDir()
 
 	// 初始化配置
-	config.Init()
+	require.NoError(t, config.Init())
 	cfg := config.Get()
 	cfg.LogPath = filepath.Join(tempDir, "logs")
+	cfg.RetryBaseDelay = time.Millisecond // 避免网络不可用时重试拖慢测试
+	cfg.NegativeCacheDuration = 0         // 测试服务器的端口可能被复用，避免负缓存影响其他测试
 
 	// 初始化日志
 	if _, err := log.New(cfg.LogPath); err != nil {
@@ -100,6 +113,77 @@ func TestFetchData(t *testing.T) {
 	}
 }
 
+func TestFetchDataHTMLCache(t *testing.T) {
+	const htmlPage = "<!DOCTYPE html><html><head><title>Just a moment...</title></head></html>"
+
+	tests := []struct {
+		name        string
+		cached      string
+		contentType string
+		response    string
+		wantErr     error
+	}{
+		{
+			name:        "缓存为HTML时重新获取",
+			cached:      htmlPage,
+			contentType: "application/json",
+			response:    `{"1":{"characterType":"unique"}}`,
+		},
+		{
+			name:        "缓存为非对象JSON时重新获取",
+			cached:      "null",
+			contentType: "application/json",
+			response:    `{"1":{"characterType":"unique"}}`,
+		},
+		{
+			name:        "响应为HTML时不写入缓存",
+			contentType: "text/html; charset=UTF-8",
+			response:    htmlPage,
+			wantErr:     errs.ErrFileUnavailable,
+		},
+		{
+			name:        "缺少Content-Type的HTML响应",
+			contentType: "application/octet-stream",
+			response:    htmlPage,
+			wantErr:     errs.ErrFileUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			tempDir := t.TempDir()
+			client := api.NewClient()
+			client.SetCharaCachePath(tempDir)
+			client.SetUseCharaCache(true)
+
+			cacheFile := filepath.Join(tempDir, "roster.json")
+			if tt.cached != "" {
+				require.NoError(t, os.WriteFile(cacheFile, []byte(tt.cached), 0600))
+			}
+
+			data, err := client.FetchData(context.Background(), server.URL, "roster.json")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				_, statErr := os.Stat(cacheFile)
+				require.True(t, os.IsNotExist(statErr), "HTML response should not be cached")
+				return
+			}
+
+			require.NoError(t, err)
+			require.Contains(t, data, "1")
+			cached, readErr := os.ReadFile(cacheFile)
+			require.NoError(t, readErr)
+			require.JSONEq(t, tt.response, string(cached))
+		})
+	}
+}
+
 func TestGetCharaRoster(t *testing.T) {
 	// 创建临时目录用于测试缓存
 	tempDir := t.TempDir()
@@ -223,3 +307,1304 @@ func TestValidateLive2dModel(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateLive2dModelProbe(t *testing.T) {
+	tests := []struct {
+		name         string
+		model        string
+		responses    map[string]int // 各服务器对构建数据的响应，-1 返回 HTML 错误页，未列出时返回 404
+		noHead       bool           // 服务器是否不支持 HEAD 请求
+		indexDown    bool           // 资源索引是否无法获取
+		failures     int            // 构建数据请求在返回 responses 之前先返回 503 的次数
+		wantExists   bool
+		wantErr      bool
+		wantRequests []string
+	}{
+		{name: "资源索引中存在", model: "001_casual", wantExists: true},
+		{name: "带 _rip 后缀", model: "001_casual_rip", wantExists: true},
+		{name: "空名称", model: " ", wantExists: false},
+		{
+			name:         "只存在于其他服务器",
+			model:        "001_cn_only",
+			responses:    map[string]int{"cn": http.StatusOK},
+			wantExists:   true,
+			wantRequests: []string{"HEAD jp", "HEAD cn"},
+		},
+		{
+			name:         "HTML 错误页视为不存在",
+			model:        "001_missing",
+			responses:    map[string]int{"jp": -1},
+			wantRequests: []string{"HEAD jp", "HEAD cn"},
+		},
+		{
+			name:         "不支持 HEAD 时改用 GET",
+			model:        "001_cn_only",
+			responses:    map[string]int{"cn": http.StatusOK},
+			noHead:       true,
+			wantExists:   true,
+			wantRequests: []string{"HEAD jp", "GET jp", "HEAD cn", "GET cn"},
+		},
+		{
+			name:         "服务器错误重试后仍然失败",
+			model:        "001_broken",
+			responses:    map[string]int{"jp": http.StatusInternalServerError},
+			wantErr:      true,
+			wantRequests: []string{"HEAD jp", "HEAD jp", "HEAD jp"},
+		},
+		{
+			name:         "短暂的失败重试后成功",
+			model:        "001_cn_only",
+			responses:    map[string]int{"cn": http.StatusOK},
+			failures:     1,
+			wantExists:   true,
+			wantRequests: []string{"HEAD jp", "HEAD jp", "HEAD cn"},
+		},
+		{
+			name:         "资源索引不可用时确认构建数据",
+			model:        "001_casual",
+			responses:    map[string]int{"jp": http.StatusOK},
+			indexDown:    true,
+			wantExists:   true,
+			wantRequests: []string{"HEAD jp"},
+		},
+		{
+			name:         "资源索引不可用且构建数据不存在",
+			model:        "001_missing",
+			indexDown:    true,
+			wantRequests: []string{"HEAD jp", "HEAD cn"},
+		},
+	}
+
+	cfg := config.Get()
+	oldBase, oldIndex, oldFallback := cfg.BaseAssetsURL, cfg.AssetsIndexURL, cfg.ServerFallback
+	oldRetries, oldDelay := cfg.MaxRetries, cfg.RetryBaseDelay
+	defer func() {
+		cfg.BaseAssetsURL, cfg.AssetsIndexURL, cfg.ServerFallback = oldBase, oldIndex, oldFallback
+		cfg.MaxRetries, cfg.RetryBaseDelay = oldRetries, oldDelay
+	}()
+	cfg.MaxRetries, cfg.RetryBaseDelay = 2, time.Millisecond
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			failures := tt.failures
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+				if parts[0] == "api" {
+					if tt.indexDown {
+						w.WriteHeader(http.StatusBadGateway)
+						return
+					}
+					_, _ = w.Write([]byte(`{"live2d":{"chara":{"001_casual":{}}}}`))
+					return
+				}
+				// /assets/<server>/live2d/chara/<name>_rip/buildData.asset
+				requests = append(requests, r.Method+" "+parts[1])
+				if failures > 0 {
+					failures--
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				if tt.noHead && r.Method == http.MethodHead {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				switch status := tt.responses[parts[1]]; status {
+				case http.StatusOK:
+					if r.Method == http.MethodGet {
+						if r.Header.Get("Range") != "bytes=0-0" {
+							t.Errorf("unexpected Range header %q", r.Header.Get("Range"))
+						}
+						w.WriteHeader(http.StatusPartialContent)
+						_, _ = w.Write([]byte("{"))
+					}
+				case -1:
+					w.Header().Set("Content-Type", "text/html")
+				case 0:
+					http.NotFound(w, r)
+				default:
+					w.WriteHeader(status)
+				}
+			}))
+			defer server.Close()
+
+			cfg.BaseAssetsURL = server.URL + "/assets/jp"
+			cfg.AssetsIndexURL = server.URL + "/api/explorer/jp/assets/_info.json"
+			cfg.ServerFallback = []string{"jp", "cn"}
+			client := api.NewClient()
+			client.SetUseCharaCache(false)
+
+			exists, err := client.ValidateLive2dModel(context.Background(), tt.model)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantExists, exists)
+			require.Equal(t, tt.wantRequests, requests)
+		})
+	}
+}
+
+func TestBuildCharaDatabase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/characters/all.2.json":
+			_, _ = w.Write([]byte(`{
+				"1": {"characterName": ["戸山香澄", "Kasumi Toyama", null, "户山香澄", null]},
+				"2": {"characterName": ["花園たえ", "Tae Hanazono", null, "花园多惠", null]}
+			}`))
+		case "/assets/_info.json":
+			_, _ = w.Write([]byte(`{"live2d": {"chara": {
+				"001_live_event_10": {}, "001_casual": {}, "001_2023_summer": {}, "001_general": {}
+			}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldRoster, oldAssets := cfg.CharaRosterURL, cfg.AssetsIndexURL
+	cfg.CharaRosterURL, cfg.AssetsIndexURL = server.URL+"/characters", server.URL+"/assets/_info.json"
+	defer func() { cfg.CharaRosterURL, cfg.AssetsIndexURL = oldRoster, oldAssets }()
+
+	client := api.NewClient()
+	client.SetUseCharaCache(false)
+
+	records, err := client.BuildCharaDatabase(context.Background())
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	require.Equal(t, 1, records[0].CharaID)
+	require.Equal(t, []string{"戸山香澄", "Kasumi Toyama", "", "户山香澄", ""}, records[0].CharaNames)
+	require.Equal(t, []string{"001_2023_summer", "001_casual", "001_live_event_10"}, records[0].Costumes)
+	require.Equal(t, 2, records[1].CharaID)
+	require.Empty(t, records[1].Costumes)
+}
+
+func TestResolveCharas(t *testing.T) {
+	var single atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/characters/all.2.json":
+			_, _ = w.Write([]byte(`{"1": {"characterName": ["戸山香澄", "Kasumi Toyama", null, "户山香澄", null]}}`))
+		case "/characters/36.json":
+			single.Add(1)
+			_, _ = w.Write([]byte(`{"characterName": ["ミッシェル", "Michelle", null, "米歇尔", null]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldRoster := cfg.CharaRosterURL
+	cfg.CharaRosterURL = server.URL + "/characters"
+	defer func() { cfg.CharaRosterURL = oldRoster }()
+
+	client := api.NewClient()
+	client.SetUseCharaCache(false)
+
+	charas, err := client.ResolveCharas(context.Background(), []int{1, 36, 99})
+	require.NoError(t, err)
+	require.Len(t, charas, 2, "charas that cannot be fetched should be skipped")
+	require.Equal(t, "户山香澄", charas[1].LocalizedName(model.LocaleCN))
+	require.Equal(t, "米歇尔", charas[36].LocalizedName(model.LocaleCN))
+	require.Equal(t, int32(1), single.Load(), "only charas missing from the roster should be fetched one by one")
+}
+
+func TestFetchDataThroughProxy(t *testing.T) {
+	// 代理收到的请求使用绝对 URL，目标主机无法解析也能通过代理获取数据
+	var mu sync.Mutex
+	var hosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.URL.Host)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"1": {"characterName": ["戸山香澄", "Kasumi Toyama", null, "户山香澄", null]}}`))
+	}))
+	defer proxy.Close()
+
+	cfg := config.Get()
+	oldRoster, oldProxy := cfg.CharaRosterURL, cfg.ProxyURL
+	cfg.CharaRosterURL, cfg.ProxyURL = "http://bestdori.invalid/api/characters", proxy.URL
+	defer func() { cfg.CharaRosterURL, cfg.ProxyURL = oldRoster, oldProxy }()
+
+	client := api.NewClient()
+	client.SetUseCharaCache(false)
+
+	roster, err := client.GetCharaRoster(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, roster, "1")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"bestdori.invalid"}, hosts)
+}
+
+func TestFetchDataWithProgress(t *testing.T) {
+	body := fmt.Sprintf(`{"data": %q}`, strings.Repeat("x", 256*1024))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := api.NewClient()
+	client.SetUseCharaCache(false)
+
+	var calls int
+	var lastRead, lastTotal int64
+	var endpoint string
+	data, err := client.FetchDataWithProgress(context.Background(), server.URL+"/big.json", "",
+		func(name string, read, total int64) {
+			calls++
+			endpoint, lastRead, lastTotal = name, read, total
+		})
+	require.NoError(t, err)
+	require.Contains(t, data, "data")
+	require.Positive(t, calls)
+	require.Equal(t, "big.json", endpoint)
+	require.Equal(t, int64(len(body)), lastRead)
+	require.Equal(t, int64(len(body)), lastTotal)
+}
+
+func TestFetchDataOffline(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"1":{}}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		cached  string
+		cache   string
+		wantErr error
+	}{
+		{name: "使用缓存数据", cached: `{"1":{"characterType":"unique"}}`, cache: "roster.json"},
+		{name: "缓存缺失", cache: "roster.json", wantErr: errs.ErrOffline},
+		{name: "不支持缓存的数据", cache: "", wantErr: errs.ErrOffline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			client := api.NewClient()
+			client.SetCharaCachePath(tempDir)
+			client.SetUseCharaCache(true)
+			client.SetOffline(true)
+
+			if tt.cached != "" {
+				cacheFile := filepath.Join(tempDir, tt.cache)
+				require.NoError(t, os.WriteFile(cacheFile, []byte(tt.cached), 0600))
+				// 离线时已过期的缓存也应被使用
+				expired := time.Now().Add(-48 * time.Hour)
+				require.NoError(t, os.Chtimes(cacheFile, expired, expired))
+			}
+
+			data, err := client.FetchData(context.Background(), server.URL, tt.cache)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Contains(t, data, "1")
+			}
+			require.Zero(t, requests, "offline mode should not send requests")
+		})
+	}
+}
+
+func TestGetLive2dDataFallback(t *testing.T) {
+	const buildData = `{"Base":{"model":{"bundleName":"live2d/chara/001_test","fileName":"model.moc.bytes"}}}`
+
+	tests := []struct {
+		name         string
+		responses    map[string]int // 各服务器返回的状态码，200 返回构建数据，-1 返回 HTML 错误页，未列出时返回 404
+		fallback     []string
+		wantServer   string
+		wantErr      error
+		wantRequests []string
+	}{
+		{
+			name:         "当前服务器存在",
+			responses:    map[string]int{"jp": http.StatusOK, "cn": http.StatusOK},
+			fallback:     []string{"jp", "cn"},
+			wantServer:   "jp",
+			wantRequests: []string{"jp"},
+		},
+		{
+			name:         "回退到国服",
+			responses:    map[string]int{"cn": http.StatusOK},
+			fallback:     []string{"jp", "cn", "tw"},
+			wantServer:   "cn",
+			wantRequests: []string{"jp", "cn"},
+		},
+		{
+			name:         "HTML 错误页触发回退",
+			responses:    map[string]int{"jp": -1, "tw": http.StatusOK},
+			fallback:     []string{"cn", "tw"},
+			wantServer:   "tw",
+			wantRequests: []string{"jp", "cn", "tw"},
+		},
+		{
+			name:         "服务器错误不触发回退",
+			responses:    map[string]int{"jp": http.StatusInternalServerError, "cn": http.StatusOK},
+			fallback:     []string{"jp", "cn"},
+			wantRequests: []string{"jp"},
+		},
+		{
+			name:         "所有服务器都不存在",
+			fallback:     []string{"jp", "cn"},
+			wantErr:      errs.ErrNotFound,
+			wantRequests: []string{"jp", "cn"},
+		},
+		{
+			name:         "未配置回退",
+			responses:    map[string]int{"cn": http.StatusOK},
+			wantErr:      errs.ErrNotFound,
+			wantRequests: []string{"jp"},
+		},
+	}
+
+	cfg := config.Get()
+	oldURL, oldFallback := cfg.BaseAssetsURL, cfg.ServerFallback
+	defer func() { cfg.BaseAssetsURL, cfg.ServerFallback = oldURL, oldFallback }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// 路径形如 /assets/<server>/live2d/chara/...
+				serverName := strings.Split(strings.TrimPrefix(r.URL.Path, "/assets/"), "/")[0]
+				requests = append(requests, serverName)
+				switch status := tt.responses[serverName]; status {
+				case http.StatusOK:
+					_, _ = w.Write([]byte(buildData))
+				case -1:
+					w.Header().Set("Content-Type", "text/html")
+					_, _ = w.Write([]byte("<!DOCTYPE html><html></html>"))
+				case 0:
+					http.NotFound(w, r)
+				default:
+					w.WriteHeader(status)
+				}
+			}))
+			defer server.Close()
+
+			cfg.BaseAssetsURL, cfg.ServerFallback = server.URL+"/assets/jp", tt.fallback
+			client := api.NewClient()
+
+			data, err := client.GetLive2dData(context.Background(), "001_test")
+			require.Equal(t, tt.wantRequests, requests)
+			switch {
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			case tt.wantServer == "":
+				require.Error(t, err)
+				require.NotErrorIs(t, err, errs.ErrNotFound)
+			default:
+				require.NoError(t, err)
+				require.Equal(t, tt.wantServer, data.Server)
+				require.Equal(t, "model.moc", data.Model.FileName)
+			}
+		})
+	}
+}
+
+func TestMultiServerAssets(t *testing.T) {
+	indexes := map[string]string{
+		"jp": `{"live2d":{"chara":{"001_casual":{},"001_jp_only":{},"002_casual":{}}}}`,
+		"cn": `{"live2d":{"chara":{"001_casual":{},"001_cn_only":{}}}}`,
+		// 国际服的资源包名称带有服务器后缀
+		"en": `{"live2d":{"chara":{"001_casual_en":{},"001_en_only_en":{},"001_general_en":{}}}}`,
+	}
+	var mu sync.Mutex
+	var buildDataRequests, buildDataBundles []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/"); parts[0] {
+		case "api":
+			// /api/explorer/<server>/assets/_info.json
+			index, ok := indexes[parts[2]]
+			if !ok {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(index))
+		case "assets":
+			// /assets/<server>/live2d/chara/<name>_rip/buildData.asset
+			mu.Lock()
+			buildDataRequests = append(buildDataRequests, parts[1])
+			buildDataBundles = append(buildDataBundles, strings.TrimSuffix(parts[4], "_rip"))
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"Base":{"model":{"bundleName":"live2d/chara/001_test","fileName":"model.moc"}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldBase, oldIndex, oldServers, oldServer := cfg.BaseAssetsURL, cfg.AssetsIndexURL, cfg.Servers, cfg.Server
+	defer func() {
+		cfg.BaseAssetsURL, cfg.AssetsIndexURL, cfg.Servers, cfg.Server = oldBase, oldIndex, oldServers, oldServer
+	}()
+
+	tests := []struct {
+		name         string
+		server       string
+		servers      []string
+		model        string
+		wantCostumes []string
+		wantServer   string
+		wantBundle   string // 请求构建数据时使用的资源包名称，为空时与模型名称相同
+	}{
+		{
+			name:         "只查询当前服务器",
+			model:        "001_casual",
+			wantCostumes: []string{"001_casual", "001_jp_only"},
+			wantServer:   "jp",
+		},
+		{
+			name:         "合并多个服务器并去重",
+			servers:      []string{"jp", "cn"},
+			model:        "001_cn_only",
+			wantCostumes: []string{"001_casual", "001_jp_only", "001_cn_only"},
+			wantServer:   "cn",
+		},
+		{
+			name:         "同名模型优先使用首选服务器",
+			servers:      []string{"cn", "jp"},
+			model:        "001_casual",
+			wantCostumes: []string{"001_casual", "001_cn_only", "001_jp_only"},
+			wantServer:   "cn",
+		},
+		{
+			name:         "跳过获取失败的服务器",
+			servers:      []string{"tw", "jp"},
+			model:        "001_jp_only",
+			wantCostumes: []string{"001_casual", "001_jp_only"},
+			wantServer:   "jp",
+		},
+		{
+			name:         "指定服务器时只查询该服务器",
+			server:       "cn",
+			servers:      []string{"jp", "cn"},
+			model:        "001_casual",
+			wantCostumes: []string{"001_casual", "001_cn_only"},
+			wantServer:   "cn",
+		},
+		{
+			name:         "all 合并查询所有已知服务器",
+			server:       config.ServerAll,
+			model:        "001_cn_only",
+			wantCostumes: []string{"001_casual", "001_jp_only", "001_cn_only", "001_en_only"},
+			wantServer:   "cn",
+		},
+		{
+			name:         "使用国际服资源索引中的资源包名称",
+			servers:      []string{"jp", "en"},
+			model:        "001_en_only",
+			wantCostumes: []string{"001_casual", "001_jp_only", "001_en_only"},
+			wantServer:   "en",
+			wantBundle:   "001_en_only_en",
+		},
+		{
+			name:         "合并各服务器名称不同的同一服装",
+			servers:      []string{"en", "jp"},
+			model:        "001_casual",
+			wantCostumes: []string{"001_casual", "001_en_only", "001_jp_only"},
+			wantServer:   "en",
+			wantBundle:   "001_casual_en",
+		},
+		{
+			name:         "指定国际服时显示统一名称",
+			server:       "en",
+			model:        "001_casual",
+			wantCostumes: []string{"001_casual", "001_en_only"},
+			wantServer:   "en",
+			wantBundle:   "001_casual_en",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buildDataRequests, buildDataBundles = nil, nil
+			cfg.BaseAssetsURL = server.URL + "/assets/jp"
+			cfg.AssetsIndexURL = server.URL + "/api/explorer/jp/assets/_info.json"
+			cfg.Servers, cfg.Server = tt.servers, tt.server
+			client := api.NewClient()
+			client.SetUseCharaCache(false)
+			if tt.server != "" {
+				require.Equal(t, tt.server, client.Server())
+			}
+
+			costumes, err := client.GetCharaCostumes(context.Background(), 1)
+			require.NoError(t, err)
+			require.ElementsMatch(t, tt.wantCostumes, costumes)
+
+			data, err := client.GetLive2dData(context.Background(), tt.model)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantServer, data.Server)
+			require.Equal(t, []string{tt.wantServer}, buildDataRequests, "should request the recorded server first")
+			wantBundle := tt.wantBundle
+			if wantBundle == "" {
+				wantBundle = tt.model
+			}
+			require.Equal(t, []string{wantBundle}, buildDataBundles)
+		})
+	}
+}
+
+func TestLayoutChange(t *testing.T) {
+	const buildData = `{"Base":{"model":{"bundleName":"live2d/chara/001_test","fileName":"model.moc"}}}`
+
+	tests := []struct {
+		name      string
+		index     string
+		failed    int // 构建数据请求失败的模型数
+		succeeded int // 构建数据请求成功的模型数
+		status    int // 失败时返回的状态码
+		want      bool
+	}{
+		{name: "少量失败不提示", failed: 2, status: http.StatusNotFound},
+		{name: "大部分成功不提示", failed: 1, succeeded: 4, status: http.StatusNotFound},
+		{name: "构建数据大量不存在", failed: 5, status: http.StatusNotFound, want: true},
+		{name: "服务器错误不计入", failed: 5, status: http.StatusBadGateway},
+		{name: "资源索引无法解析", index: `{"live2d":{}}`, want: true},
+	}
+
+	cfg := config.Get()
+	oldBase, oldIndex := cfg.BaseAssetsURL, cfg.AssetsIndexURL
+	oldServers, oldFallback := cfg.Servers, cfg.ServerFallback
+	defer func() {
+		cfg.BaseAssetsURL, cfg.AssetsIndexURL = oldBase, oldIndex
+		cfg.Servers, cfg.ServerFallback = oldServers, oldFallback
+	}()
+	cfg.Servers, cfg.ServerFallback = nil, nil
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "_info.json"):
+					_, _ = w.Write([]byte(tt.index))
+				case strings.Contains(r.URL.Path, "/ok_"):
+					_, _ = w.Write([]byte(buildData))
+				default:
+					w.WriteHeader(tt.status)
+				}
+			}))
+			defer server.Close()
+
+			cfg.BaseAssetsURL = server.URL + "/assets/jp"
+			cfg.AssetsIndexURL = server.URL + "/api/explorer/jp/assets/_info.json"
+			client := api.NewClient()
+			client.SetUseCharaCache(false)
+
+			if tt.index != "" {
+				_, err := client.GetCharaCostumes(context.Background(), 1)
+				require.ErrorIs(t, err, errs.ErrInvalidAssetsIndex)
+			}
+			for i := range tt.failed {
+				_, err := client.GetLive2dData(context.Background(), fmt.Sprintf("failed_%d", i))
+				require.Error(t, err)
+			}
+			for i := range tt.succeeded {
+				_, err := client.GetLive2dData(context.Background(), fmt.Sprintf("ok_%d", i))
+				require.NoError(t, err)
+			}
+
+			if !tt.want {
+				require.NoError(t, client.LayoutChange())
+				return
+			}
+			require.ErrorIs(t, client.LayoutChange(), errs.ErrLayoutChanged)
+			require.NoError(t, client.LayoutChange(), "should only report once")
+		})
+	}
+}
+
+func TestGetCharaCostumeInfos(t *testing.T) {
+	const index = `{"live2d":{"chara":{"037_casual-2023":{},"037_live_event_205":{},"037_2019_school":{}}}}`
+	// 2023-05-15 与 2019-04-15 的毫秒时间戳，取月中以免受时区影响
+	const costumes = `{
+		"1": {"assetBundleName": "037_live_event_205", "description": ["活动服", null, null, "活动服（国服）", null], "publishedAt": ["1684108800000", null]},
+		"2": {"assetBundleName": "037_2019_school", "description": [null, "School Uniform"], "publishedAt": ["1555286400000"]},
+		"3": "格式错误的条目",
+		"4": {"assetBundleName": 37, "description": "不是数组"}
+	}`
+
+	tests := []struct {
+		name       string
+		server     string
+		sort       model.CostumeSort
+		costumesOK bool
+		want       []string
+	}{
+		{
+			name:       "按服装ID排序并显示描述",
+			server:     "jp",
+			costumesOK: true,
+			want: []string{
+				"037_2019_school — School Uniform (2019-04)",
+				"037_casual-2023",
+				"037_live_event_205 — 活动服 (2023-05)",
+			},
+		},
+		{
+			name:       "优先使用当前服务器的描述",
+			server:     "cn",
+			costumesOK: true,
+			want: []string{
+				"037_2019_school — School Uniform (2019-04)",
+				"037_casual-2023",
+				"037_live_event_205 — 活动服（国服） (2023-05)",
+			},
+		},
+		{
+			name:       "按发布时间排序",
+			server:     "jp",
+			sort:       model.CostumeSortRelease,
+			costumesOK: true,
+			want: []string{
+				"037_2019_school — School Uniform (2019-04)",
+				"037_live_event_205 — 活动服 (2023-05)",
+				"037_casual-2023",
+			},
+		},
+		{
+			name:   "服装信息获取失败时只显示资源包名称",
+			server: "jp",
+			want:   []string{"037_2019_school", "037_casual-2023", "037_live_event_205"},
+		},
+	}
+
+	cfg := config.Get()
+	oldBase, oldIndex, oldCostumes := cfg.BaseAssetsURL, cfg.AssetsIndexURL, cfg.CostumesURL
+	oldServers, oldSort := cfg.Servers, cfg.CostumeSort
+	defer func() {
+		cfg.BaseAssetsURL, cfg.AssetsIndexURL, cfg.CostumesURL = oldBase, oldIndex, oldCostumes
+		cfg.Servers, cfg.CostumeSort = oldServers, oldSort
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "_info.json"):
+					_, _ = w.Write([]byte(index))
+				case strings.HasPrefix(r.URL.Path, "/api/costumes/") && tt.costumesOK:
+					_, _ = w.Write([]byte(costumes))
+				default:
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			cfg.BaseAssetsURL = server.URL + "/assets/" + tt.server
+			cfg.AssetsIndexURL = server.URL + "/api/explorer/" + tt.server + "/assets/_info.json"
+			cfg.CostumesURL = server.URL + "/api/costumes/all.5.json"
+			cfg.Servers, cfg.CostumeSort = nil, tt.sort
+			client := api.NewClient()
+			client.SetUseCharaCache(false)
+
+			infos, err := client.GetCharaCostumeInfos(context.Background(), 37)
+			require.NoError(t, err)
+			labels := make([]string, 0, len(infos))
+			for _, info := range infos {
+				labels = append(labels, info.Label())
+				require.Equal(t, strings.Contains(info.Name, "live_event"), info.EventLimited)
+			}
+			require.Equal(t, tt.want, labels)
+		})
+	}
+}
+
+func TestGetCharaBandName(t *testing.T) {
+	const bands = `{"1":{"bandName":["Poppin'Party","Poppin'Party","Poppin'Party","Poppin'Party","Poppin'Party"]},` +
+		`"2":{"bandName":["Afterglow",null,null,null,null]}}`
+
+	tests := []struct {
+		name     string
+		bandsOK  bool
+		bandID   int
+		want     string
+		wantErr  bool
+		requests int
+	}{
+		{name: "国服名称", bandsOK: true, bandID: 1, want: "Poppin'Party", requests: 1},
+		{name: "回退到日服名称", bandsOK: true, bandID: 2, want: "Afterglow", requests: 1},
+		{name: "乐队信息中没有该乐队", bandsOK: true, bandID: 99, want: "", requests: 1},
+		{name: "角色不属于任何乐队", bandsOK: true, bandID: 0, want: "", requests: 0},
+		{name: "乐队信息获取失败", bandsOK: false, bandID: 1, wantErr: true, requests: 1},
+	}
+
+	cfg := config.Get()
+	oldBands := cfg.BandsURL
+	defer func() { cfg.BandsURL = oldBands }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				requests.Add(1)
+				if !tt.bandsOK {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				_, _ = w.Write([]byte(bands))
+			}))
+			defer server.Close()
+
+			cfg.BandsURL = server.URL + "/api/bands/main.1.json"
+			client := api.NewClient()
+			client.SetUseCharaCache(false)
+
+			chara := &model.CharaInfo{BandID: tt.bandID}
+			// 第二次查询使用已获取的结果，获取失败时也不重复请求
+			for range 2 {
+				got, err := client.GetCharaBandName(context.Background(), chara, model.LocaleCN)
+				if tt.wantErr {
+					require.Error(t, err)
+				} else {
+					require.NoError(t, err)
+				}
+				require.Equal(t, tt.want, got)
+			}
+			require.Equal(t, int32(tt.requests), requests.Load())
+		})
+	}
+}
+
+func TestGetBandMembers(t *testing.T) {
+	const roster = `{
+		"1":{"characterName":["戸山 香澄"],"bandId":1},
+		"36":{"characterName":["高松 燈"],"bandId":45},
+		"37":{"characterName":["千早 愛音"],"bandId":45},
+		"601":{"characterName":["ミッシェル"],"bandId":3},
+		"1001":{"characterName":["特殊角色"],"bandId":45}
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(roster))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldRoster := cfg.CharaRosterURL
+	cfg.CharaRosterURL = server.URL + "/api/characters"
+	defer func() { cfg.CharaRosterURL = oldRoster }()
+
+	client := api.NewClient()
+	client.SetUseCharaCache(false)
+
+	tests := []struct {
+		name   string
+		bandID int
+		want   []int
+	}{
+		{name: "按角色ID排序", bandID: 45, want: []int{36, 37}},
+		{name: "单个成员", bandID: 1, want: []int{1}},
+		{name: "没有成员", bandID: 99, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := client.GetBandMembers(context.Background(), tt.bandID)
+			require.NoError(t, err)
+			var ids []int
+			for _, member := range members {
+				ids = append(ids, member.ID)
+				require.Equal(t, tt.bandID, member.Chara.BandID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestGetAllCharas(t *testing.T) {
+	const roster = `{
+		"37":{"characterName":["千早 愛音"],"bandId":45},
+		"1":{"characterName":["戸山 香澄"],"bandId":1},
+		"36":{"characterName":["高松 燈"],"bandId":45},
+		"601":{"characterName":["ミッシェル"],"bandId":0},
+		"2":{"characterName":[],"bandId":1},
+		"1001":{"characterName":["特殊角色"],"bandId":45}
+	}`
+	const bands = `{"1":{"bandName":["Poppin'Party"]},"45":{"bandName":["MyGO!!!!!"]}}`
+
+	tests := []struct {
+		name      string
+		bandsOK   bool
+		wantBands []string
+	}{
+		{name: "包含乐队名称", bandsOK: true, wantBands: []string{"Poppin'Party", "MyGO!!!!!", "MyGO!!!!!", ""}},
+		{name: "乐队信息获取失败", bandsOK: false, wantBands: []string{"", "", "", ""}},
+	}
+
+	cfg := config.Get()
+	oldRoster, oldBands := cfg.CharaRosterURL, cfg.BandsURL
+	defer func() { cfg.CharaRosterURL, cfg.BandsURL = oldRoster, oldBands }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.URL.Path, "bands") {
+					if !tt.bandsOK {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					_, _ = w.Write([]byte(bands))
+					return
+				}
+				_, _ = w.Write([]byte(roster))
+			}))
+			defer server.Close()
+
+			cfg.CharaRosterURL = server.URL + "/api/characters"
+			cfg.BandsURL = server.URL + "/api/bands/main.1.json"
+			client := api.NewClient()
+			client.SetUseCharaCache(false)
+
+			charas, err := client.GetAllCharas(context.Background())
+			require.NoError(t, err)
+			var ids []int
+			var bandNames []string
+			for _, chara := range charas {
+				ids = append(ids, chara.ID)
+				bandNames = append(bandNames, chara.BandName.Get(model.LocaleJP))
+			}
+			// 按乐队排序，不属于任何乐队的角色排在最后，没有名称的角色与特殊角色被排除
+			require.Equal(t, []int{1, 36, 37, 601}, ids)
+			require.Equal(t, tt.wantBands, bandNames)
+		})
+	}
+}
+
+func TestMaxCharaID(t *testing.T) {
+	const roster = `{
+		"36":{"characterName":["高松 燈"],"bandId":45},
+		"1001":{"characterName":["特殊角色"],"bandId":45}
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "bands") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(roster))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldRoster, oldBands, oldMax := cfg.CharaRosterURL, cfg.BandsURL, cfg.MaxCharaID
+	cfg.CharaRosterURL = server.URL + "/api/characters"
+	cfg.BandsURL = server.URL + "/api/bands/main.1.json"
+	defer func() { cfg.CharaRosterURL, cfg.BandsURL, cfg.MaxCharaID = oldRoster, oldBands, oldMax }()
+
+	tests := []struct {
+		name       string
+		maxCharaID int
+		want       []int
+	}{
+		{name: "默认上限", maxCharaID: 1000, want: []int{36}},
+		{name: "不限制角色ID", maxCharaID: 0, want: []int{36, 1001}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.MaxCharaID = tt.maxCharaID
+			client := api.NewClient()
+			client.SetUseCharaCache(false)
+
+			charas, err := client.GetAllCharas(context.Background())
+			require.NoError(t, err)
+			var ids []int
+			for _, chara := range charas {
+				ids = append(ids, chara.ID)
+			}
+			require.Equal(t, tt.want, ids)
+
+			members, err := client.GetBandMembers(context.Background(), 45)
+			require.NoError(t, err)
+			ids = nil
+			for _, member := range members {
+				ids = append(ids, member.ID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestSpecialChara(t *testing.T) {
+	// 按 Bestdori 接口的格式构造 ID 大于 1000 的特殊角色，这类角色没有 firstName 与乐队
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/characters/all.2.json":
+			_, _ = w.Write([]byte(`{
+				"1": {"characterName": ["戸山香澄", "Kasumi Toyama", null, "户山香澄", null], "bandId": 1},
+				"1001": {"characterName": ["特殊角色", "Special Chara", null, null, null]},
+				"1002": {"characterName": ["没有服装的角色", null, null, null, null]}
+			}`))
+		case "/characters/1001.json":
+			_, _ = w.Write([]byte(`{"characterName": ["特殊角色", "Special Chara", null, null, null]}`))
+		case "/bands/all.1.json":
+			_, _ = w.Write([]byte(`{"1": {"bandName": ["Poppin'Party", null, null, null, null]}}`))
+		case "/assets/_info.json":
+			_, _ = w.Write([]byte(`{"live2d": {"chara": {
+				"001_casual": {}, "1001_casual": {}, "1001_live_event_1": {}, "1001_general": {}
+			}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldMax, oldRoster, oldBands, oldAssets := cfg.MaxCharaID, cfg.CharaRosterURL, cfg.BandsURL, cfg.AssetsIndexURL
+	oldServers, oldFallback := cfg.Servers, cfg.ServerFallback
+	defer func() {
+		cfg.MaxCharaID, cfg.CharaRosterURL, cfg.BandsURL, cfg.AssetsIndexURL = oldMax, oldRoster, oldBands, oldAssets
+		cfg.Servers, cfg.ServerFallback = oldServers, oldFallback
+	}()
+	cfg.MaxCharaID = 0
+	cfg.CharaRosterURL = server.URL + "/characters"
+	cfg.BandsURL = server.URL + "/bands/all.1.json"
+	cfg.AssetsIndexURL = server.URL + "/assets/_info.json"
+	cfg.Servers, cfg.ServerFallback = nil, nil
+
+	client := api.NewClient()
+	client.SetUseCharaCache(false)
+	ctx := context.Background()
+
+	charas, err := client.GetAllCharas(ctx)
+	require.NoError(t, err)
+	ids := make([]int, 0, len(charas))
+	for _, chara := range charas {
+		ids = append(ids, chara.ID)
+	}
+	require.Equal(t, []int{1, 1001, 1002}, ids, "charas without a band should come after all bands")
+
+	costumes, err := client.GetCharaCostumes(ctx, 1001)
+	require.NoError(t, err)
+	require.Len(t, costumes, 2)
+	for _, costume := range costumes {
+		id, ok := model.Live2dCharaID(costume)
+		require.True(t, ok, costume)
+		require.Equal(t, 1001, id, costume)
+		_, normalizeErr := model.NormalizeLive2dName(costume)
+		require.NoError(t, normalizeErr, costume)
+	}
+
+	info, err := client.GetChara(ctx, 1001)
+	require.NoError(t, err)
+	require.Equal(t, "Special Chara", info.LocalizedName(model.LocaleEN))
+	require.Empty(t, info.LocalizedFirstName(model.LocaleEN))
+
+	// 限制角色ID时不再列出特殊角色
+	cfg.MaxCharaID = 1000
+	charas, err = client.GetAllCharas(ctx)
+	require.NoError(t, err)
+	require.Len(t, charas, 1)
+}
+
+func TestNegativeCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		ttl          time.Duration
+		recordedAgo  time.Duration // 预先写入的负缓存记录距今的时间，为 0 时不预先写入
+		wantRequests int32         // 两次获取数据发送的请求数
+	}{
+		{name: "有效期内不再请求", ttl: time.Hour, wantRequests: 1},
+		{name: "重新创建客户端后仍然有效", ttl: time.Hour, recordedAgo: time.Minute, wantRequests: 0},
+		{name: "过期后重新请求", ttl: time.Hour, recordedAgo: 2 * time.Hour, wantRequests: 1},
+		{name: "关闭负缓存", ttl: 0, wantRequests: 2},
+	}
+
+	cfg := config.Get()
+	oldTTL := cfg.NegativeCacheDuration
+	defer func() { cfg.NegativeCacheDuration = oldTTL }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer server.Close()
+			url := server.URL + "/missing.json"
+
+			cacheDir := t.TempDir()
+			cachePath := filepath.Join(cacheDir, api.NegativeCacheFileName)
+			if tt.recordedAgo > 0 {
+				data := fmt.Sprintf(`{%q: %q}`, url, time.Now().Add(-tt.recordedAgo).Format(time.RFC3339Nano))
+				require.NoError(t, os.WriteFile(cachePath, []byte(data), 0600))
+			}
+			cfg.NegativeCacheDuration = tt.ttl
+			client := api.NewClient()
+			client.SetCharaCachePath(cacheDir)
+
+			for range 2 {
+				_, err := client.FetchData(context.Background(), url, "")
+				require.ErrorIs(t, err, errs.ErrNotFound)
+			}
+			require.Equal(t, tt.wantRequests, requests.Load())
+			require.Equal(t, tt.ttl > 0, client.IsKnownNotFound(url))
+			if tt.ttl > 0 {
+				require.FileExists(t, cachePath)
+			} else {
+				require.NoFileExists(t, cachePath)
+			}
+		})
+	}
+}
+
+func TestCostumeTitles(t *testing.T) {
+	const index = `{"live2d":{"chara":{"037_casual-2023":{},"037_live_event_205":{}}}}`
+	const costumes = `{"1": {"assetBundleName": "037_live_event_205", "description": ["活动服", null]}}`
+
+	tests := []struct {
+		name        string
+		fetch       bool          // 是否先获取服装信息
+		useCache    bool          // 是否使用角色信息缓存
+		recordedAgo time.Duration // 预先写入的缓存记录距今的时间，为 0 时不预先写入
+		costume     string
+		wantTitle   string
+		wantLabel   string
+	}{
+		{name: "获取服装信息后可以查询", fetch: true, useCache: true, costume: "037_live_event_205", wantTitle: "活动服", wantLabel: "037_live_event_205 — 活动服"},
+		{name: "没有标题的服装显示资源包名称", fetch: true, useCache: true, costume: "037_casual-2023", wantLabel: "037_casual-2023"},
+		{name: "重新创建客户端后仍然有效", useCache: true, recordedAgo: time.Minute, costume: "037_live_event_205", wantTitle: "活动服", wantLabel: "037_live_event_205 — 活动服"},
+		{name: "过期后视为没有记录", useCache: true, recordedAgo: 48 * time.Hour, costume: "037_live_event_205", wantLabel: "037_live_event_205"},
+		{name: "不使用缓存时不写入文件", fetch: true, costume: "037_live_event_205", wantTitle: "活动服", wantLabel: "037_live_event_205 — 活动服"},
+	}
+
+	cfg := config.Get()
+	oldBase, oldIndex, oldCostumes := cfg.BaseAssetsURL, cfg.AssetsIndexURL, cfg.CostumesURL
+	oldServers, oldDuration := cfg.Servers, cfg.CacheDuration
+	defer func() {
+		cfg.BaseAssetsURL, cfg.AssetsIndexURL, cfg.CostumesURL = oldBase, oldIndex, oldCostumes
+		cfg.Servers, cfg.CacheDuration = oldServers, oldDuration
+	}()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "_info.json"):
+					_, _ = w.Write([]byte(index))
+				case strings.HasPrefix(r.URL.Path, "/api/costumes/"):
+					_, _ = w.Write([]byte(costumes))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			cfg.BaseAssetsURL = server.URL + "/assets/jp"
+			cfg.AssetsIndexURL = server.URL + "/api/explorer/jp/assets/_info.json"
+			cfg.CostumesURL = server.URL + "/api/costumes/all.5.json"
+			cfg.Servers, cfg.CacheDuration = nil, 24*time.Hour
+
+			cacheDir := t.TempDir()
+			cachePath := filepath.Join(cacheDir, api.CostumeTitlesFileName)
+			if tt.recordedAgo > 0 {
+				data := fmt.Sprintf(`{"jp": {"updated_at": %q, "titles": {"037_live_event_205": "活动服"}}}`,
+					time.Now().Add(-tt.recordedAgo).Format(time.RFC3339Nano))
+				require.NoError(t, os.WriteFile(cachePath, []byte(data), 0600))
+			}
+
+			client := api.NewClient()
+			client.SetCharaCachePath(cacheDir)
+			client.SetUseCharaCache(tt.useCache)
+			if tt.fetch {
+				_, err := client.GetCharaCostumeInfos(context.Background(), 37)
+				require.NoError(t, err)
+				if tt.useCache {
+					// 新的客户端从缓存文件读取，不需要再次获取服装信息
+					client = api.NewClient()
+					client.SetCharaCachePath(cacheDir)
+				} else {
+					require.NoFileExists(t, cachePath)
+				}
+			}
+
+			title, ok := client.CostumeTitle(tt.costume)
+			require.Equal(t, tt.wantTitle != "", ok)
+			require.Equal(t, tt.wantTitle, title)
+			require.Equal(t, tt.wantLabel, client.CostumeLabel(tt.costume))
+		})
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		status    int  // 服务器返回的状态码
+		block     bool // 服务器是否一直等待到请求被取消
+		wantFirst string
+		wantCache string // 刷新结束后缓存文件中的数据
+	}{
+		{name: "先返回旧数据并在后台刷新", enabled: true, status: http.StatusOK, wantFirst: "old", wantCache: "new"},
+		{name: "关闭时等待获取新数据", status: http.StatusOK, wantFirst: "new", wantCache: "new"},
+		{name: "后台刷新失败时保留旧数据", enabled: true, status: http.StatusInternalServerError, wantFirst: "old", wantCache: "old"},
+		{name: "后台刷新随上下文取消", enabled: true, block: true, wantFirst: "old", wantCache: "old"},
+	}
+
+	cfg := config.Get()
+	oldEnabled := cfg.StaleWhileRevalidate
+	defer func() { cfg.StaleWhileRevalidate = oldEnabled }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests, cancelled atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if tt.block {
+					<-r.Context().Done()
+					cancelled.Add(1)
+					return
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"version": "new"}`))
+			}))
+			defer server.Close()
+
+			cacheDir := t.TempDir()
+			cacheFile := filepath.Join(cacheDir, "stale.json")
+			require.NoError(t, os.WriteFile(cacheFile, []byte(`{"version": "old"}`), 0600))
+			expired := time.Now().Add(-48 * time.Hour)
+			require.NoError(t, os.Chtimes(cacheFile, expired, expired))
+
+			cfg.StaleWhileRevalidate = tt.enabled
+			client := api.NewClient()
+			client.SetCharaCachePath(cacheDir)
+			client.SetUseCharaCache(true)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			data, err := client.FetchData(ctx, server.URL+"/stale.json", "stale.json")
+			require.NoError(t, err)
+			require.Equal(t, tt.wantFirst, data["version"])
+
+			require.Eventually(t, func() bool { return requests.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+			if tt.block {
+				cancel()
+				require.Eventually(t, func() bool { return cancelled.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+			}
+			require.Eventually(t, func() bool {
+				raw, readErr := os.ReadFile(cacheFile)
+				return readErr == nil && strings.Contains(string(raw), tt.wantCache)
+			}, 5*time.Second, 10*time.Millisecond)
+			if tt.wantCache == "new" {
+				// 刷新后的缓存在有效期内，不再发送请求
+				data, err = client.FetchData(context.Background(), server.URL+"/stale.json", "stale.json")
+				require.NoError(t, err)
+				require.Equal(t, "new", data["version"])
+				require.Equal(t, int32(1), requests.Load())
+			}
+		})
+	}
+}
+
+func TestCacheCleanup(t *testing.T) {
+	tests := []struct {
+		name        string
+		factor      int
+		offline     bool
+		clear       bool
+		wantRemoved []string
+	}{
+		{name: "删除过期很久的缓存", factor: 7, wantRemoved: []string{
+			"chara_1.json", "chara_4.json.123.bdl.tmp", "assets_info_en.json", api.NegativeCacheFileName,
+		}},
+		{name: "倍数为 0 时不删除", factor: 0},
+		{name: "离线模式不删除", factor: 7, offline: true},
+		{name: "清空缓存", factor: 0, clear: true, wantRemoved: []string{
+			"chara_1.json", "chara_2.json", "chara_4.json.123.bdl.tmp", "assets_info_en.json", api.NegativeCacheFileName,
+		}},
+	}
+
+	cfg := config.Get()
+	oldDuration, oldFactor, oldOffline := cfg.CacheDuration, cfg.CachePruneFactor, cfg.Offline
+	defer func() { cfg.CacheDuration, cfg.CachePruneFactor, cfg.Offline = oldDuration, oldFactor, oldOffline }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := filepath.Join(t.TempDir(), "cache")
+			outside := filepath.Join(t.TempDir(), "outside.json")
+			old := time.Now().Add(-8 * time.Hour)
+			files := map[string]time.Time{
+				"chara_1.json":             old,
+				"chara_2.json":             time.Now(),
+				"chara_4.json.123.bdl.tmp": old,
+				"assets_info_en.json":      old,
+				api.NegativeCacheFileName:  old,
+				"notes.txt":                old, // 不是缓存文件
+				"sub/chara_3.json":         old, // 子目录中的文件
+				"chara_abc.json":           old, // 不是客户端写入的缓存文件名
+				// 同在缓存目录中的下载历史与角色搜索缓存不会随缓存一起删除
+				history.FileName:    old,
+				"chara_search.json": old,
+			}
+			for name, modTime := range files {
+				path := filepath.Join(cacheDir, filepath.FromSlash(name))
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+				require.NoError(t, os.WriteFile(path, []byte(`{}`), 0600))
+				require.NoError(t, os.Chtimes(path, modTime, modTime))
+			}
+			// 指向缓存目录之外的符号链接不会被删除，也不会删除其指向的文件
+			require.NoError(t, os.WriteFile(outside, []byte(`{}`), 0600))
+			require.NoError(t, os.Chtimes(outside, old, old))
+			linked := os.Symlink(outside, filepath.Join(cacheDir, "link.json")) == nil
+
+			cfg.CacheDuration, cfg.CachePruneFactor, cfg.Offline = time.Hour, tt.factor, tt.offline
+			client := api.NewClient()
+			client.SetCharaCachePath(cacheDir)
+
+			var removed []string
+			var err error
+			if tt.clear {
+				removed, err = client.ClearCache()
+			} else {
+				removed, err = client.PruneExpiredCache()
+			}
+			require.NoError(t, err)
+
+			var wantPaths []string
+			for _, name := range tt.wantRemoved {
+				wantPaths = append(wantPaths, filepath.Join(cacheDir, name))
+			}
+			require.ElementsMatch(t, wantPaths, removed)
+			for name := range files {
+				path := filepath.Join(cacheDir, filepath.FromSlash(name))
+				_, statErr := os.Stat(path)
+				require.Equal(t, slices.Contains(tt.wantRemoved, name), os.IsNotExist(statErr), name)
+			}
+			require.FileExists(t, outside)
+			if linked {
+				_, lstatErr := os.Lstat(filepath.Join(cacheDir, "link.json"))
+				require.NoError(t, lstatErr)
+			}
+		})
+	}
+}
+
+func TestClearMissingCacheDir(t *testing.T) {
+	client := api.NewClient()
+	client.SetCharaCachePath(filepath.Join(t.TempDir(), "missing"))
+
+	removed, err := client.ClearCache()
+	require.NoError(t, err)
+	require.Empty(t, removed)
+}
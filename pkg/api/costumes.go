@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// GetCharaCostumeInfos 获取指定角色的所有 Live2D 服装及其描述与发布时间
+// 服装信息获取失败或查不到对应条目时只返回资源包名称
+// 参数:
+//   - ctx: 上下文
+//   - charaID: 角色ID
+//
+// 返回:
+//   - []model.CostumeInfo: 服装列表，按配置的方式排序
+//   - error: 错误信息
+func (c *Client) GetCharaCostumeInfos(ctx context.Context, charaID int) ([]model.CostumeInfo, error) {
+	costumes, err := c.GetCharaCostumes(ctx, charaID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := c.getCostumeDetails(ctx)
+	infos := make([]model.CostumeInfo, 0, len(costumes))
+	for _, name := range costumes {
+		info := model.CostumeInfo{Name: name, EventLimited: strings.Contains(name, "live_event")}
+		if detail, ok := details[name]; ok {
+			info.Description = c.localized(detail, "description")
+			info.PublishedAt = parsePublishedAt(c.localized(detail, "publishedAt"))
+		}
+		infos = append(infos, info)
+	}
+
+	if c.costumeSort == model.CostumeSortRelease {
+		model.SortCostumeInfosByRelease(infos)
+	}
+	return infos, nil
+}
+
+// getCostumeDetails 获取服装信息，key 为服装的资源包名称
+// 同时将服装标题记录到服装标题缓存；获取失败时只记录日志并返回空映射，调用方按没有描述处理.
+func (c *Client) getCostumeDetails(ctx context.Context) map[string]map[string]any {
+	data, err := c.FetchData(ctx, c.costumesURL, costumesCache)
+	if err != nil {
+		log.DefaultLogger.Warn().Err(err).Msg("获取服装信息失败，只显示资源包名称")
+		return nil
+	}
+
+	details := make(map[string]map[string]any, len(data))
+	titles := make(map[string]string, len(data))
+	var malformed []string
+	for id, value := range data {
+		detail, ok := value.(map[string]any)
+		bundle, _ := model.StringAt(value, "assetBundleName")
+		if !ok || bundle == "" {
+			malformed = append(malformed, id)
+			continue
+		}
+		details[bundle] = detail
+		if title := c.localized(detail, "description"); title != "" {
+			titles[bundle] = title
+		}
+	}
+	if len(malformed) > 0 {
+		slices.Sort(malformed)
+		log.DefaultLogger.Debug().Strs("ids", malformed).Msg("跳过缺少资源包名称的服装信息")
+	}
+	c.titles.record(c.currentServer(), titles, c.useCharaCache)
+	return details
+}
+
+// localized 从服装信息中按服务器排列的多语言数组取值
+// 优先使用当前服务器的值，没有时使用第一个非空值；字段缺失或格式错误时返回空字符串.
+func (c *Client) localized(detail map[string]any, key string) string {
+	values, _ := model.StringsAt(detail, key)
+	// Bestdori 多语言数组的服务器顺序
+	return values.Get(slices.Index([]string{"jp", "en", "tw", "cn", "kr"}, c.currentServer()))
+}
+
+// parsePublishedAt 解析以毫秒时间戳字符串表示的发布时间，无法解析时返回零值.
+func parsePublishedAt(value string) time.Time {
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || millis <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(millis)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	fetchRetryCount = 3               // 获取数据失败时的最大尝试次数
+	fetchRetryDelay = 2 * time.Second // 重试的基础间隔，每次重试翻倍
+
+	charaFetchConcurrency = 10 // 逐个获取角色信息时的最大并发数
+)
+
+// fetchWithRetry 获取数据，失败时按指数退避重试
+// 资源不存在的错误不会重试
+// 参数:
+//   - ctx: 上下文
+//   - fetch: 获取数据的函数
+//
+// 返回:
+//   - T: 获取的数据
+//   - error: 最后一次尝试的错误信息
+func fetchWithRetry[T any](ctx context.Context, fetch func() (T, error)) (T, error) {
+	delay := fetchRetryDelay
+	var zero T
+	var lastErr error
+	for attempt := 1; attempt <= fetchRetryCount; attempt++ {
+		data, err := fetch()
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+		if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrOffline) || attempt == fetchRetryCount {
+			break
+		}
+
+		log.DefaultLogger.Warn().Int("attempt", attempt).Dur("delay", delay).Err(err).Msg("获取数据失败，稍后重试")
+		select {
+		case <-ctx.Done():
+			return zero, fmt.Errorf("获取数据已取消: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return zero, lastErr
+}
+
+// GetAllCostumes 获取所有角色的 Live2D 服装列表
+// 只请求一次资源索引，避免逐个角色请求
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - map[int][]string: 服装列表映射，key 为角色ID
+//   - error: 错误信息
+func (c *Client) GetAllCostumes(ctx context.Context) (map[int][]string, error) {
+	assetsInfo, err := fetchWithRetry(ctx, func() (map[string]any, error) {
+		return c.FetchData(ctx, c.assetsIndexURL, assetsIndexCache)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := model.NewAssetsIndex(assetsInfo)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // 已包含上下文
+	}
+
+	costumes := index.Costumes()
+	for charaID := range costumes {
+		sortCostumes(costumes[charaID])
+	}
+	return costumes, nil
+}
+
+// ResolveCharas 获取指定角色的信息
+// 先从角色列表中查找，角色列表中没有的角色再并发逐个请求，单个角色获取失败时只记录日志
+// 参数:
+//   - ctx: 上下文
+//   - charaIDs: 角色ID列表
+//
+// 返回:
+//   - map[int]*model.CharaInfo: 角色信息，key 为角色ID，不包含获取失败的角色
+//   - error: 获取角色列表失败或被取消时返回错误
+func (c *Client) ResolveCharas(ctx context.Context, charaIDs []int) (map[int]*model.CharaInfo, error) {
+	roster, err := fetchWithRetry(ctx, func() (map[string]*model.CharaInfo, error) {
+		return c.GetCharaRoster(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取角色列表失败: %w", err)
+	}
+
+	charas := make(map[int]*model.CharaInfo, len(charaIDs))
+	var missing []int
+	for _, charaID := range charaIDs {
+		if info, ok := roster[strconv.Itoa(charaID)]; ok {
+			charas[charaID] = info
+		} else {
+			missing = append(missing, charaID)
+		}
+	}
+
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(charaFetchConcurrency)
+	for _, charaID := range missing {
+		g.Go(func() error {
+			info, fetchErr := c.GetChara(gctx, charaID)
+			if errs.IsCancelled(fetchErr) {
+				return fetchErr
+			}
+			if fetchErr != nil {
+				log.DefaultLogger.Debug().Int("charaID", charaID).Err(fetchErr).Msg("获取角色信息失败，跳过")
+				return nil
+			}
+			mu.Lock()
+			charas[charaID] = info
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return nil, fmt.Errorf("获取角色信息失败: %w", err)
+	}
+	return charas, nil
+}
+
+// BuildCharaDatabase 构建完整的角色-模型映射数据库
+// 任一请求在重试后仍然失败时返回错误，避免生成不完整的数据库
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - []model.CharaRecord: 按角色ID排序的映射记录
+//   - error: 错误信息
+func (c *Client) BuildCharaDatabase(ctx context.Context) ([]model.CharaRecord, error) {
+	roster, err := fetchWithRetry(ctx, func() (map[string]*model.CharaInfo, error) {
+		return c.GetCharaRoster(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取角色列表失败: %w", err)
+	}
+
+	costumes, err := c.GetAllCostumes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取服装列表失败: %w", err)
+	}
+
+	records := make([]model.CharaRecord, 0, len(roster))
+	for id, info := range roster {
+		charaID, parseErr := strconv.Atoi(id)
+		if parseErr != nil {
+			continue
+		}
+
+		record := model.CharaRecord{
+			CharaID:    charaID,
+			CharaNames: []string{},
+			Costumes:   costumes[charaID],
+		}
+		// 保留各服务器的位置，缺失的名称记为空字符串
+		record.CharaNames = append(record.CharaNames, info.CharacterName...)
+		if record.Costumes == nil {
+			record.Costumes = []string{}
+		}
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CharaID < records[j].CharaID
+	})
+
+	log.DefaultLogger.Info().Int("charaCount", len(records)).Msg("角色-模型数据库构建完成")
+	return records, nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+)
+
+const (
+	// layoutMinSamples 是判断资源结构变化前至少需要的构建数据请求数，避免个别模型下架造成误报.
+	layoutMinSamples = 5
+	// layoutFailureRatio 是判断资源结构变化的构建数据失败率.
+	layoutFailureRatio = 0.8
+)
+
+// layoutMonitor 统计依赖 Bestdori 资源结构的请求结果
+// 网络错误与取消不计入统计，只有格式无法解析、路径不存在等结构性失败才会计入.
+type layoutMonitor struct {
+	mu          sync.Mutex
+	attempts    int  // 构建数据请求数
+	failures    int  // 构建数据结构性失败数
+	indexBroken bool // 资源索引是否无法解析
+	reported    bool // 是否已报告过资源结构变化
+}
+
+// isStructuralError 判断错误是否表示返回的内容不符合预期的资源结构
+// notFound 表示是否将资源不存在视为结构性失败，资源索引中存在的模型取不到构建数据时才应如此.
+func isStructuralError(err error, notFound bool) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.Is(err, errs.ErrInvalidAssetsIndex) ||
+		errors.Is(err, errs.ErrInvalidBuildData) ||
+		errors.Is(err, errs.ErrFileUnavailable) ||
+		(notFound && errors.Is(err, errs.ErrNotFound)) ||
+		errors.As(err, &syntaxErr) ||
+		errors.As(err, &typeErr)
+}
+
+// recordBuildData 记录一次构建数据请求的结果.
+func (m *layoutMonitor) recordBuildData(err error) {
+	if err != nil && !isStructuralError(err, true) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts++
+	if err != nil {
+		m.failures++
+	}
+}
+
+// recordIndex 记录一次资源索引请求的结果
+// 资源索引无法解析时直接视为资源结构已变化.
+func (m *layoutMonitor) recordIndex(err error) {
+	if err == nil || !isStructuralError(err, false) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.indexBroken = true
+}
+
+// changed 判断资源结构是否可能已变化.
+func (m *layoutMonitor) changed() bool {
+	if m.indexBroken {
+		return true
+	}
+	return m.attempts >= layoutMinSamples && float64(m.failures) >= layoutFailureRatio*float64(m.attempts)
+}
+
+// LayoutChange 检查本次运行中是否出现了异常高的结构性失败
+// 只在第一次检测到时返回错误，调用方可以在每次失败后调用而不会重复提示
+// 返回:
+//   - error: 检测到资源结构可能变化时返回 errs.ErrLayoutChanged
+func (c *Client) LayoutChange() error {
+	m := &c.layout
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reported || !m.changed() {
+		return nil
+	}
+	m.reported = true
+	log.DefaultLogger.Error().
+		Int("attempts", m.attempts).
+		Int("failures", m.failures).
+		Bool("indexBroken", m.indexBroken).
+		Msg("检测到大量结构性失败，Bestdori 资源结构可能已变化")
+	return errs.ErrLayoutChanged
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+)
+
+// NegativeCacheFileName 是角色信息缓存目录下记录已确认不存在的 URL 的文件名.
+const NegativeCacheFileName = "negative_cache.json"
+
+// negativeCache 记录最近返回 404 的 URL，有效期内不再重复请求
+// 第一次使用时从缓存文件加载，记录新的 URL 时写回并清理已过期的记录.
+type negativeCache struct {
+	mu      sync.Mutex
+	path    string               // 缓存文件路径
+	ttl     time.Duration        // 记录的有效期，为 0 时不使用负缓存
+	loaded  bool                 // 是否已加载缓存文件
+	entries map[string]time.Time // 返回 404 的时间，key 为 URL
+}
+
+// newNegativeCache 创建负缓存，ttl 为 0 时不记录也不命中.
+func newNegativeCache(path string, ttl time.Duration) *negativeCache {
+	return &negativeCache{path: path, ttl: ttl, entries: make(map[string]time.Time)}
+}
+
+// load 在第一次使用时加载缓存文件，文件不存在或损坏时从空缓存开始.
+func (n *negativeCache) load() {
+	if n.loaded {
+		return
+	}
+	n.loaded = true
+	raw, err := os.ReadFile(n.path)
+	if err != nil {
+		return
+	}
+	var entries map[string]time.Time
+	if unmarshalErr := json.Unmarshal(raw, &entries); unmarshalErr != nil {
+		log.DefaultLogger.Warn().Str("path", n.path).Err(unmarshalErr).Msg("负缓存文件损坏，忽略")
+		return
+	}
+	for url, at := range entries {
+		if time.Since(at) < n.ttl {
+			n.entries[url] = at
+		}
+	}
+}
+
+// contains 判断 URL 是否在有效期内返回过 404.
+func (n *negativeCache) contains(url string) bool {
+	if n.ttl <= 0 {
+		return false
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.load()
+	at, ok := n.entries[url]
+	return ok && time.Since(at) < n.ttl
+}
+
+// add 记录 URL 返回了 404 并写回缓存文件，写入失败只记录日志.
+func (n *negativeCache) add(url string) {
+	if n.ttl <= 0 {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.load()
+	for key, at := range n.entries {
+		if time.Since(at) >= n.ttl {
+			delete(n.entries, key)
+		}
+	}
+	n.entries[url] = time.Now()
+	if err := n.save(); err != nil {
+		log.DefaultLogger.Warn().Str("path", n.path).Err(err).Msg("写入负缓存失败")
+	}
+}
+
+// save 将记录写入缓存文件.
+func (n *negativeCache) save() error {
+	raw, err := json.Marshal(n.entries)
+	if err != nil {
+		return fmt.Errorf("序列化负缓存失败: %w", err)
+	}
+	if mkdirErr := os.MkdirAll(filepath.Dir(n.path), 0750); mkdirErr != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", mkdirErr)
+	}
+	file, err := fsutil.CreateTemp(n.path)
+	if err != nil {
+		return fmt.Errorf("写入负缓存失败: %w", err)
+	}
+	if _, writeErr := file.Write(raw); writeErr != nil {
+		fsutil.DiscardTemp(file)
+		return fmt.Errorf("写入负缓存失败: %w", writeErr)
+	}
+	if commitErr := fsutil.CommitTemp(file, n.path); commitErr != nil {
+		return fmt.Errorf("写入负缓存失败: %w", commitErr)
+	}
+	return nil
+}
+
+// IsKnownNotFound 判断 URL 是否在负缓存的有效期内返回过 404
+// 参数:
+//   - url: 请求的 URL
+//
+// 返回:
+//   - bool: 是否已确认不存在，负缓存关闭时始终为 false
+func (c *Client) IsKnownNotFound(url string) bool {
+	return c.notFound.contains(url)
+}
+
+// RecordNotFound 记录 URL 返回了 404，负缓存的有效期内再次请求时直接返回资源不存在
+// 参数:
+//   - url: 请求的 URL
+func (c *Client) RecordNotFound(url string) {
+	c.notFound.add(url)
+}
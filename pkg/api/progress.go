@@ -0,0 +1,63 @@
+package api
+
+import (
+	"io"
+	"path"
+	"time"
+)
+
+// progressInterval 是两次进度回调之间的最小间隔.
+const progressInterval = 100 * time.Millisecond
+
+// FetchProgressFunc 表示获取数据时的进度回调
+// 参数:
+//   - endpoint: 正在获取的接口名称
+//   - read: 已读取的字节数
+//   - total: 总字节数，未知时为 -1
+type FetchProgressFunc func(endpoint string, read, total int64)
+
+// progressReader 在读取数据时报告进度.
+type progressReader struct {
+	reader     io.Reader         // 原始读取器
+	endpoint   string            // 接口名称
+	read       int64             // 已读取的字节数
+	total      int64             // 总字节数
+	lastReport time.Time         // 上一次报告的时间
+	onProgress FetchProgressFunc // 进度回调
+}
+
+// Read 读取数据并按间隔报告进度，读取结束时总会报告一次.
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if err != nil || time.Since(r.lastReport) >= progressInterval {
+		r.lastReport = time.Now()
+		r.onProgress(r.endpoint, r.read, r.total)
+	}
+	return n, err //nolint:wrapcheck // 需要原样返回 io.EOF
+}
+
+// endpointName 返回用于显示的接口名称.
+func (c *Client) endpointName(url string) string {
+	switch url {
+	case c.assetsIndexURL:
+		return "资源索引"
+	case c.charaRosterURL + "/all.2.json":
+		return "角色列表"
+	case c.costumesURL:
+		return "服装信息"
+	}
+	for _, server := range c.indexServers() {
+		if indexURL, _ := c.serverIndex(server); url == indexURL {
+			return "资源索引 (" + server + ")"
+		}
+	}
+	return path.Base(url)
+}
+
+// SetFetchProgress 设置获取数据时的默认进度回调
+// 参数:
+//   - onProgress: 进度回调，为 nil 时不报告进度
+func (c *Client) SetFetchProgress(onProgress FetchProgressFunc) {
+	c.fetchProgress = onProgress
+}
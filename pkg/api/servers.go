@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"slices"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// configServers 返回配置中合并查询资源索引的服务器
+// 指定单个服务器时只查询该服务器，为 all 且未配置 Servers 时查询所有已知服务器.
+func configServers(cfg *config.Config) []string {
+	switch cfg.Server {
+	case "":
+		return cfg.Servers
+	case config.ServerAll:
+		if len(cfg.Servers) == 0 {
+			return config.KnownServers()
+		}
+		return cfg.Servers
+	default:
+		return []string{cfg.AssetsServer()}
+	}
+}
+
+// Server 返回当前使用的服务器，合并查询所有服务器时返回 all.
+func (c *Client) Server() string {
+	if c.server == config.ServerAll {
+		return config.ServerAll
+	}
+	return c.currentServer()
+}
+
+// currentServer 返回资源基础 URL 对应的服务器.
+func (c *Client) currentServer() string {
+	return config.AssetsURLServer(c.baseAssetsURL)
+}
+
+// indexServers 返回合并查询资源索引的服务器
+// 未配置时只查询当前服务器.
+func (c *Client) indexServers() []string {
+	if len(c.servers) == 0 {
+		return []string{c.currentServer()}
+	}
+	return c.servers
+}
+
+// serverIndex 返回指定服务器的资源索引 URL 与缓存文件名
+// 当前服务器沿用原有的缓存文件名.
+func (c *Client) serverIndex(server string) (string, string) {
+	current := c.currentServer()
+	if server == current {
+		return c.assetsIndexURL, assetsIndexCache
+	}
+	return config.ServerAssetsIndexURL(c.assetsIndexURL, current, server), serverAssetsIndexPrefix + server + cacheFileExt
+}
+
+// getServerLive2dAssets 获取指定服务器的 Live2D 资源映射
+// 参数:
+//   - ctx: 上下文
+//   - server: 服务器名称
+//
+// 返回:
+//   - *model.AssetsIndex: 资源索引
+//   - error: 错误信息
+func (c *Client) getServerLive2dAssets(ctx context.Context, server string) (*model.AssetsIndex, error) {
+	url, cache := c.serverIndex(server)
+	assetsInfo, err := c.FetchData(ctx, url, cache)
+	if err != nil {
+		c.layout.recordIndex(err)
+		return nil, err
+	}
+
+	// 资源结构变化时字段可能缺失，逐层校验以免 panic
+	index, err := model.NewAssetsIndex(assetsInfo)
+	if err != nil {
+		c.layout.recordIndex(err)
+		return nil, err //nolint:wrapcheck // 已包含上下文
+	}
+	return index, nil
+}
+
+// modelSource 表示合并资源索引后一个模型的来源.
+type modelSource struct {
+	server  string            // 最先收录该模型的服务器
+	bundles map[string]string // 各服务器资源索引中该模型的资源包名称，key 为服务器
+}
+
+// getLive2dAssets 合并查询各服务器的资源索引
+// 模型按 model.CanonicalLive2dName 的统一名称合并，同时记录各服务器中的原始资源包名称；
+// 同名模型记录为排在前面的服务器，部分服务器获取失败时只记录日志，全部失败时返回第一个错误
+// 参数:
+//   - ctx: 上下文
+//
+// 返回:
+//   - map[string]string: 模型统一名称到来源服务器的映射
+//   - error: 错误信息
+func (c *Client) getLive2dAssets(ctx context.Context) (map[string]string, error) {
+	merged := make(map[string]string)
+	sources := make(map[string]*modelSource)
+	var firstErr error
+	succeeded := 0
+	for _, server := range c.indexServers() {
+		index, err := c.getServerLive2dAssets(ctx, server)
+		if err != nil {
+			log.DefaultLogger.Warn().Str("server", server).Err(err).Msg("获取资源索引失败，跳过该服务器")
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		succeeded++
+		added := 0
+		for _, bundle := range index.Live2dCharas {
+			name := model.CanonicalLive2dName(bundle, server)
+			if name != bundle {
+				log.DefaultLogger.Debug().Str("server", server).Str("bundle", bundle).Str("live2dName", name).Msg("资源包名称带有服务器后缀")
+			}
+			source, exists := sources[name]
+			if !exists {
+				source = &modelSource{server: server, bundles: make(map[string]string)}
+				sources[name] = source
+				merged[name] = server
+				added++
+			}
+			if _, recorded := source.bundles[server]; !recorded {
+				source.bundles[server] = bundle
+			}
+		}
+		log.DefaultLogger.Debug().
+			Str("server", server).
+			Int("models", len(index.Live2dCharas)).
+			Int("added", added).
+			Msg("合并资源索引")
+	}
+	if succeeded == 0 {
+		return nil, firstErr
+	}
+
+	c.serversMu.Lock()
+	c.modelSources = sources
+	c.serversMu.Unlock()
+	return merged, nil
+}
+
+// modelServer 返回资源索引中记录的模型来源服务器.
+func (c *Client) modelServer(live2dName string) (string, bool) {
+	c.serversMu.RLock()
+	defer c.serversMu.RUnlock()
+	source, ok := c.modelSources[live2dName]
+	if !ok {
+		return "", false
+	}
+	return source.server, true
+}
+
+// bundleName 返回模型在指定服务器上的资源包名称
+// 资源索引中没有记录该服务器时按统一名称访问.
+func (c *Client) bundleName(live2dName, server string) string {
+	c.serversMu.RLock()
+	defer c.serversMu.RUnlock()
+	if source, ok := c.modelSources[live2dName]; ok {
+		if bundle, recorded := source.bundles[server]; recorded {
+			return bundle
+		}
+	}
+	return live2dName
+}
+
+// fallbackServers 返回获取构建数据时依次尝试的服务器
+// 资源索引中记录的来源服务器最先尝试，其次是当前服务器，其余按回退顺序排列并去除重复.
+func (c *Client) fallbackServers(live2dName string) []string {
+	var servers []string
+	if server, ok := c.modelServer(live2dName); ok {
+		servers = append(servers, server)
+	}
+	for _, server := range append([]string{c.currentServer()}, c.serverFallback...) {
+		if !slices.Contains(servers, server) {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// CostumeTitlesFileName 是角色信息缓存目录下记录服装标题的文件名.
+const CostumeTitlesFileName = "costume_titles.json"
+
+// serverTitles 表示一个服务器的服装标题.
+type serverTitles struct {
+	UpdatedAt time.Time         `json:"updated_at"` // 最近一次获取服装信息的时间
+	Titles    map[string]string `json:"titles"`     // 服装标题，key 为资源包名称
+}
+
+// titleCache 记录资源包名称到服装标题的映射，按服务器分开保存
+// 获取服装信息时写入，下载列表、下载历史等只有资源包名称的地方据此显示服装标题；
+// 第一次使用时从缓存文件加载，超过缓存过期时间的服务器视为没有记录；不使用角色信息缓存时只保存在内存中.
+type titleCache struct {
+	mu      sync.Mutex
+	path    string                   // 缓存文件路径
+	ttl     time.Duration            // 记录的有效期，为 0 时不过期
+	loaded  bool                     // 是否已加载缓存文件
+	servers map[string]*serverTitles // 各服务器的服装标题，key 为服务器名称
+}
+
+// newTitleCache 创建服装标题缓存.
+func newTitleCache(path string, ttl time.Duration) *titleCache {
+	return &titleCache{path: path, ttl: ttl, servers: make(map[string]*serverTitles)}
+}
+
+// load 在第一次使用时加载缓存文件，文件不存在或损坏时从空缓存开始.
+func (t *titleCache) load() {
+	if t.loaded {
+		return
+	}
+	t.loaded = true
+	raw, err := os.ReadFile(t.path)
+	if err != nil {
+		return
+	}
+	var servers map[string]*serverTitles
+	if unmarshalErr := json.Unmarshal(raw, &servers); unmarshalErr != nil {
+		log.DefaultLogger.Warn().Str("path", t.path).Err(unmarshalErr).Msg("服装标题缓存文件损坏，忽略")
+		return
+	}
+	for server, titles := range servers {
+		if titles != nil && !t.expired(titles) {
+			t.servers[server] = titles
+		}
+	}
+}
+
+// expired 判断服务器的记录是否已超过有效期.
+func (t *titleCache) expired(titles *serverTitles) bool {
+	return t.ttl > 0 && time.Since(titles.UpdatedAt) >= t.ttl
+}
+
+// lookup 返回服务器中资源包对应的服装标题.
+func (t *titleCache) lookup(server, name string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.load()
+	titles, ok := t.servers[server]
+	if !ok || t.expired(titles) {
+		return "", false
+	}
+	title, ok := titles.Titles[name]
+	return title, ok && title != ""
+}
+
+// record 用新获取的服装标题替换服务器原有的记录
+// persist 为 true 时写回缓存文件，写入失败只记录日志.
+func (t *titleCache) record(server string, titles map[string]string, persist bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.load()
+	t.servers[server] = &serverTitles{UpdatedAt: time.Now(), Titles: titles}
+	if !persist {
+		return
+	}
+	if err := t.save(); err != nil {
+		log.DefaultLogger.Warn().Str("path", t.path).Err(err).Msg("写入服装标题缓存失败")
+	}
+}
+
+// save 将记录写入缓存文件.
+func (t *titleCache) save() error {
+	raw, err := json.Marshal(t.servers)
+	if err != nil {
+		return fmt.Errorf("序列化服装标题缓存失败: %w", err)
+	}
+	if mkdirErr := os.MkdirAll(filepath.Dir(t.path), 0750); mkdirErr != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", mkdirErr)
+	}
+	file, err := fsutil.CreateTemp(t.path)
+	if err != nil {
+		return fmt.Errorf("写入服装标题缓存失败: %w", err)
+	}
+	if _, writeErr := file.Write(raw); writeErr != nil {
+		fsutil.DiscardTemp(file)
+		return fmt.Errorf("写入服装标题缓存失败: %w", writeErr)
+	}
+	if commitErr := fsutil.CommitTemp(file, t.path); commitErr != nil {
+		return fmt.Errorf("写入服装标题缓存失败: %w", commitErr)
+	}
+	return nil
+}
+
+// CostumeTitle 返回资源包在当前服务器的服装标题
+// 标题在获取服装信息时记录，不发送网络请求
+// 参数:
+//   - name: 资源包名称
+//
+// 返回:
+//   - string: 服装标题，没有记录或记录已过期时为空
+//   - bool: 是否有记录
+func (c *Client) CostumeTitle(name string) (string, bool) {
+	return c.titles.lookup(c.currentServer(), name)
+}
+
+// CostumeLabel 返回显示用的服装名称，格式与服装列表相同，例如 "037_casual-2023 — 私服"
+// 没有服装标题时返回资源包名称
+// 参数:
+//   - name: 资源包名称
+//
+// 返回:
+//   - string: 显示用的服装名称
+func (c *Client) CostumeLabel(name string) string {
+	title, _ := c.CostumeTitle(name)
+	return model.CostumeInfo{Name: name, Description: title}.Label()
+}
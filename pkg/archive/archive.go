@@ -0,0 +1,179 @@
+// Package archive 提供了将下载的模型目录打包为 ZIP 的功能
+// 与 pack 包的分发包不同，生成的是不附加描述信息的普通 ZIP，便于直接导入 WebGAL 等工具
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+// Extension 是模型 ZIP 的文件扩展名.
+const Extension = ".zip"
+
+// ZipModel 将模型目录打包为 ZIP，以模型目录名作为 ZIP 的顶层目录
+// 参数:
+//   - srcDir: 模型目录
+//   - destZip: ZIP 文件路径，位于模型目录中时不会把自身写入 ZIP
+//
+// 返回:
+//   - error: 错误信息
+func ZipModel(srcDir, destZip string) error {
+	return ZipModelAs(srcDir, destZip, filepath.Base(srcDir))
+}
+
+// ZipModelAs 将模型目录中的所有文件写入 ZIP，保留相对于模型目录的路径
+// 所有条目位于唯一的顶层目录 root 下，解压后得到 <root>/data/...，不会散落在当前目录；
+// .json 文件使用 deflate 压缩，纹理、动作等二进制文件只存储不压缩；
+// 先写入临时文件，完成后再重命名，避免留下不完整的 ZIP
+// 参数:
+//   - srcDir: 模型目录
+//   - destZip: ZIP 文件路径，位于模型目录中时不会把自身写入 ZIP
+//   - root: ZIP 的顶层目录名称，通常为模型名称，不能包含路径分隔符
+//
+// 返回:
+//   - error: 错误信息
+func ZipModelAs(srcDir, destZip, root string) error {
+	if root == "" || root == "." || root == ".." || strings.ContainsAny(root, `/\`) {
+		return fmt.Errorf("无效的 ZIP 顶层目录名称: %q", root)
+	}
+
+	names, err := listFiles(srcDir, destZip)
+	if err != nil {
+		return err
+	}
+
+	file, err := fsutil.CreateTemp(destZip)
+	if err != nil {
+		return fmt.Errorf("创建 ZIP 失败: %w", err)
+	}
+
+	zw := zip.NewWriter(file)
+	var writeErr error
+	for _, name := range names {
+		if writeErr = addFile(zw, srcDir, root, name); writeErr != nil {
+			break
+		}
+	}
+	if writeErr == nil {
+		writeErr = zw.Close()
+	}
+	if writeErr != nil {
+		fsutil.DiscardTemp(file)
+		return fmt.Errorf("写入 ZIP 失败: %w", writeErr)
+	}
+
+	if commitErr := fsutil.CommitTemp(file, destZip); commitErr != nil {
+		return fmt.Errorf("保存 ZIP 失败: %w", commitErr)
+	}
+	return nil
+}
+
+// listFiles 返回模型目录中需要写入 ZIP 的文件，路径相对于模型目录并使用正斜杠
+// 跳过下载中断留下的临时文件与 ZIP 自身.
+func listFiles(srcDir, destZip string) ([]string, error) {
+	destAbs, err := filepath.Abs(destZip)
+	if err != nil {
+		return nil, fmt.Errorf("解析 ZIP 路径失败: %w", err)
+	}
+
+	var names []string
+	walkErr := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || fsutil.IsTempFile(d.Name()) {
+			return nil
+		}
+		if abs, absErr := filepath.Abs(path); absErr == nil && abs == destAbs {
+			return nil
+		}
+		rel, relErr := filepath.Rel(srcDir, path)
+		if relErr != nil {
+			return relErr //nolint:wrapcheck // 由调用方统一包装
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if walkErr != nil {
+		return nil, fmt.Errorf("遍历模型目录失败: %w", walkErr)
+	}
+	return names, nil
+}
+
+// addFile 将模型目录中的文件写入 ZIP 中的 root 目录下.
+func addFile(zw *zip.Writer, srcDir, root, name string) error {
+	src, err := os.Open(filepath.Join(srcDir, filepath.FromSlash(name)))
+	if err != nil {
+		return err //nolint:wrapcheck // 由 ZipModelAs 统一包装
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err //nolint:wrapcheck // 由 ZipModelAs 统一包装
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err //nolint:wrapcheck // 由 ZipModelAs 统一包装
+	}
+	header.Name = path.Join(root, name)
+	header.Method = compressionMethod(name)
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err //nolint:wrapcheck // 由 ZipModelAs 统一包装
+	}
+	_, err = io.Copy(w, src)
+	return err //nolint:wrapcheck // 由 ZipModelAs 统一包装
+}
+
+// compressionMethod 返回文件的压缩方式，只有 JSON 文件值得压缩.
+func compressionMethod(name string) uint16 {
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		return zip.Deflate
+	}
+	return zip.Store
+}
+
+// RemoveSource 在模型打包为 ZIP 后删除模型目录
+// 先确认 ZIP 可以打开且不在模型目录中，避免删除后只剩下损坏的 ZIP 或连同 ZIP 一起删除
+// 参数:
+//   - srcDir: 模型目录
+//   - destZip: ZipModel 生成的 ZIP 文件路径
+//
+// 返回:
+//   - error: 错误信息
+func RemoveSource(srcDir, destZip string) error {
+	srcAbs, err := filepath.Abs(srcDir)
+	if err != nil {
+		return fmt.Errorf("解析模型目录失败: %w", err)
+	}
+	destAbs, err := filepath.Abs(destZip)
+	if err != nil {
+		return fmt.Errorf("解析 ZIP 路径失败: %w", err)
+	}
+	if rel, relErr := filepath.Rel(srcAbs, destAbs); relErr == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("ZIP 位于模型目录中，不能删除模型目录: %s", destZip)
+	}
+
+	zr, err := zip.OpenReader(destZip)
+	if err != nil {
+		return fmt.Errorf("打开 ZIP 失败: %w", err)
+	}
+	if closeErr := zr.Close(); closeErr != nil {
+		return fmt.Errorf("关闭 ZIP 失败: %w", closeErr)
+	}
+
+	if removeErr := os.RemoveAll(srcDir); removeErr != nil {
+		return fmt.Errorf("删除模型目录失败: %w", removeErr)
+	}
+	return nil
+}
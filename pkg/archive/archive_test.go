@@ -0,0 +1,180 @@
+package archive_test
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/archive"
+)
+
+// writeFiles 在目录中写入测试文件.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+}
+
+func TestZipModel(t *testing.T) {
+	files := map[string]string{
+		"model.json":                   `{"version":"1.0.0"}`,
+		"data/model.moc":               "moc",
+		"data/textures/texture_00.png": "png",
+		"data/motions/idle01.MTN":      "mtn",
+		"data/physics.JSON":            `{}`,
+	}
+
+	tests := []struct {
+		name string
+		dest func(srcDir string) string
+	}{
+		{"写入模型目录之外", func(srcDir string) string { return srcDir + archive.Extension }},
+		{"写入模型目录之内", func(srcDir string) string { return filepath.Join(srcDir, "model.zip") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcDir := filepath.Join(t.TempDir(), "037_casual-2023")
+			writeFiles(t, srcDir, files)
+			// 下载中断留下的临时文件不会写入 ZIP
+			writeFiles(t, srcDir, map[string]string{"data/texture_01.png.123.bdl.tmp": "partial"})
+
+			dest := tt.dest(srcDir)
+			require.NoError(t, archive.ZipModel(srcDir, dest))
+
+			zr, err := zip.OpenReader(dest)
+			require.NoError(t, err)
+			defer zr.Close()
+
+			got := make(map[string]string, len(zr.File))
+			for _, f := range zr.File {
+				wantMethod := zip.Store
+				if filepath.Ext(f.Name) == ".json" || filepath.Ext(f.Name) == ".JSON" {
+					wantMethod = zip.Deflate
+				}
+				assert.Equal(t, wantMethod, f.Method, f.Name)
+
+				rc, openErr := f.Open()
+				require.NoError(t, openErr)
+				data, readErr := io.ReadAll(rc)
+				require.NoError(t, readErr)
+				require.NoError(t, rc.Close())
+				got[f.Name] = string(data)
+			}
+			want := make(map[string]string, len(files))
+			for name, content := range files {
+				want["037_casual-2023/"+name] = content
+			}
+			assert.Equal(t, want, got)
+
+			entries, err := os.ReadDir(filepath.Dir(dest))
+			require.NoError(t, err)
+			for _, entry := range entries {
+				assert.NotContains(t, entry.Name(), ".bdl.tmp", "temp file should be renamed")
+			}
+		})
+	}
+}
+
+func TestZipModelTopLevelDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		root     string
+		wantRoot string
+		wantErr  bool
+	}{
+		{"使用模型目录名", "", "casual", false},
+		{"使用模型名称", "037_casual-2023", "037_casual-2023", false},
+		{"名称包含路径分隔符", "a/b", "", true},
+		{"名称为上级目录", "..", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcDir := filepath.Join(t.TempDir(), "casual")
+			writeFiles(t, srcDir, map[string]string{
+				"model.json":                   `{}`,
+				"data/model.moc":               "moc",
+				"data/textures/texture_00.png": "png",
+			})
+
+			dest := srcDir + archive.Extension
+			var err error
+			if tt.root == "" {
+				err = archive.ZipModel(srcDir, dest)
+			} else {
+				err = archive.ZipModelAs(srcDir, dest, tt.root)
+			}
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.NoFileExists(t, dest)
+				return
+			}
+			require.NoError(t, err)
+
+			zr, err := zip.OpenReader(dest)
+			require.NoError(t, err)
+			defer zr.Close()
+
+			require.Len(t, zr.File, 3)
+			for _, f := range zr.File {
+				assert.True(t, strings.HasPrefix(f.Name, tt.wantRoot+"/"), f.Name)
+			}
+		})
+	}
+}
+
+func TestZipModelMissingDir(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "missing.zip")
+
+	require.Error(t, archive.ZipModel(filepath.Join(dir, "missing"), dest))
+	assert.NoFileExists(t, dest)
+}
+
+func TestRemoveSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		dest       func(srcDir string) string
+		corrupt    bool
+		wantErr    bool
+		wantRemove bool
+	}{
+		{"ZIP 在模型目录之外", func(srcDir string) string { return srcDir + archive.Extension }, false, false, true},
+		{"ZIP 在模型目录之内", func(srcDir string) string { return filepath.Join(srcDir, "model.zip") }, false, true, false},
+		{"ZIP 已损坏", func(srcDir string) string { return srcDir + archive.Extension }, true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcDir := filepath.Join(t.TempDir(), "037_casual-2023")
+			writeFiles(t, srcDir, map[string]string{"model.json": `{}`, "data/model.moc": "moc"})
+
+			dest := tt.dest(srcDir)
+			require.NoError(t, archive.ZipModel(srcDir, dest))
+			if tt.corrupt {
+				require.NoError(t, os.WriteFile(dest, []byte("not a zip"), 0600))
+			}
+
+			err := archive.RemoveSource(srcDir, dest)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			if tt.wantRemove {
+				assert.NoDirExists(t, srcDir)
+			} else {
+				assert.DirExists(t, srcDir)
+			}
+			assert.FileExists(t, dest)
+		})
+	}
+}
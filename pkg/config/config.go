@@ -1,27 +1,122 @@
 // Package config 提供了程序的配置管理功能
 package config
 
-import "time"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+
+	"gopkg.in/yaml.v3"
+)
 
 // Config 表示程序的配置结构.
 type Config struct {
 	// 路径配置
-	Live2dSavePath string // Live2D 模型保存路径
-	CharaCachePath string // 角色信息缓存路径
-	LogPath        string // 日志文件保存路径
+	Live2dSavePath string `yaml:"live2d_save_path"` // Live2D 模型保存路径
+	CharaCachePath string `yaml:"chara_cache_path"` // 角色信息缓存路径
+	LogPath        string `yaml:"log_path"`         // 日志文件保存路径
+	StatePath      string `yaml:"state_path"`       // 本地状态文件路径
+	TaskExportPath string `yaml:"task_export_path"` // 导出下载任务时写入的任务文件路径
+	SelectionPath  string `yaml:"selection_path"`   // 导出服装选择时写入的选择文件路径
+	BlocklistPath  string `yaml:"blocklist_path"`   // 屏蔽列表文件路径
+	PerModelLog    bool   `yaml:"per_model_log"`    // 是否为每个模型在 <日志目录>/<日期>/<模型名>.log 额外写入独立日志
+
+	CharaDirOverrides map[int]string     `yaml:"chara_dir_overrides"` // 角色目录名覆盖映射，key 为角色ID，优先于自动解析的角色名
+	DirNameStyle      model.DirNameStyle `yaml:"dir_name_style"`      // 自动生成的角色目录名的大小写风格（lower、upper、original 或 pascal）
+	DirNameAppendID   bool               `yaml:"dir_name_append_id"`  // 是否在自动生成的角色目录名后附加 "-<三位角色ID>"，避免不同角色重名
+	Blocklist         []string           `yaml:"blocklist"`           // 配置中直接指定的屏蔽服装名称或通配符模式，与屏蔽列表文件合并
 
 	// 缓存配置
-	UseCharaCache bool          // 是否使用角色信息缓存
-	CacheDuration time.Duration // 缓存过期时间
+	UseCharaCache bool          `yaml:"use_chara_cache"` // 是否使用角色信息缓存
+	CacheDuration time.Duration `yaml:"cache_duration"`  // 缓存过期时间
+	Offline       bool          `yaml:"offline"`         // 离线模式，只读取缓存（包括已过期的缓存），不发送网络请求
+
+	StaleWhileRevalidate bool `yaml:"stale_while_revalidate"` // 缓存过期时先使用旧数据，同时在后台刷新缓存，下次使用即为新数据
+
+	NegativeCacheDuration time.Duration `yaml:"negative_cache_duration"` // 返回 404 的 URL 在该时长内不再请求，直接视为不存在，为 0 时关闭
+
+	CachePruneFactor int `yaml:"cache_prune_factor"` // 启动时删除修改时间超过 CacheDuration 该倍数的缓存文件，为 0 时不删除
 
 	// API 配置
-	BaseAssetsURL  string // Bestdori 资源基础 URL
-	CharaRosterURL string // 角色信息 API URL
-	AssetsIndexURL string // 资源索引 API URL
+	Server         string   `yaml:"server"`           // 使用的服务器（jp、en、tw、cn、kr 或 all），为空时沿用资源 URL 中的服务器
+	BaseAssetsURL  string   `yaml:"base_assets_url"`  // Bestdori 资源基础 URL
+	CharaRosterURL string   `yaml:"chara_roster_url"` // 角色信息 API URL
+	MaxCharaID     int      `yaml:"max_chara_id"`     // 可以搜索和浏览的最大角色ID，更大的ID通常是 NPC 等特殊角色，为 0 时不限制
+	CostumesURL    string   `yaml:"costumes_url"`     // 服装信息 API URL，用于显示服装描述与发布时间
+	BandsURL       string   `yaml:"bands_url"`        // 乐队信息 API URL，用于在标题中显示角色所属乐队及按乐队浏览角色
+	AssetsIndexURL string   `yaml:"assets_index_url"` // 资源索引 API URL
+	ServerFallback []string `yaml:"server_fallback"`  // 构建数据不存在时依次尝试的服务器，当前服务器总是最先尝试，为空时不回退
+	Servers        []string `yaml:"servers"`          // 搜索模型时合并查询资源索引的服务器，同名模型优先使用排在前面的服务器，为空时只查询当前服务器
+	ProxyURL       string   `yaml:"proxy_url"`        // HTTP 请求使用的代理（http、https 或 socks5），为空时使用 HTTPS_PROXY 等环境变量中的代理
 
 	// 下载配置
-	MaxConcurrentDownloads int // 单个模型下载时的最大并发文件下载数
-	MaxConcurrentModels    int // 最大并发模型下载数
+	MaxConcurrentDownloads int                   `yaml:"max_concurrent_downloads"`  // 单个模型下载时的最大并发文件下载数
+	MaxConcurrentModels    int                   `yaml:"max_concurrent_models"`     // 最大并发模型下载数
+	MaxConnsPerHost        int                   `yaml:"max_conns_per_host"`        // 与同一主机同时保持的最大连接数，与并发数无关，超出的请求会排队复用连接，为 0 时不限制
+	MaxBandwidthKBps       int                   `yaml:"max_bandwidth_kbps"`        // 所有并发下载共享的最大下载速率（KB/s），为 0 时不限速
+	MaxBytesPerSecond      int64                 `yaml:"max_bytes_per_second"`      // 已弃用，改用 MaxBandwidthKBps；以字节/秒表示的最大下载速率，只在 MaxBandwidthKBps 为 0 时生效
+	FileTimeout            time.Duration         `yaml:"file_timeout"`              // 单个文件的下载超时时间，超时的可选文件会被跳过，为 0 时不限制
+	MaxRetries             int                   `yaml:"max_retries"`               // 下载文件或确认构建数据是否存在时遇到网络错误或 404 以外的非 2xx 响应的最大重试次数，为 0 时不重试
+	RetryBaseDelay         time.Duration         `yaml:"retry_base_delay"`          // 第一次重试前的等待时间，之后每次重试翻倍，最长 30 秒
+	BatchFailFast          bool                  `yaml:"batch_fail_fast"`           // 批量下载时是否在第一个模型失败后中止整个批次
+	ModelScheduling        model.ModelScheduling `yaml:"model_scheduling"`          // 批量下载时模型的开始顺序，fewest-files 时优先下载文件数少的模型，fifo 时按选择的顺序
+	AdaptiveConcurrency    bool                  `yaml:"adaptive_concurrency"`      // 是否根据最近下载的成功率和平均速度自动调整同时在途的文件数
+	AdaptiveMinConcurrency int                   `yaml:"adaptive_min_concurrency"`  // 自适应调整时同时在途文件数的下限，上限为 MaxConcurrentDownloads × MaxConcurrentModels
+	AdaptiveWindow         int                   `yaml:"adaptive_window"`           // 自适应调整时每统计多少个文件结果调整一次并发数
+	AdaptiveMinSuccessRate float64               `yaml:"adaptive_min_success_rate"` // 窗口内成功率低于该值时并发数减半，否则尝试增加并发数
+	WarmupConnections      int                   `yaml:"warmup_connections"`        // 批量下载开始前预先建立的连接数，为 0 时不预热
+	TempFileMaxAge         time.Duration         `yaml:"temp_file_max_age"`         // 启动时清理早于该时长的遗留临时文件，为 0 时不清理
+	MaxPatternMatches      int                   `yaml:"max_pattern_matches"`       // 通配符模式最多匹配的模型数，超过时要求缩小范围，为 0 时不限制
+	AllowMissingFiles      map[string]bool       `yaml:"allow_missing_files"`       // 按文件类型（physics、texture、motion、expression、transition）或扩展名（如 .png）指定文件是否允许不存在，扩展名优先，未指定时只有物理文件与过渡文件允许不存在
+	VerifyFileIntegrity    bool                  `yaml:"verify_file_integrity"`     // 复用已存在的文件前是否按下载清单校验 SHA-256，关闭时只比较文件大小
+
+	// 磁盘空间配置
+	DiskCheckInterval time.Duration `yaml:"disk_check_interval"` // 下载时检查保存路径剩余空间的间隔，为 0 时不检查
+	DiskWarnThreshold uint64        `yaml:"disk_warn_threshold"` // 剩余空间低于该值（字节）时显示警告
+	DiskSafetyMargin  uint64        `yaml:"disk_safety_margin"`  // 暂停下载的安全余量（字节），剩余空间低于进行中文件的预估大小加上该值时暂停
+	EstimateDiskSpace bool          `yaml:"estimate_disk_space"` // 批量下载开始前是否通过 HEAD 请求估算所需空间并与剩余空间对比
+
+	// 服务器冲突配置
+	ServerConflictPolicy map[string]model.ServerConflictDecision `yaml:"server_conflict_policy"` // 角色目录服务器冲突时的处理方式，key 为角色目录名
+
+	// 模型数据配置
+	LayoutPreset        string                        `yaml:"layout_preset"`         // 指定使用的布局预设，为空时按模型名称自动选择
+	LayoutPresets       map[string]model.LayoutPreset `yaml:"layout_presets"`        // 可用的布局预设，key 为预设名称
+	GenerateModelIndex  bool                          `yaml:"generate_model_index"`  // 是否在模型目录生成列出所有动作与表情的 index.json
+	GenerateTextureMeta bool                          `yaml:"generate_texture_meta"` // 是否在模型目录生成记录各纹理尺寸的 textures.json
+	OutputLayout        model.OutputLayout            `yaml:"output_layout"`         // 模型文件的组织方式，默认按文件类型整理到 data 目录
+	OutputFormat        model.OutputFormat            `yaml:"output_format"`         // 生成的模型数据格式，cubism3 时在 model.json 之外生成 <模型名>.model3.json
+	DirectoryLayout     model.DirectoryLayout         `yaml:"directory_layout"`      // 模型目录在保存路径下的组织方式，默认按角色分目录，flat 时所有模型并列保存且不查询角色信息
+	ZipOutput           bool                          `yaml:"zip_output"`            // 是否在模型下载完成后将模型目录打包为 ZIP
+	PackageAsZip        bool                          `yaml:"package_as_zip"`        // ZipOutput 的别名，任一为 true 时打包
+	ZipPath             string                        `yaml:"zip_path"`              // ZIP 的保存目录，文件名为模型名称，为空时保存在模型目录旁
+	ZipRemoveSource     bool                          `yaml:"zip_remove_source"`     // 打包为 ZIP 成功后是否删除模型目录
+
+	// 纹理配置
+	TextureAlphaMode   model.TextureAlphaMode            `yaml:"texture_alpha_mode"`   // 下载的纹理默认使用的 alpha 通道修正方式，为空时不处理
+	TextureAlphaModels map[string]model.TextureAlphaMode `yaml:"texture_alpha_models"` // 按模型指定的 alpha 通道修正方式，key 为模型名称，优先于默认方式
+
+	// 界面配置
+	CompactDownloadView bool              `yaml:"compact_download_view"` // 下载列表是否默认使用紧凑视图，每个模型只占一行
+	NarrowWidth         int               `yaml:"narrow_width"`          // 终端宽度小于该列数时下载列表自动使用窄屏单行视图，为 0 时不自动切换
+	CostumeSort         model.CostumeSort `yaml:"costume_sort"`          // 服装列表的排序方式，默认按服装ID排序
+	NoTUI               bool              `yaml:"no_tui"`                // 是否不使用 TUI，只按启动参数下载并将进度输出到标准输出
+
+	// 统计配置
+	UsageStats        bool `yaml:"usage_stats"`         // 是否在本地记录服装的下载次数和最近使用时间
+	HistoryMaxEntries int  `yaml:"history_max_entries"` // 下载历史保留的最大记录条数，超过时丢弃最早的记录，为 0 时不记录下载历史
 }
 
 var (
@@ -37,31 +132,412 @@ func DefaultConfig() *Config {
 		Live2dSavePath: "live2d_download",
 		CharaCachePath: "live2d_chara_cache",
 		LogPath:        "logs",
+		StatePath:      "live2d_state.json",
+		TaskExportPath: "task.json",
+		SelectionPath:  "selection.json",
+		BlocklistPath:  "blocklist.json",
+		PerModelLog:    false,
+
+		CharaDirOverrides: make(map[int]string),
+		DirNameStyle:      model.DirNameStyleLower,
+		DirNameAppendID:   false,
+		Blocklist:         []string{},
 
 		// 缓存配置
 		UseCharaCache: true,
 		CacheDuration: 24 * time.Hour,
+		Offline:       false,
+
+		StaleWhileRevalidate: false,
+
+		NegativeCacheDuration: time.Hour,
+
+		CachePruneFactor: 7,
 
 		// API 配置
+		Server:         "",
 		BaseAssetsURL:  "https://bestdori.com/assets/jp",
 		CharaRosterURL: "https://bestdori.com/api/characters",
+		MaxCharaID:     1000,
+		CostumesURL:    "https://bestdori.com/api/costumes/all.5.json",
+		BandsURL:       "https://bestdori.com/api/bands/all.1.json",
 		AssetsIndexURL: "https://bestdori.com/api/explorer/jp/assets/_info.json",
+		ServerFallback: []string{"jp", "cn", "tw", "en", "kr"},
+		Servers:        []string{"jp", "cn", "tw", "en", "kr"},
+		ProxyURL:       "",
 
 		// 下载配置
 		MaxConcurrentDownloads: 20,
 		MaxConcurrentModels:    3,
+		MaxConnsPerHost:        16,
+		MaxBandwidthKBps:       0,
+		MaxBytesPerSecond:      0,
+		FileTimeout:            30 * time.Second,
+		MaxRetries:             3,
+		RetryBaseDelay:         500 * time.Millisecond,
+		BatchFailFast:          false,
+		ModelScheduling:        model.ModelSchedulingFewestFiles,
+		AdaptiveConcurrency:    false,
+		AdaptiveMinConcurrency: 2,
+		AdaptiveWindow:         20,
+		AdaptiveMinSuccessRate: 0.9,
+		WarmupConnections:      0,
+		TempFileMaxAge:         24 * time.Hour,
+		MaxPatternMatches:      100,
+		AllowMissingFiles:      make(map[string]bool),
+		VerifyFileIntegrity:    false,
+
+		// 磁盘空间配置
+		DiskCheckInterval: 15 * time.Second,
+		DiskWarnThreshold: 2 << 30,
+		DiskSafetyMargin:  256 << 20,
+		EstimateDiskSpace: true,
+
+		// 服务器冲突配置
+		ServerConflictPolicy: make(map[string]model.ServerConflictDecision),
+
+		// 模型数据配置
+		LayoutPreset:        "",
+		LayoutPresets:       DefaultLayoutPresets(),
+		GenerateModelIndex:  false,
+		GenerateTextureMeta: false,
+		OutputLayout:        model.OutputLayoutRestructured,
+		OutputFormat:        model.OutputFormatCubism2,
+		DirectoryLayout:     model.DirectoryLayoutByCharacter,
+		ZipOutput:           false,
+		PackageAsZip:        false,
+		ZipPath:             "",
+		ZipRemoveSource:     false,
+
+		// 纹理配置
+		TextureAlphaMode:   model.TextureAlphaNone,
+		TextureAlphaModels: make(map[string]model.TextureAlphaMode),
+
+		// 界面配置
+		CompactDownloadView: false,
+		NarrowWidth:         70,
+		CostumeSort:         model.CostumeSortID,
+		NoTUI:               false,
+
+		// 统计配置
+		UsageStats:        true,
+		HistoryMaxEntries: 1000,
 	}
 }
 
-// Init 初始化全局配置.
-func Init() {
-	globalConfig = DefaultConfig()
+// DefaultLayoutPresets 返回内置的布局预设.
+func DefaultLayoutPresets() map[string]model.LayoutPreset {
+	return map[string]model.LayoutPreset{
+		model.LayoutPresetStandard: {
+			Layout: map[string]float64{
+				"center_x": 0,
+				"center_y": 0,
+				"width":    2,
+			},
+			HitAreasCustom: map[string][]float64{
+				"head_x": {-0.25, 1},
+				"head_y": {0.25, 0.2},
+				"body_x": {-0.3, 0.2},
+				"body_y": {0.3, -1.9},
+			},
+		},
+		model.LayoutPresetChibi: {
+			Match: []string{"_sd", "chibi"},
+			Layout: map[string]float64{
+				"center_x": 0,
+				"center_y": 0.1,
+				"width":    1.6,
+			},
+			HitAreasCustom: map[string][]float64{
+				"head_x": {-0.4, 0.4},
+				"head_y": {0.6, -0.1},
+				"body_x": {-0.35, 0.35},
+				"body_y": {-0.1, -0.9},
+			},
+		},
+		model.LayoutPresetHalf: {
+			Match: []string{"_half", "_bust"},
+			Layout: map[string]float64{
+				"center_x": 0,
+				"center_y": -0.4,
+				"width":    2.6,
+			},
+			HitAreasCustom: map[string][]float64{
+				"head_x": {-0.3, 0.3},
+				"head_y": {0.9, 0.3},
+				"body_x": {-0.5, 0.5},
+				"body_y": {0.3, -1},
+			},
+		},
+	}
+}
+
+// ServerAll 表示合并查询所有服务器的资源索引.
+const ServerAll = "all"
+
+// KnownServers 返回 Bestdori 支持的服务器.
+func KnownServers() []string {
+	return []string{"jp", "en", "tw", "cn", "kr"}
+}
+
+// ValidateServer 检查服务器名称是否为已知服务器或 all
+// 参数:
+//   - server: 服务器名称
+//
+// 返回:
+//   - error: 服务器名称无效时返回错误
+func ValidateServer(server string) error {
+	if server == ServerAll || slices.Contains(KnownServers(), server) {
+		return nil
+	}
+	return fmt.Errorf("无效的服务器名称: %q，可选值为 %s 或 %s",
+		server, strings.Join(KnownServers(), "、"), ServerAll)
+}
+
+// ApplyServer 按 Server 改写资源基础 URL 与资源索引 URL
+// Server 为空或 all 时不改写，使下载器、任务文件与下载历史记录的服务器与 API 客户端一致
+// 返回:
+//   - error: 服务器名称无效时返回错误
+func (c *Config) ApplyServer() error {
+	if c.Server == "" || c.Server == ServerAll {
+		return nil
+	}
+	return c.SetAssetsServer(c.Server)
+}
+
+// AssetsServer 返回资源基础 URL 对应的服务器名称，例如 jp.
+func (c *Config) AssetsServer() string {
+	return AssetsURLServer(c.BaseAssetsURL)
+}
+
+// AssetsURLServer 返回资源基础 URL 对应的服务器名称，无法解析时返回空字符串
+// 参数:
+//   - baseURL: 资源基础 URL
+//
+// 返回:
+//   - string: 服务器名称
+func AssetsURLServer(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return path.Base(u.Path)
+}
+
+// ServerAssetsURL 将资源基础 URL 中的服务器替换为指定服务器
+// 参数:
+//   - baseURL: 资源基础 URL
+//   - server: 服务器名称
+//
+// 返回:
+//   - string: 指定服务器的资源基础 URL
+//   - error: 服务器名称或 URL 无效时返回错误
+func ServerAssetsURL(baseURL, server string) (string, error) {
+	if server == "" || strings.ContainsAny(server, "/?#") {
+		return "", fmt.Errorf("无效的服务器名称: %q", server)
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("解析资源基础 URL 失败: %w", err)
+	}
+	u.Path = path.Join(path.Dir(u.Path), server)
+	return u.String(), nil
+}
+
+// SetAssetsServer 切换资源所在的服务器，例如 jp、en、cn
+// 同时修改资源基础 URL 与资源索引 URL 中的服务器名称
+// 参数:
+//   - server: 服务器名称
+//
+// 返回:
+//   - error: 服务器名称无效时返回错误
+func (c *Config) SetAssetsServer(server string) error {
+	baseURL, err := ServerAssetsURL(c.BaseAssetsURL, server)
+	if err != nil {
+		return err
+	}
+	if c.Server != "" && c.Server != ServerAll {
+		c.Server = server
+	}
+	current := c.AssetsServer()
+	if current == server {
+		return nil
+	}
+
+	c.BaseAssetsURL = baseURL
+	c.AssetsIndexURL = ServerAssetsIndexURL(c.AssetsIndexURL, current, server)
+	return nil
+}
+
+// ServerAssetsIndexURL 将资源索引 URL 中的服务器替换为指定服务器
+// 参数:
+//   - indexURL: 资源索引 URL
+//   - current: 资源索引 URL 当前对应的服务器
+//   - server: 服务器名称
+//
+// 返回:
+//   - string: 指定服务器的资源索引 URL
+func ServerAssetsIndexURL(indexURL, current, server string) string {
+	return strings.Replace(indexURL, "/"+current+"/", "/"+server+"/", 1)
+}
+
+// Proxy 返回 HTTP 传输层使用的代理函数
+// ProxyURL 为空时使用 HTTPS_PROXY、HTTP_PROXY 与 NO_PROXY 环境变量
+// 返回:
+//   - func(*http.Request) (*url.URL, error): 代理函数，可直接赋值给 http.Transport.Proxy
+//   - error: 代理 URL 无效或协议不受支持时返回错误
+func (c *Config) Proxy() (func(*http.Request) (*url.URL, error), error) {
+	if c.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(c.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("无效的代理 URL %q: %w", c.ProxyURL, err)
+	}
+	if !slices.Contains([]string{"http", "https", "socks5", "socks5h"}, u.Scheme) {
+		return nil, fmt.Errorf("无效的代理 URL %q: 不支持的协议 %q，可选值为 http、https、socks5", c.ProxyURL, u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("无效的代理 URL %q: 缺少主机", c.ProxyURL)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// FileName 是程序启动时从当前目录读取的配置文件名.
+const FileName = "bestdori.yaml"
+
+// LoadFromFile 从 YAML 文件读取配置并覆盖到当前配置上
+// 只覆盖文件中出现的字段，未出现的字段保持原值；显式写入的零值（例如 false、0）同样会生效，
+// 映射类型的字段与原有的键合并，切片类型的字段整体替换. 文件中出现未知字段时返回错误，避免拼写错误被忽略
+// 参数:
+//   - path: 配置文件路径
+//
+// 返回:
+//   - error: 文件不存在时返回包裹 fs.ErrNotExist 的错误，解析失败时配置可能已被部分修改
+func (c *Config) LoadFromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// SaveField 将单个配置项写入 YAML 文件
+// 只替换该配置项，文件中的其他配置项与注释保持不变；文件不存在时创建只包含该配置项的文件
+// 参数:
+//   - path: 配置文件路径
+//   - key: 配置项在 YAML 中的名称，例如 server_conflict_policy
+//   - value: 配置项的值
+//
+// 返回:
+//   - error: 错误信息
+func SaveField(path, key string, value any) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	var doc yaml.Node
+	if unmarshalErr := yaml.Unmarshal(data, &doc); unmarshalErr != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %w", path, unmarshalErr)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("解析配置文件 %s 失败: 顶层不是映射", path)
+	}
+
+	var valueNode yaml.Node
+	if encodeErr := valueNode.Encode(value); encodeErr != nil {
+		return fmt.Errorf("序列化配置项 %s 失败: %w", key, encodeErr)
+	}
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			root.Content[i+1] = &valueNode
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &valueNode)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if encodeErr := encoder.Encode(&doc); encodeErr != nil {
+		return fmt.Errorf("序列化配置文件失败: %w", encodeErr)
+	}
+	if closeErr := encoder.Close(); closeErr != nil {
+		return fmt.Errorf("序列化配置文件失败: %w", closeErr)
+	}
+
+	file, err := fsutil.CreateTemp(path)
+	if err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	if _, writeErr := file.Write(buf.Bytes()); writeErr != nil {
+		fsutil.DiscardTemp(file)
+		return fmt.Errorf("写入配置文件失败: %w", writeErr)
+	}
+	if commitErr := fsutil.CommitTemp(file, path); commitErr != nil {
+		return fmt.Errorf("写入配置文件失败: %w", commitErr)
+	}
+	return nil
+}
+
+// Init 初始化全局配置
+// 当前目录存在 bestdori.yaml 时读取其中的配置覆盖默认值
+// 返回:
+//   - error: 配置文件解析失败或代理 URL 无效时返回错误，此时全局配置为默认配置
+func Init() error {
+	cfg := DefaultConfig()
+	if err := cfg.LoadFromFile(FileName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		globalConfig = DefaultConfig()
+		return err
+	}
+	if _, err := cfg.Proxy(); err != nil {
+		globalConfig = DefaultConfig()
+		return err
+	}
+	globalConfig = cfg
+	return nil
+}
+
+// BandwidthLimit 返回所有下载共享的最大下载速率（字节/秒），为 0 时不限速
+// 优先使用 MaxBandwidthKBps，未设置时使用已弃用的 MaxBytesPerSecond.
+func (c *Config) BandwidthLimit() int64 {
+	if c.MaxBandwidthKBps > 0 {
+		return int64(c.MaxBandwidthKBps) * 1024
+	}
+	return max(c.MaxBytesPerSecond, 0)
+}
+
+// ZipEnabled 判断模型构建完成后是否打包为 ZIP，ZipOutput 与别名 PackageAsZip 任一开启即可.
+func (c *Config) ZipEnabled() bool {
+	return c.ZipOutput || c.PackageAsZip
+}
+
+// CharaIDAllowed 判断角色ID是否在可以搜索和浏览的范围内
+// MaxCharaID 为 0 时不限制.
+func (c *Config) CharaIDAllowed(charaID int) bool {
+	return c.MaxCharaID <= 0 || charaID <= c.MaxCharaID
 }
 
-// Get 获取全局配置实例.
+// Get 获取全局配置实例
+// 尚未调用 Init 时返回默认配置.
 func Get() *Config {
 	if globalConfig == nil {
-		Init()
+		globalConfig = DefaultConfig()
 	}
 	return globalConfig
 }
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source 表示配置项生效值的来源.
+type Source string
+
+const (
+	// SourceDefault 表示使用默认值.
+	SourceDefault Source = "默认"
+	// SourceFile 表示来自配置文件.
+	SourceFile Source = "文件"
+	// SourceEnv 表示来自环境变量.
+	SourceEnv Source = "环境"
+	// SourceFlag 表示来自命令行参数.
+	SourceFlag Source = "命令行"
+)
+
+// proxyEnvKeys 是 ProxyURL 为空时按顺序读取的代理环境变量.
+var proxyEnvKeys = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"}
+
+// Entry 表示一个配置项的生效值.
+type Entry struct {
+	Key    string // 配置文件中的配置项名称
+	Value  string // 生效值，敏感信息已脱敏
+	Source Source // 生效值的来源
+}
+
+// FileKeys 返回配置文件中出现的配置项名称
+// 参数:
+//   - path: 配置文件路径
+//
+// 返回:
+//   - map[string]bool: 配置文件中出现的配置项名称，文件不存在时为空
+//   - error: 读取或解析配置文件失败时返回错误
+func FileKeys(path string) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	var values map[string]any
+	if err = yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	for key := range values {
+		keys[key] = true
+	}
+	return keys, nil
+}
+
+// Entries 按配置结构中的顺序返回所有配置项的生效值
+// ProxyURL 为空时显示实际使用的代理环境变量，代理 URL 中的密码会被脱敏
+// 参数:
+//   - sources: 不是默认值的配置项的来源，key 为配置项名称
+//
+// 返回:
+//   - []Entry: 所有配置项的生效值
+func (c *Config) Entries(sources map[string]Source) []Entry {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	entries := make([]Entry, 0, t.NumField())
+	for i := range t.NumField() {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		source, ok := sources[key]
+		if !ok {
+			source = SourceDefault
+		}
+		value := formatValue(v.Field(i).Interface())
+		if key == "proxy_url" {
+			value, source = c.proxyEntry(source)
+		}
+		entries = append(entries, Entry{Key: key, Value: value, Source: source})
+	}
+	return entries
+}
+
+// proxyEntry 返回代理的生效值与来源，ProxyURL 为空时返回实际使用的代理环境变量.
+func (c *Config) proxyEntry(source Source) (string, Source) {
+	if c.ProxyURL != "" {
+		return redactURL(c.ProxyURL), source
+	}
+	for _, env := range proxyEnvKeys {
+		if value := os.Getenv(env); value != "" {
+			return fmt.Sprintf("%s (%s)", redactURL(value), env), SourceEnv
+		}
+	}
+	return `""`, source
+}
+
+// formatValue 将配置值格式化为单行 YAML，列表与映射使用流式写法.
+func formatValue(value any) string {
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	setFlowStyle(&node)
+	data, err := yaml.Marshal(&node)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// setFlowStyle 将节点及其子节点设置为流式写法.
+func setFlowStyle(node *yaml.Node) {
+	node.Style |= yaml.FlowStyle
+	for _, child := range node.Content {
+		setFlowStyle(child)
+	}
+}
+
+// redactURL 隐藏 URL 中的密码，无法解析时隐藏整个值.
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return "xxxxx"
+	}
+	return u.Redacted()
+}
+
+// PrintEntries 逐行输出配置项，格式为 "配置项: 值  # 来源"
+// 参数:
+//   - w: 输出目标
+//   - entries: 配置项
+//
+// 返回:
+//   - error: 写入失败时返回错误
+func PrintEntries(w io.Writer, entries []Entry) error {
+	for _, entry := range entries {
+		if _, err := fmt.Fprintf(w, "%s: %s  # %s\n", entry.Key, entry.Value, entry.Source); err != nil {
+			return fmt.Errorf("输出配置失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+)
+
+// BatchResult 表示批量下载的结果.
+type BatchResult struct {
+	Completed []string         // 下载成功的模型
+	Failed    map[string]error // 下载失败的模型及其错误
+	Skipped   []string         // 因快速失败而未下载的模型
+}
+
+// BatchFunc 表示批量下载中处理单个模型的函数.
+type BatchFunc func(ctx context.Context, name string) error
+
+// RunBatch 并发执行批量下载
+// 默认在单个模型失败后继续下载其余模型；开启 failFast 时，第一个失败会取消整个批次，
+// 尚未完成的模型会被标记为跳过
+// 参数:
+//   - ctx: 上下文
+//   - names: 模型名称列表
+//   - concurrency: 最大并发数
+//   - failFast: 是否在第一个失败时中止批次
+//   - fn: 处理单个模型的函数
+//
+// 返回:
+//   - *BatchResult: 批量下载结果
+//   - error: 批次被取消时返回 errs.ErrDownloadCancelled，被快速失败中止时返回包裹 errs.ErrBatchAborted 的错误
+func RunBatch(
+	ctx context.Context,
+	names []string,
+	concurrency int,
+	failFast bool,
+	fn BatchFunc,
+) (*BatchResult, error) {
+	result := &BatchResult{Failed: make(map[string]error)}
+	var mu sync.Mutex
+
+	g, gctx := errgroup.WithContext(ctx)
+	if concurrency > 0 {
+		g.SetLimit(concurrency)
+	}
+
+	for _, name := range names {
+		// 批次已中止时不再启动新的下载
+		if gctx.Err() != nil {
+			mu.Lock()
+			result.Skipped = append(result.Skipped, name)
+			mu.Unlock()
+			continue
+		}
+
+		g.Go(func() error {
+			err := fn(gctx, name)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				result.Completed = append(result.Completed, name)
+				return nil
+			case errs.IsCancelled(err) && ctx.Err() == nil:
+				// 由快速失败引起的取消
+				result.Skipped = append(result.Skipped, name)
+				return nil
+			case errs.IsCancelled(err):
+				return errs.ErrDownloadCancelled
+			}
+
+			result.Failed[name] = err
+			if failFast {
+				log.DefaultLogger.Warn().Str("model", name).Err(err).Msg("快速失败已开启，中止批量下载")
+				return fmt.Errorf("%w: %s: %w", errs.ErrBatchAborted, name, err)
+			}
+			return nil
+		})
+	}
+
+	err := g.Wait()
+	if ctx.Err() != nil {
+		return result, errs.ErrDownloadCancelled
+	}
+	return result, err
+}
@@ -0,0 +1,48 @@
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// BlocklistFile 表示用户编辑的屏蔽列表文件
+// 屏蔽的服装默认不显示在服装列表中，也不会被全选或通配符批量下载.
+type BlocklistFile struct {
+	Patterns []string `json:"patterns"` // 屏蔽的服装名称或通配符模式，例如 037_broken 或 *_placeholder
+}
+
+// LoadBlocklist 读取屏蔽列表文件并与配置中的屏蔽模式合并
+// 参数:
+//   - path: 屏蔽列表文件路径，文件不存在时只使用配置中的模式
+//   - extra: 配置中直接指定的屏蔽模式
+//
+// 返回:
+//   - model.Live2dPatterns: 屏蔽模式
+//   - error: 文件无法解析或模式语法无效时返回错误
+func LoadBlocklist(path string, extra []string) (model.Live2dPatterns, error) {
+	inputs := append([]string(nil), extra...)
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("读取屏蔽列表失败: %w", err)
+	default:
+		var file BlocklistFile
+		if unmarshalErr := json.Unmarshal(data, &file); unmarshalErr != nil {
+			return nil, fmt.Errorf("解析屏蔽列表失败: %w", unmarshalErr)
+		}
+		inputs = append(inputs, file.Patterns...)
+	}
+
+	patterns, err := model.ParseLive2dPatterns(inputs)
+	if err != nil {
+		return nil, fmt.Errorf("解析屏蔽列表失败: %w", err)
+	}
+	return patterns, nil
+}
@@ -0,0 +1,157 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+)
+
+const (
+	// DiagnosticsFileName 是日志目录下记录最终下载失败的文件诊断信息的文件名.
+	DiagnosticsFileName = "diagnostics.log"
+	// diagnosticsBodyLimit 是诊断信息中保留的错误页响应体的最大字节数.
+	diagnosticsBodyLimit = 512
+)
+
+// attemptDiagnostic 表示一次下载请求的诊断信息.
+type attemptDiagnostic struct {
+	startedAt     time.Time // 请求开始时间
+	url           string    // 请求的 URL
+	status        string    // HTTP 状态，没有收到响应时为空
+	contentType   string    // 响应的 Content-Type
+	contentLength string    // 响应的 Content-Length
+	server        string    // 响应的 Server
+	body          string    // 文本错误页的响应体开头
+	err           error     // 本次请求的错误
+}
+
+// fileDiagnostics 收集同一文件各次下载请求的诊断信息
+// 通过上下文传递给每次请求，同一文件的请求依次进行，不需要加锁.
+type fileDiagnostics struct {
+	filePath string               // 保存路径
+	attempts []*attemptDiagnostic // 各次请求的诊断信息
+}
+
+// diagnosticsKey 是上下文中保存文件诊断信息的键.
+type diagnosticsKey struct{}
+
+// withDiagnostics 返回收集文件诊断信息的上下文.
+func withDiagnostics(ctx context.Context, diag *fileDiagnostics) context.Context {
+	return context.WithValue(ctx, diagnosticsKey{}, diag)
+}
+
+// beginAttempt 在上下文中的诊断信息里登记一次新的请求，上下文中没有诊断信息时返回 nil.
+func beginAttempt(ctx context.Context) *attemptDiagnostic {
+	diag, ok := ctx.Value(diagnosticsKey{}).(*fileDiagnostics)
+	if !ok {
+		return nil
+	}
+	attempt := &attemptDiagnostic{startedAt: time.Now()}
+	diag.attempts = append(diag.attempts, attempt)
+	return attempt
+}
+
+// request 记录请求的 URL.
+func (a *attemptDiagnostic) request(req *http.Request) {
+	if a != nil {
+		a.url = req.URL.String()
+	}
+}
+
+// response 记录响应状态与排查问题常用的响应头.
+func (a *attemptDiagnostic) response(resp *http.Response) {
+	if a == nil {
+		return
+	}
+	a.status = resp.Status
+	a.contentType = resp.Header.Get("Content-Type")
+	a.contentLength = resp.Header.Get("Content-Length")
+	a.server = resp.Header.Get("Server")
+}
+
+// readBody 在响应为文本错误页时读取响应体的开头，二进制内容不读取.
+func (a *attemptDiagnostic) readBody(resp *http.Response) {
+	if a == nil || !isTextContent(a.contentType) {
+		return
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, diagnosticsBodyLimit)) // 只用于诊断，读取失败时保留已读取的部分
+	a.body = strings.ToValidUTF8(string(data), "�")
+}
+
+// end 记录本次请求的错误.
+func (a *attemptDiagnostic) end(err error) {
+	if a != nil {
+		a.err = err
+	}
+}
+
+// isTextContent 判断 Content-Type 是否为文本内容，例如 HTML 错误页或 JSON 错误信息.
+func isTextContent(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml")
+}
+
+// format 将诊断信息格式化为便于附在问题报告中的文本.
+func (f *fileDiagnostics) format(err error, failedAt time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s %s ===\n", failedAt.Format(time.RFC3339), f.filePath)
+	fmt.Fprintf(&b, "最终错误: %v\n", err)
+	for i, attempt := range f.attempts {
+		fmt.Fprintf(&b, "第 %d 次请求 (%s)\n", i+1, attempt.startedAt.Format(time.TimeOnly))
+		writeField(&b, "URL", attempt.url)
+		writeField(&b, "状态", attempt.status)
+		writeField(&b, "Content-Type", attempt.contentType)
+		writeField(&b, "Content-Length", attempt.contentLength)
+		writeField(&b, "Server", attempt.server)
+		if attempt.body != "" {
+			fmt.Fprintf(&b, "  响应体: %q\n", attempt.body)
+		}
+		if attempt.err != nil {
+			fmt.Fprintf(&b, "  错误: %v\n", attempt.err)
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// writeField 写入非空的诊断字段.
+func writeField(b *strings.Builder, name, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "  %s: %s\n", name, value)
+	}
+}
+
+// writeDiagnostics 将最终下载失败的文件的诊断信息追加到日志目录下的 diagnostics.log
+// 下载被取消或没有发出任何请求时不记录，写入失败只记录日志.
+func (d *Downloader) writeDiagnostics(ctx context.Context, diag *fileDiagnostics, err error) {
+	if errs.IsCancelled(err) || len(diag.attempts) == 0 {
+		return
+	}
+
+	path := filepath.Join(config.Get().LogPath, DiagnosticsFileName)
+	entry := diag.format(err, time.Now())
+
+	d.diagnosticsMu.Lock()
+	defer d.diagnosticsMu.Unlock()
+	file, openErr := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if openErr != nil {
+		log.FromContext(ctx).Warn().Str("path", path).Err(openErr).Msg("写入诊断信息失败")
+		return
+	}
+	defer file.Close()
+	if _, writeErr := file.WriteString(entry); writeErr != nil {
+		log.FromContext(ctx).Warn().Str("path", path).Err(writeErr).Msg("写入诊断信息失败")
+		return
+	}
+	log.FromContext(ctx).Info().Str("filePath", diag.filePath).Str("path", path).Msg("已记录下载失败的诊断信息")
+}
@@ -0,0 +1,158 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/tui"
+)
+
+// defaultFileSizeEstimate 是还没有文件下载完成时，单个文件的预估大小.
+const defaultFileSizeEstimate = 4 << 20
+
+// diskGuard 记录进行中的文件数量，并在剩余空间不足时阻止开始新的文件下载.
+type diskGuard struct {
+	mu       sync.Mutex
+	paused   bool          // 是否已暂停
+	resume   chan struct{} // 恢复下载时关闭
+	inflight uint64        // 进行中的文件数
+	written  uint64        // 已完成文件的总大小
+	files    uint64        // 已完成的文件数
+}
+
+// newDiskGuard 创建新的剩余空间守卫.
+func newDiskGuard() *diskGuard {
+	return &diskGuard{}
+}
+
+// begin 在开始下载文件前调用，暂停期间会阻塞直到恢复或上下文被取消.
+func (g *diskGuard) begin(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		if !g.paused {
+			g.inflight++
+			g.mu.Unlock()
+			return nil
+		}
+		resume := g.resume
+		g.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return errs.ErrDownloadCancelled
+		case <-resume:
+		}
+	}
+}
+
+// end 在文件下载结束时调用，size 为文件大小，下载失败时为 0.
+func (g *diskGuard) end(size int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inflight--
+	if size > 0 {
+		g.written += uint64(size) //nolint:gosec // size 已确认为正数
+		g.files++
+	}
+}
+
+// required 返回进行中的文件预估需要的空间加上安全余量
+// 单个文件的大小按已完成文件的平均大小估算.
+func (g *diskGuard) required(margin uint64) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	average := uint64(defaultFileSizeEstimate)
+	if g.files > 0 {
+		average = g.written / g.files
+	}
+	return g.inflight*average + margin
+}
+
+// setPaused 暂停或恢复开始新的文件下载，返回暂停状态是否发生变化.
+func (g *diskGuard) setPaused(paused bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if paused == g.paused {
+		return false
+	}
+	if paused {
+		g.resume = make(chan struct{})
+	} else {
+		close(g.resume)
+	}
+	g.paused = paused
+	return true
+}
+
+// diskLevel 根据剩余空间判断空间状态.
+func diskLevel(free, required, warnThreshold uint64) tui.DiskLevel {
+	switch {
+	case free < required:
+		return tui.DiskCritical
+	case free < warnThreshold:
+		return tui.DiskLow
+	default:
+		return tui.DiskOK
+	}
+}
+
+// MonitorDiskSpace 定期检查保存路径的剩余空间，直到上下文被取消
+// 剩余空间低于警告阈值时在状态栏中警告，低于进行中文件的预估大小加上安全余量时暂停开始新的文件下载，
+// 空间恢复后自动继续。应在批量下载期间单独的协程中运行
+// 参数:
+//   - ctx: 上下文，批量下载结束时取消
+func (d *Downloader) MonitorDiskSpace(ctx context.Context) {
+	cfg := config.Get()
+	if cfg.DiskCheckInterval <= 0 {
+		return
+	}
+	// 退出时恢复下载，避免有文件一直等待
+	defer d.disk.setPaused(false)
+
+	ticker := time.NewTicker(cfg.DiskCheckInterval)
+	defer ticker.Stop()
+	for {
+		if !d.checkDiskSpace(cfg) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDiskSpace 检查一次剩余空间并更新暂停状态，无法查询剩余空间时返回 false.
+func (d *Downloader) checkDiskSpace(cfg *config.Config) bool {
+	free, err := fsutil.FreeSpace(d.savePath)
+	if err != nil {
+		if errors.Is(err, fsutil.ErrFreeSpaceUnsupported) {
+			log.DefaultLogger.Debug().Msg("当前平台不支持查询剩余空间，停止检查")
+		} else {
+			log.DefaultLogger.Warn().Str("path", d.savePath).Err(err).Msg("查询剩余空间失败，停止检查")
+		}
+		return false
+	}
+
+	required := d.disk.required(cfg.DiskSafetyMargin)
+	level := diskLevel(free, required, cfg.DiskWarnThreshold)
+	paused := level == tui.DiskCritical
+	if d.disk.setPaused(paused) {
+		if paused {
+			log.DefaultLogger.Warn().Uint64("free", free).Uint64("required", required).Msg("剩余空间不足，暂停下载")
+		} else {
+			log.DefaultLogger.Info().Uint64("free", free).Msg("剩余空间已恢复，继续下载")
+		}
+	}
+	if d.Progress != nil {
+		d.Progress.SendDiskSpace(free, required, level)
+	}
+	return true
+}
@@ -8,19 +8,27 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/adaptive"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/api"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
-	"github.com/A-kirami/bestdori-live2d-downloader/pkg/tui"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/stats"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
 )
 
 // MotionFile 表示动作文件的类型.
@@ -31,6 +39,7 @@ type ExpressionFile = model.ExpressionFile
 
 // downloadTask 表示下载任务.
 type downloadTask struct {
+	kind          FileKind            // 文件类型
 	bundleFile    model.BundleFile    // 要下载的资源包文件信息
 	filePath      string              // 保存路径
 	allowNotFound bool                // 是否允许文件不存在
@@ -39,64 +48,136 @@ type downloadTask struct {
 
 // downloadResult 表示下载结果.
 type downloadResult struct {
-	relPath string // 相对路径
-	err     error  // 错误信息
+	relPath    string               // 相对路径
+	provenance model.FileProvenance // 文件来源信息
+	err        error                // 错误信息
 }
 
 // Downloader 表示下载器
 // 负责处理文件下载、并发控制和进度显示.
 type Downloader struct {
-	apiClient  *api.Client   // API 客户端
-	savePath   string        // 保存路径
-	TuiModel   *tui.Model    // TUI 模型
-	program    *tea.Program  // TUI 程序
-	modelSem   chan struct{} // 模型并发控制信号量
-	httpClient *http.Client  // HTTP 客户端
+	apiClient  *api.Client          // API 客户端
+	savePath   string               // 保存路径
+	Progress   ProgressSink         // 进度接收者，为 nil 时不报告进度
+	program    *tea.Program         // TUI 程序
+	modelSem   chan struct{}        // 模型并发控制信号量
+	httpClient *http.Client         // HTTP 客户端
+	disk       *diskGuard           // 剩余空间守卫
+	adaptive   *adaptive.Controller // 自适应并发控制器，未启用时为 nil
+	limiter    *rate.Limiter        // 所有下载共享的速率限制器，不限速时为 nil
+	status     *statusTracker       // 模型构建状态
+	items      *itemRegistry        // 已向进度接收者登记的模型
+	inflight   singleflight.Group   // 合并对同一模型的并发构建，key 为模型名称
+
+	diagnosticsMu sync.Mutex // 保证诊断信息逐条写入 diagnostics.log
+
+	FinalizationHook func(modelPath string) error // 模型构建成功后由调用方执行的收尾处理，在 ZIP 打包之后执行，为 nil 时不执行
+	ForceRefresh     bool                         // 是否忽略下载清单与整模型哈希，重新下载所有文件
 }
 
 // NewDownloader 创建新的下载器实例
 // 参数:
 //   - apiClient: API 客户端实例
-//   - tuiModel: TUI 模型实例
-//   - program: TUI 程序实例
+//   - progress: 进度接收者，例如 TUI 模型或 NullProgressSink，为 nil 时不报告进度
+//   - program: TUI 程序实例，不使用 TUI 时为 nil
 //
 // 返回:
 //   - *Downloader: 新的下载器实例
-func NewDownloader(apiClient *api.Client, tuiModel *tui.Model, program *tea.Program) *Downloader {
+func NewDownloader(apiClient *api.Client, progress ProgressSink, program *tea.Program) *Downloader {
 	cfg := config.Get()
 	return &Downloader{
 		apiClient: apiClient,
 		savePath:  cfg.Live2dSavePath,
-		TuiModel:  tuiModel,
+		Progress:  progress,
 		program:   program,
 		modelSem:  make(chan struct{}, cfg.MaxConcurrentModels),
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   cfg.FileTimeout,
+			Transport: newTransport(cfg),
 		},
+		disk:     newDiskGuard(),
+		adaptive: newAdaptiveController(cfg),
+		limiter:  newRateLimiter(cfg),
+		status:   newStatusTracker(),
+		items:    newItemRegistry(),
 	}
 }
 
+// newAdaptiveController 按配置创建所有模型共享的自适应并发控制器
+// 并发数上限为 MaxConcurrentDownloads × MaxConcurrentModels，即不启用自适应时同时在途的文件数.
+func newAdaptiveController(cfg *config.Config) *adaptive.Controller {
+	if !cfg.AdaptiveConcurrency {
+		return nil
+	}
+	return adaptive.New(adaptive.Options{
+		Min:            cfg.AdaptiveMinConcurrency,
+		Max:            cfg.MaxConcurrentDownloads * cfg.MaxConcurrentModels,
+		Window:         cfg.AdaptiveWindow,
+		MinSuccessRate: cfg.AdaptiveMinSuccessRate,
+	})
+}
+
+// newTransport 创建下载使用的 HTTP 传输层
+// 所有模型共享同一个传输层，同时在途的文件最多为 MaxConcurrentDownloads × MaxConcurrentModels 个，
+// MaxConnsPerHost 限制实际的连接数，超出的请求会等待空闲连接，避免大量连接触发服务器重置；
+// 空闲连接数需要覆盖可用的连接数，否则连接会被频繁关闭重建，预热的连接也会被立即关闭.
+func newTransport(cfg *config.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // 标准库的默认传输层类型固定
+	// 代理 URL 已在初始化配置时校验，运行期间被改为无效值时沿用环境变量中的代理
+	if proxy, err := cfg.Proxy(); err != nil {
+		log.DefaultLogger.Error().Err(err).Msg("代理配置无效，使用环境变量中的代理")
+	} else {
+		transport.Proxy = proxy
+	}
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	idle := max(cfg.MaxConcurrentDownloads*cfg.MaxConcurrentModels, cfg.WarmupConnections)
+	if cfg.MaxConnsPerHost > 0 {
+		idle = min(idle, cfg.MaxConnsPerHost)
+	}
+	transport.MaxIdleConnsPerHost = max(idle, transport.MaxIdleConnsPerHost)
+	transport.MaxIdleConns = max(transport.MaxIdleConnsPerHost, transport.MaxIdleConns)
+	return transport
+}
+
 // createDownloadRequest 创建下载请求
 // 参数:
 //   - ctx: 上下文
+//   - assetsURL: 资源基础 URL
 //   - bundleFile: 资源包文件信息
+//   - cacheBust: 是否附加时间戳查询参数与 Cache-Control: no-cache 请求头，绕过中间缓存
 //
 // 返回:
 //   - *http.Request: HTTP请求
 //   - error: 错误信息
-func (d *Downloader) createDownloadRequest(ctx context.Context, bundleFile model.BundleFile) (*http.Request, error) {
-	url := fmt.Sprintf("%s/%s_rip/%s", config.Get().BaseAssetsURL, bundleFile.BundleName, bundleFile.FileName)
-	log.DefaultLogger.Info().Str("url", url).Msg("开始下载文件")
+func (d *Downloader) createDownloadRequest(
+	ctx context.Context,
+	assetsURL string,
+	bundleFile model.BundleFile,
+	cacheBust bool,
+) (*http.Request, error) {
+	url := bundleFileURL(assetsURL, bundleFile)
+	if cacheBust {
+		url += fmt.Sprintf("?t=%d", time.Now().Unix())
+	}
+	log.FromContext(ctx).Info().Str("url", url).Msg("开始下载文件")
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		log.DefaultLogger.Error().Str("url", url).Err(err).Msg("创建请求失败")
+		log.FromContext(ctx).Error().Str("url", url).Err(err).Msg("创建请求失败")
 		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
+	if cacheBust {
+		req.Header.Set("Cache-Control", "no-cache")
+	}
 
 	return req, nil
 }
 
+// bundleFileURL 返回资源包文件的 URL，不含绕过缓存的查询参数.
+func bundleFileURL(assetsURL string, bundleFile model.BundleFile) string {
+	return fmt.Sprintf("%s/%s_rip/%s", assetsURL, bundleFile.BundleName, bundleFile.FileName)
+}
+
 // validateResponse 验证HTTP响应
 // 参数:
 //   - resp: HTTP响应
@@ -106,42 +187,51 @@ func (d *Downloader) createDownloadRequest(ctx context.Context, bundleFile model
 // 返回:
 //   - error: 错误信息
 func (d *Downloader) validateResponse(resp *http.Response, url string, allowNotFound bool) error {
-	if resp.StatusCode != http.StatusOK {
+	// 只有断点续传的请求才接受 206
+	partial := resp.StatusCode == http.StatusPartialContent && resp.Request.Header.Get("Range") != ""
+	if resp.StatusCode != http.StatusOK && !partial {
 		// 如果允许文件不存在，404错误被视为正常情况
 		if allowNotFound && resp.StatusCode == http.StatusNotFound {
-			log.DefaultLogger.Info().Str("url", url).Msg("文件不存在，跳过下载")
+			log.FromContext(resp.Request.Context()).Info().Str("url", url).Msg("文件不存在，跳过下载")
 			return nil
 		}
-		log.DefaultLogger.Error().Str("url", url).Int("statusCode", resp.StatusCode).Msg("下载文件HTTP错误")
-		return fmt.Errorf("下载文件HTTP错误: %d", resp.StatusCode)
+		log.FromContext(resp.Request.Context()).Error().Str("url", url).Int("statusCode", resp.StatusCode).Msg("下载文件HTTP错误")
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: 下载文件HTTP错误: %d", errs.ErrNotFound, resp.StatusCode)
+		}
+		return fmt.Errorf("%w: 下载文件HTTP错误: %d", errs.ErrHTTPStatus, resp.StatusCode)
 	}
 
 	// 检查Content-Type是否为HTML，如果是则说明是错误页面
 	contentType := resp.Header.Get("Content-Type")
 	if strings.HasPrefix(contentType, "text/html") {
-		log.DefaultLogger.Error().Str("url", url).Str("contentType", contentType).Msg("文件不存在或无法访问")
-		return errors.New("文件不存在或无法访问")
+		log.FromContext(resp.Request.Context()).Error().Str("url", url).Str("contentType", contentType).Msg("文件不存在或无法访问")
+		return errs.ErrFileUnavailable
 	}
 
 	return nil
 }
 
-// createFileAndDirectory 创建文件和目录
+// createFileAndDirectory 创建目录并打开下载用的部分文件
+// 打开前会清理该文件上次中断时遗留的临时文件
 // 参数:
-//   - filePath: 文件路径
+//   - ctx: 上下文
+//   - filePath: 目标文件路径
+//   - resume: 为 true 时在已下载的部分文件末尾追加，否则从头写入
 //
 // 返回:
-//   - *os.File: 文件句柄
+//   - *os.File: 部分文件句柄，下载完成后需重命名为目标文件
 //   - error: 错误信息
-func (d *Downloader) createFileAndDirectory(filePath string) (*os.File, error) {
-	if mkdirErr := os.MkdirAll(filepath.Dir(filePath), 0750); mkdirErr != nil {
-		log.DefaultLogger.Error().Str("filePath", filePath).Err(mkdirErr).Msg("创建目录失败")
-		return nil, fmt.Errorf("创建目录失败: %w", mkdirErr)
+func (d *Downloader) createFileAndDirectory(ctx context.Context, filePath string, resume bool) (*os.File, error) {
+	if removed, removeErr := fsutil.RemoveTemps(filePath); removeErr != nil {
+		log.FromContext(ctx).Warn().Str("filePath", filePath).Err(removeErr).Msg("清理遗留的临时文件失败")
+	} else if len(removed) > 0 {
+		log.FromContext(ctx).Debug().Str("filePath", filePath).Int("count", len(removed)).Msg("已清理遗留的临时文件")
 	}
 
-	file, err := os.Create(filePath)
+	file, err := fsutil.OpenPart(filePath, resume)
 	if err != nil {
-		log.DefaultLogger.Error().Str("filePath", filePath).Err(err).Msg("创建文件失败")
+		log.FromContext(ctx).Error().Str("filePath", filePath).Err(err).Msg("创建文件失败")
 		return nil, fmt.Errorf("创建文件失败: %w", err)
 	}
 
@@ -153,21 +243,36 @@ func (d *Downloader) createFileAndDirectory(filePath string) (*os.File, error) {
 //   - file: 文件句柄
 //   - resp: HTTP响应
 //   - filePath: 文件路径
+//   - onBytes: 接收到数据时的回调，可以为 nil
 //
 // 返回:
+//   - int64: 写入的字节数
 //   - error: 错误信息
-func (d *Downloader) writeFileContent(file *os.File, resp *http.Response, filePath string) error {
-	_, err := io.Copy(file, resp.Body)
+func (d *Downloader) writeFileContent(
+	file *os.File,
+	resp *http.Response,
+	filePath string,
+	onBytes func(int64),
+) (int64, error) {
+	var dst io.Writer = file
+	if onBytes != nil {
+		dst = &countingWriter{w: file, onWrite: onBytes}
+	}
+	var src io.Reader = resp.Body
+	if d.limiter != nil {
+		src = &rateLimitedReader{ctx: resp.Request.Context(), r: resp.Body, limiter: d.limiter}
+	}
+	written, err := fsutil.Copy(dst, src)
 	if err != nil {
 		// 判断是否为 context 超时或取消
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-			log.DefaultLogger.Error().Str("filePath", filePath).Err(err).Msg("下载超时或被取消")
-			return fmt.Errorf("下载超时或被取消: %w", err)
+			log.FromContext(resp.Request.Context()).Error().Str("filePath", filePath).Err(err).Msg("下载超时或被取消")
+			return written, fmt.Errorf("下载超时或被取消: %w", err)
 		}
-		log.DefaultLogger.Error().Str("filePath", filePath).Err(err).Msg("写入文件失败")
-		return fmt.Errorf("写入文件失败: %w", err)
+		log.FromContext(resp.Request.Context()).Error().Str("filePath", filePath).Err(err).Msg("写入文件失败")
+		return written, fmt.Errorf("写入文件失败: %w", err)
 	}
-	return nil
+	return written, nil
 }
 
 // DownloadBundleFile 下载资源包文件
@@ -185,62 +290,381 @@ func (d *Downloader) DownloadBundleFile(
 	filePath string,
 	allowNotFound bool,
 ) error {
+	_, err := d.downloadBundleFile(ctx, config.Get().BaseAssetsURL, bundleFile, filePath, allowNotFound, nil)
+	return err
+}
+
+// countingWriter 在写入数据时回调写入的字节数.
+type countingWriter struct {
+	w       io.Writer   // 实际写入的目标
+	onWrite func(int64) // 写入回调
+}
+
+// Write 写入数据并回调写入的字节数.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.onWrite(int64(n))
+	return n, err //nolint:wrapcheck // 透传底层写入错误
+}
+
+// downloadBundleFile 从 assetsURL 下载资源包文件并返回文件来源信息
+// 遇到网络错误或 404 以外的非 2xx 响应时按指数退避重试，重试次数用尽后返回包含尝试次数的最后一次错误；
+// 文件多次校验失败时改用绕过缓存的请求与其他服务器下载，见 recoverVerification；
+// 最终仍然失败时将各次请求的诊断信息追加到日志目录下的 diagnostics.log
+// 负缓存中记录不存在的文件不发送请求，与收到 404 的处理相同
+// 文件因允许不存在而被跳过时，返回的来源信息为零值
+// onBytes 不为 nil 时，每接收到一段数据都会回调其字节数.
+func (d *Downloader) downloadBundleFile(
+	ctx context.Context,
+	assetsURL string,
+	bundleFile model.BundleFile,
+	filePath string,
+	allowNotFound bool,
+	onBytes func(int64),
+) (model.FileProvenance, error) {
+	if url := bundleFileURL(assetsURL, bundleFile); d.apiClient.IsKnownNotFound(url) {
+		log.FromContext(ctx).Info().Str("url", url).Msg("负缓存中记录该文件不存在，跳过下载")
+		if allowNotFound {
+			return model.FileProvenance{}, nil
+		}
+		return model.FileProvenance{}, fmt.Errorf("%w: 最近已确认不存在", errs.ErrNotFound)
+	}
+
+	diag := &fileDiagnostics{filePath: filePath}
+	ctx = withDiagnostics(ctx, diag)
+
+	provenance, err := d.downloadBundleFileRetry(ctx, assetsURL, bundleFile, filePath, allowNotFound, onBytes)
+	if err != nil && isVerificationError(err) {
+		provenance, err = d.recoverVerification(ctx, assetsURL, bundleFile, filePath, allowNotFound, onBytes, err)
+	}
+	if err != nil {
+		d.writeDiagnostics(ctx, diag, err)
+	}
+	return provenance, err
+}
+
+// downloadBundleFileRetry 从 assetsURL 下载资源包文件，可以重试的错误按指数退避重试
+// 校验失败达到 verifyFailuresBeforeCacheBust 次时不再重试原 URL，直接返回最后一次错误.
+func (d *Downloader) downloadBundleFileRetry(
+	ctx context.Context,
+	assetsURL string,
+	bundleFile model.BundleFile,
+	filePath string,
+	allowNotFound bool,
+	onBytes func(int64),
+) (model.FileProvenance, error) {
+	cfg := config.Get()
+	delay := min(cfg.RetryBaseDelay, maxRetryDelay)
+	verifyFailures := 0
+	for attempt := 0; ; attempt++ {
+		provenance, err := d.downloadBundleFileAdaptive(ctx, assetsURL, bundleFile, filePath, allowNotFound, onBytes, false)
+		if err == nil || !isRetryableError(err) {
+			return provenance, err
+		}
+		if isVerificationError(err) {
+			if verifyFailures++; verifyFailures >= verifyFailuresBeforeCacheBust {
+				return provenance, fmt.Errorf("已尝试 %d 次: %w", attempt+1, err)
+			}
+		}
+		if attempt >= cfg.MaxRetries {
+			if attempt == 0 {
+				return provenance, err
+			}
+			log.FromContext(ctx).Error().Str("filePath", filePath).Int("attempts", attempt+1).Err(err).Msg("重试后仍然下载失败")
+			return provenance, fmt.Errorf("已尝试 %d 次: %w", attempt+1, err)
+		}
+
+		log.FromContext(ctx).Warn().
+			Str("filePath", filePath).
+			Int("attempt", attempt+1).
+			Int("maxRetries", cfg.MaxRetries).
+			Dur("delay", delay).
+			Err(err).
+			Msg("下载文件失败，稍后重试")
+		select {
+		case <-ctx.Done():
+			log.FromContext(ctx).Info().Str("filePath", filePath).Msg("下载已取消")
+			return model.FileProvenance{}, errs.ErrDownloadCancelled
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// downloadBundleFileAdaptive 在自适应并发控制器允许时下载一次文件，并将结果反馈给控制器
+// 只有成功、网络错误与超时会计入统计，被取消或因其他原因失败的下载不影响并发数.
+func (d *Downloader) downloadBundleFileAdaptive(
+	ctx context.Context,
+	assetsURL string,
+	bundleFile model.BundleFile,
+	filePath string,
+	allowNotFound bool,
+	onBytes func(int64),
+	cacheBust bool,
+) (model.FileProvenance, error) {
+	if d.adaptive == nil {
+		return d.downloadBundleFileOnce(ctx, assetsURL, bundleFile, filePath, allowNotFound, onBytes, cacheBust)
+	}
+	if err := d.adaptive.Acquire(ctx); err != nil {
+		return model.FileProvenance{}, errs.ErrDownloadCancelled
+	}
+
+	start := time.Now()
+	provenance, err := d.downloadBundleFileOnce(ctx, assetsURL, bundleFile, filePath, allowNotFound, onBytes, cacheBust)
+	var sample *adaptive.Sample
+	switch {
+	case ctx.Err() != nil:
+	case err == nil:
+		sample = &adaptive.Sample{Success: true, Bytes: provenance.Size, Duration: time.Since(start)}
+	case isRetryableError(err) || isTimeoutError(err):
+		sample = &adaptive.Sample{Success: false}
+	}
+	if limit := d.adaptive.Release(sample); limit > 0 {
+		log.FromContext(ctx).Info().Int("limit", limit).Msg("已调整并发下载数")
+	}
+	return provenance, err
+}
+
+// maxRetryDelay 是两次重试之间的最长等待时间.
+const maxRetryDelay = 30 * time.Second
+
+// isRetryableError 判断下载错误是否可以重试
+// 404 以外的非 2xx 响应、不完整的下载与连接中断等网络错误可以重试
+// 资源不存在、错误页面、磁盘错误、超时、取消以及域名不存在时重试没有意义.
+func isRetryableError(err error) bool {
+	if errs.IsCancelled(err) || isTimeoutError(err) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, errs.ErrHTTPStatus) ||
+		errors.Is(err, errs.ErrIncompleteDownload) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
+
+// downloadBundleFileOnce 从 assetsURL 下载一次资源包文件，不进行重试
+// 数据先写入 <filePath>.part，中断时保留；部分文件存在时使用 Range 请求从已下载的位置继续，
+// 服务器不支持 Range 而返回 200 时从头覆盖写入，下载完成后重命名为目标文件
+// cacheBust 为 true 时请求附加绕过中间缓存的查询参数与请求头.
+func (d *Downloader) downloadBundleFileOnce(
+	ctx context.Context,
+	assetsURL string,
+	bundleFile model.BundleFile,
+	filePath string,
+	allowNotFound bool,
+	onBytes func(int64),
+	cacheBust bool,
+) (provenance model.FileProvenance, err error) {
+	logger := log.FromContext(ctx)
+	attempt := beginAttempt(ctx)
+	defer func() { attempt.end(err) }()
 	select {
 	case <-ctx.Done():
-		log.DefaultLogger.Info().Str("filePath", filePath).Msg("下载已取消")
-		return errors.New("下载已取消")
+		logger.Info().Str("filePath", filePath).Msg("下载已取消")
+		return model.FileProvenance{}, errs.ErrDownloadCancelled
 	default:
 	}
 
 	// 创建请求
-	req, err := d.createDownloadRequest(ctx, bundleFile)
+	req, err := d.createDownloadRequest(ctx, assetsURL, bundleFile, cacheBust)
 	if err != nil {
-		return err
+		return model.FileProvenance{}, err
+	}
+	offset := fsutil.PartSize(filePath)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		logger.Info().Str("filePath", filePath).Int64("offset", offset).Msg("从已下载的位置继续下载")
 	}
+	attempt.request(req)
 
 	// 执行请求
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
-		log.DefaultLogger.Error().Str("url", req.URL.String()).Err(err).Msg("下载文件失败")
-		return fmt.Errorf("下载文件失败: %w", err)
+		logger.Error().Str("url", req.URL.String()).Err(err).Msg("下载文件失败")
+		return model.FileProvenance{}, fmt.Errorf("下载文件失败: %w", err)
 	}
 	defer resp.Body.Close()
+	attempt.response(resp)
+
+	if offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return d.handleRangeNotSatisfiable(ctx, req, resp, filePath, offset)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		d.apiClient.RecordNotFound(bundleFileURL(assetsURL, bundleFile))
+	}
 
 	// 验证响应
 	if validateErr := d.validateResponse(resp, req.URL.String(), allowNotFound); validateErr != nil {
-		return validateErr
+		attempt.readBody(resp)
+		return model.FileProvenance{}, validateErr
 	}
 
 	// 如果允许文件不存在且文件不存在，直接返回
 	if allowNotFound && resp.StatusCode == http.StatusNotFound {
-		return nil
+		return model.FileProvenance{}, nil
+	}
+
+	offset, err = resumeOffset(resp, offset)
+	if err != nil {
+		_ = fsutil.RemovePart(filePath)
+		logger.Warn().Str("filePath", filePath).Err(err).Msg("断点续传的响应范围无效，删除部分文件")
+		return model.FileProvenance{}, err
 	}
 
-	// 创建文件和目录
-	file, createErr := d.createFileAndDirectory(filePath)
+	// 先写入部分文件，下载完成后再重命名，避免留下不完整的文件
+	file, createErr := d.createFileAndDirectory(ctx, filePath, offset > 0)
 	if createErr != nil {
-		return createErr
+		return model.FileProvenance{}, createErr
 	}
-	defer file.Close()
 
-	// 写入文件内容
-	if writeErr := d.writeFileContent(file, resp, filePath); writeErr != nil {
-		return writeErr
+	// 写入文件内容，失败时保留部分文件以便下次继续
+	written, writeErr := d.writeFileContent(file, resp, filePath, onBytes)
+	if writeErr != nil {
+		_ = file.Close()
+		return model.FileProvenance{}, writeErr
+	}
+	if resp.ContentLength < 0 {
+		logger.Debug().Str("filePath", filePath).Int64("written", written).Msg("响应没有 Content-Length，跳过文件大小校验")
+	} else if written != resp.ContentLength {
+		_ = file.Close()
+		logger.Error().
+			Str("filePath", filePath).
+			Int64("written", written).
+			Int64("contentLength", resp.ContentLength).
+			Msg("下载的文件不完整")
+		return model.FileProvenance{}, fmt.Errorf(
+			"%w: 已写入 %d 字节，应为 %d 字节", errs.ErrIncompleteDownload, written, resp.ContentLength,
+		)
+	}
+	if commitErr := fsutil.CommitTemp(file, filePath); commitErr != nil {
+		logger.Error().Str("filePath", filePath).Err(commitErr).Msg("保存文件失败")
+		return model.FileProvenance{}, fmt.Errorf("保存文件失败: %w", commitErr)
 	}
 
-	log.DefaultLogger.Info().Str("filePath", filePath).Msg("文件下载完成")
-	return nil
+	logger.Info().Str("filePath", filePath).Msg("文件下载完成")
+	var expected int64
+	if resp.ContentLength >= 0 {
+		expected = offset + resp.ContentLength
+	}
+	return model.FileProvenance{
+		Source:       model.FileSourceNetwork,
+		Host:         req.URL.Host,
+		URL:          req.URL.String(),
+		DownloadedAt: time.Now(),
+		Size:         offset + written,
+		ExpectedSize: expected,
+	}, nil
+}
+
+// resumeOffset 根据响应确定写入部分文件的起始位置
+// 200 表示服务器忽略了 Range，从头写入；206 时 Content-Range 的起始位置必须与已下载的字节数一致.
+func resumeOffset(resp *http.Response, offset int64) (int64, error) {
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, nil
+	}
+	start, _, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || start != offset {
+		return 0, fmt.Errorf(
+			"%w: Content-Range %q 与已下载的 %d 字节不一致",
+			errs.ErrHTTPStatus, resp.Header.Get("Content-Range"), offset,
+		)
+	}
+	return offset, nil
+}
+
+// handleRangeNotSatisfiable 处理断点续传请求返回的 416
+// Content-Range 中的文件大小与部分文件一致时说明部分文件已经完整，直接重命名为目标文件；
+// 否则删除部分文件，返回可以重试的错误，下次从头下载.
+func (d *Downloader) handleRangeNotSatisfiable(
+	ctx context.Context,
+	req *http.Request,
+	resp *http.Response,
+	filePath string,
+	offset int64,
+) (model.FileProvenance, error) {
+	logger := log.FromContext(ctx)
+	_, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || total != offset {
+		_ = fsutil.RemovePart(filePath)
+		logger.Warn().Str("filePath", filePath).Int64("offset", offset).Int64("total", total).Msg("部分文件与服务器上的文件不一致，重新下载")
+		return model.FileProvenance{}, fmt.Errorf("%w: 断点续传的范围无效: %d", errs.ErrHTTPStatus, resp.StatusCode)
+	}
+
+	file, err := fsutil.OpenPart(filePath, true)
+	if err != nil {
+		return model.FileProvenance{}, fmt.Errorf("保存文件失败: %w", err)
+	}
+	if commitErr := fsutil.CommitTemp(file, filePath); commitErr != nil {
+		logger.Error().Str("filePath", filePath).Err(commitErr).Msg("保存文件失败")
+		return model.FileProvenance{}, fmt.Errorf("保存文件失败: %w", commitErr)
+	}
+	logger.Info().Str("filePath", filePath).Msg("部分文件已完整，文件下载完成")
+	return model.FileProvenance{
+		Source:       model.FileSourceNetwork,
+		Host:         req.URL.Host,
+		URL:          req.URL.String(),
+		DownloadedAt: time.Now(),
+		Size:         offset,
+		ExpectedSize: total,
+	}, nil
+}
+
+// parseContentRange 解析 "bytes <start>-<end>/<total>" 或 "bytes */<total>" 格式的 Content-Range
+// 返回起始位置与文件总大小，未知时为 -1.
+func parseContentRange(value string) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	total := int64(-1)
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = n
+	}
+	if rng == "*" {
+		return -1, total, true
+	}
+	first, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
 }
 
 // Live2dBuilder 表示 Live2D 构建器
 // 负责构建完整的 Live2D 模型，包括下载所有必要文件.
 type Live2dBuilder struct {
-	path       string             // 模型保存路径
-	data       *model.BuildData   // 构建数据
-	model      *model.Live2dModel // Live2D 模型
-	dataPath   string             // 数据文件路径
-	downloader *Downloader        // 下载器实例
-	ModelName  string             // 模型名称
+	path             string                  // 模型保存路径
+	data             *model.BuildData        // 构建数据
+	server           string                  // 下载文件所用的服务器
+	assetsURL        string                  // 下载文件所用的资源基础 URL
+	model            *model.Live2dModel      // Live2D 模型
+	dataPath         string                  // 数据文件目录，原始布局时为模型目录
+	downloader       *Downloader             // 下载器实例
+	manifest         *model.DownloadManifest // 本次构建的下载清单
+	previousManifest *model.DownloadManifest // 上一次构建留下的下载清单
+	skippedFiles     []string                // 因超时被跳过的可选文件
+	missingFiles     []string                // 因允许缺失而跳过的不存在的文件
+	layout           model.OutputLayout      // 模型文件的组织方式
+	logger           *log.Logger             // 构建过程使用的日志，开启模型独立日志时同时写入模型日志文件
+	status           *modelEntry             // 下载器中的构建状态记录，未通过 ConstructWithContext 构建时为 nil
+	redownload       bool                    // 远端模型已更新，不复用已存在的文件
+	ModelName        string                  // 模型名称
 }
 
 // NewLive2dBuilder 创建新的 Live2D 构建器实例
@@ -258,14 +682,40 @@ func NewLive2dBuilder(
 	downloader *Downloader,
 	modelName string,
 ) *Live2dBuilder {
-	return &Live2dBuilder{
+	server, assetsURL := buildDataServer(buildData)
+	builder := &Live2dBuilder{
 		path:       path,
 		data:       buildData,
+		server:     server,
+		assetsURL:  assetsURL,
 		model:      &model.Live2dModel{Motions: make(map[string][]model.MotionFile)},
-		dataPath:   filepath.Join(path, "data"),
 		downloader: downloader,
-		ModelName:  modelName,
+		manifest: &model.DownloadManifest{
+			Model:  modelName,
+			Server: server,
+			Files:  make(map[string]model.ManifestFile),
+		},
+		previousManifest: &model.DownloadManifest{Model: modelName, Files: make(map[string]model.ManifestFile)},
+		logger:           log.DefaultLogger,
+		ModelName:        modelName,
+	}
+	builder.SetOutputLayout(config.Get().OutputLayout)
+	return builder
+}
+
+// buildDataServer 返回下载构建数据对应文件所用的服务器与资源基础 URL
+// 构建数据来自回退服务器时使用同一服务器，否则使用配置中的服务器.
+func buildDataServer(buildData *model.BuildData) (string, string) {
+	cfg := config.Get()
+	if buildData.Server == "" || buildData.Server == cfg.AssetsServer() {
+		return cfg.AssetsServer(), cfg.BaseAssetsURL
+	}
+	assetsURL, err := config.ServerAssetsURL(cfg.BaseAssetsURL, buildData.Server)
+	if err != nil {
+		log.DefaultLogger.Warn().Str("server", buildData.Server).Err(err).Msg("无效的构建数据服务器，使用配置中的服务器")
+		return cfg.AssetsServer(), cfg.BaseAssetsURL
 	}
+	return buildData.Server, assetsURL
 }
 
 // ProcessFile 处理单个文件
@@ -284,64 +734,25 @@ func (b *Live2dBuilder) ProcessFile(
 	filePath string,
 	allowNotFound bool,
 ) (string, error) {
-	if _, statErr := os.Stat(filePath); os.IsNotExist(statErr) {
-		if downloadErr := b.downloader.DownloadBundleFile(ctx, bundleFile, filePath, allowNotFound); downloadErr != nil {
-			return "", fmt.Errorf("下载文件失败: %w", downloadErr)
-		}
-	}
 	relPath, relErr := filepath.Rel(b.path, filePath)
 	if relErr != nil {
 		return "", fmt.Errorf("获取相对路径失败: %w", relErr)
 	}
-	return filepath.ToSlash(relPath), nil
-}
+	relPath = filepath.ToSlash(relPath)
 
-// getFileType 根据文件路径判断文件类型
-// 参数:
-//   - filePath: 文件路径
-//
-// 返回:
-//   - string: 文件类型（"model", "physics", "texture", "motion", "expression"）
-func getFileType(filePath string) string {
-	switch {
-	case strings.HasSuffix(filePath, "model.moc"):
-		return "model"
-	case strings.HasSuffix(filePath, "physics.json"):
-		return "physics"
-	case strings.Contains(filePath, "textures"):
-		return "texture"
-	case strings.Contains(filePath, "motions"):
-		return "motion"
-	case strings.Contains(filePath, "expressions"):
-		return "expression"
-	default:
-		return "unknown"
+	if b.reusableFile(ctx, filePath, relPath) {
+		b.recordProvenance(relPath, b.cachedProvenance(filePath, relPath))
+		return relPath, nil
 	}
-}
 
-// updateModelData 根据文件类型更新模型数据
-// 参数:
-//   - model: Live2D 模型
-//   - filePath: 文件路径
-//   - relPath: 相对路径
-func updateModelData(model *model.Live2dModel, filePath, relPath string) {
-	switch getFileType(filePath) {
-	case "model":
-		model.Model = relPath
-	case "physics":
-		model.Physics = relPath
-	case "texture":
-		model.Textures = append(model.Textures, relPath)
-	case "motion":
-		motionName := strings.Split(filepath.Base(filePath), ".")[0]
-		model.Motions[motionName] = []MotionFile{{File: relPath}}
-	case "expression":
-		expressionName := strings.Split(filepath.Base(filePath), ".")[0]
-		model.Expressions = append(model.Expressions, ExpressionFile{
-			Name: expressionName,
-			File: relPath,
-		})
+	provenance, downloadErr := b.downloader.downloadBundleFile(ctx, b.assetsURL, bundleFile, filePath, allowNotFound, nil)
+	if downloadErr != nil {
+		return "", fmt.Errorf("下载文件失败: %w", downloadErr)
 	}
+	if provenance.Source != "" {
+		b.recordProvenance(relPath, provenance)
+	}
+	return relPath, nil
 }
 
 // processExistingFiles 处理已存在的文件
@@ -352,23 +763,22 @@ func updateModelData(model *model.Live2dModel, filePath, relPath string) {
 // 返回:
 //   - int: 已处理的文件数量
 //   - error: 错误信息
-func (b *Live2dBuilder) processExistingFiles(existingFiles []string) (int, error) {
+func (b *Live2dBuilder) processExistingFiles(existingFiles []plannedFile) (int, error) {
 	completedFiles := 0
 	for _, file := range existingFiles {
-		relPath, err := filepath.Rel(b.path, file)
+		relPath, err := filepath.Rel(b.path, file.filePath)
 		if err != nil {
 			return completedFiles, fmt.Errorf("获取相对路径失败: %w", err)
 		}
 		relPath = filepath.ToSlash(relPath)
+		b.recordProvenance(relPath, b.cachedProvenance(file.filePath, relPath))
 
 		// 更新当前文件的进度
 		completedFiles++
-		if b.downloader.TuiModel != nil {
-			b.downloader.TuiModel.UpdateProgress(b.ModelName, completedFiles)
-		}
+		b.updateProgress(completedFiles)
 
 		// 更新模型数据
-		updateModelData(b.model, file, relPath)
+		updateModelData(b.model, file.kind, relPath)
 	}
 	return completedFiles, nil
 }
@@ -380,130 +790,93 @@ func (b *Live2dBuilder) processExistingFiles(existingFiles []string) (int, error
 // 返回:
 //   - error: 错误信息
 func (b *Live2dBuilder) createModelData() error {
+	if err := b.checkPreviousModelData(); err != nil {
+		if b.downloader.Progress != nil {
+			b.downloader.Progress.SetError(fmt.Sprintf("%s: %v", b.ModelName, err))
+		}
+		return err
+	}
+
+	if err := ValidateModelReferences(b.model); err != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Str("dataPath", b.dataPath).Err(err).Msg("模型数据引用越界")
+		if b.downloader.Progress != nil {
+			b.downloader.Progress.SetError(fmt.Sprintf("%s: %v，请检查保存路径设置", b.ModelName, err))
+		}
+		return err
+	}
+
+	presetName, preset := SelectLayoutPreset(config.Get(), b.ModelName)
+	b.logger.Debug().Str("modelName", b.ModelName).Str("preset", presetName).Msg("应用布局预设")
+
 	modelData := model.Data{
-		Version: "Sample 1.0.0",
-		Layout: map[string]float64{
-			"center_x": 0,
-			"center_y": 0,
-			"width":    2,
-		},
-		HitAreasCustom: map[string][]float64{
-			"head_x": {-0.25, 1},
-			"head_y": {0.25, 0.2},
-			"body_x": {-0.3, 0.2},
-			"body_y": {0.3, -1.9},
-		},
-		Model:       b.model.Model,
-		Physics:     b.model.Physics,
-		Textures:    b.model.Textures,
-		Motions:     b.model.Motions,
-		Expressions: b.model.Expressions,
+		Version:        "Sample 1.0.0",
+		Layout:         preset.Layout,
+		HitAreasCustom: preset.HitAreasCustom,
+		Model:          b.model.Model,
+		Physics:        b.model.Physics,
+		Transition:     b.model.Transition,
+		Textures:       b.model.Textures,
+		Motions:        b.model.Motions,
+		Expressions:    b.model.Expressions,
+		Extension:      newExtension(presetName),
 	}
 
-	log.DefaultLogger.Info().Str("modelName", b.ModelName).Msg("开始创建模型数据")
+	b.logger.Info().Str("modelName", b.ModelName).Msg("开始创建模型数据")
 
 	finalJSON, err := json.MarshalIndent(modelData, "", "  ")
 	if err != nil {
-		log.DefaultLogger.Error().Str("modelName", b.ModelName).Err(err).Msg("序列化模型数据失败")
-		if b.downloader.TuiModel != nil {
-			b.downloader.TuiModel.SetError(fmt.Sprintf("%s: 创建模型数据失败: %v", b.ModelName, err))
+		b.logger.Error().Str("modelName", b.ModelName).Err(err).Msg("序列化模型数据失败")
+		if b.downloader.Progress != nil {
+			b.downloader.Progress.SetError(fmt.Sprintf("%s: 创建模型数据失败: %v", b.ModelName, err))
 		}
 		return fmt.Errorf("序列化模型数据失败: %w", err)
 	}
 
-	modelJSONPath := filepath.Join(b.path, "model.json")
+	modelJSONPath := filepath.Join(b.path, model.ModelDataFileName)
 	if writeErr := os.WriteFile(modelJSONPath, finalJSON, 0600); writeErr != nil {
-		log.DefaultLogger.Error().Str("modelName", b.ModelName).Str("path", modelJSONPath).Err(writeErr).Msg("写入模型数据失败")
+		b.logger.Error().Str("modelName", b.ModelName).Str("path", modelJSONPath).Err(writeErr).Msg("写入模型数据失败")
 		return fmt.Errorf("写入模型数据失败: %w", writeErr)
 	}
 
-	log.DefaultLogger.Info().Str("modelName", b.ModelName).Str("path", modelJSONPath).Msg("模型数据创建完成")
+	b.logger.Info().Str("modelName", b.ModelName).Str("path", modelJSONPath).Msg("模型数据创建完成")
 	return nil
 }
 
 // prepareDownloadTasks 准备下载任务列表
+// 已存在但与下载清单记录不一致的文件会被重新下载
+// 参数:
+//   - ctx: 上下文
+//
 // 返回:
 //   - []downloadTask: 下载任务列表
-//   - []string: 已存在的文件列表
-func (b *Live2dBuilder) prepareDownloadTasks() ([]downloadTask, []string) {
-	var tasks []downloadTask
-	var existingFiles []string
-
-	// 模型文件
-	modelFile := filepath.Join(b.dataPath, "model.moc")
-	if _, err := os.Stat(modelFile); os.IsNotExist(err) {
-		tasks = append(tasks, downloadTask{
-			bundleFile:    b.data.Model,
-			filePath:      modelFile,
-			allowNotFound: false, // 模型文件必须存在
-			result:        make(chan downloadResult, 1),
-		})
-	} else {
-		existingFiles = append(existingFiles, modelFile)
-	}
-
-	// 物理文件
-	physicsFile := filepath.Join(b.dataPath, "physics.json")
-	if _, err := os.Stat(physicsFile); os.IsNotExist(err) {
-		tasks = append(tasks, downloadTask{
-			bundleFile:    b.data.Physics,
-			filePath:      physicsFile,
-			allowNotFound: true, // physics.json文件允许不存在
-			result:        make(chan downloadResult, 1),
-		})
-	} else {
-		existingFiles = append(existingFiles, physicsFile)
-	}
-
-	// 纹理文件
-	texturePath := filepath.Join(b.dataPath, "textures")
-	for _, texture := range b.data.Textures {
-		file := filepath.Join(texturePath, texture.FileName)
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			tasks = append(tasks, downloadTask{
-				bundleFile:    texture,
-				filePath:      file,
-				allowNotFound: false, // 纹理文件必须存在
-				result:        make(chan downloadResult, 1),
-			})
-		} else {
-			existingFiles = append(existingFiles, file)
-		}
+//   - []plannedFile: 已存在的文件列表
+//   - error: 错误信息
+func (b *Live2dBuilder) prepareDownloadTasks(ctx context.Context) ([]downloadTask, []plannedFile, error) {
+	files, err := b.plannedFiles()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 动作文件
-	motionPath := filepath.Join(b.dataPath, "motions")
-	for _, motion := range b.data.Motions {
-		file := filepath.Join(motionPath, motion.FileName)
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			tasks = append(tasks, downloadTask{
-				bundleFile:    motion,
-				filePath:      file,
-				allowNotFound: false, // 动作文件必须存在
-				result:        make(chan downloadResult, 1),
-			})
-		} else {
-			existingFiles = append(existingFiles, file)
+	var tasks []downloadTask
+	var existingFiles []plannedFile
+	for _, file := range files {
+		relPath, relErr := filepath.Rel(b.path, file.filePath)
+		if relErr != nil {
+			return nil, nil, fmt.Errorf("获取相对路径失败: %w", relErr)
 		}
-	}
-
-	// 表情文件
-	expressionPath := filepath.Join(b.dataPath, "expressions")
-	for _, expression := range b.data.Expressions {
-		file := filepath.Join(expressionPath, expression.FileName)
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			tasks = append(tasks, downloadTask{
-				bundleFile:    expression,
-				filePath:      file,
-				allowNotFound: false, // 表情文件必须存在
-				result:        make(chan downloadResult, 1),
-			})
-		} else {
+		if b.reusableFile(ctx, file.filePath, filepath.ToSlash(relPath)) {
 			existingFiles = append(existingFiles, file)
+			continue
 		}
+		tasks = append(tasks, downloadTask{
+			kind:          file.kind,
+			bundleFile:    file.bundleFile,
+			filePath:      file.filePath,
+			allowNotFound: file.allowNotFound,
+			result:        make(chan downloadResult, 1),
+		})
 	}
-
-	return tasks, existingFiles
+	return tasks, existingFiles, nil
 }
 
 // startWorkerPool 启动工作池处理下载任务
@@ -518,100 +891,173 @@ func (b *Live2dBuilder) startWorkerPool(ctx context.Context, taskChan chan downl
 			for task := range taskChan {
 				select {
 				case <-ctx.Done():
-					errorChan <- errors.New("下载已取消")
+					errorChan <- errs.ErrDownloadCancelled
 					return
 				default:
-					if downloadErr := b.downloader.DownloadBundleFile(ctx, task.bundleFile, task.filePath, task.allowNotFound); downloadErr != nil {
-						task.result <- downloadResult{err: fmt.Errorf("下载文件失败: %w", downloadErr)}
-						continue
-					}
+					provenance, downloadErr := b.downloadTrackedFile(ctx, task)
 					relPath, relErr := filepath.Rel(b.path, task.filePath)
 					if relErr != nil {
 						task.result <- downloadResult{err: fmt.Errorf("获取相对路径失败: %w", relErr)}
 						continue
 					}
-					task.result <- downloadResult{relPath: filepath.ToSlash(relPath)}
+					relPath = filepath.ToSlash(relPath)
+					if downloadErr != nil {
+						if isTimeoutError(downloadErr) && ctx.Err() == nil {
+							downloadErr = fmt.Errorf("%w: %s: %w", errs.ErrFileStalled, relPath, downloadErr)
+						}
+						task.result <- downloadResult{relPath: relPath, err: fmt.Errorf("下载文件失败: %w", downloadErr)}
+						continue
+					}
+					task.result <- downloadResult{relPath: relPath, provenance: provenance}
 				}
 			}
 		}()
 	}
 }
 
+// maxStallRetries 是停滞的文件被用户取消后重新下载的最大次数.
+const maxStallRetries = 3
+
+// downloadTrackedFile 下载任务中的文件，并向进度接收者报告字节级进度用于停滞检测
+// 文件因停滞被用户取消时会重新连接下载.
+func (b *Live2dBuilder) downloadTrackedFile(ctx context.Context, task downloadTask) (model.FileProvenance, error) {
+	// 剩余空间不足时等待空间恢复后再开始下载
+	if err := b.downloader.disk.begin(ctx); err != nil {
+		return model.FileProvenance{}, err
+	}
+	var size int64
+	defer func() { b.downloader.disk.end(size) }()
+
+	for attempt := 0; ; attempt++ {
+		fileCtx, cancel := context.WithCancelCause(ctx)
+		var onBytes func(int64)
+		untrack := func() {}
+		if b.downloader.Progress != nil {
+			onBytes, untrack = b.downloader.Progress.TrackFile(b.ModelName, cancel)
+		}
+
+		provenance, err := b.downloader.downloadBundleFile(
+			fileCtx,
+			b.assetsURL,
+			task.bundleFile,
+			task.filePath,
+			task.allowNotFound,
+			onBytes,
+		)
+		untrack()
+		stalled := errors.Is(context.Cause(fileCtx), errs.ErrStallCancelled)
+		cancel(nil)
+
+		if err == nil && provenance.Source != "" {
+			// 只处理新下载的纹理，避免已存在的纹理被重复修正
+			err = b.postProcessTexture(task, &provenance)
+		}
+		if err == nil || !stalled || ctx.Err() != nil || attempt >= maxStallRetries {
+			size = provenance.Size
+			return provenance, err
+		}
+		b.logger.Warn().Str("modelName", b.ModelName).Str("filePath", task.filePath).Stringer("kind", task.kind).Int("attempt", attempt+1).Msg("停滞的文件已取消，重新下载")
+	}
+}
+
+// isTimeoutError 判断错误是否为下载超时.
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
 // processDownloadResults 处理下载结果
+// 单个文件失败时会继续等待其他文件下载完成，超时的可选文件会被跳过
 // 参数:
 //   - ctx: 上下文
 //   - tasks: 下载任务列表
 //   - completedFiles: 已完成的文件数
 //
 // 返回:
-//   - error: 错误信息
+//   - error: 第一个必需文件的错误信息
 func (b *Live2dBuilder) processDownloadResults(ctx context.Context, tasks []downloadTask, completedFiles int) error {
+	var firstErr error
 	for i := range tasks {
 		select {
 		case <-ctx.Done():
-			return errors.New("下载已取消")
+			return errs.ErrDownloadCancelled
 		case result := <-tasks[i].result:
 			if result.err != nil {
-				// 直接返回第一个错误，不包裹
-				return result.err
+				if tasks[i].allowNotFound && errors.Is(result.err, errs.ErrFileStalled) {
+					b.logger.Warn().Str("modelName", b.ModelName).Str("file", result.relPath).Msg("可选文件下载超时，已跳过")
+					b.skippedFiles = append(b.skippedFiles, result.relPath)
+					completedFiles++
+					b.updateProgress(completedFiles)
+					continue
+				}
+				// 记录第一个错误，不包裹
+				if firstErr == nil {
+					firstErr = result.err
+				}
+				continue
 			}
 
 			// 更新当前文件的进度
 			completedFiles++
-			if b.downloader.TuiModel != nil {
-				b.downloader.TuiModel.UpdateProgress(b.ModelName, completedFiles)
+			b.updateProgress(completedFiles)
+
+			// 文件不存在且允许缺失时没有来源信息，不写入模型数据，避免引用不存在的文件
+			if result.provenance.Source == "" {
+				b.logger.Warn().Str("modelName", b.ModelName).Str("file", result.relPath).Msg("文件不存在，已按配置跳过")
+				b.missingFiles = append(b.missingFiles, result.relPath)
+				continue
 			}
+			b.recordProvenance(result.relPath, result.provenance)
 
 			// 更新模型数据
-			updateModelData(b.model, tasks[i].filePath, result.relPath)
+			updateModelData(b.model, tasks[i].kind, result.relPath)
 		}
 	}
-	return nil
+	return firstErr
 }
 
-// setupDownloadEnvironment 设置下载环境
-// 包括上下文设置、信号量获取、目录创建等初始化工作.
-func (b *Live2dBuilder) setupDownloadEnvironment() (context.Context, error) {
-	// 设置上下文
-	ctx := context.Background()
-	if b.downloader.TuiModel != nil && b.downloader.TuiModel.Ctx != nil {
-		ctx = b.downloader.TuiModel.Ctx
-	}
+// SkippedFiles 返回因下载超时而被跳过的可选文件
+// 返回:
+//   - []string: 相对于模型目录的文件路径列表
+func (b *Live2dBuilder) SkippedFiles() []string {
+	return b.skippedFiles
+}
 
+// MissingFiles 返回因允许缺失而跳过的不存在的文件
+// 返回:
+//   - []string: 相对于模型目录的文件路径列表
+func (b *Live2dBuilder) MissingFiles() []string {
+	return b.missingFiles
+}
+
+// setupDownloadEnvironment 设置下载环境
+// 包括信号量获取、目录创建等初始化工作.
+func (b *Live2dBuilder) setupDownloadEnvironment(ctx context.Context) error {
 	// 获取信号量
 	select {
 	case <-ctx.Done():
-		log.DefaultLogger.Info().Str("modelName", b.ModelName).Msg("构建已取消")
-		return nil, errors.New("下载已取消")
+		b.logger.Info().Str("modelName", b.ModelName).Msg("构建已取消")
+		return errs.ErrDownloadCancelled
 	case b.downloader.modelSem <- struct{}{}:
 	}
 
 	// 确保目录存在
 	if err := os.MkdirAll(b.dataPath, 0750); err != nil {
-		log.DefaultLogger.Error().Str("modelName", b.ModelName).Str("path", b.dataPath).Err(err).Msg("创建目录失败")
-		if b.downloader.TuiModel != nil {
-			b.downloader.TuiModel.SetError(fmt.Sprintf("%s: 创建目录失败: %v", b.ModelName, err))
+		b.logger.Error().Str("modelName", b.ModelName).Str("path", b.dataPath).Err(err).Msg("创建目录失败")
+		if b.downloader.Progress != nil {
+			b.downloader.Progress.SetError(fmt.Sprintf("%s: 创建目录失败: %v", b.ModelName, err))
 		}
 		<-b.downloader.modelSem // 释放信号量
-		return nil, fmt.Errorf("创建目录失败: %w", err)
+		return fmt.Errorf("创建目录失败: %w", err)
 	}
 
-	return ctx, nil
+	return nil
 }
 
 // initializeDownloadProgress 初始化下载进度.
-func (b *Live2dBuilder) initializeDownloadProgress() {
-	totalFiles := 1 + // model.moc
-		1 + // physics.json
-		len(b.data.Textures) + // textures
-		len(b.data.Motions) + // motions
-		len(b.data.Expressions) // expressions
-
-	log.DefaultLogger.Info().Str("modelName", b.ModelName).Int("totalFiles", totalFiles).Msg("需要下载的文件总数")
-
-	if b.downloader.TuiModel != nil {
-		b.downloader.TuiModel.AddDownloadItem(b.ModelName, totalFiles)
-	}
+func (b *Live2dBuilder) initializeDownloadProgress(totalFiles int) {
+	b.logger.Info().Str("modelName", b.ModelName).Int("totalFiles", totalFiles).Msg("需要下载的文件总数")
+	b.setTotalFiles(totalFiles)
 }
 
 // handleDownloadTasks 处理下载任务.
@@ -630,7 +1076,7 @@ func (b *Live2dBuilder) handleDownloadTasks(ctx context.Context, tasks []downloa
 	for _, task := range tasks {
 		select {
 		case <-ctx.Done():
-			return errors.New("下载已取消")
+			return errs.ErrDownloadCancelled
 		case taskChan <- task:
 		}
 	}
@@ -638,8 +1084,8 @@ func (b *Live2dBuilder) handleDownloadTasks(ctx context.Context, tasks []downloa
 
 	// 处理下载结果
 	if err := b.processDownloadResults(ctx, tasks, completedFiles); err != nil {
-		if b.downloader.TuiModel != nil {
-			b.downloader.TuiModel.SendError(b.ModelName, err)
+		if b.downloader.Progress != nil {
+			b.downloader.Progress.SendError(b.ModelName, err)
 		}
 		return err
 	}
@@ -649,26 +1095,93 @@ func (b *Live2dBuilder) handleDownloadTasks(ctx context.Context, tasks []downloa
 
 // Construct 构建完整的 Live2D 模型.
 func (b *Live2dBuilder) Construct() error {
-	log.DefaultLogger.Info().Str("modelName", b.ModelName).Msg("开始构建Live2D模型")
+	return b.ConstructWithContext(context.Background())
+}
+
+// ConstructWithContext 使用指定的上下文构建完整的 Live2D 模型
+// 上下文或进度接收者的上下文任一被取消时，构建都会中止；构建期间可以通过 Downloader.CancelModel 单独取消，
+// 此时返回包裹 errs.ErrModelCancelled 的错误.
+// 同一下载器中同名模型正在构建时不会重复构建，而是等待并返回正在进行的构建的结果，
+// 等待期间随正在进行的构建一起取消；构建结束后再次调用会重新构建.
+func (b *Live2dBuilder) ConstructWithContext(ctx context.Context) error {
+	result, err, _ := b.downloader.inflight.Do(b.ModelName, func() (any, error) {
+		return b, b.constructTracked(ctx)
+	})
+	if leader, ok := result.(*Live2dBuilder); ok && leader != b {
+		b.logger.Info().Str("modelName", b.ModelName).Msg("同一模型正在构建，复用其构建结果")
+		b.skippedFiles, b.missingFiles = leader.skippedFiles, leader.missingFiles
+	}
+	return err
+}
+
+// constructTracked 构建完整的 Live2D 模型，并在下载器中记录构建状态.
+func (b *Live2dBuilder) constructTracked(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	b.status = b.downloader.status.begin(b.ModelName, cancel)
+	defer b.downloader.items.forget(b.ModelName)
+
+	// 同时响应界面的取消操作
+	if b.downloader.Progress != nil {
+		if progressCtx := b.downloader.Progress.Context(); progressCtx != nil {
+			stop := context.AfterFunc(progressCtx, func() { cancel(context.Canceled) })
+			defer stop()
+		}
+	}
+
+	err := b.cancelledError(ctx, b.construct(ctx))
+	b.downloader.status.finish(b.status, err)
+	return err
+}
+
+// construct 构建完整的 Live2D 模型.
+func (b *Live2dBuilder) construct(ctx context.Context) error {
+	b.logger.Info().Str("modelName", b.ModelName).Msg("开始构建Live2D模型")
+	startedAt := time.Now()
 
 	// 设置下载环境
-	ctx, err := b.setupDownloadEnvironment()
+	err := b.setupDownloadEnvironment(ctx)
 	if err != nil {
 		return err
 	}
 	defer func() { <-b.downloader.modelSem }() // 完成后释放信号量
+	b.setState(ModelDownloading)
 
-	// 初始化下载进度
-	b.initializeDownloadProgress()
+	// 模型日志在获取信号量后打开，同时打开的文件数不超过并发模型数
+	if closeLog := b.openModelLog(); closeLog != nil {
+		defer closeLog()
+	}
+	ctx = log.WithContext(ctx, b.logger)
+
+	// 读取上一次的下载清单，用于保留复用文件的原始来源信息
+	b.previousManifest = b.loadPreviousManifest()
+
+	// 构建数据与本地文件都和上次下载时相同则跳过整个模型，远端模型更新或指定强制刷新时整体重新下载
+	if b.downloader.ForceRefresh {
+		b.logger.Info().Str("modelName", b.ModelName).Msg("强制刷新，忽略下载清单重新下载整个模型")
+		b.redownload = true
+	} else if b.checkModelHash(ctx) {
+		b.skipUnchanged()
+		return b.packageModel(b.path)
+	}
 
 	// 准备下载任务
-	tasks, existingFiles := b.prepareDownloadTasks()
+	tasks, existingFiles, err := b.prepareDownloadTasks(ctx)
+	if err != nil {
+		if b.downloader.Progress != nil {
+			b.downloader.Progress.SendError(b.ModelName, err)
+		}
+		return err
+	}
+
+	// 初始化下载进度
+	b.initializeDownloadProgress(len(tasks) + len(existingFiles))
 
 	// 处理已存在的文件
 	completedFiles, err := b.processExistingFiles(existingFiles)
 	if err != nil {
-		if b.downloader.TuiModel != nil {
-			b.downloader.TuiModel.SendError(b.ModelName, err)
+		if b.downloader.Progress != nil {
+			b.downloader.Progress.SendError(b.ModelName, err)
 		}
 		return err
 	}
@@ -677,9 +1190,79 @@ func (b *Live2dBuilder) Construct() error {
 	if err = b.handleDownloadTasks(ctx, tasks, completedFiles); err != nil {
 		return err
 	}
+	b.setState(ModelVerifying)
 
 	// 创建最终的模型数据
-	return b.createModelData()
+	if err = b.createModelData(); err != nil {
+		return err
+	}
+	if err = b.createModel3Data(); err != nil {
+		return err
+	}
+
+	// 生成动作与表情索引
+	if err = b.writeModelIndex(); err != nil {
+		return err
+	}
+
+	// 生成纹理元信息
+	if err = b.writeTexturesMeta(); err != nil {
+		return err
+	}
+
+	// 写入下载清单
+	if err = b.writeManifest(ctx); err != nil {
+		return err
+	}
+	if err = b.writeModelHash(); err != nil {
+		return err
+	}
+
+	b.recordUsage()
+	if err = b.packageModel(b.path); err != nil {
+		return err
+	}
+	if b.downloader.Progress != nil {
+		b.downloader.Progress.SendSummary(b.ModelName, b.Summary(time.Since(startedAt)))
+	}
+	return nil
+}
+
+// openModelLog 开启模型独立日志时为本次构建打开模型日志文件
+// 返回用于关闭日志文件的函数，未开启或打开失败时返回 nil，打开失败时只记录到主日志.
+func (b *Live2dBuilder) openModelLog() func() {
+	cfg := config.Get()
+	if !cfg.PerModelLog {
+		return nil
+	}
+	logger, closer, err := b.logger.ForModel(cfg.LogPath, b.ModelName)
+	if err != nil {
+		b.logger.Warn().Str("modelName", b.ModelName).Err(err).Msg("打开模型日志失败，只写入主日志")
+		return nil
+	}
+	parent := b.logger
+	b.logger = logger
+	return func() {
+		b.logger = parent
+		if closeErr := closer.Close(); closeErr != nil {
+			parent.Warn().Str("modelName", b.ModelName).Err(closeErr).Msg("关闭模型日志失败")
+		}
+	}
+}
+
+// recordUsage 记录本次下载的使用统计
+// 统计只是辅助信息，记录失败不影响下载结果.
+func (b *Live2dBuilder) recordUsage() {
+	cfg := config.Get()
+	if !cfg.UsageStats {
+		return
+	}
+	// 已有下载清单说明是在已有目录上重新下载
+	repair := len(b.previousManifest.Files) > 0
+	chara := filepath.Base(filepath.Dir(b.path))
+	if err := stats.RecordUsage(cfg.StatePath, b.ModelName, chara, b.path, repair, time.Now()); err != nil {
+		b.logger.Warn().Str("modelName", b.ModelName).Err(err).Msg("记录使用统计失败")
+	}
 }
 
 // GetAPIClient 获取API客户端实例
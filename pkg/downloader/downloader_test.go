@@ -1,15 +1,36 @@
 package downloader_test
 
 import (
+	"archive/zip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/api"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/downloader"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
 
@@ -17,15 +38,21 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// update 为 true 时用本次的输出重新生成 testdata 中的 golden 文件.
+var update = flag.Bool("update", false, "重新生成 testdata/builder 中的 golden 文件")
+
 // setupTest 设置测试环境.
 func setupTest(t *testing.T) {
 	// 创建临时目录
 	tempDir := t.TempDir()
 
 	// 初始化配置
-	config.Init()
+	require.NoError(t, config.Init())
 	cfg := config.Get()
 	cfg.LogPath = filepath.Join(tempDir, "logs")
+	cfg.StatePath = filepath.Join(tempDir, "state.json")
+	cfg.RetryBaseDelay = time.Millisecond // 避免网络不可用时重试拖慢测试
+	cfg.NegativeCacheDuration = 0         // 测试服务器的端口可能被复用，避免负缓存影响其他测试
 
 	// 初始化日志
 	if _, err := log.New(cfg.LogPath); err != nil {
@@ -113,6 +140,7 @@ func TestLive2dBuilder(t *testing.T) {
 		"data/textures/texture_01.png",
 		"data/motions/idle01.mtn",
 		"data/expressions/default.exp.json",
+		"data/anonTransitionData.asset",
 	}
 	for _, file := range testFiles {
 		filePath := filepath.Join(tempDir, file)
@@ -199,6 +227,2470 @@ func TestLive2dBuilder(t *testing.T) {
 				modelJSON := filepath.Join(tt.path, "model.json")
 				_, jsonStatErr := os.Stat(modelJSON)
 				require.NoError(t, jsonStatErr, "model.json should exist")
+
+				// 检查下载清单是否记录了复用文件的来源
+				manifest, manifestErr := downloader.LoadManifest(tt.path)
+				require.NoError(t, manifestErr, "download_manifest.json should be readable")
+				entry, ok := manifest.Files["data/textures/texture_00.png"]
+				require.True(t, ok, "manifest should contain texture_00.png")
+				assert.Equal(t, model.FileSourceCache, entry.Provenance.Source, "existing file should come from cache")
+				assert.Equal(t, int64(len("test")), entry.Provenance.Size, "provenance size should match file size")
+			}
+		})
+	}
+}
+
+func TestSelectLayoutPreset(t *testing.T) {
+	custom := config.DefaultConfig()
+	custom.LayoutPresets["wide"] = model.LayoutPreset{
+		Match:  []string{"_wide"},
+		Layout: map[string]float64{"width": 3},
+	}
+
+	tests := []struct {
+		name      string
+		preset    string
+		modelName string
+		want      string
+	}{
+		{name: "默认使用标准预设", modelName: "037_casual-2023", want: model.LayoutPresetStandard},
+		{name: "按名称匹配Q版预设", modelName: "001_SD_summer", want: model.LayoutPresetChibi},
+		{name: "按名称匹配半身预设", modelName: "001_half", want: model.LayoutPresetHalf},
+		{name: "按名称匹配自定义预设", modelName: "001_wide", want: "wide"},
+		{name: "用户指定预设优先", preset: model.LayoutPresetHalf, modelName: "001_sd", want: model.LayoutPresetHalf},
+		{name: "指定预设不存在时自动选择", preset: "missing", modelName: "001_sd", want: model.LayoutPresetChibi},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *custom
+			cfg.LayoutPreset = tt.preset
+			name, preset := downloader.SelectLayoutPreset(&cfg, tt.modelName)
+			assert.Equal(t, tt.want, name)
+			assert.Equal(t, cfg.LayoutPresets[tt.want].Layout, preset.Layout)
+		})
+	}
+}
+
+func TestLive2dBuilderStalledFiles(t *testing.T) {
+	// 模拟卡住的文件：请求会一直挂起直到超时
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "stalled") {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldTimeout := cfg.BaseAssetsURL, cfg.FileTimeout
+	cfg.BaseAssetsURL, cfg.FileTimeout = server.URL, 200*time.Millisecond
+	defer func() { cfg.BaseAssetsURL, cfg.FileTimeout = oldURL, oldTimeout }()
+
+	tests := []struct {
+		name        string
+		physics     string
+		texture     string
+		wantErr     bool
+		wantSkipped []string
+	}{
+		{
+			name:        "跳过卡住的可选文件",
+			physics:     "stalled_physics.json",
+			texture:     "texture_00.png",
+			wantSkipped: []string{"data/physics.json"},
+		},
+		{
+			name:    "必需文件卡住时失败",
+			physics: "physics.json",
+			texture: "stalled_texture.png",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			buildData := &model.BuildData{
+				Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+				Physics:  model.BundleFile{BundleName: "live2d/chara/001_test", FileName: tt.physics},
+				Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: tt.texture}},
+				Motions:  []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "idle01.mtn"}},
+			}
+			builder := downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test")
+
+			err := builder.Construct()
+			if tt.wantErr {
+				require.ErrorIs(t, err, errs.ErrFileStalled)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantSkipped, builder.SkippedFiles())
+
+			// 其他文件应先下载完成
+			_, statErr := os.Stat(filepath.Join(tempDir, "data", "motions", "idle01.mtn"))
+			require.NoError(t, statErr, "Other files should be downloaded")
+		})
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	errBroken := errors.New("broken")
+
+	tests := []struct {
+		name          string
+		failFast      bool
+		wantErr       error
+		wantCompleted []string
+		wantFailed    []string
+		wantSkipped   []string
+	}{
+		{
+			name:          "失败后继续下载",
+			failFast:      false,
+			wantCompleted: []string{"001_a", "001_c", "001_d"},
+			wantFailed:    []string{"001_b"},
+		},
+		{
+			name:          "快速失败中止批次",
+			failFast:      true,
+			wantErr:       errs.ErrBatchAborted,
+			wantCompleted: []string{"001_a"},
+			wantFailed:    []string{"001_b"},
+			wantSkipped:   []string{"001_c", "001_d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{"001_a", "001_b", "001_c", "001_d"}
+			result, err := downloader.RunBatch(context.Background(), names, 1, tt.failFast,
+				func(ctx context.Context, name string) error {
+					if err := ctx.Err(); err != nil {
+						return errs.ErrDownloadCancelled
+					}
+					if name == "001_b" {
+						return errBroken
+					}
+					return nil
+				})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.ErrorIs(t, err, errBroken)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.ElementsMatch(t, tt.wantCompleted, result.Completed)
+			assert.ElementsMatch(t, tt.wantSkipped, result.Skipped)
+			failed := make([]string, 0, len(result.Failed))
+			for name := range result.Failed {
+				failed = append(failed, name)
+			}
+			assert.ElementsMatch(t, tt.wantFailed, failed)
+		})
+	}
+}
+
+func TestRunBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := downloader.RunBatch(ctx, []string{"001_a", "001_b"}, 1, false,
+		func(context.Context, string) error { return nil })
+	require.ErrorIs(t, err, errs.ErrDownloadCancelled)
+	assert.Empty(t, result.Completed)
+}
+
+func TestResolveServerFolder(t *testing.T) {
+	tests := []struct {
+		name         string
+		folderServer string
+		decision     model.ServerConflictDecision
+		wantDir      string
+		wantErr      error
+		wantAsked    bool
+	}{
+		{name: "目录没有标记", wantDir: "anon"},
+		{name: "服务器一致", folderServer: "cn", wantDir: "anon"},
+		{name: "冲突时继续", folderServer: "jp", decision: model.ServerConflictProceed, wantDir: "anon", wantAsked: true},
+		{name: "冲突时使用后缀目录", folderServer: "jp", decision: model.ServerConflictSuffix, wantDir: "anon-cn", wantAsked: true},
+		{name: "冲突时中止", folderServer: "jp", decision: model.ServerConflictAbort, wantErr: errs.ErrServerConflict, wantAsked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			charaDir := filepath.Join(root, "anon")
+			require.NoError(t, os.MkdirAll(charaDir, 0750))
+			if tt.folderServer != "" {
+				require.NoError(t, downloader.EnsureFolderMarker(charaDir, tt.folderServer))
+			}
+
+			asked := false
+			modelPath := filepath.Join(charaDir, "casual")
+			got, err := downloader.ResolveServerFolder(modelPath, "cn", func(folderServer string) (model.ServerConflictDecision, error) {
+				asked = true
+				assert.Equal(t, tt.folderServer, folderServer)
+				return tt.decision, nil
+			})
+
+			assert.Equal(t, tt.wantAsked, asked)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(root, tt.wantDir, "casual"), got)
+		})
+	}
+}
+
+func TestEnsureFolderMarker(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, downloader.EnsureFolderMarker(dir, "jp"))
+	// 已有标记时不会被覆盖
+	require.NoError(t, downloader.EnsureFolderMarker(dir, "cn"))
+
+	marker, err := downloader.ReadFolderMarker(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "jp", marker.Server)
+}
+
+func TestFindModelInOtherLayout(t *testing.T) {
+	root := t.TempDir()
+	writeManifest := func(dir, name string) {
+		require.NoError(t, os.MkdirAll(dir, 0750))
+		data := fmt.Sprintf(`{"model":%q,"files":{}}`, name)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, model.ManifestFileName), []byte(data), 0600))
+	}
+	writeManifest(filepath.Join(root, "爱音-cn", "casual-2023"), "037_casual-2023")
+	// 其他角色的同名服装不会被误判
+	writeManifest(filepath.Join(root, "香澄", "casual-2023"), "001_casual-2023")
+	writeManifest(filepath.Join(root, "002_school"), "002_school")
+
+	tests := []struct {
+		name   string
+		model  string
+		layout model.DirectoryLayout
+		want   string
+	}{
+		{"平铺布局找到按角色保存的模型", "037_casual-2023", model.DirectoryLayoutFlat, filepath.Join(root, "爱音-cn", "casual-2023")},
+		{"按角色布局找到平铺保存的模型", "002_school", model.DirectoryLayoutByCharacter, filepath.Join(root, "002_school")},
+		{"平铺布局没有下载过", "002_school", model.DirectoryLayoutFlat, ""},
+		{"按角色布局没有下载过", "037_casual-2023", model.DirectoryLayoutByCharacter, ""},
+		{"无效的名称", "casual", model.DirectoryLayoutFlat, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, downloader.FindModelInOtherLayout(root, tt.model, tt.layout))
+		})
+	}
+}
+
+func TestWarmupConnections(t *testing.T) {
+	var mu sync.Mutex
+	newConns := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// 保证所有预热请求同时在途，从而各自建立连接
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			newConns++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		url       string
+		count     int
+		wantConns int
+	}{
+		{name: "未开启预热", url: server.URL + "/assets/jp", count: 0, wantConns: 0},
+		{name: "预热指定数量的连接", url: server.URL + "/assets/jp", count: 3, wantConns: 3},
+		{name: "预热失败不影响流程", url: "http://127.0.0.1:1/assets/jp", count: 2, wantConns: 0},
+	}
+
+	cfg := config.Get()
+	oldURL, oldCount := cfg.BaseAssetsURL, cfg.WarmupConnections
+	defer func() { cfg.BaseAssetsURL, cfg.WarmupConnections = oldURL, oldCount }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			newConns = 0
+			mu.Unlock()
+
+			cfg.BaseAssetsURL, cfg.WarmupConnections = tt.url, tt.count
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			d.WarmupConnections(context.Background())
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantConns, newConns)
+		})
+	}
+}
+
+func TestMaxConnsPerHost(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// 让请求在途一段时间，使并发的下载争抢连接
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("test"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch state { //nolint:exhaustive // 只统计连接的建立与关闭
+		case http.StateNew:
+			active++
+			peak = max(peak, active)
+		case http.StateClosed, http.StateHijacked:
+			active--
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldDownloads, oldConns := cfg.BaseAssetsURL, cfg.MaxConcurrentDownloads, cfg.MaxConnsPerHost
+	defer func() {
+		cfg.BaseAssetsURL, cfg.MaxConcurrentDownloads, cfg.MaxConnsPerHost = oldURL, oldDownloads, oldConns
+	}()
+	cfg.BaseAssetsURL, cfg.MaxConcurrentDownloads = server.URL, 10
+
+	textures := make([]model.BundleFile, 0, 20)
+	for i := range 20 {
+		textures = append(textures, model.BundleFile{
+			BundleName: "live2d/chara/001_test",
+			FileName:   fmt.Sprintf("texture_%02d.png", i),
+		})
+	}
+	buildData := &model.BuildData{
+		Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Textures: textures,
+	}
+
+	tests := []struct {
+		name     string
+		maxConns int
+	}{
+		{name: "限制为单个连接", maxConns: 1},
+		{name: "限制为多个连接", maxConns: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			active, peak = 0, 0
+			mu.Unlock()
+
+			cfg.MaxConnsPerHost = tt.maxConns
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			require.NoError(t, downloader.NewLive2dBuilder(t.TempDir(), buildData, d, "001_test").Construct())
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Positive(t, peak)
+			assert.LessOrEqual(t, peak, tt.maxConns)
+		})
+	}
+}
+
+func TestLoadModelData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL := cfg.BaseAssetsURL
+	cfg.BaseAssetsURL = server.URL
+	defer func() { cfg.BaseAssetsURL = oldURL }()
+
+	tests := []struct {
+		name       string
+		legacy     string
+		wantSchema int
+		wantErr    error
+	}{
+		{
+			name:       "旧版模型数据",
+			legacy:     `{"version":"Sample 1.0.0","model":"data/model.moc","textures":["data/textures/texture_00.png"]}`,
+			wantSchema: model.ExtensionSchemaLegacy,
+		},
+		{
+			name:       "当前版本模型数据",
+			wantSchema: model.ExtensionSchemaVersion,
+		},
+		{
+			name:    "更新版本的模型数据",
+			legacy:  `{"version":"Sample 1.0.0","model":"data/model.moc","bestdori_dl":{"schema":99}}`,
+			wantErr: errs.ErrUnsupportedModelSchema,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			if tt.legacy != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(tempDir, model.ModelDataFileName), []byte(tt.legacy), 0600))
+			} else {
+				d := downloader.NewDownloader(api.NewClient(), nil, nil)
+				buildData := &model.BuildData{
+					Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+					Physics:  model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "physics.json"},
+					Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+				}
+				require.NoError(t, downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test").Construct())
+			}
+
+			data, err := downloader.LoadModelData(tempDir)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, data.Extension)
+			assert.Equal(t, tt.wantSchema, data.Extension.Schema)
+			assert.Equal(t, "data/model.moc", data.Model)
+			assert.Equal(t, []string{"data/textures/texture_00.png"}, data.Textures)
+			if tt.wantSchema == model.ExtensionSchemaVersion {
+				assert.Equal(t, model.LayoutPresetStandard, data.Extension.LayoutPreset)
+				assert.Contains(t, data.Extension.Features, model.FeatureProvenance)
+			}
+		})
+	}
+}
+
+func TestParseModelList(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []downloader.ModelListEntry
+		wantErr bool
+	}{
+		{
+			name: "模型名称与输出路径",
+			input: `# 注释
+001_casual
+002_live_rip => archive/live
+  live2d/chara/003_Summer/ 
+
+036_summer=>D:\live2d\summer
+`,
+			want: []downloader.ModelListEntry{
+				{Name: "001_casual"},
+				{Name: "002_live", OutputPath: "archive/live"},
+				{Name: "003_summer"},
+				{Name: "036_summer", OutputPath: `D:\live2d\summer`},
+			},
+		},
+		{name: "缺少输出路径", input: "001_casual =>", wantErr: true},
+		{name: "缺少模型名称", input: "=> archive", wantErr: true},
+		{name: "无效的模型名称", input: "casual => archive", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := downloader.ParseModelList(strings.NewReader(tt.input))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, entries)
+		})
+	}
+}
+
+func TestProcessTextureAlpha(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		mode        model.TextureAlphaMode
+		fileName    string
+		pixel       color.NRGBA
+		wantChanged bool
+		want        color.NRGBA
+	}{
+		{
+			name:        "预乘",
+			mode:        model.TextureAlphaPremultiply,
+			fileName:    "texture_00.png",
+			pixel:       color.NRGBA{R: 200, G: 100, B: 0, A: 128},
+			wantChanged: true,
+			want:        color.NRGBA{R: 100, G: 50, B: 0, A: 128},
+		},
+		{
+			name:        "反预乘",
+			mode:        model.TextureAlphaUnpremultiply,
+			fileName:    "texture_00.png",
+			pixel:       color.NRGBA{R: 100, G: 50, B: 0, A: 128},
+			wantChanged: true,
+			want:        color.NRGBA{R: 199, G: 100, B: 0, A: 128},
+		},
+		{
+			name:     "不处理",
+			mode:     model.TextureAlphaNone,
+			fileName: "texture_00.png",
+			pixel:    color.NRGBA{R: 200, G: 100, B: 0, A: 128},
+			want:     color.NRGBA{R: 200, G: 100, B: 0, A: 128},
+		},
+		{
+			name:     "不透明像素不变",
+			mode:     model.TextureAlphaPremultiply,
+			fileName: "texture_00.png",
+			pixel:    color.NRGBA{R: 200, G: 100, B: 0, A: 255},
+			want:     color.NRGBA{R: 200, G: 100, B: 0, A: 255},
+		},
+		{
+			name:     "跳过非 png 文件",
+			mode:     model.TextureAlphaPremultiply,
+			fileName: "texture_00.bin",
+			pixel:    color.NRGBA{R: 200, G: 100, B: 0, A: 128},
+			want:     color.NRGBA{R: 200, G: 100, B: 0, A: 128},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), tt.fileName)
+			img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			img.SetNRGBA(0, 0, tt.pixel)
+			file, err := os.Create(path)
+			require.NoError(t, err)
+			require.NoError(t, png.Encode(file, img))
+			require.NoError(t, file.Close())
+
+			changed, err := downloader.ProcessTextureAlpha(path, tt.mode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChanged, changed)
+
+			file, err = os.Open(path)
+			require.NoError(t, err)
+			defer file.Close()
+			decoded, err := png.Decode(file)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, color.NRGBAModel.Convert(decoded.At(0, 0)))
+		})
+	}
+}
+
+func TestSelectTextureAlphaMode(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.DefaultConfig()
+	cfg.TextureAlphaMode = model.TextureAlphaPremultiply
+	cfg.TextureAlphaModels = map[string]model.TextureAlphaMode{
+		"001_casual":  model.TextureAlphaNone,
+		"002_unknown": "invalid",
+	}
+
+	assert.Equal(t, model.TextureAlphaPremultiply, downloader.SelectTextureAlphaMode(cfg, "003_live"))
+	assert.Equal(t, model.TextureAlphaNone, downloader.SelectTextureAlphaMode(cfg, "001_casual"))
+	assert.Equal(t, model.TextureAlphaNone, downloader.SelectTextureAlphaMode(cfg, "002_unknown"))
+}
+
+func TestTexturesMeta(t *testing.T) {
+	var texture strings.Builder
+	require.NoError(t, png.Encode(&texture, image.NewNRGBA(image.Rect(0, 0, 4, 2))))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "texture_00.png") {
+			_, _ = w.Write([]byte(texture.String()))
+			return
+		}
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldMeta := cfg.BaseAssetsURL, cfg.GenerateTextureMeta
+	defer func() { cfg.BaseAssetsURL, cfg.GenerateTextureMeta = oldURL, oldMeta }()
+	cfg.BaseAssetsURL, cfg.GenerateTextureMeta = server.URL, true
+
+	dir := t.TempDir()
+	buildData := &model.BuildData{
+		Model: model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Textures: []model.BundleFile{
+			{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"},
+			{BundleName: "live2d/chara/001_test", FileName: "texture_01.png"},
+		},
+	}
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	require.NoError(t, downloader.NewLive2dBuilder(dir, buildData, d, "001_test").Construct())
+
+	raw, err := os.ReadFile(filepath.Join(dir, model.TexturesMetaFileName))
+	require.NoError(t, err)
+	var meta model.TexturesMeta
+	require.NoError(t, json.Unmarshal(raw, &meta))
+	assert.Equal(t, model.TexturesMeta{
+		Model: "001_test",
+		Count: 2,
+		Textures: []model.TextureMeta{
+			{File: "data/textures/texture_00.png", Width: 4, Height: 2, Format: "png"},
+			{File: "data/textures/texture_01.png", Unknown: true},
+		},
+	}, meta)
+}
+
+func TestBuildModelIndex(t *testing.T) {
+	live2d := &model.Live2dModel{
+		Motions: map[string][]model.MotionFile{
+			"smile01": {{File: "data/motions/smile01.mtn"}},
+			"idle01":  {{File: "data/motions/idle01.mtn"}},
+		},
+		Expressions: []model.ExpressionFile{
+			{Name: "sad", File: "data/expressions/sad.exp.json"},
+			{Name: "default", File: "data/expressions/default.exp.json"},
+		},
+	}
+
+	index := downloader.BuildModelIndex("037_casual-2023", live2d)
+	assert.Equal(t, &model.ModelIndex{
+		Model: "037_casual-2023",
+		Motions: []model.IndexMotionGroup{
+			{Name: "idle01", Files: []string{"data/motions/idle01.mtn"}},
+			{Name: "smile01", Files: []string{"data/motions/smile01.mtn"}},
+		},
+		Expressions: []model.IndexExpression{
+			{Name: "default", File: "data/expressions/default.exp.json"},
+			{Name: "sad", File: "data/expressions/sad.exp.json"},
+		},
+	}, index)
+
+	empty := downloader.BuildModelIndex("037_empty", &model.Live2dModel{})
+	assert.Empty(t, empty.Motions)
+	assert.NotNil(t, empty.Motions, "空列表应序列化为 [] 而不是 null")
+	assert.NotNil(t, empty.Expressions, "空列表应序列化为 [] 而不是 null")
+}
+
+func TestBuildModel3Data(t *testing.T) {
+	live2d := &model.Live2dModel{
+		Model:    "data/model.moc",
+		Physics:  "data/physics.json",
+		Textures: []string{"data/textures/texture_00.png"},
+		Motions: map[string][]model.MotionFile{
+			"idle01": {{File: "data/motions/idle01.mtn"}},
+		},
+		Expressions: []model.ExpressionFile{{Name: "default", File: "data/expressions/default.exp.json"}},
+	}
+
+	data, unconverted := downloader.BuildModel3Data(live2d, map[string]float64{"center_x": 0, "width": 2, "scale": 1})
+	assert.Equal(t, model.Model3Version, data.Version)
+	assert.Equal(t, model.Model3FileReferences{
+		Moc:         "data/model.moc",
+		Textures:    []string{"data/textures/texture_00.png"},
+		Physics:     "data/physics.json",
+		Motions:     map[string][]model.Model3Motion{"idle01": {{File: "data/motions/idle01.mtn"}}},
+		Expressions: []model.Model3Expression{{Name: "default", File: "data/expressions/default.exp.json"}},
+	}, data.FileReferences)
+	assert.Equal(t, map[string]float64{"CenterX": 0, "Width": 2}, data.Layout)
+	assert.Equal(t, []string{"scale"}, unconverted)
+	require.Len(t, data.Groups, 2)
+	assert.Equal(t, "EyeBlink", data.Groups[0].Name)
+	assert.Equal(t, "LipSync", data.Groups[1].Name)
+
+	empty, _ := downloader.BuildModel3Data(&model.Live2dModel{}, nil)
+	assert.NotNil(t, empty.FileReferences.Textures, "空列表应序列化为 [] 而不是 null")
+	assert.Nil(t, empty.Layout)
+}
+
+func TestOutputFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldFormat := cfg.BaseAssetsURL, cfg.OutputFormat
+	defer func() { cfg.BaseAssetsURL, cfg.OutputFormat = oldURL, oldFormat }()
+	cfg.BaseAssetsURL = server.URL
+
+	buildData := &model.BuildData{
+		Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+	}
+
+	tests := []struct {
+		name       string
+		format     model.OutputFormat
+		wantModel3 bool
+	}{
+		{name: "Cubism 2 只生成 model.json", format: model.OutputFormatCubism2},
+		{name: "Cubism 3 同时生成 model3.json", format: model.OutputFormatCubism3, wantModel3: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.OutputFormat = tt.format
+			dir := t.TempDir()
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			require.NoError(t, downloader.NewLive2dBuilder(dir, buildData, d, "001_test").Construct())
+			assert.FileExists(t, filepath.Join(dir, model.ModelDataFileName))
+
+			model3Path := filepath.Join(dir, "001_test"+model.Model3FileSuffix)
+			if !tt.wantModel3 {
+				assert.NoFileExists(t, model3Path)
+				return
+			}
+			raw, err := os.ReadFile(model3Path)
+			require.NoError(t, err)
+			var data model.Model3Data
+			require.NoError(t, json.Unmarshal(raw, &data))
+			assert.Equal(t, "data/model.moc", data.FileReferences.Moc)
+			assert.Equal(t, []string{"data/textures/texture_00.png"}, data.FileReferences.Textures)
+			assert.Contains(t, string(raw), `"FileReferences"`)
+		})
+	}
+}
+
+func TestPackageModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldZip, oldAlias, oldRemove := cfg.BaseAssetsURL, cfg.ZipOutput, cfg.PackageAsZip, cfg.ZipRemoveSource
+	defer func() {
+		cfg.BaseAssetsURL, cfg.ZipOutput, cfg.PackageAsZip, cfg.ZipRemoveSource = oldURL, oldZip, oldAlias, oldRemove
+	}()
+	cfg.BaseAssetsURL = server.URL
+
+	tests := []struct {
+		name         string
+		zipOutput    bool
+		packageAsZip bool
+		removeSource bool
+		wantZip      bool
+	}{
+		{name: "未开启打包", wantZip: false},
+		{name: "开启 ZipOutput", zipOutput: true, wantZip: true},
+		{name: "使用别名 PackageAsZip", packageAsZip: true, wantZip: true},
+		{name: "打包后删除模型目录", zipOutput: true, removeSource: true, wantZip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.ZipOutput, cfg.PackageAsZip, cfg.ZipRemoveSource = tt.zipOutput, tt.packageAsZip, tt.removeSource
+
+			// 模型目录以服装名命名，ZIP 的顶层目录使用完整的模型名称
+			modelPath := filepath.Join(t.TempDir(), "001", "test")
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			buildData := &model.BuildData{
+				Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+				Physics:  model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "physics.json"},
+				Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+			}
+			require.NoError(t, downloader.NewLive2dBuilder(modelPath, buildData, d, "001_test").Construct())
+
+			zipPath := modelPath + ".zip"
+			if !tt.wantZip {
+				assert.NoFileExists(t, zipPath)
+				return
+			}
+			zr, err := zip.OpenReader(zipPath)
+			require.NoError(t, err)
+			defer zr.Close()
+
+			names := make([]string, 0, len(zr.File))
+			for _, f := range zr.File {
+				names = append(names, f.Name)
+			}
+			assert.Contains(t, names, "001_test/data/model.moc")
+			assert.Contains(t, names, "001_test/"+model.ModelDataFileName)
+			if tt.removeSource {
+				assert.NoDirExists(t, modelPath)
+			} else {
+				assert.DirExists(t, modelPath)
+			}
+		})
+	}
+}
+
+func TestOutputLayout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL := cfg.BaseAssetsURL
+	cfg.BaseAssetsURL = server.URL
+	defer func() { cfg.BaseAssetsURL = oldURL }()
+
+	buildData := &model.BuildData{
+		Model:       model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "001_test.moc"},
+		Physics:     model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "001_test.physics.json"},
+		Textures:    []model.BundleFile{{BundleName: "live2d/chara/001_general", FileName: "texture_00.png"}},
+		Motions:     []model.BundleFile{{BundleName: "live2d/chara/001_general", FileName: "idle01.mtn"}},
+		Expressions: []model.BundleFile{{BundleName: "live2d/chara/001_general", FileName: "smile.exp.json"}},
+	}
+
+	tests := []struct {
+		name       string
+		layout     model.OutputLayout
+		wantModel  string
+		wantMotion string
+		wantFiles  []string
+	}{
+		{
+			name:       "整理后的布局",
+			layout:     model.OutputLayoutRestructured,
+			wantModel:  "data/model.moc",
+			wantMotion: "data/motions/idle01.mtn",
+		},
+		{
+			name:       "原始布局",
+			layout:     model.OutputLayoutOriginal,
+			wantModel:  "live2d/chara/001_test_rip/001_test.moc",
+			wantMotion: "live2d/chara/001_general_rip/idle01.mtn",
+			wantFiles:  []string{"live2d/chara/001_test_rip/buildData.asset"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			builder := downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test")
+			builder.SetOutputLayout(tt.layout)
+			require.NoError(t, builder.Construct())
+
+			data, err := downloader.LoadModelData(tempDir)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantModel, data.Model)
+			assert.Equal(t, []model.MotionFile{{File: tt.wantMotion}}, data.Motions["idle01"])
+			require.Len(t, data.Expressions, 1)
+			assert.Equal(t, "smile", data.Expressions[0].Name)
+
+			// model.json 引用的所有文件都应存在
+			referenced := append([]string{data.Model, data.Physics, data.Expressions[0].File}, data.Textures...)
+			for _, motions := range data.Motions {
+				for _, motion := range motions {
+					referenced = append(referenced, motion.File)
+				}
+			}
+			for _, file := range append(referenced, tt.wantFiles...) {
+				assert.FileExists(t, filepath.Join(tempDir, filepath.FromSlash(file)))
+			}
+		})
+	}
+}
+
+func TestFileKindClassification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL := cfg.BaseAssetsURL
+	cfg.BaseAssetsURL = server.URL
+	defer func() { cfg.BaseAssetsURL = oldURL }()
+
+	// 文件名中包含其他类型的关键字时，仍应按构建数据中的字段归类
+	buildData := &model.BuildData{
+		Model:       model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "001_test.moc"},
+		Physics:     model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "001_test.physics.json"},
+		Textures:    []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "motions.png"}},
+		Motions:     []model.BundleFile{{BundleName: "live2d/chara/001_textures", FileName: "expressions.mtn"}},
+		Expressions: []model.BundleFile{{BundleName: "live2d/chara/001_motions", FileName: "textures.exp.json"}},
+	}
+
+	for _, layout := range []model.OutputLayout{model.OutputLayoutRestructured, model.OutputLayoutOriginal} {
+		t.Run(string(layout), func(t *testing.T) {
+			tempDir := t.TempDir()
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+
+			// 第二次构建时文件已存在，走已有文件的处理流程
+			for range 2 {
+				builder := downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test")
+				builder.SetOutputLayout(layout)
+				require.NoError(t, builder.Construct())
+
+				data, err := downloader.LoadModelData(tempDir)
+				require.NoError(t, err)
+				require.Len(t, data.Textures, 1)
+				assert.Equal(t, "motions.png", filepath.Base(data.Textures[0]))
+				require.Len(t, data.Motions["expressions"], 1)
+				assert.Equal(t, "expressions.mtn", filepath.Base(data.Motions["expressions"][0].File))
+				require.Len(t, data.Expressions, 1)
+				assert.Equal(t, "textures", data.Expressions[0].Name)
+			}
+		})
+	}
+}
+
+func TestValidateModelReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		live2d  *model.Live2dModel
+		wantErr bool
+	}{
+		{
+			name: "全部位于模型目录内",
+			live2d: &model.Live2dModel{
+				Model:       "data/model.moc",
+				Textures:    []string{"data/textures/texture_00.png"},
+				Motions:     map[string][]model.MotionFile{"idle01": {{File: "data/motions/idle01.mtn"}}},
+				Expressions: []model.ExpressionFile{{Name: "smile", File: "data/expressions/smile.exp.json"}},
+			},
+		},
+		{
+			name:    "纹理位于上级目录",
+			live2d:  &model.Live2dModel{Model: "data/model.moc", Textures: []string{"../../data/textures/texture_00.png"}},
+			wantErr: true,
+		},
+		{
+			name: "动作位于上级目录",
+			live2d: &model.Live2dModel{
+				Model:   "data/model.moc",
+				Motions: map[string][]model.MotionFile{"idle01": {{File: "../motions/idle01.mtn"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "绝对路径",
+			live2d:  &model.Live2dModel{Model: "/tmp/model.moc"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := downloader.ValidateModelReferences(tt.live2d)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errs.ErrReferenceOutsideModel)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDataPathOutsideModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL := cfg.BaseAssetsURL
+	cfg.BaseAssetsURL = server.URL
+	defer func() { cfg.BaseAssetsURL = oldURL }()
+
+	buildData := &model.BuildData{
+		Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "001_test.moc"},
+		Physics:  model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "001_test.physics.json"},
+		Textures: []model.BundleFile{{BundleName: "live2d/chara/001_general", FileName: "texture_00.png"}},
+	}
+
+	root := t.TempDir()
+	modelPath := filepath.Join(root, "models", "001_test")
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	builder := downloader.NewLive2dBuilder(modelPath, buildData, d, "001_test")
+	builder.SetDataPath(filepath.Join(root, "elsewhere"))
+
+	err := builder.Construct()
+	require.ErrorIs(t, err, errs.ErrReferenceOutsideModel)
+	assert.NoFileExists(t, filepath.Join(modelPath, model.ModelDataFileName))
+}
+
+func TestTransitionFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "missing") {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL := cfg.BaseAssetsURL
+	cfg.BaseAssetsURL = server.URL
+	defer func() { cfg.BaseAssetsURL = oldURL }()
+
+	tests := []struct {
+		name           string
+		layout         model.OutputLayout
+		transition     model.BundleFile
+		wantTransition string
+		wantMissing    []string
+	}{
+		{
+			name:           "保存到数据目录",
+			layout:         model.OutputLayoutRestructured,
+			transition:     model.BundleFile{BundleName: "live2d/chara/001_general", FileName: "anonTransitionData.asset"},
+			wantTransition: "data/anonTransitionData.asset",
+		},
+		{
+			name:           "原始布局",
+			layout:         model.OutputLayoutOriginal,
+			transition:     model.BundleFile{BundleName: "live2d/chara/001_general", FileName: "anonTransitionData.asset"},
+			wantTransition: "live2d/chara/001_general_rip/anonTransitionData.asset",
+		},
+		{
+			name:        "过渡文件不存在",
+			layout:      model.OutputLayoutRestructured,
+			transition:  model.BundleFile{BundleName: "live2d/chara/001_general", FileName: "missingTransitionData.asset"},
+			wantMissing: []string{"data/missingTransitionData.asset"},
+		},
+		{
+			name:   "构建数据中没有过渡文件",
+			layout: model.OutputLayoutRestructured,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buildData := &model.BuildData{
+				Model:      model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+				Textures:   []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+				Transition: tt.transition,
+			}
+
+			tempDir := t.TempDir()
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			builder := downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test")
+			builder.SetOutputLayout(tt.layout)
+			require.NoError(t, builder.Construct())
+			assert.Equal(t, tt.wantMissing, builder.MissingFiles())
+
+			data, err := downloader.LoadModelData(tempDir)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTransition, data.Transition)
+			if tt.wantTransition != "" {
+				assert.FileExists(t, filepath.Join(tempDir, filepath.FromSlash(tt.wantTransition)))
+			}
+		})
+	}
+}
+
+func TestAllowMissing(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AllowMissingFiles = map[string]bool{
+		"texture": true,
+		".png":    false,
+		".mtn":    true,
+		"physics": false,
+		"model":   true,
+	}
+
+	tests := []struct {
+		name     string
+		kind     downloader.FileKind
+		fileName string
+		want     bool
+	}{
+		{name: "扩展名优先于类型", kind: downloader.FileKindTexture, fileName: "texture_00.png", want: false},
+		{name: "按类型允许缺失", kind: downloader.FileKindTexture, fileName: "texture_00.tga", want: true},
+		{name: "按扩展名允许缺失", kind: downloader.FileKindMotion, fileName: "idle01.MTN", want: true},
+		{name: "按类型禁止缺失", kind: downloader.FileKindPhysics, fileName: "physics.json", want: false},
+		{name: "未配置的类型必须存在", kind: downloader.FileKindExpression, fileName: "smile.exp.json", want: false},
+		{name: "模型文件始终必须存在", kind: downloader.FileKindModel, fileName: "model.moc", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, downloader.AllowMissing(cfg, tt.kind, tt.fileName))
+		})
+	}
+
+	// 未配置时只有物理文件与过渡文件允许缺失
+	assert.True(t, downloader.AllowMissing(config.DefaultConfig(), downloader.FileKindPhysics, "physics.json"))
+	assert.True(t, downloader.AllowMissing(config.DefaultConfig(), downloader.FileKindTransition, "anonTransitionData.asset"))
+	assert.False(t, downloader.AllowMissing(config.DefaultConfig(), downloader.FileKindTexture, "texture_00.png"))
+}
+
+func TestMissingFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "missing") {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldAllow := cfg.BaseAssetsURL, cfg.AllowMissingFiles
+	cfg.BaseAssetsURL, cfg.AllowMissingFiles = server.URL, map[string]bool{".png": true}
+	defer func() { cfg.BaseAssetsURL, cfg.AllowMissingFiles = oldURL, oldAllow }()
+
+	buildData := &model.BuildData{
+		Model:   model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Physics: model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "physics.json"},
+		Textures: []model.BundleFile{
+			{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"},
+			{BundleName: "live2d/chara/001_test", FileName: "missing_01.png"},
+		},
+	}
+
+	tempDir := t.TempDir()
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	builder := downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test")
+	require.NoError(t, builder.Construct())
+	assert.Equal(t, []string{"data/textures/missing_01.png"}, builder.MissingFiles())
+
+	// 跳过的文件不写入模型数据
+	data, err := downloader.LoadModelData(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"data/textures/texture_00.png"}, data.Textures)
+}
+
+func TestPreviousManifestWarning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldLogPath, oldPerModel := cfg.BaseAssetsURL, cfg.LogPath, cfg.PerModelLog
+	defer func() { cfg.BaseAssetsURL, cfg.LogPath, cfg.PerModelLog = oldURL, oldLogPath, oldPerModel }()
+	cfg.BaseAssetsURL, cfg.LogPath, cfg.PerModelLog = server.URL, t.TempDir(), true
+
+	tests := []struct {
+		name     string
+		manifest string // 构建前写入的下载清单，为空时不写入
+		wantWarn bool
+	}{
+		{name: "首次下载没有下载清单", wantWarn: false},
+		{name: "下载清单已损坏", manifest: "not json", wantWarn: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			if tt.manifest != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(tempDir, model.ManifestFileName), []byte(tt.manifest), 0600))
+			}
+
+			modelName := fmt.Sprintf("001_manifest_%d", i)
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			buildData := &model.BuildData{
+				Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+				Physics:  model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "physics.json"},
+				Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+			}
+			require.NoError(t, downloader.NewLive2dBuilder(tempDir, buildData, d, modelName).Construct())
+
+			modelLog, err := os.ReadFile(filepath.Join(cfg.LogPath, time.Now().Format(time.DateOnly), modelName+".log"))
+			require.NoError(t, err)
+			if tt.wantWarn {
+				assert.Contains(t, string(modelLog), "读取下载清单失败")
+			} else {
+				assert.NotContains(t, string(modelLog), "读取下载清单失败")
+			}
+		})
+	}
+}
+
+func TestFileIntegrity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldVerify := cfg.BaseAssetsURL, cfg.VerifyFileIntegrity
+	cfg.BaseAssetsURL = server.URL
+	defer func() { cfg.BaseAssetsURL, cfg.VerifyFileIntegrity = oldURL, oldVerify }()
+
+	tests := []struct {
+		name    string
+		content string
+		remove  bool // 删除清单中记录的文件
+		verify  bool
+		refresh bool // 强制刷新
+		want    string
+	}{
+		{name: "截断的文件重新下载", content: "te", want: "test"},
+		{name: "空文件重新下载", content: "", want: "test"},
+		{name: "大小一致时不校验哈希", content: "tost", want: "tost"},
+		{name: "开启完整性校验时哈希不一致重新下载", content: "tost", verify: true, want: "test"},
+		{name: "清单中记录的文件缺失时重新下载", remove: true, want: "test"},
+		{name: "强制刷新时忽略下载清单", content: "tost", refresh: true, want: "test"},
+	}
+
+	buildData := &model.BuildData{
+		Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.VerifyFileIntegrity = tt.verify
+			tempDir := t.TempDir()
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			require.NoError(t, downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test").Construct())
+
+			manifest, err := downloader.LoadManifest(tempDir)
+			require.NoError(t, err)
+			entry := manifest.Files["data/model.moc"]
+			assert.Equal(t, int64(4), entry.Provenance.ExpectedSize)
+			assert.NotEmpty(t, entry.SHA256)
+			assert.NotEmpty(t, manifest.ToolVersion)
+			assert.False(t, manifest.DownloadedAt.IsZero())
+
+			modelPath := filepath.Join(tempDir, "data", "model.moc")
+			if tt.remove {
+				require.NoError(t, os.Remove(modelPath))
+			} else {
+				require.NoError(t, os.WriteFile(modelPath, []byte(tt.content), 0600))
+			}
+			d.ForceRefresh = tt.refresh
+			require.NoError(t, downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test").Construct())
+
+			content, err := os.ReadFile(modelPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(content))
+		})
+	}
+}
+
+func TestDownloadRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxRetries   int
+		failures     int  // 前几次请求失败
+		status       int  // 失败时返回的状态码，为 0 时直接断开连接
+		truncate     bool // 失败时声明的 Content-Length 大于实际发送的内容
+		chunked      bool // 成功时不返回 Content-Length
+		wantRequests int  // 期望的请求次数
+		wantErr      error
+	}{
+		{name: "5xx 后重试成功", maxRetries: 3, failures: 2, status: http.StatusServiceUnavailable, wantRequests: 3},
+		{name: "连接中断后重试成功", maxRetries: 3, failures: 1, wantRequests: 2},
+		{name: "内容不完整时重试成功", maxRetries: 3, failures: 1, truncate: true, wantRequests: 2},
+		{name: "没有 Content-Length 时跳过校验", maxRetries: 3, chunked: true, wantRequests: 1},
+		{
+			name:         "重试次数用尽",
+			maxRetries:   2,
+			failures:     10,
+			status:       http.StatusBadGateway,
+			wantRequests: 3,
+			wantErr:      errs.ErrHTTPStatus,
+		},
+		{name: "4xx 也会重试", maxRetries: 3, failures: 1, status: http.StatusTooManyRequests, wantRequests: 2},
+		{name: "404 不重试", maxRetries: 3, failures: 10, status: http.StatusNotFound, wantRequests: 1, wantErr: errs.ErrNotFound},
+		{
+			name:         "关闭重试",
+			failures:     10,
+			status:       http.StatusServiceUnavailable,
+			wantRequests: 1,
+			wantErr:      errs.ErrHTTPStatus,
+		},
+	}
+
+	cfg := config.Get()
+	oldURL, oldRetries := cfg.BaseAssetsURL, cfg.MaxRetries
+	defer func() { cfg.BaseAssetsURL, cfg.MaxRetries = oldURL, oldRetries }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				mu.Lock()
+				requests++
+				failed := requests <= tt.failures
+				mu.Unlock()
+				switch {
+				case !failed && tt.chunked:
+					_, _ = w.Write([]byte("te"))
+					http.NewResponseController(w).Flush() //nolint:errcheck // 刷新后响应使用分块编码
+					_, _ = w.Write([]byte("st"))
+				case !failed:
+					_, _ = w.Write([]byte("test"))
+				case tt.truncate:
+					w.Header().Set("Content-Length", "100")
+					_, _ = w.Write([]byte("test"))
+				case tt.status == 0:
+					conn, _, err := http.NewResponseController(w).Hijack()
+					if err == nil {
+						_ = conn.Close()
+					}
+				default:
+					w.WriteHeader(tt.status)
+				}
+			}))
+			defer server.Close()
+			cfg.BaseAssetsURL, cfg.MaxRetries = server.URL, tt.maxRetries
+
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			filePath := filepath.Join(t.TempDir(), "model.moc")
+			bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}
+			err := d.DownloadBundleFile(context.Background(), bundleFile, filePath, false)
+
+			mu.Lock()
+			assert.Equal(t, tt.wantRequests, requests)
+			mu.Unlock()
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				if tt.wantRequests > 1 {
+					assert.Contains(t, err.Error(), fmt.Sprintf("已尝试 %d 次", tt.wantRequests))
+				}
+				return
+			}
+			require.NoError(t, err)
+			content, err := os.ReadFile(filePath)
+			require.NoError(t, err)
+			assert.Equal(t, "test", string(content))
+		})
+	}
+
+	t.Run("等待重试时取消", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		oldDelay := cfg.RetryBaseDelay
+		defer func() { cfg.RetryBaseDelay = oldDelay }()
+		cfg.BaseAssetsURL, cfg.MaxRetries, cfg.RetryBaseDelay = server.URL, 3, time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		d := downloader.NewDownloader(api.NewClient(), nil, nil)
+		bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}
+		err := d.DownloadBundleFile(ctx, bundleFile, filepath.Join(t.TempDir(), "model.moc"), false)
+		require.ErrorIs(t, err, errs.ErrDownloadCancelled)
+	})
+}
+
+func TestDownloadResume(t *testing.T) {
+	const content = "hello world"
+	tests := []struct {
+		name         string
+		part         string // 下载前已存在的部分文件内容，为空时不创建
+		ignoreRange  bool   // 服务器忽略 Range 返回完整内容
+		badRange     bool   // 第一次请求返回起始位置不一致的 206
+		wantRange    string // 第一次请求的 Range 请求头
+		wantRequests int
+	}{
+		{name: "没有部分文件时从头下载", wantRequests: 1},
+		{name: "从部分文件续传", part: "hello ", wantRange: "bytes=6-", wantRequests: 1},
+		{name: "服务器不支持 Range 时覆盖写入", part: "hello ", ignoreRange: true, wantRange: "bytes=6-", wantRequests: 1},
+		{name: "部分文件已完整", part: content, wantRange: "bytes=11-", wantRequests: 1},
+		{name: "部分文件比服务器上的文件大", part: content + "!!", wantRange: "bytes=13-", wantRequests: 2},
+		{name: "Content-Range 不一致时重新下载", part: "hello ", badRange: true, wantRange: "bytes=6-", wantRequests: 2},
+	}
+
+	cfg := config.Get()
+	oldURL, oldRetries := cfg.BaseAssetsURL, cfg.MaxRetries
+	defer func() { cfg.BaseAssetsURL, cfg.MaxRetries = oldURL, oldRetries }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var ranges []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				ranges = append(ranges, r.Header.Get("Range"))
+				first := len(ranges) == 1
+				mu.Unlock()
+				switch {
+				case tt.ignoreRange:
+					_, _ = w.Write([]byte(content))
+				case tt.badRange && first:
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+					w.WriteHeader(http.StatusPartialContent)
+					_, _ = w.Write([]byte(content))
+				default:
+					http.ServeContent(w, r, "model.moc", time.Time{}, strings.NewReader(content))
+				}
+			}))
+			defer server.Close()
+			cfg.BaseAssetsURL, cfg.MaxRetries = server.URL, 3
+
+			filePath := filepath.Join(t.TempDir(), "model.moc")
+			if tt.part != "" {
+				require.NoError(t, os.WriteFile(filePath+".part", []byte(tt.part), 0600))
+			}
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}
+			require.NoError(t, d.DownloadBundleFile(context.Background(), bundleFile, filePath, false))
+
+			mu.Lock()
+			assert.Len(t, ranges, tt.wantRequests)
+			assert.Equal(t, tt.wantRange, ranges[0])
+			mu.Unlock()
+			data, err := os.ReadFile(filePath)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(data))
+			assert.NoFileExists(t, filePath+".part")
+		})
+	}
+
+	t.Run("中断后保留部分文件并续传", func(t *testing.T) {
+		var interrupted atomic.Bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if interrupted.CompareAndSwap(false, true) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				_, _ = w.Write([]byte("hello "))
+				http.NewResponseController(w).Flush() //nolint:errcheck // 先发送部分内容
+				<-r.Context().Done()
+				return
+			}
+			http.ServeContent(w, r, "model.moc", time.Time{}, strings.NewReader(content))
+		}))
+		defer server.Close()
+		cfg.BaseAssetsURL, cfg.MaxRetries = server.URL, 0
+
+		filePath := filepath.Join(t.TempDir(), "model.moc")
+		d := downloader.NewDownloader(api.NewClient(), nil, nil)
+		bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		require.Error(t, d.DownloadBundleFile(ctx, bundleFile, filePath, false))
+		assert.NoFileExists(t, filePath)
+		part, err := os.ReadFile(filePath + ".part")
+		require.NoError(t, err)
+		assert.Equal(t, "hello ", string(part))
+
+		require.NoError(t, d.DownloadBundleFile(context.Background(), bundleFile, filePath, false))
+		data, err := os.ReadFile(filePath)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+		assert.NoFileExists(t, filePath+".part")
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	const (
+		files    = 4
+		fileSize = 3 * 1024
+		kbps     = 8
+		rate     = kbps * 1024
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", fileSize)))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldKBps := cfg.BaseAssetsURL, cfg.MaxBandwidthKBps
+	defer func() { cfg.BaseAssetsURL, cfg.MaxBandwidthKBps = oldURL, oldKBps }()
+	cfg.BaseAssetsURL, cfg.MaxBandwidthKBps = server.URL, kbps
+
+	// 单个文件小于一秒的限额，只有所有下载共享同一个限制器时总耗时才会超过 (总大小 - 一秒的限额) / 速率
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	dir := t.TempDir()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: fmt.Sprintf("texture_%02d.png", i)}
+			assert.NoError(t, d.DownloadBundleFile(context.Background(), bundleFile, filepath.Join(dir, bundleFile.FileName), false))
+		}()
+	}
+	wg.Wait()
+
+	minElapsed := time.Duration(files*fileSize-rate) * time.Second / rate
+	assert.GreaterOrEqual(t, time.Since(start), minElapsed*9/10)
+}
+
+func TestRateLimitAccuracy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("限速测试需要数秒")
+	}
+
+	const (
+		size  = 1024 * 1024
+		kbps  = 256
+		rate  = kbps * 1024
+		burst = 64 * 1024 // 限速器一开始就放行的字节数，不需要等待
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(make([]byte, size))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldKBps := cfg.BaseAssetsURL, cfg.MaxBandwidthKBps
+	defer func() { cfg.BaseAssetsURL, cfg.MaxBandwidthKBps = oldURL, oldKBps }()
+	cfg.BaseAssetsURL, cfg.MaxBandwidthKBps = server.URL, kbps
+
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}
+	start := time.Now()
+	require.NoError(t, d.DownloadBundleFile(context.Background(), bundleFile, filepath.Join(t.TempDir(), "model.moc"), false))
+	elapsed := time.Since(start)
+
+	// 1 MB 以 256 KB/s 写入，扣除一开始放行的 64 KB 后约需 3.75 秒
+	// 限速只会让下载变慢，下限只留 10% 的计时误差；负载较高的机器上调度延迟会拉长耗时，上限放宽到 50%
+	want := time.Duration(size-burst) * time.Second / rate
+	assert.GreaterOrEqual(t, elapsed, want*9/10)
+	assert.LessOrEqual(t, elapsed, want*3/2)
+}
+
+func TestDownloadNegativeCache(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldTTL := cfg.BaseAssetsURL, cfg.NegativeCacheDuration
+	defer func() { cfg.BaseAssetsURL, cfg.NegativeCacheDuration = oldURL, oldTTL }()
+	cfg.BaseAssetsURL, cfg.NegativeCacheDuration = server.URL, time.Hour
+
+	client := api.NewClient()
+	client.SetCharaCachePath(t.TempDir())
+	d := downloader.NewDownloader(client, nil, nil)
+	bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}
+	filePath := filepath.Join(t.TempDir(), "model.moc")
+
+	require.ErrorIs(t, d.DownloadBundleFile(context.Background(), bundleFile, filePath, false), errs.ErrNotFound)
+	require.ErrorIs(t, d.DownloadBundleFile(context.Background(), bundleFile, filePath, false), errs.ErrNotFound)
+	require.NoError(t, d.DownloadBundleFile(context.Background(), bundleFile, filePath, true))
+	assert.Equal(t, int32(1), requests.Load())
+	assert.NoFileExists(t, filePath)
+}
+
+func TestVerificationRecovery(t *testing.T) {
+	tests := []struct {
+		name         string
+		corrupt      func(r *http.Request) bool // 返回不完整内容的请求
+		wantRecovery model.DownloadRecovery
+		wantURL      string
+		wantErr      bool
+	}{
+		{
+			name:         "绕过缓存后成功",
+			corrupt:      func(r *http.Request) bool { return r.URL.Query().Get("t") == "" },
+			wantRecovery: model.DownloadRecoveryCacheBust,
+			wantURL:      "/assets/jp/live2d/chara/001_test_rip/texture_00.png?t=",
+		},
+		{
+			name:         "切换服务器后成功",
+			corrupt:      func(r *http.Request) bool { return strings.HasPrefix(r.URL.Path, "/assets/jp/") },
+			wantRecovery: model.DownloadRecoveryFallbackServer,
+			wantURL:      "/assets/en/live2d/chara/001_test_rip/texture_00.png",
+		},
+		{
+			name:    "所有方式均失败",
+			corrupt: func(*http.Request) bool { return true },
+			wantErr: true,
+		},
+	}
+
+	cfg := config.Get()
+	oldURL, oldRetries, oldFallback := cfg.BaseAssetsURL, cfg.MaxRetries, cfg.ServerFallback
+	defer func() { cfg.BaseAssetsURL, cfg.MaxRetries, cfg.ServerFallback = oldURL, oldRetries, oldFallback }()
+
+	buildData := &model.BuildData{
+		Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var cacheBusts int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("t") != "" && r.Header.Get("Cache-Control") == "no-cache" {
+					mu.Lock()
+					cacheBusts++
+					mu.Unlock()
+				}
+				if strings.Contains(r.URL.Path, "texture") && tt.corrupt(r) {
+					w.Header().Set("Content-Length", "100")
+					_, _ = w.Write([]byte("test"))
+					return
+				}
+				_, _ = w.Write([]byte("test"))
+			}))
+			defer server.Close()
+			cfg.BaseAssetsURL, cfg.MaxRetries = server.URL+"/assets/jp", 3
+			cfg.ServerFallback = []string{"jp", "en"}
+
+			tempDir := t.TempDir()
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			err := downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test").Construct()
+
+			mu.Lock()
+			assert.Equal(t, 1, cacheBusts, "cache-busting request should be sent once")
+			mu.Unlock()
+			if tt.wantErr {
+				require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+				return
+			}
+			require.NoError(t, err)
+
+			manifest, err := downloader.LoadManifest(tempDir)
+			require.NoError(t, err)
+			var texture model.FileProvenance
+			for name, entry := range manifest.Files {
+				if strings.HasSuffix(name, "texture_00.png") {
+					texture = entry.Provenance
+				}
+			}
+			assert.Equal(t, tt.wantRecovery, texture.Recovery)
+			assert.Contains(t, texture.URL, tt.wantURL)
+			assert.Empty(t, manifest.Files["data/model.moc"].Provenance.Recovery)
+		})
+	}
+}
+
+func TestNullProgressSink(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL := cfg.BaseAssetsURL
+	cfg.BaseAssetsURL = server.URL
+	defer func() { cfg.BaseAssetsURL = oldURL }()
+
+	var out strings.Builder
+	sink := downloader.NewNullProgressSink(context.Background(), &out)
+	d := downloader.NewDownloader(api.NewClient(), sink, nil)
+	buildData := &model.BuildData{
+		Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+	}
+	sink.SetTotalModels(1)
+	sink.SetItemTitle("001_test", "制服")
+	require.NoError(t, downloader.NewLive2dBuilder(t.TempDir(), buildData, d, "001_test").Construct())
+	sink.UpdateTotalProgress()
+	sink.SendError("002_test", errs.ErrModelNotFound)
+	sink.SetError("")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.NotEmpty(t, lines)
+	assert.Equal(t, "event=batch total=1", lines[0])
+	assert.Contains(t, lines, "event=start model=001_test files=3")
+	assert.Contains(t, lines, "event=progress model=001_test completed=3 total=3")
+	assert.Contains(t, out.String(), "event=done model=001_test textures=1 ")
+	assert.Contains(t, out.String(), ` title="制服"`)
+	assert.Equal(t, "event=batch completed=1 total=1", lines[len(lines)-2])
+	assert.Equal(t, fmt.Sprintf("event=error model=002_test error=%q", errs.ErrModelNotFound.Error()), lines[len(lines)-1])
+}
+
+// recordingSink 记录进度接收者收到的下载项登记与进度.
+type recordingSink struct {
+	*downloader.NullProgressSink
+	mu       sync.Mutex
+	totals   []int // 每次登记的文件总数
+	progress []int // 每次报告的已完成文件数
+}
+
+func (s *recordingSink) AddDownloadItem(_ string, totalFiles int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals = append(s.totals, totalFiles)
+}
+
+func (s *recordingSink) UpdateProgress(_ string, current int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress = append(s.progress, current)
+}
+
+// remaining 返回最后登记的文件总数与最后报告的已完成文件数之差，尚未报告进度时返回 -1.
+func (s *recordingSink) remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.totals) == 0 || len(s.progress) == 0 {
+		return -1
+	}
+	return s.totals[len(s.totals)-1] - s.progress[len(s.progress)-1]
+}
+
+func TestProgressRegistration(t *testing.T) {
+	// texture_01 的请求会挂起，直到 release 被关闭
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "texture_01.png") {
+			<-release
+		}
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldTimeout := cfg.BaseAssetsURL, cfg.FileTimeout
+	defer func() { cfg.BaseAssetsURL, cfg.FileTimeout = oldURL, oldTimeout }()
+	cfg.BaseAssetsURL, cfg.FileTimeout = server.URL, 0
+
+	sink := &recordingSink{NullProgressSink: downloader.NewNullProgressSink(context.Background(), io.Discard)}
+	d := downloader.NewDownloader(api.NewClient(), sink, nil)
+	buildData := &model.BuildData{
+		Model: model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Textures: []model.BundleFile{
+			{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"},
+			{BundleName: "live2d/chara/001_test", FileName: "texture_01.png"},
+		},
+	}
+
+	// 批量下载开始时先占位登记，再由构建器登记实际的文件总数
+	d.EnsureItem("001_test", 0)
+	result := make(chan error, 1)
+	builder := downloader.NewLive2dBuilder(t.TempDir(), buildData, d, "001_test")
+	go func() { result <- builder.ConstructWithContext(context.Background()) }()
+	require.Eventually(t, func() bool { return sink.remaining() == 1 }, time.Second, time.Millisecond)
+
+	// 构建进行中再次登记同一模型不会重置进度
+	d.EnsureItem("001_test", 0)
+	close(release)
+	require.NoError(t, <-result)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	require.Len(t, sink.totals, 2)
+	assert.Equal(t, 0, sink.totals[0], "the placeholder registration has no total")
+	total := sink.totals[1]
+	assert.Positive(t, total)
+	require.NotEmpty(t, sink.progress)
+	for i := 1; i < len(sink.progress); i++ {
+		assert.Greater(t, sink.progress[i], sink.progress[i-1], "progress should be monotonic")
+	}
+	assert.Equal(t, total, sink.progress[len(sink.progress)-1])
+}
+
+func TestDownloadThroughProxy(t *testing.T) {
+	// 代理收到的请求使用绝对 URL，目标主机无法解析也能通过代理下载
+	var mu sync.Mutex
+	var hosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.URL.Host)
+		mu.Unlock()
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer proxy.Close()
+
+	cfg := config.Get()
+	oldURL, oldProxy := cfg.BaseAssetsURL, cfg.ProxyURL
+	cfg.BaseAssetsURL, cfg.ProxyURL = "http://bestdori.invalid/assets/jp", proxy.URL
+	defer func() { cfg.BaseAssetsURL, cfg.ProxyURL = oldURL, oldProxy }()
+
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	filePath := filepath.Join(t.TempDir(), "model.moc")
+	bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}
+	require.NoError(t, d.DownloadBundleFile(context.Background(), bundleFile, filePath, false))
+
+	mu.Lock()
+	assert.Equal(t, []string{"bestdori.invalid"}, hosts)
+	mu.Unlock()
+}
+
+func TestDownloadDiagnostics(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		wantBody    bool
+	}{
+		{name: "文本错误页记录响应体", contentType: "text/html; charset=utf-8", wantBody: true},
+		{name: "二进制响应不记录响应体", contentType: "application/octet-stream"},
+	}
+
+	cfg := config.Get()
+	oldURL, oldRetries, oldLogPath := cfg.BaseAssetsURL, cfg.MaxRetries, cfg.LogPath
+	defer func() { cfg.BaseAssetsURL, cfg.MaxRetries, cfg.LogPath = oldURL, oldRetries, oldLogPath }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("Server", "mirror-cache")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte("<html>upstream busy</html>"))
+			}))
+			defer server.Close()
+			cfg.BaseAssetsURL, cfg.MaxRetries, cfg.LogPath = server.URL, 1, t.TempDir()
+
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			filePath := filepath.Join(t.TempDir(), "model.moc")
+			bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}
+			require.ErrorIs(t, d.DownloadBundleFile(context.Background(), bundleFile, filePath, false), errs.ErrHTTPStatus)
+
+			data, err := os.ReadFile(filepath.Join(cfg.LogPath, downloader.DiagnosticsFileName))
+			require.NoError(t, err)
+			report := string(data)
+			assert.Contains(t, report, filePath)
+			assert.Contains(t, report, "URL: "+server.URL+"/live2d/chara/001_test_rip/model.moc")
+			assert.Contains(t, report, "状态: 503 Service Unavailable")
+			assert.Contains(t, report, "Content-Type: "+tt.contentType)
+			assert.Contains(t, report, "Server: mirror-cache")
+			assert.Contains(t, report, "第 2 次请求")
+			assert.Equal(t, tt.wantBody, strings.Contains(report, "upstream busy"))
+		})
+	}
+
+	t.Run("成功或被取消时不记录", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("test"))
+		}))
+		defer server.Close()
+		cfg.BaseAssetsURL, cfg.LogPath = server.URL, t.TempDir()
+
+		d := downloader.NewDownloader(api.NewClient(), nil, nil)
+		bundleFile := model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}
+		require.NoError(t, d.DownloadBundleFile(context.Background(), bundleFile, filepath.Join(t.TempDir(), "a"), false))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.Error(t, d.DownloadBundleFile(ctx, bundleFile, filepath.Join(t.TempDir(), "b"), false))
+
+		assert.NoFileExists(t, filepath.Join(cfg.LogPath, downloader.DiagnosticsFileName))
+	})
+}
+
+func TestBuildDataServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/assets/cn/") {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL := cfg.BaseAssetsURL
+	cfg.BaseAssetsURL = server.URL + "/assets/jp"
+	defer func() { cfg.BaseAssetsURL = oldURL }()
+
+	// 构建数据来自回退服务器时，后续文件也从同一服务器下载
+	buildData := &model.BuildData{
+		Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+		Server:   "cn",
+	}
+	tempDir := t.TempDir()
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	require.NoError(t, downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test").Construct())
+
+	manifest, err := downloader.LoadManifest(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, "cn", manifest.Server)
+	assert.Contains(t, manifest.Files["data/model.moc"].Provenance.URL, "/assets/cn/")
+}
+
+func TestEstimateSpace(t *testing.T) {
+	sizes := map[string]string{"model.moc": "1000", "texture_00.png": "3000"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, ok := sizes[filepath.Base(r.URL.Path)]
+		if r.Method != http.MethodHead || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", size)
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL := cfg.BaseAssetsURL
+	cfg.BaseAssetsURL = server.URL
+	defer func() { cfg.BaseAssetsURL = oldURL }()
+
+	buildData := &model.BuildData{
+		Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+		Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+		Motions:  []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "idle01.mtn"}},
+	}
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	estimate := d.EstimateSpace(context.Background(), []*model.BuildData{buildData})
+
+	// 无法获取大小的动作文件按已知文件的平均大小估算
+	assert.Equal(t, 3, estimate.Files)
+	assert.Equal(t, 2, estimate.SizedFiles)
+	assert.Equal(t, uint64(6000), estimate.Bytes)
+	assert.True(t, estimate.Estimated())
+	assert.Contains(t, estimate.String(), "1 个文件的大小为估计值")
+
+	assert.True(t, downloader.SpaceEstimate{Bytes: 1 << 30}.Sufficient(0), "unknown free space should be sufficient")
+	full := downloader.SpaceEstimate{Bytes: 1 << 30, Free: 1 << 30, FreeKnown: true}
+	assert.True(t, full.Sufficient(0))
+	assert.False(t, full.Sufficient(1))
+	assert.Equal(t, "预计占用 1.0 GiB，可用 1.0 GiB", full.String())
+}
+
+func TestSelectionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selection.json")
+	selection := downloader.NewSelectionFile("cn", "香澄", []string{"001_casual-2023", "001_live_event_307_ssr"})
+	require.NoError(t, downloader.WriteSelectionFile(path, selection))
+
+	loaded, err := downloader.ReadSelectionFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, downloader.SelectionFormatVersion, loaded.FormatVersion)
+	assert.Equal(t, "cn", loaded.Server)
+	assert.Equal(t, []downloader.SelectionEntry{
+		{Costume: "001_casual-2023", CharaID: 1, Chara: "香澄"},
+		{Costume: "001_live_event_307_ssr", CharaID: 1, Chara: "香澄"},
+	}, loaded.Costumes)
+
+	tests := []struct {
+		name        string
+		content     string
+		wantErr     error
+		wantFound   []string
+		wantUnknown []string
+	}{
+		{
+			name:    "更新的格式版本",
+			content: `{"formatVersion": 2, "costumes": [{"costume": "001_casual-2023"}]}`,
+			wantErr: errs.ErrUnsupportedSelectionVersion,
+		},
+		{
+			name:    "无效的服装名称",
+			content: `{"formatVersion": 1, "costumes": [{"costume": "casual"}]}`,
+			wantErr: errs.ErrInvalidLive2dName,
+		},
+		{
+			name: "报告已不存在的服装",
+			content: `{"formatVersion": 1, "costumes": [
+				{"costume": "001_casual-2023"}, {"costume": "001_renamed"}, {"costume": "live2d/chara/001_Casual-2023_rip"}
+			]}`,
+			wantFound:   []string{"001_casual-2023"},
+			wantUnknown: []string{"001_renamed"},
+		},
+	}
+
+	index := map[string]bool{"001_casual-2023": true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "selection.json")
+			require.NoError(t, os.WriteFile(file, []byte(tt.content), 0600))
+
+			got, readErr := downloader.ReadSelectionFile(file)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, readErr, tt.wantErr)
+				return
+			}
+			require.NoError(t, readErr)
+
+			found, unknown, resolveErr := got.Resolve(func(costume string) (bool, error) {
+				return index[costume], nil
+			})
+			require.NoError(t, resolveErr)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantUnknown, unknown)
+		})
+	}
+}
+
+func TestTaskFile(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.BatchFailFast = true
+	cfg.OutputLayout = model.OutputLayoutOriginal
+	cfg.GenerateModelIndex = true
+
+	path := filepath.Join(t.TempDir(), "task.json")
+	task := downloader.NewTaskFile(cfg, []downloader.ModelListEntry{
+		{Name: "001_casual-2023"},
+		{Name: "036_live_event_307_ssr", OutputPath: "out/ran"},
+	})
+	require.NoError(t, downloader.WriteTaskFile(path, task))
+
+	loaded, err := downloader.ReadTaskFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, downloader.TaskFormatVersion, loaded.FormatVersion)
+	assert.Equal(t, "jp", loaded.Server)
+	assert.Equal(t, task.Models, loaded.Models)
+	assert.Equal(t, task.Options, loaded.Options)
+
+	target := config.DefaultConfig()
+	loaded.Server = "cn"
+	require.NoError(t, loaded.Apply(target))
+	assert.Equal(t, "https://bestdori.com/assets/cn", target.BaseAssetsURL)
+	assert.Equal(t, "https://bestdori.com/api/explorer/cn/assets/_info.json", target.AssetsIndexURL)
+	assert.True(t, target.BatchFailFast)
+	assert.Equal(t, model.OutputLayoutOriginal, target.OutputLayout)
+	assert.True(t, target.GenerateModelIndex)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{
+			name:    "更新的格式版本",
+			content: `{"formatVersion": 99, "server": "jp", "models": [{"name": "001_casual-2023"}]}`,
+			wantErr: errs.ErrUnsupportedTaskVersion,
+		},
+		{
+			name:    "无效的模型名称",
+			content: `{"formatVersion": 1, "server": "jp", "models": [{"name": "casual"}]}`,
+			wantErr: errs.ErrInvalidLive2dName,
+		},
+		{
+			name:    "规范化模型名称",
+			content: `{"formatVersion": 1, "server": "jp", "models": [{"name": " live2d/chara/001_Casual-2023_rip/ "}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "task.json")
+			require.NoError(t, os.WriteFile(file, []byte(tt.content), 0600))
+
+			got, readErr := downloader.ReadTaskFile(file)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, readErr, tt.wantErr)
+				return
+			}
+			require.NoError(t, readErr)
+			assert.Equal(t, "001_casual-2023", got.Models[0].Name)
+		})
+	}
+}
+
+func TestLoadBlocklist(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // 为空时不创建屏蔽列表文件
+		extra   []string
+		want    model.Live2dPatterns
+		wantErr bool
+	}{
+		{name: "文件不存在时只使用配置", extra: []string{"037_broken"}, want: model.Live2dPatterns{"037_broken"}},
+		{
+			name:    "合并文件与配置",
+			content: `{"patterns": ["*_Placeholder", "live2d/chara/036_test_rip"]}`,
+			extra:   []string{"037_broken"},
+			want:    model.Live2dPatterns{"037_broken", "*_placeholder", "036_test"},
+		},
+		{name: "格式错误", content: `["037_broken"]`, wantErr: true},
+		{name: "模式语法错误", content: `{"patterns": ["037_[broken"]}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "blocklist.json")
+			if tt.content != "" {
+				require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+			}
+
+			patterns, err := downloader.LoadBlocklist(path, tt.extra)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, patterns)
+		})
+	}
+}
+
+func TestModelStatus(t *testing.T) {
+	// 001_slow 的文件会一直挂起，直到请求被取消
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "001_slow") {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldModels, oldTimeout := cfg.BaseAssetsURL, cfg.MaxConcurrentModels, cfg.FileTimeout
+	defer func() { cfg.BaseAssetsURL, cfg.MaxConcurrentModels, cfg.FileTimeout = oldURL, oldModels, oldTimeout }()
+	cfg.BaseAssetsURL, cfg.MaxConcurrentModels, cfg.FileTimeout = server.URL, 1, 0
+
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	require.NoError(t, d.Wait(context.Background()), "Wait should return immediately when idle")
+
+	// 同时查询状态，配合 -race 检查并发安全
+	stop := make(chan struct{})
+	var pollers sync.WaitGroup
+	for range 4 {
+		pollers.Add(1)
+		go func() {
+			defer pollers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					_ = d.Active()
+				}
+			}
+		}()
+	}
+
+	names := []string{"001_slow", "002_queued", "003_fast"}
+	results := make(map[string]chan error, len(names))
+	for i, name := range names {
+		buildData := &model.BuildData{
+			Model:    model.BundleFile{BundleName: "live2d/chara/" + name, FileName: "model.moc"},
+			Textures: []model.BundleFile{{BundleName: "live2d/chara/" + name, FileName: "texture_00.png"}},
+		}
+		builder := downloader.NewLive2dBuilder(t.TempDir(), buildData, d, name)
+		results[name] = make(chan error, 1)
+		go func() { results[name] <- builder.ConstructWithContext(context.Background()) }()
+		// 逐个启动，使排队顺序确定
+		require.Eventually(t, func() bool { return len(d.Active()) == i+1 }, time.Second, time.Millisecond)
+	}
+
+	require.Eventually(t, func() bool {
+		return d.Active()[0].State == downloader.ModelDownloading
+	}, time.Second, time.Millisecond)
+	statuses := d.Active()
+	assert.Equal(t, downloader.ModelQueued, statuses[1].State)
+	assert.Equal(t, downloader.ModelQueued, statuses[2].State)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.Error(t, d.Wait(ctx), "Wait should stop when its context is done")
+
+	assert.False(t, d.CancelModel("999_unknown"))
+	assert.True(t, d.CancelModel("002_queued"), "queued models can be cancelled")
+	assert.True(t, d.CancelModel("001_slow"), "downloading models can be cancelled")
+
+	require.NoError(t, d.Wait(context.Background()))
+	close(stop)
+	pollers.Wait()
+
+	require.ErrorIs(t, <-results["001_slow"], errs.ErrModelCancelled)
+	require.ErrorIs(t, <-results["002_queued"], errs.ErrModelCancelled)
+	require.NoError(t, <-results["003_fast"])
+	assert.False(t, d.CancelModel("003_fast"), "finished models cannot be cancelled")
+
+	statuses = d.Active()
+	require.Len(t, statuses, 3)
+	for i, name := range names {
+		assert.Equal(t, name, statuses[i].Name)
+	}
+	assert.Equal(t, downloader.ModelFailed, statuses[0].State)
+	assert.Positive(t, statuses[0].Total)
+	assert.Equal(t, downloader.ModelFailed, statuses[1].State)
+	require.ErrorIs(t, statuses[1].Err, errs.ErrModelCancelled)
+	assert.Equal(t, downloader.ModelDone, statuses[2].State)
+	assert.Positive(t, statuses[2].Total)
+	assert.Equal(t, statuses[2].Total, statuses[2].Completed)
+	assert.NoError(t, statuses[2].Err)
+}
+
+func TestConstructDeduplication(t *testing.T) {
+	// 模型文件的请求会挂起，直到 release 被关闭
+	release := make(chan struct{})
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "model.moc") {
+			requests.Add(1)
+			<-release
+		}
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL, oldTimeout := cfg.BaseAssetsURL, cfg.FileTimeout
+	defer func() { cfg.BaseAssetsURL, cfg.FileTimeout = oldURL, oldTimeout }()
+	cfg.BaseAssetsURL, cfg.FileTimeout = server.URL, 0
+
+	d := downloader.NewDownloader(api.NewClient(), nil, nil)
+	d.ForceRefresh = true
+	dir := t.TempDir()
+	buildData := &model.BuildData{
+		Model:    model.BundleFile{BundleName: "live2d/chara/001_dedup", FileName: "model.moc"},
+		Textures: []model.BundleFile{{BundleName: "live2d/chara/001_dedup", FileName: "texture_00.png"}},
+	}
+	construct := func() chan error {
+		result := make(chan error, 1)
+		builder := downloader.NewLive2dBuilder(dir, buildData, d, "001_dedup")
+		go func() { result <- builder.ConstructWithContext(context.Background()) }()
+		return result
+	}
+
+	first := construct()
+	require.Eventually(t, func() bool { return requests.Load() == 1 }, time.Second, time.Millisecond)
+	second := construct()
+	time.Sleep(50 * time.Millisecond) // 等待第二次构建加入正在进行的构建
+	close(release)
+
+	require.NoError(t, <-first)
+	require.NoError(t, <-second)
+	assert.Equal(t, int32(1), requests.Load(), "concurrent builds of the same model should run once")
+	assert.Len(t, d.Active(), 1)
+
+	// 构建结束后再次构建不复用之前的结果
+	require.NoError(t, <-construct())
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestModelHash(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte("test"))
+	}))
+	defer server.Close()
+
+	cfg := config.Get()
+	oldURL := cfg.BaseAssetsURL
+	cfg.BaseAssetsURL = server.URL
+	defer func() { cfg.BaseAssetsURL = oldURL }()
+
+	newBuildData := func() *model.BuildData {
+		return &model.BuildData{
+			Model:    model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"},
+			Textures: []model.BundleFile{{BundleName: "live2d/chara/001_test", FileName: "texture_00.png"}},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		prepare      func(t *testing.T, dir string, data *model.BuildData)
+		wantRequests int32
+	}{
+		{
+			name:         "模型未变化时整体跳过",
+			prepare:      func(*testing.T, string, *model.BuildData) {},
+			wantRequests: 0,
+		},
+		{
+			name: "远端模型更新时整体重新下载",
+			prepare: func(_ *testing.T, _ string, data *model.BuildData) {
+				data.Textures = append(data.Textures, model.BundleFile{
+					BundleName: "live2d/chara/001_test", FileName: "texture_01.png",
+				})
+			},
+			wantRequests: 4,
+		},
+		{
+			name: "本地文件被修改时只重新下载不一致的文件",
+			prepare: func(t *testing.T, dir string, _ *model.BuildData) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "data", "model.moc"), []byte("te"), 0600))
+			},
+			wantRequests: 1,
+		},
+		{
+			name: "哈希记录缺失时按普通下载处理",
+			prepare: func(t *testing.T, dir string, _ *model.BuildData) {
+				require.NoError(t, os.Remove(filepath.Join(dir, model.ModelHashFileName)))
+			},
+			wantRequests: 0,
+		},
+		{
+			name: "哈希记录损坏时按普通下载处理",
+			prepare: func(t *testing.T, dir string, _ *model.BuildData) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, model.ModelHashFileName), []byte("{"), 0600))
+			},
+			wantRequests: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			d := downloader.NewDownloader(api.NewClient(), nil, nil)
+			buildData := newBuildData()
+			require.NoError(t, downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test").Construct())
+			first, err := downloader.LoadModelHash(tempDir)
+			require.NoError(t, err)
+
+			tt.prepare(t, tempDir, buildData)
+			requests.Store(0)
+			require.NoError(t, downloader.NewLive2dBuilder(tempDir, buildData, d, "001_test").Construct())
+			assert.Equal(t, tt.wantRequests, requests.Load())
+
+			record, err := downloader.LoadModelHash(tempDir)
+			require.NoError(t, err)
+			wantBuildData, err := downloader.BuildDataHash(buildData)
+			require.NoError(t, err)
+			assert.Equal(t, wantBuildData, record.BuildData)
+			// 只有新增了文件的情况下文件内容才会变化
+			assert.Equal(t, tt.wantRequests != 4, first.Content == record.Content)
+		})
+	}
+}
+
+func TestLoadModelHash(t *testing.T) {
+	valid := strings.Repeat("a", 64)
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{name: "有效的记录", content: `{"version":1,"buildData":"` + valid + `","content":"` + valid + `"}`},
+		{name: "文件不存在", wantErr: fs.ErrNotExist},
+		{name: "JSON 格式错误", content: `{"version":`, wantErr: errs.ErrInvalidModelHash},
+		{name: "格式版本不受支持", content: `{"version":2,"buildData":"` + valid + `","content":"` + valid + `"}`,
+			wantErr: errs.ErrInvalidModelHash},
+		{name: "哈希值无效", content: `{"version":1,"buildData":"abc","content":"` + valid + `"}`,
+			wantErr: errs.ErrInvalidModelHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.content != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, model.ModelHashFileName), []byte(tt.content), 0600))
+			}
+			record, err := downloader.LoadModelHash(dir)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, valid, record.Content)
+		})
+	}
+}
+
+func TestScheduleModels(t *testing.T) {
+	withFiles := func(textures int) *model.BuildData {
+		data := &model.BuildData{Model: model.BundleFile{BundleName: "live2d/chara/001_test", FileName: "model.moc"}}
+		for i := range textures {
+			data.Textures = append(data.Textures, model.BundleFile{
+				BundleName: "live2d/chara/001_test", FileName: fmt.Sprintf("texture_%02d.png", i),
+			})
+		}
+		return data
+	}
+	names := []string{"large_1", "unknown", "large_2", "small", "medium_1", "medium_2"}
+	buildData := map[string]*model.BuildData{
+		"large_1":  withFiles(30),
+		"large_2":  withFiles(30),
+		"small":    withFiles(1),
+		"medium_1": withFiles(5),
+		"medium_2": withFiles(5),
+	}
+
+	tests := []struct {
+		name       string
+		scheduling model.ModelScheduling
+		want       []string
+	}{
+		{
+			name:       "优先文件数少的模型",
+			scheduling: model.ModelSchedulingFewestFiles,
+			want:       []string{"small", "medium_1", "medium_2", "large_1", "large_2", "unknown"},
+		},
+		{name: "按选择的顺序", scheduling: model.ModelSchedulingFIFO, want: names},
+		{name: "未知的调度方式按选择的顺序", scheduling: "unknown", want: names},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, downloader.ScheduleModels(names, buildData, tt.scheduling))
+		})
+	}
+	assert.Equal(t, []string{"large_1", "unknown", "large_2", "small", "medium_1", "medium_2"}, names, "不修改原列表")
+}
+
+// goldenVolatile 匹配输出中随版本与时间变化的 JSON 字段.
+var goldenVolatile = regexp.MustCompile(`"(tool_version|toolVersion|downloadedAt)": "[^"]*"`)
+
+// normalizeGolden 将输出中随测试服务器地址、程序版本与时间变化的内容替换为固定值.
+func normalizeGolden(data []byte, serverURL string) []byte {
+	data = []byte(strings.ReplaceAll(string(data), serverURL, "http://assets.test"))
+	data = []byte(strings.ReplaceAll(string(data), strings.TrimPrefix(serverURL, "http://"), "assets.test"))
+	return goldenVolatile.ReplaceAll(data, []byte(`"$1": "<volatile>"`))
+}
+
+// outputTree 返回模型目录的文件列表，每行为 "<sha256>  <大小>  <相对路径>"
+// JSON 文件按 normalizeGolden 处理后计算，其内容也一并返回.
+func outputTree(t *testing.T, dir, serverURL string) (string, map[string][]byte) {
+	t.Helper()
+	var b strings.Builder
+	jsonFiles := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return walkErr
+		}
+		rel, relErr := filepath.Rel(dir, path)
+		if relErr != nil {
+			return relErr
+		}
+		rel = filepath.ToSlash(rel)
+		data, readErr := os.ReadFile(path)
+		if readErr != nil {
+			return readErr
+		}
+		if !strings.Contains(rel, "/") && strings.HasSuffix(rel, ".json") {
+			data = normalizeGolden(data, serverURL)
+			jsonFiles[rel] = data
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(&b, "%s  %d  %s\n", hex.EncodeToString(sum[:]), len(data), rel)
+		return nil
+	})
+	require.NoError(t, err)
+	return b.String(), jsonFiles
+}
+
+// assertGolden 比较输出与 golden 文件，指定 -update 时改为写入 golden 文件.
+func assertGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, got, 0600))
+		return
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "golden 文件不存在，使用 go test ./pkg/downloader -run TestBuilderGolden -update 生成")
+	assert.Equal(t, string(want), string(got), "输出与 %s 不一致，确认改动符合预期后使用 -update 更新", path)
+}
+
+// TestBuilderGolden 使用 testdata/builder 中采集的构建数据构建模型，并与提交的 golden 数据比较完整的输出
+// 模拟的资源服务器按路径返回固定内容，输出结构的任何变化都需要用 -update 显式更新 golden 数据.
+func TestBuilderGolden(t *testing.T) {
+	fixtures, err := os.ReadDir(filepath.Join("testdata", "builder"))
+	require.NoError(t, err)
+
+	for _, fixture := range fixtures {
+		if !fixture.IsDir() {
+			continue
+		}
+		name := fixture.Name()
+		t.Run(name, func(t *testing.T) {
+			fixtureDir := filepath.Join("testdata", "builder", name)
+			buildData, readErr := os.ReadFile(filepath.Join(fixtureDir, "buildData.asset"))
+			require.NoError(t, readErr)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/live2d/chara/"+name+"_rip/buildData.asset") {
+					_, _ = w.Write(buildData)
+					return
+				}
+				fmt.Fprintf(w, "bestdori:%s", r.URL.Path)
+			}))
+			defer server.Close()
+
+			cfg := config.Get()
+			oldURL := cfg.BaseAssetsURL
+			defer func() { cfg.BaseAssetsURL = oldURL }()
+			cfg.BaseAssetsURL = server.URL + "/assets/jp"
+
+			client := api.NewClient()
+			client.SetUseCharaCache(false)
+			data, fetchErr := client.GetLive2dData(context.Background(), name)
+			require.NoError(t, fetchErr)
+
+			modelDir := t.TempDir()
+			d := downloader.NewDownloader(client, nil, nil)
+			require.NoError(t, downloader.NewLive2dBuilder(modelDir, data, d, name).Construct())
+
+			tree, jsonFiles := outputTree(t, modelDir, server.URL)
+			goldenDir := filepath.Join(fixtureDir, "golden")
+			assertGolden(t, filepath.Join(goldenDir, "files.txt"), []byte(tree))
+			for _, file := range []string{model.ModelDataFileName, model.ManifestFileName} {
+				assertGolden(t, filepath.Join(goldenDir, file), jsonFiles[file])
 			}
 		})
 	}
@@ -0,0 +1,137 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// SpaceEstimate 表示下载前估算的所需磁盘空间.
+type SpaceEstimate struct {
+	Files      int    // 文件总数
+	SizedFiles int    // 通过 HEAD 请求获取到大小的文件数，其余文件按平均大小估算
+	Bytes      uint64 // 预计占用的空间（字节）
+	Free       uint64 // 保存路径的剩余空间（字节）
+	FreeKnown  bool   // 是否查询到了剩余空间
+}
+
+// Estimated 判断预计占用的空间中是否包含估计值.
+func (e SpaceEstimate) Estimated() bool {
+	return e.SizedFiles < e.Files
+}
+
+// Sufficient 判断剩余空间是否足以容纳预计占用的空间与安全余量，无法查询剩余空间时视为充足.
+func (e SpaceEstimate) Sufficient(margin uint64) bool {
+	return !e.FreeKnown || e.Bytes+margin <= e.Free
+}
+
+// String 返回展示给用户的估算结果，例如 "预计占用 1.2 GiB，可用 50.0 GiB".
+func (e SpaceEstimate) String() string {
+	text := "预计占用 " + fsutil.FormatBytes(e.Bytes)
+	if e.Estimated() {
+		text += fmt.Sprintf("（%d 个文件的大小为估计值）", e.Files-e.SizedFiles)
+	}
+	if e.FreeKnown {
+		text += "，可用 " + fsutil.FormatBytes(e.Free)
+	}
+	return text
+}
+
+// EstimateSpace 通过 HEAD 请求估算下载模型所需的磁盘空间
+// 无法获取大小的文件按已知文件的平均大小估算，没有已知文件时按默认大小估算；
+// 不考虑本地已存在、可以复用的文件，因此结果偏保守
+// 参数:
+//   - ctx: 上下文
+//   - models: 要下载的模型的构建数据
+//
+// 返回:
+//   - SpaceEstimate: 估算结果
+func (d *Downloader) EstimateSpace(ctx context.Context, models []*model.BuildData) SpaceEstimate {
+	cfg := config.Get()
+	var urls []string
+	for _, buildData := range models {
+		_, assetsURL := buildDataServer(buildData)
+		for _, bundleFile := range buildDataFiles(buildData) {
+			urls = append(urls, fmt.Sprintf("%s/%s_rip/%s", assetsURL, bundleFile.BundleName, bundleFile.FileName))
+		}
+	}
+
+	var mu sync.Mutex
+	estimate := SpaceEstimate{Files: len(urls)}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(cfg.MaxConcurrentDownloads*cfg.MaxConcurrentModels, 1))
+	for _, url := range urls {
+		g.Go(func() error {
+			size, err := d.headSize(gctx, url)
+			if err != nil {
+				log.DefaultLogger.Debug().Str("url", url).Err(err).Msg("获取文件大小失败，按平均大小估算")
+				return nil
+			}
+			mu.Lock()
+			estimate.SizedFiles++
+			estimate.Bytes += size
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	average := uint64(defaultFileSizeEstimate)
+	if estimate.SizedFiles > 0 {
+		average = estimate.Bytes / uint64(estimate.SizedFiles) //nolint:gosec // 文件数不会为负
+	}
+	estimate.Bytes += uint64(estimate.Files-estimate.SizedFiles) * average //nolint:gosec // 已知文件数不超过文件总数
+
+	if free, err := fsutil.FreeSpace(d.savePath); err == nil {
+		estimate.Free, estimate.FreeKnown = free, true
+	} else {
+		log.DefaultLogger.Debug().Str("path", d.savePath).Err(err).Msg("查询剩余空间失败")
+	}
+	return estimate
+}
+
+// buildDataFiles 返回构建数据中需要下载的文件.
+func buildDataFiles(buildData *model.BuildData) []model.BundleFile {
+	files := []model.BundleFile{buildData.Model, buildData.Physics, buildData.Transition}
+	files = append(files, buildData.Textures...)
+	files = append(files, buildData.Motions...)
+	files = append(files, buildData.Expressions...)
+	result := files[:0]
+	for _, file := range files {
+		if file.FileName != "" {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// headSize 通过 HEAD 请求获取文件大小，响应没有 Content-Length 时返回错误.
+func (d *Downloader) headSize(ctx context.Context, url string) (uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("请求文件大小失败: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("请求文件大小失败: 状态码 %d", resp.StatusCode)
+	}
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("响应没有 Content-Length")
+	}
+	return uint64(resp.ContentLength), nil
+}
@@ -0,0 +1,141 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// ServerConflictFunc 在角色目录已有模型来自其他服务器时决定如何处理
+// 参数:
+//   - folderServer: 角色目录已记录的服务器
+//
+// 返回:
+//   - model.ServerConflictDecision: 处理方式
+//   - error: 错误信息
+type ServerConflictFunc func(folderServer string) (model.ServerConflictDecision, error)
+
+// ReadFolderMarker 读取角色目录的标记文件
+// 标记文件不存在时返回空标记.
+func ReadFolderMarker(charaDir string) (model.FolderMarker, error) {
+	var marker model.FolderMarker
+	data, err := os.ReadFile(filepath.Join(charaDir, model.FolderMarkerFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return marker, nil
+		}
+		return marker, fmt.Errorf("读取目录标记失败: %w", err)
+	}
+	if unmarshalErr := json.Unmarshal(data, &marker); unmarshalErr != nil {
+		return marker, fmt.Errorf("解析目录标记失败: %w", unmarshalErr)
+	}
+	return marker, nil
+}
+
+// EnsureFolderMarker 在角色目录尚未记录服务器时写入标记文件
+// 已有标记时保持不变.
+func EnsureFolderMarker(charaDir, server string) error {
+	if server == "" {
+		return nil
+	}
+	marker, err := ReadFolderMarker(charaDir)
+	if err != nil || marker.Server != "" {
+		return err
+	}
+
+	data, err := json.MarshalIndent(model.FolderMarker{Server: server}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化目录标记失败: %w", err)
+	}
+	if writeErr := os.WriteFile(filepath.Join(charaDir, model.FolderMarkerFileName), data, 0600); writeErr != nil {
+		return fmt.Errorf("写入目录标记失败: %w", writeErr)
+	}
+	return nil
+}
+
+// ResolveServerFolder 检查模型所在角色目录的服务器是否与本次下载一致
+// 不一致时由 decide 决定继续使用原目录、改用带服务器后缀的目录或中止下载
+// 参数:
+//   - modelPath: 模型保存路径
+//   - server: 本次下载使用的服务器
+//   - decide: 冲突处理函数
+//
+// 返回:
+//   - string: 最终的模型保存路径
+//   - error: 选择中止时返回 errs.ErrServerConflict
+func ResolveServerFolder(modelPath, server string, decide ServerConflictFunc) (string, error) {
+	charaDir := filepath.Dir(modelPath)
+	marker, err := ReadFolderMarker(charaDir)
+	if err != nil {
+		log.DefaultLogger.Warn().Str("path", charaDir).Err(err).Msg("读取目录标记失败，跳过服务器检查")
+		return modelPath, nil
+	}
+	if marker.Server == "" || server == "" || marker.Server == server {
+		return modelPath, nil
+	}
+
+	log.DefaultLogger.Warn().
+		Str("path", charaDir).
+		Str("folderServer", marker.Server).
+		Str("server", server).
+		Msg("角色目录中的模型来自其他服务器")
+
+	decision, err := decide(marker.Server)
+	if err != nil {
+		return "", err
+	}
+
+	switch decision {
+	case model.ServerConflictSuffix:
+		suffixed := filepath.Join(charaDir+"-"+server, filepath.Base(modelPath))
+		log.DefaultLogger.Info().Str("path", suffixed).Msg("使用带服务器后缀的角色目录")
+		return suffixed, nil
+	case model.ServerConflictAbort:
+		return "", fmt.Errorf("%w: %s 来自 %s，目录已有 %s 的模型", errs.ErrServerConflict, filepath.Base(modelPath), server, marker.Server)
+	default:
+		return modelPath, nil
+	}
+}
+
+// FindModelInOtherLayout 在另一种目录布局下查找已下载的模型
+// 切换 DirectoryLayout 后用于发现按原布局下载过的模型，避免重复下载；
+// 只认可下载清单中记录的模型名称一致的目录，避免不同角色的同名服装被误判
+// 参数:
+//   - root: 模型保存根目录
+//   - live2dName: Live2D 名称，例如 037_casual-2023
+//   - layout: 当前使用的目录布局
+//
+// 返回:
+//   - string: 按另一种布局保存的模型目录，没有找到时为空字符串
+func FindModelInOtherLayout(root, live2dName string, layout model.DirectoryLayout) string {
+	var candidates []string
+	if layout == model.DirectoryLayoutFlat {
+		_, costume, found := strings.Cut(live2dName, "_")
+		if !found {
+			return ""
+		}
+		// 包括因服务器冲突而使用的带服务器后缀的角色目录
+		entries, _ := os.ReadDir(root)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				candidates = append(candidates, filepath.Join(root, entry.Name(), costume))
+			}
+		}
+	} else {
+		candidates = []string{filepath.Join(root, live2dName)}
+	}
+
+	for _, candidate := range candidates {
+		manifest, err := LoadManifest(candidate)
+		if err == nil && manifest.Model == live2dName {
+			return candidate
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// BuildModelIndex 从模型信息中提取动作与表情索引
+// 参数:
+//   - modelName: 模型名称
+//   - live2d: Live2D 模型
+//
+// 返回:
+//   - *model.ModelIndex: 动作与表情索引
+func BuildModelIndex(modelName string, live2d *model.Live2dModel) *model.ModelIndex {
+	index := &model.ModelIndex{
+		Model:       modelName,
+		Motions:     make([]model.IndexMotionGroup, 0, len(live2d.Motions)),
+		Expressions: make([]model.IndexExpression, 0, len(live2d.Expressions)),
+	}
+
+	for name, motions := range live2d.Motions {
+		group := model.IndexMotionGroup{Name: name, Files: make([]string, 0, len(motions))}
+		for _, motion := range motions {
+			group.Files = append(group.Files, motion.File)
+		}
+		index.Motions = append(index.Motions, group)
+	}
+	slices.SortFunc(index.Motions, func(a, b model.IndexMotionGroup) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, expression := range live2d.Expressions {
+		index.Expressions = append(index.Expressions, model.IndexExpression{Name: expression.Name, File: expression.File})
+	}
+	slices.SortFunc(index.Expressions, func(a, b model.IndexExpression) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return index
+}
+
+// writeModelIndex 在模型目录生成动作与表情索引
+// 未启用时不生成.
+func (b *Live2dBuilder) writeModelIndex() error {
+	if !config.Get().GenerateModelIndex {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(BuildModelIndex(b.ModelName, b.model), "", "  ")
+	if err != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Err(err).Msg("序列化动作与表情索引失败")
+		return fmt.Errorf("序列化动作与表情索引失败: %w", err)
+	}
+
+	indexPath := filepath.Join(b.path, model.IndexFileName)
+	if writeErr := os.WriteFile(indexPath, data, 0600); writeErr != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Str("path", indexPath).Err(writeErr).Msg("写入动作与表情索引失败")
+		return fmt.Errorf("写入动作与表情索引失败: %w", writeErr)
+	}
+
+	b.logger.Info().Str("modelName", b.ModelName).Str("path", indexPath).Msg("动作与表情索引写入完成")
+	return nil
+}
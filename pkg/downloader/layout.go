@@ -0,0 +1,220 @@
+package downloader
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// buildDataFileName 是资源包中构建数据的文件名.
+const buildDataFileName = "buildData.asset"
+
+// FileKind 表示文件在构建数据中的类型
+// 由文件来自构建数据的哪个字段决定，而不是根据保存路径推断.
+type FileKind int
+
+const (
+	FileKindModel      FileKind = iota // 模型文件
+	FileKindPhysics                    // 物理文件
+	FileKindTexture                    // 纹理文件
+	FileKindMotion                     // 动作文件
+	FileKindExpression                 // 表情文件
+	FileKindBuildData                  // 构建数据，只在原始布局中保存
+	FileKindTransition                 // 过渡文件，部分模型的切换动作需要
+)
+
+// String 返回文件类型的名称.
+func (k FileKind) String() string {
+	switch k {
+	case FileKindModel:
+		return "model"
+	case FileKindPhysics:
+		return "physics"
+	case FileKindTexture:
+		return "texture"
+	case FileKindMotion:
+		return "motion"
+	case FileKindExpression:
+		return "expression"
+	case FileKindBuildData:
+		return "buildData"
+	case FileKindTransition:
+		return "transition"
+	default:
+		return fmt.Sprintf("FileKind(%d)", int(k))
+	}
+}
+
+// plannedFile 表示构建模型需要的一个文件.
+type plannedFile struct {
+	kind          FileKind         // 文件类型，由文件来自构建数据的哪个字段决定
+	bundleFile    model.BundleFile // 资源包文件信息
+	filePath      string           // 保存路径
+	allowNotFound bool             // 是否允许文件不存在
+}
+
+// SetOutputLayout 设置模型文件的组织方式
+// 参数:
+//   - layout: 组织方式，无法识别时使用 model.OutputLayoutRestructured
+func (b *Live2dBuilder) SetOutputLayout(layout model.OutputLayout) {
+	if layout != model.OutputLayoutOriginal {
+		layout = model.OutputLayoutRestructured
+	}
+	b.layout = layout
+	b.dataPath = b.path
+	if layout == model.OutputLayoutRestructured {
+		b.dataPath = filepath.Join(b.path, "data")
+	}
+}
+
+// SetDataPath 设置整理后布局中数据文件的保存目录
+// 目录必须位于模型目录内，否则构建时 model.json 中的引用会指向模型目录之外，构建将失败
+// 参数:
+//   - dataPath: 数据文件目录，默认为模型目录下的 data
+func (b *Live2dBuilder) SetDataPath(dataPath string) {
+	if b.layout == model.OutputLayoutRestructured {
+		b.dataPath = dataPath
+	}
+}
+
+// plannedFiles 返回构建模型需要的所有文件及其保存路径.
+func (b *Live2dBuilder) plannedFiles() ([]plannedFile, error) {
+	files := make([]plannedFile, 0, 3+len(b.data.Textures)+len(b.data.Motions)+len(b.data.Expressions)+1)
+	cfg := config.Get()
+	add := func(kind FileKind, bundleFile model.BundleFile) error {
+		filePath, err := b.layoutPath(kind, bundleFile)
+		if err != nil {
+			return err
+		}
+		allowNotFound := AllowMissing(cfg, kind, bundleFile.FileName)
+		files = append(files, plannedFile{kind: kind, bundleFile: bundleFile, filePath: filePath, allowNotFound: allowNotFound})
+		return nil
+	}
+
+	// 默认只有 physics.json 与过渡文件允许不存在，可通过配置调整
+	if err := add(FileKindModel, b.data.Model); err != nil {
+		return nil, err
+	}
+	if err := add(FileKindPhysics, b.data.Physics); err != nil {
+		return nil, err
+	}
+	groups := []struct {
+		kind  FileKind
+		files []model.BundleFile
+	}{
+		{FileKindTexture, b.data.Textures},
+		{FileKindMotion, b.data.Motions},
+		{FileKindExpression, b.data.Expressions},
+	}
+	for _, group := range groups {
+		for _, bundleFile := range group.files {
+			if err := add(group.kind, bundleFile); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// 不是所有模型都有过渡文件，构建数据中没有时不下载
+	if b.data.Transition.FileName != "" {
+		if err := add(FileKindTransition, b.data.Transition); err != nil {
+			return nil, err
+		}
+	}
+	if b.layout == model.OutputLayoutOriginal {
+		buildData := model.BundleFile{BundleName: "live2d/chara/" + b.ModelName, FileName: buildDataFileName}
+		if err := add(FileKindBuildData, buildData); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// layoutPath 返回文件在当前组织方式下的保存路径.
+func (b *Live2dBuilder) layoutPath(kind FileKind, bundleFile model.BundleFile) (string, error) {
+	var rel string
+	if b.layout == model.OutputLayoutOriginal {
+		// 与 Bestdori 提供资源的路径一致：<资源包>_rip/<文件名>
+		rel = path.Join(bundleFile.BundleName+"_rip", bundleFile.FileName)
+	} else {
+		switch kind {
+		case FileKindModel:
+			rel = "model.moc"
+		case FileKindPhysics:
+			rel = "physics.json"
+		case FileKindTexture:
+			rel = path.Join("textures", bundleFile.FileName)
+		case FileKindMotion:
+			rel = path.Join("motions", bundleFile.FileName)
+		case FileKindExpression:
+			rel = path.Join("expressions", bundleFile.FileName)
+		case FileKindBuildData, FileKindTransition:
+			rel = bundleFile.FileName
+		}
+	}
+
+	localPath := filepath.FromSlash(rel)
+	if !filepath.IsLocal(localPath) {
+		return "", fmt.Errorf("%w: 非法的文件路径 %s", errs.ErrInvalidBuildData, rel)
+	}
+	return filepath.Join(b.dataPath, localPath), nil
+}
+
+// ValidateModelReferences 检查模型数据引用的所有文件是否都位于模型目录内
+// 引用模型目录之外的文件时，移动模型目录后引用会失效，部分加载器也会拒绝加载
+// 参数:
+//   - live2d: Live2D 模型
+//
+// 返回:
+//   - error: 第一个越界引用的错误信息
+func ValidateModelReferences(live2d *model.Live2dModel) error {
+	refs := append([]string{live2d.Model, live2d.Physics, live2d.Transition}, live2d.Textures...)
+	for _, name := range slices.Sorted(maps.Keys(live2d.Motions)) {
+		for _, motion := range live2d.Motions[name] {
+			refs = append(refs, motion.File)
+		}
+	}
+	for _, expression := range live2d.Expressions {
+		refs = append(refs, expression.File)
+	}
+
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(ref)) {
+			return fmt.Errorf("%w: %s", errs.ErrReferenceOutsideModel, ref)
+		}
+	}
+	return nil
+}
+
+// updateModelData 根据文件类型更新模型数据
+// 参数:
+//   - live2d: Live2D 模型
+//   - kind: 文件类型
+//   - relPath: 相对于模型目录的路径
+func updateModelData(live2d *model.Live2dModel, kind FileKind, relPath string) {
+	baseName := strings.Split(path.Base(relPath), ".")[0]
+	switch kind {
+	case FileKindModel:
+		live2d.Model = relPath
+	case FileKindPhysics:
+		live2d.Physics = relPath
+	case FileKindTexture:
+		live2d.Textures = append(live2d.Textures, relPath)
+	case FileKindMotion:
+		live2d.Motions[baseName] = []MotionFile{{File: relPath}}
+	case FileKindExpression:
+		live2d.Expressions = append(live2d.Expressions, ExpressionFile{Name: baseName, File: relPath})
+	case FileKindTransition:
+		live2d.Transition = relPath
+	case FileKindBuildData:
+		// 构建数据不写入模型数据
+	}
+}
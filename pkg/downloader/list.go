@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// listOutputSeparator 是模型列表中分隔模型名称和输出路径的符号.
+const listOutputSeparator = "=>"
+
+// ModelListEntry 表示模型列表中的一项.
+type ModelListEntry struct {
+	Name       string `json:"name"`                 // 模型名称
+	OutputPath string `json:"outputPath,omitempty"` // 该模型的保存路径，覆盖全局保存路径，为空时使用全局保存路径
+}
+
+// ParseModelList 解析模型列表
+// 每行一个模型名称，可以用 "模型名 => 输出路径" 为单个模型指定保存路径
+// 空行和以 # 开头的行会被忽略，模型名称会使用 model.NormalizeLive2dName 规范化
+// 参数:
+//   - r: 模型列表内容
+//
+// 返回:
+//   - []ModelListEntry: 模型列表
+//   - error: 错误信息
+func ParseModelList(r io.Reader) ([]ModelListEntry, error) {
+	var entries []ModelListEntry
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, output, hasOutput := strings.Cut(line, listOutputSeparator)
+		name = strings.TrimSpace(name)
+		output = strings.TrimSpace(output)
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("第 %d 行缺少模型名称", lineNo)
+		case hasOutput && output == "":
+			return nil, fmt.Errorf("第 %d 行缺少输出路径", lineNo)
+		}
+		normalized, err := model.NormalizeLive2dName(name)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", lineNo, err)
+		}
+		entries = append(entries, ModelListEntry{Name: normalized, OutputPath: output})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取模型列表失败: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,168 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/version"
+)
+
+// LoadManifest 读取模型目录下的下载清单
+// 参数:
+//   - modelPath: 模型目录
+//
+// 返回:
+//   - *model.DownloadManifest: 下载清单
+//   - error: 错误信息
+func LoadManifest(modelPath string) (*model.DownloadManifest, error) {
+	data, err := os.ReadFile(filepath.Join(modelPath, model.ManifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("读取下载清单失败: %w", err)
+	}
+
+	var manifest model.DownloadManifest
+	if unmarshalErr := json.Unmarshal(data, &manifest); unmarshalErr != nil {
+		return nil, fmt.Errorf("解析下载清单失败: %w", unmarshalErr)
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]model.ManifestFile)
+	}
+	return &manifest, nil
+}
+
+// loadPreviousManifest 读取上一次构建留下的下载清单
+// 清单不存在或损坏时返回空清单.
+func (b *Live2dBuilder) loadPreviousManifest() *model.DownloadManifest {
+	manifest, err := LoadManifest(b.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			b.logger.Warn().Str("modelName", b.ModelName).Err(err).Msg("读取下载清单失败，将重新生成")
+		}
+		return &model.DownloadManifest{Model: b.ModelName, Files: make(map[string]model.ManifestFile)}
+	}
+	return manifest
+}
+
+// hashManifestFiles 计算下载清单中所有文件的 SHA-256.
+func (b *Live2dBuilder) hashManifestFiles(ctx context.Context) error {
+	relPaths := make(map[string]string, len(b.manifest.Files))
+	paths := make([]string, 0, len(b.manifest.Files))
+	for relPath := range b.manifest.Files {
+		path := filepath.Join(b.path, filepath.FromSlash(relPath))
+		relPaths[path] = relPath
+		paths = append(paths, path)
+	}
+
+	for path, result := range fsutil.HashFiles(ctx, paths, 0) {
+		if result.Err != nil {
+			b.logger.Error().Str("modelName", b.ModelName).Str("path", path).Err(result.Err).Msg("计算文件哈希失败")
+			return fmt.Errorf("计算文件哈希失败: %w", result.Err)
+		}
+		relPath := relPaths[path]
+		entry := b.manifest.Files[relPath]
+		entry.SHA256 = result.SHA256
+		b.manifest.Files[relPath] = entry
+	}
+	return nil
+}
+
+// writeManifest 计算文件哈希并将下载清单写入模型目录.
+func (b *Live2dBuilder) writeManifest(ctx context.Context) error {
+	if err := b.hashManifestFiles(ctx); err != nil {
+		return err
+	}
+	b.manifest.DownloadedAt = time.Now()
+	b.manifest.ToolVersion = version.GetVersion()
+
+	data, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Err(err).Msg("序列化下载清单失败")
+		return fmt.Errorf("序列化下载清单失败: %w", err)
+	}
+
+	manifestPath := filepath.Join(b.path, model.ManifestFileName)
+	if writeErr := os.WriteFile(manifestPath, data, 0600); writeErr != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Str("path", manifestPath).Err(writeErr).Msg("写入下载清单失败")
+		return fmt.Errorf("写入下载清单失败: %w", writeErr)
+	}
+
+	b.logger.Info().Str("modelName", b.ModelName).Str("path", manifestPath).Msg("下载清单写入完成")
+	return nil
+}
+
+// reusableFile 判断已存在的文件能否直接复用
+// 文件大小与上一次清单记录不一致时需要重新下载，开启 VerifyFileIntegrity 时还会校验 SHA-256；
+// 清单中没有记录的空文件视为上一次下载中断留下的残缺文件，远端模型更新或指定强制刷新时所有文件都需要重新下载.
+func (b *Live2dBuilder) reusableFile(ctx context.Context, filePath, relPath string) bool {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+	if b.redownload {
+		return false
+	}
+
+	previous, ok := b.previousManifest.Files[relPath]
+	if !ok {
+		if info.Size() == 0 {
+			b.logger.Warn().Str("modelName", b.ModelName).Str("file", relPath).Msg("已存在的文件为空，重新下载")
+			return false
+		}
+		return true
+	}
+
+	if info.Size() != previous.Provenance.Size {
+		b.logger.Warn().
+			Str("modelName", b.ModelName).
+			Str("file", relPath).
+			Int64("size", info.Size()).
+			Int64("expected", previous.Provenance.Size).
+			Msg("已存在的文件大小与下载清单不一致，重新下载")
+		return false
+	}
+
+	if !config.Get().VerifyFileIntegrity || previous.SHA256 == "" {
+		return true
+	}
+	result := fsutil.HashFiles(ctx, []string{filePath}, 1)[filePath]
+	if result.Err != nil {
+		b.logger.Warn().Str("modelName", b.ModelName).Str("file", relPath).Err(result.Err).Msg("校验文件哈希失败，重新下载")
+		return false
+	}
+	if result.SHA256 != previous.SHA256 {
+		b.logger.Warn().Str("modelName", b.ModelName).Str("file", relPath).Msg("已存在的文件哈希与下载清单不一致，重新下载")
+		return false
+	}
+	return true
+}
+
+// cachedProvenance 生成复用本地文件时的来源信息
+// 优先沿用上一次清单中记录的原始下载信息.
+func (b *Live2dBuilder) cachedProvenance(filePath, relPath string) model.FileProvenance {
+	if previous, ok := b.previousManifest.Files[relPath]; ok {
+		provenance := previous.Provenance
+		provenance.Source = model.FileSourceCache
+		return provenance
+	}
+
+	provenance := model.FileProvenance{Source: model.FileSourceCache}
+	if info, err := os.Stat(filePath); err == nil {
+		provenance.DownloadedAt = info.ModTime()
+		provenance.Size = info.Size()
+	}
+	return provenance
+}
+
+// recordProvenance 在下载清单中记录文件来源.
+func (b *Live2dBuilder) recordProvenance(relPath string, provenance model.FileProvenance) {
+	b.manifest.Files[relPath] = model.ManifestFile{Provenance: provenance}
+}
@@ -0,0 +1,122 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// model3LayoutKeys 是 Cubism 2 布局字段与 Cubism 3 布局字段的对应关系.
+var model3LayoutKeys = map[string]string{
+	"center_x": "CenterX",
+	"center_y": "CenterY",
+	"x":        "X",
+	"y":        "Y",
+	"width":    "Width",
+	"height":   "Height",
+}
+
+// model3Groups 是 Cubism 3 模型数据中的参数分组
+// 参数ID保存在模型文件中，无法从构建数据得知，分组保持为空.
+var model3Groups = []model.Model3Group{
+	{Target: "Parameter", Name: "EyeBlink", Ids: []string{}},
+	{Target: "Parameter", Name: "LipSync", Ids: []string{}},
+}
+
+// BuildModel3Data 将模型转换为 Cubism 3 的模型数据
+// 只转换模型数据中的文件引用与布局，模型、动作、物理与表情文件本身仍为 Cubism 2 格式
+// 参数:
+//   - live2d: 模型
+//   - layout: 布局预设中的布局
+//
+// 返回:
+//   - *model.Model3Data: Cubism 3 模型数据
+//   - []string: 无法转换的布局字段，按名称排序
+func BuildModel3Data(live2d *model.Live2dModel, layout map[string]float64) (*model.Model3Data, []string) {
+	data := &model.Model3Data{
+		Version: model.Model3Version,
+		FileReferences: model.Model3FileReferences{
+			Moc:      live2d.Model,
+			Textures: live2d.Textures,
+			Physics:  live2d.Physics,
+		},
+		Groups: model3Groups,
+	}
+	if data.FileReferences.Textures == nil {
+		data.FileReferences.Textures = []string{}
+	}
+
+	if len(live2d.Motions) > 0 {
+		data.FileReferences.Motions = make(map[string][]model.Model3Motion, len(live2d.Motions))
+		for group, motions := range live2d.Motions {
+			for _, motion := range motions {
+				data.FileReferences.Motions[group] = append(data.FileReferences.Motions[group], model.Model3Motion{File: motion.File})
+			}
+		}
+	}
+	for _, expression := range live2d.Expressions {
+		data.FileReferences.Expressions = append(data.FileReferences.Expressions,
+			model.Model3Expression{Name: expression.Name, File: expression.File})
+	}
+
+	var unconverted []string
+	for key, value := range layout {
+		name, ok := model3LayoutKeys[key]
+		if !ok {
+			unconverted = append(unconverted, key)
+			continue
+		}
+		if data.Layout == nil {
+			data.Layout = make(map[string]float64, len(layout))
+		}
+		data.Layout[name] = value
+	}
+	slices.Sort(unconverted)
+	return data, unconverted
+}
+
+// createModel3Data 在模型目录生成 Cubism 3 的 <模型名>.model3.json
+// 输出格式不是 cubism3 时不生成；无法转换的内容只记录日志，不影响构建结果.
+func (b *Live2dBuilder) createModel3Data() error {
+	cfg := config.Get()
+	if cfg.OutputFormat != model.OutputFormatCubism3 {
+		return nil
+	}
+
+	_, preset := SelectLayoutPreset(cfg, b.ModelName)
+	data, unconverted := BuildModel3Data(b.model, preset.Layout)
+
+	// 下载的文件来自 Cubism 2 模型，生成的模型数据只能引用原有格式的文件
+	b.logger.Warn().Str("modelName", b.ModelName).
+		Msg("模型、动作、物理与表情文件仍为 Cubism 2 格式，只支持 .moc3 的软件可能无法加载")
+	if len(preset.HitAreasCustom) > 0 {
+		b.logger.Warn().Str("modelName", b.ModelName).
+			Msg("Cubism 3 模型数据不支持 hit_areas_custom，已忽略布局预设中的点击区域")
+	}
+	if len(unconverted) > 0 {
+		b.logger.Warn().Str("modelName", b.ModelName).Strs("fields", unconverted).
+			Msg("布局预设中的字段没有对应的 Cubism 3 布局字段，已忽略")
+	}
+	b.logger.Info().Str("modelName", b.ModelName).
+		Msg("眨眼与口型同步的参数ID保存在模型文件中，Groups 中的参数列表为空")
+
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Err(err).Msg("序列化 Cubism 3 模型数据失败")
+		return fmt.Errorf("序列化 Cubism 3 模型数据失败: %w", err)
+	}
+
+	model3Path := filepath.Join(b.path, b.ModelName+model.Model3FileSuffix)
+	if writeErr := os.WriteFile(model3Path, raw, 0600); writeErr != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Str("path", model3Path).Err(writeErr).Msg("写入 Cubism 3 模型数据失败")
+		return fmt.Errorf("写入 Cubism 3 模型数据失败: %w", writeErr)
+	}
+
+	b.logger.Info().Str("modelName", b.ModelName).Str("path", model3Path).Msg("Cubism 3 模型数据创建完成")
+	return nil
+}
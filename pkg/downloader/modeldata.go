@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/version"
+)
+
+// LoadModelData 读取模型目录下生成的模型数据
+// 旧版（格式版本 1）的模型数据不包含扩展信息，读取后会补充格式版本为 1 的扩展信息
+// 参数:
+//   - modelPath: 模型目录
+//
+// 返回:
+//   - *model.Data: 模型数据，Extension 一定不为 nil
+//   - error: 错误信息
+func LoadModelData(modelPath string) (*model.Data, error) {
+	raw, err := os.ReadFile(filepath.Join(modelPath, model.ModelDataFileName))
+	if err != nil {
+		return nil, fmt.Errorf("读取模型数据失败: %w", err)
+	}
+
+	var data model.Data
+	if unmarshalErr := json.Unmarshal(raw, &data); unmarshalErr != nil {
+		return nil, fmt.Errorf("解析模型数据失败: %w", unmarshalErr)
+	}
+
+	switch {
+	case data.Extension == nil:
+		data.Extension = &model.Extension{Schema: model.ExtensionSchemaLegacy}
+	case data.Extension.Schema > model.ExtensionSchemaVersion:
+		return nil, fmt.Errorf("%w: 格式版本 %d，当前程序支持 %d",
+			errs.ErrUnsupportedModelSchema, data.Extension.Schema, model.ExtensionSchemaVersion)
+	}
+	return &data, nil
+}
+
+// newExtension 创建当前版本的模型数据扩展信息.
+func newExtension(presetName string) *model.Extension {
+	return &model.Extension{
+		Schema:       model.ExtensionSchemaVersion,
+		ToolVersion:  version.GetVersion(),
+		LayoutPreset: presetName,
+		Features:     []string{model.FeatureProvenance},
+	}
+}
+
+// checkPreviousModelData 检查上一次生成的模型数据
+// 比当前程序更新的模型数据不会被覆盖，旧版模型数据会被重新生成为当前格式.
+func (b *Live2dBuilder) checkPreviousModelData() error {
+	previous, err := LoadModelData(b.path)
+	switch {
+	case err == nil:
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case errors.Is(err, errs.ErrUnsupportedModelSchema):
+		b.logger.Error().Str("modelName", b.ModelName).Err(err).Msg("模型数据由更新的版本生成，跳过覆盖")
+		return err
+	default:
+		// 损坏的模型数据直接重新生成
+		b.logger.Warn().Str("modelName", b.ModelName).Err(err).Msg("读取已有模型数据失败，将重新生成")
+		return nil
+	}
+
+	if previous.Extension.Schema == model.ExtensionSchemaLegacy {
+		b.logger.Info().Str("modelName", b.ModelName).Msg("升级旧版模型数据")
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package downloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// BuildDataHash 计算构建数据及其来源服务器的 SHA-256
+// 构建数据列出了模型的所有文件，远端模型更新时哈希随之变化
+// 参数:
+//   - data: 构建数据
+//
+// 返回:
+//   - string: 十六进制编码的 SHA-256
+//   - error: 错误信息
+func BuildDataHash(data *model.BuildData) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("序列化构建数据失败: %w", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(data.Server))
+	h.Write([]byte{0})
+	h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AggregateHash 按相对路径排序后汇总各文件的 SHA-256
+// 参数:
+//   - files: 文件的 SHA-256，key 为相对于模型目录的路径
+//
+// 返回:
+//   - string: 十六进制编码的聚合 SHA-256
+func AggregateHash(files map[string]string) string {
+	relPaths := make([]string, 0, len(files))
+	for relPath := range files {
+		relPaths = append(relPaths, relPath)
+	}
+	slices.Sort(relPaths)
+
+	h := sha256.New()
+	for _, relPath := range relPaths {
+		fmt.Fprintf(h, "%s\x00%s\n", relPath, files[relPath])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LoadModelHash 读取模型目录下的整模型哈希记录
+// 参数:
+//   - modelPath: 模型目录
+//
+// 返回:
+//   - *model.ModelHash: 整模型哈希记录
+//   - error: 文件不存在时返回包裹 fs.ErrNotExist 的错误，格式损坏时返回包裹 errs.ErrInvalidModelHash 的错误
+func LoadModelHash(modelPath string) (*model.ModelHash, error) {
+	data, err := os.ReadFile(filepath.Join(modelPath, model.ModelHashFileName))
+	if err != nil {
+		return nil, fmt.Errorf("读取整模型哈希记录失败: %w", err)
+	}
+
+	var record model.ModelHash
+	if unmarshalErr := json.Unmarshal(data, &record); unmarshalErr != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidModelHash, unmarshalErr)
+	}
+	if record.Version != model.ModelHashVersion {
+		return nil, fmt.Errorf("%w: 格式版本为 %d", errs.ErrInvalidModelHash, record.Version)
+	}
+	if !isSHA256(record.BuildData) || !isSHA256(record.Content) {
+		return nil, fmt.Errorf("%w: 哈希值无效", errs.ErrInvalidModelHash)
+	}
+	return &record, nil
+}
+
+// isSHA256 判断字符串是否为十六进制编码的 SHA-256.
+func isSHA256(value string) bool {
+	decoded, err := hex.DecodeString(value)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// checkModelHash 将本地的整模型哈希记录与本次的构建数据比较
+// 构建数据相同且本地文件未被修改时返回 true，调用方可以跳过整个模型；
+// 构建数据不同时标记为整体重新下载；记录缺失或损坏时按普通下载处理.
+func (b *Live2dBuilder) checkModelHash(ctx context.Context) bool {
+	record, err := LoadModelHash(b.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			b.logger.Warn().Str("modelName", b.ModelName).Err(err).Msg("整模型哈希记录无效，按普通下载处理")
+		}
+		return false
+	}
+
+	expected, err := BuildDataHash(b.data)
+	if err != nil {
+		b.logger.Warn().Str("modelName", b.ModelName).Err(err).Msg("计算构建数据哈希失败，按普通下载处理")
+		return false
+	}
+	if expected != record.BuildData {
+		b.logger.Info().Str("modelName", b.ModelName).Msg("远端模型已更新，重新下载整个模型")
+		b.redownload = true
+		return false
+	}
+
+	if _, statErr := os.Stat(filepath.Join(b.path, model.ModelDataFileName)); statErr != nil {
+		b.logger.Info().Str("modelName", b.ModelName).Msg("模型数据文件缺失，按普通下载处理")
+		return false
+	}
+	content, err := b.localContentHash(ctx)
+	if err != nil {
+		b.logger.Info().Str("modelName", b.ModelName).Err(err).Msg("计算本地文件哈希失败，按普通下载处理")
+		return false
+	}
+	if content != record.Content {
+		b.logger.Info().Str("modelName", b.ModelName).Msg("本地文件已被修改，按普通下载处理")
+		return false
+	}
+	return true
+}
+
+// localContentHash 计算上一次下载清单中所有本地文件的聚合哈希.
+func (b *Live2dBuilder) localContentHash(ctx context.Context) (string, error) {
+	paths := make([]string, 0, len(b.previousManifest.Files))
+	relPaths := make(map[string]string, len(b.previousManifest.Files))
+	for relPath := range b.previousManifest.Files {
+		path := filepath.Join(b.path, filepath.FromSlash(relPath))
+		paths = append(paths, path)
+		relPaths[path] = relPath
+	}
+
+	hashes := make(map[string]string, len(paths))
+	for path, result := range fsutil.HashFiles(ctx, paths, 0) {
+		if result.Err != nil {
+			return "", result.Err
+		}
+		hashes[relPaths[path]] = result.SHA256
+	}
+	return AggregateHash(hashes), nil
+}
+
+// skipUnchanged 跳过未变化的模型，将进度直接标记为完成.
+func (b *Live2dBuilder) skipUnchanged() {
+	total := len(b.previousManifest.Files)
+	b.logger.Info().Str("modelName", b.ModelName).Int("files", total).Msg("模型与上次下载时相同，跳过下载")
+	b.setTotalFiles(total)
+	b.updateProgress(total)
+}
+
+// writeModelHash 根据下载清单中的文件哈希写入整模型哈希记录
+// 需要在 writeManifest 计算文件哈希之后调用.
+func (b *Live2dBuilder) writeModelHash() error {
+	buildDataHash, err := BuildDataHash(b.data)
+	if err != nil {
+		return err
+	}
+	hashes := make(map[string]string, len(b.manifest.Files))
+	for relPath, file := range b.manifest.Files {
+		hashes[relPath] = file.SHA256
+	}
+
+	data, err := json.MarshalIndent(model.ModelHash{
+		Version:   model.ModelHashVersion,
+		BuildData: buildDataHash,
+		Content:   AggregateHash(hashes),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化整模型哈希记录失败: %w", err)
+	}
+
+	hashPath := filepath.Join(b.path, model.ModelHashFileName)
+	if writeErr := os.WriteFile(hashPath, data, 0600); writeErr != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Str("path", hashPath).Err(writeErr).Msg("写入整模型哈希记录失败")
+		return fmt.Errorf("写入整模型哈希记录失败: %w", writeErr)
+	}
+	return nil
+}
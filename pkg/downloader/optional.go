@@ -0,0 +1,33 @@
+package downloader
+
+import (
+	"path"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+)
+
+// AllowMissing 判断文件是否允许不存在
+// 按扩展名的配置优先于按文件类型的配置，都未配置时只有物理文件与过渡文件允许不存在
+// 模型文件是模型的核心，始终不允许不存在
+// 参数:
+//   - cfg: 程序配置
+//   - kind: 文件类型
+//   - fileName: 文件名
+//
+// 返回:
+//   - bool: 是否允许文件不存在
+func AllowMissing(cfg *config.Config, kind FileKind, fileName string) bool {
+	if kind == FileKindModel {
+		return false
+	}
+	if ext := strings.ToLower(path.Ext(fileName)); ext != "" {
+		if allow, ok := cfg.AllowMissingFiles[ext]; ok {
+			return allow
+		}
+	}
+	if allow, ok := cfg.AllowMissingFiles[kind.String()]; ok {
+		return allow
+	}
+	return kind == FileKindPhysics || kind == FileKindBuildData || kind == FileKindTransition
+}
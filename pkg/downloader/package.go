@@ -0,0 +1,47 @@
+package downloader
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/archive"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+)
+
+// packageModel 在开启 ZIP 打包时将构建完成的模型目录打包为 ZIP
+// ZIP 的顶层目录与文件名使用完整的模型名称，模型目录可能以去掉角色前缀的服装名命名；
+// 配置了 ZipPath 时保存到该目录，避免不同角色的同名服装相互覆盖；配置了 ZipRemoveSource 时打包成功后删除模型目录
+// 参数:
+//   - path: 模型目录
+//
+// 返回:
+//   - error: 错误信息
+func (b *Live2dBuilder) packageModel(path string) error {
+	cfg := config.Get()
+	if !cfg.ZipEnabled() {
+		return nil
+	}
+
+	name := b.ModelName
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	dest := path + archive.Extension
+	if cfg.ZipPath != "" {
+		dest = filepath.Join(cfg.ZipPath, name+archive.Extension)
+	}
+	if err := archive.ZipModelAs(path, dest, name); err != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Str("path", path).Err(err).Msg("打包模型失败")
+		return fmt.Errorf("打包模型失败: %w", err)
+	}
+	b.logger.Info().Str("modelName", b.ModelName).Str("zip", dest).Msg("已将模型打包为 ZIP")
+
+	if cfg.ZipRemoveSource {
+		if err := archive.RemoveSource(path, dest); err != nil {
+			b.logger.Error().Str("modelName", b.ModelName).Str("path", path).Err(err).Msg("清理打包后的模型目录失败")
+			return fmt.Errorf("清理模型目录失败: %w", err)
+		}
+		b.logger.Info().Str("modelName", b.ModelName).Str("path", path).Msg("已删除打包后的模型目录")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package downloader
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// SelectLayoutPreset 为模型选择布局预设
+// 优先使用配置中指定的预设，否则按模型名称中的关键字匹配，都不满足时使用标准预设
+// 参数:
+//   - cfg: 程序配置
+//   - modelName: 模型名称
+//
+// 返回:
+//   - string: 预设名称
+//   - model.LayoutPreset: 布局预设
+func SelectLayoutPreset(cfg *config.Config, modelName string) (string, model.LayoutPreset) {
+	presets := cfg.LayoutPresets
+	if len(presets) == 0 {
+		presets = config.DefaultLayoutPresets()
+	}
+
+	// 用户指定的预设
+	if cfg.LayoutPreset != "" {
+		if preset, ok := presets[cfg.LayoutPreset]; ok {
+			return cfg.LayoutPreset, preset
+		}
+		log.DefaultLogger.Warn().Str("preset", cfg.LayoutPreset).Msg("布局预设不存在，按模型名称自动选择")
+	}
+
+	// 按名称排序以保证匹配结果稳定
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lowerName := strings.ToLower(modelName)
+	for _, name := range names {
+		for _, keyword := range presets[name].Match {
+			if keyword != "" && strings.Contains(lowerName, strings.ToLower(keyword)) {
+				return name, presets[name]
+			}
+		}
+	}
+
+	if preset, ok := presets[model.LayoutPresetStandard]; ok {
+		return model.LayoutPresetStandard, preset
+	}
+	return model.LayoutPresetStandard, config.DefaultLayoutPresets()[model.LayoutPresetStandard]
+}
@@ -0,0 +1,245 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/tui"
+)
+
+// ProgressSink 接收模型构建的进度与错误
+// TUI 模型实现了该接口；不使用 TUI 时使用 NullProgressSink 将进度写成文本行.
+type ProgressSink interface {
+	// AddDownloadItem 登记一个模型及其文件总数，文件总数为 0 表示尚未知道
+	// 同一模型再次登记时只更新文件总数，不重置已完成的文件数.
+	AddDownloadItem(name string, totalFiles int)
+	// UpdateProgress 更新模型已完成的文件数.
+	UpdateProgress(name string, current int)
+	// SetError 显示一条错误或提示信息.
+	SetError(message string)
+	// SendError 标记模型下载失败.
+	SendError(itemName string, err error)
+	// SendSummary 报告下载完成的模型的文件统计.
+	SendSummary(itemName string, summary tui.Summary)
+	// SendDiskSpace 报告保存路径的剩余空间.
+	SendDiskSpace(free, required uint64, level tui.DiskLevel)
+	// TrackFile 登记一个正在下载的文件，返回接收到数据时的回调与下载结束时的回调.
+	TrackFile(name string, cancel context.CancelCauseFunc) (func(int64), func())
+	// Context 返回界面的上下文，被取消时中止所有构建，可以为 nil.
+	Context() context.Context
+}
+
+// NullProgressSink 是不使用 TUI 时的进度接收者
+// 将进度与错误以 key=value 的文本行写入输出，便于脚本与 CI 解析；不跟踪文件停滞，也不能被交互取消.
+type NullProgressSink struct {
+	mu        sync.Mutex
+	out       io.Writer
+	ctx       context.Context
+	totals    map[string]int    // 每个模型的文件总数
+	titles    map[string]string // 每个模型的服装标题
+	total     int               // 批量下载的模型总数
+	completed int               // 批量下载已结束的模型数
+	diskLevel tui.DiskLevel     // 上一次报告的剩余空间状态
+}
+
+// NewNullProgressSink 创建将进度写入 out 的进度接收者
+// 参数:
+//   - ctx: 上下文，被取消时中止所有构建
+//   - out: 输出目标，通常为标准输出
+//
+// 返回:
+//   - *NullProgressSink: 进度接收者
+func NewNullProgressSink(ctx context.Context, out io.Writer) *NullProgressSink {
+	return &NullProgressSink{out: out, ctx: ctx, totals: make(map[string]int), titles: make(map[string]string)}
+}
+
+// printf 写入一行输出，写入失败时忽略.
+func (s *NullProgressSink) printf(format string, args ...any) {
+	_, _ = fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+// AddDownloadItem 登记一个模型及其文件总数，文件总数未知时不输出.
+func (s *NullProgressSink) AddDownloadItem(name string, totalFiles int) {
+	if totalFiles <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals[name] = totalFiles
+	s.printf("event=start model=%s files=%d", name, totalFiles)
+}
+
+// UpdateProgress 输出模型已完成的文件数.
+func (s *NullProgressSink) UpdateProgress(name string, current int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.printf("event=progress model=%s completed=%d total=%d", name, current, s.totals[name])
+}
+
+// SetError 输出一条错误或提示信息.
+func (s *NullProgressSink) SetError(message string) {
+	if message == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.printf("event=message message=%q", message)
+}
+
+// SendError 输出模型下载失败的原因.
+func (s *NullProgressSink) SendError(itemName string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.printf("event=error model=%s error=%q", itemName, err.Error())
+}
+
+// SendSummary 输出下载完成的模型的文件统计，已知服装标题时一并输出.
+func (s *NullProgressSink) SendSummary(itemName string, summary tui.Summary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	line := fmt.Sprintf("event=done model=%s textures=%d motions=%d expressions=%d bytes=%d elapsed=%s",
+		itemName, summary.Textures, summary.Motions, summary.Expressions, summary.Bytes, summary.Elapsed)
+	if title, ok := s.titles[itemName]; ok {
+		line += fmt.Sprintf(" title=%q", title)
+	}
+	s.printf("%s", line)
+}
+
+// SendDiskSpace 在剩余空间状态变化时输出剩余空间.
+func (s *NullProgressSink) SendDiskSpace(free, required uint64, level tui.DiskLevel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if level == s.diskLevel {
+		return
+	}
+	s.diskLevel = level
+	if level == tui.DiskLow || level == tui.DiskCritical {
+		s.printf("event=disk free=%d required=%d paused=%t", free, required, level == tui.DiskCritical)
+	}
+}
+
+// TrackFile 不跟踪文件停滞，返回空的回调.
+func (s *NullProgressSink) TrackFile(string, context.CancelCauseFunc) (func(int64), func()) {
+	return nil, func() {}
+}
+
+// Context 返回创建时传入的上下文.
+func (s *NullProgressSink) Context() context.Context {
+	return s.ctx
+}
+
+// SetTotalModels 设置批量下载的模型总数.
+func (s *NullProgressSink) SetTotalModels(total int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total, s.completed = total, 0
+	s.printf("event=batch total=%d", total)
+}
+
+// UpdateTotalProgress 输出批量下载的总体进度.
+func (s *NullProgressSink) UpdateTotalProgress() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed++
+	s.printf("event=batch completed=%d total=%d", s.completed, s.total)
+}
+
+// SetItemGroup 不需要分组显示，忽略.
+func (s *NullProgressSink) SetItemGroup(string, string, string) {}
+
+// SetItemTitle 记录模型的服装标题，在下载完成时输出.
+func (s *NullProgressSink) SetItemTitle(name, title string) {
+	if title == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.titles[name] = title
+}
+
+// SendSpaceEstimate 输出所需空间的估算结果.
+func (s *NullProgressSink) SendSpaceEstimate(text string, insufficient bool) {
+	if text == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.printf("event=estimate estimate=%q insufficient=%t", text, insufficient)
+}
+
+// itemRegistry 记录已向进度接收者登记的模型
+// 下载器是登记下载项的唯一入口，同一模型重复登记不会重置进度，已完成的文件数只增不减.
+type itemRegistry struct {
+	mu    sync.Mutex
+	items map[string]*progressItem // 已登记的模型，key 为模型名称
+}
+
+// progressItem 表示一个已登记的模型
+// mu 保证同一模型的登记与进度按顺序送达进度接收者.
+type progressItem struct {
+	mu        sync.Mutex
+	total     int // 文件总数，尚未知道时为 0
+	completed int // 已完成的文件数
+}
+
+// newItemRegistry 创建空的登记表.
+func newItemRegistry() *itemRegistry {
+	return &itemRegistry{items: make(map[string]*progressItem)}
+}
+
+// item 返回模型的登记记录，不存在时创建并返回 true.
+func (r *itemRegistry) item(name string) (*progressItem, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if item, ok := r.items[name]; ok {
+		return item, false
+	}
+	item := &progressItem{}
+	r.items[name] = item
+	return item, true
+}
+
+// EnsureItem 在进度接收者中登记模型，可以重复调用
+// 模型已登记时只在文件总数变化时更新总数，不会重置已完成的文件数，
+// 因此批量下载开始时的占位登记与构建器登记的实际文件总数不会互相覆盖
+// 参数:
+//   - name: 模型名称
+//   - totalFiles: 文件总数，为 0 时表示尚未知道，只在模型未登记时登记
+func (d *Downloader) EnsureItem(name string, totalFiles int) {
+	item, created := d.items.item(name)
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if !created && (totalFiles <= 0 || totalFiles == item.total) {
+		return
+	}
+	if totalFiles > 0 {
+		item.total = totalFiles
+	}
+	if d.Progress != nil {
+		d.Progress.AddDownloadItem(name, item.total)
+	}
+}
+
+// forget 在模型构建结束后移除登记记录，之后再次下载同一模型时重新登记并从 0 开始报告进度
+// 同一模型的构建不会同时进行，移除记录不会影响正在进行的构建.
+func (r *itemRegistry) forget(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.items, name)
+}
+
+// reportItemProgress 向进度接收者报告模型已完成的文件数，不比已报告的值大时忽略.
+func (d *Downloader) reportItemProgress(name string, completed int) {
+	item, _ := d.items.item(name)
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if completed <= item.completed {
+		return
+	}
+	item.completed = completed
+	if d.Progress != nil {
+		d.Progress.UpdateProgress(name, completed)
+	}
+}
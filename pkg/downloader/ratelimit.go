@@ -0,0 +1,46 @@
+package downloader
+
+import (
+	"context"
+	"io"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+
+	"golang.org/x/time/rate"
+)
+
+// maxRateBurst 是速率限制器一次最多放行的字节数
+// 限速较高时不必攒满一秒的令牌，避免刚开始下载时瞬间超出限速.
+const maxRateBurst = 64 * 1024
+
+// newRateLimiter 按配置创建所有下载共享的速率限制器，不限速时返回 nil.
+func newRateLimiter(cfg *config.Config) *rate.Limiter {
+	bytesPerSecond := cfg.BandwidthLimit()
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	burst := int(min(bytesPerSecond, maxRateBurst))
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+// rateLimitedReader 在读取数据后等待速率限制器放行
+// 所有并发下载共享同一个限制器，限制的是总下载速率而不是单个连接的速率.
+type rateLimitedReader struct {
+	ctx     context.Context // 请求的上下文，取消时停止等待
+	r       io.Reader       // 实际读取的来源
+	limiter *rate.Limiter   // 共享的速率限制器
+}
+
+// Read 读取不超过限制器单次放行量的数据，并等待限制器放行读取的字节数.
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	if burst := l.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := l.r.Read(p)
+	if n > 0 {
+		if waitErr := l.limiter.WaitN(l.ctx, n); waitErr != nil {
+			return n, waitErr //nolint:wrapcheck // 由 writeFileContent 包装错误
+		}
+	}
+	return n, err //nolint:wrapcheck // 透传底层读取错误
+}
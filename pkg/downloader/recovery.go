@@ -0,0 +1,125 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// verifyFailuresBeforeCacheBust 是同一 URL 校验失败多少次后改用绕过缓存的请求.
+const verifyFailuresBeforeCacheBust = 2
+
+// isVerificationError 判断下载错误是否为文件校验失败
+// 收到的内容与 Content-Length 声明的大小不一致时，中间缓存可能保存了损坏的副本，重试同一 URL 会得到相同的内容.
+func isVerificationError(err error) bool {
+	return errors.Is(err, errs.ErrIncompleteDownload) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// recoverVerification 在文件多次校验失败后改用其他方式下载
+// 先附加 ?t=<unix> 查询参数与 Cache-Control: no-cache 请求头绕过中间缓存重新下载一次，
+// 仍然失败时按 ServerFallback 的顺序依次从其他服务器下载；
+// 成功时在来源信息中记录最终生效的方式并写入日志，便于附上证据向上游报告缓存问题
+// 参数:
+//   - ctx: 上下文
+//   - assetsURL: 校验失败的资源基础 URL
+//   - bundleFile: 资源包文件信息
+//   - filePath: 保存路径
+//   - allowNotFound: 是否允许文件不存在，只对原服务器生效
+//   - onBytes: 接收到数据时的回调，可以为 nil
+//   - cause: 原 URL 最后一次校验失败的错误
+//
+// 返回:
+//   - model.FileProvenance: 文件来源信息
+//   - error: 所有方式均失败时返回包含 cause 的错误
+func (d *Downloader) recoverVerification(
+	ctx context.Context,
+	assetsURL string,
+	bundleFile model.BundleFile,
+	filePath string,
+	allowNotFound bool,
+	onBytes func(int64),
+	cause error,
+) (model.FileProvenance, error) {
+	logger := log.FromContext(ctx)
+	logger.Warn().Str("filePath", filePath).Err(cause).Msg("文件多次校验失败，改用绕过缓存的请求重新下载")
+
+	// 部分文件可能来自损坏的缓存，换用其他方式时从头下载
+	discardPart(ctx, filePath)
+	provenance, err := d.downloadBundleFileAdaptive(ctx, assetsURL, bundleFile, filePath, allowNotFound, onBytes, true)
+	if err == nil {
+		return recovered(ctx, provenance, model.DownloadRecoveryCacheBust, filePath, cause), nil
+	}
+	if errs.IsCancelled(err) {
+		return provenance, err
+	}
+	logger.Warn().Str("filePath", filePath).Err(err).Msg("绕过缓存后仍然下载失败，尝试其他服务器")
+
+	for _, server := range fallbackServers(assetsURL) {
+		fallbackURL, urlErr := config.ServerAssetsURL(assetsURL, server)
+		if urlErr != nil {
+			logger.Warn().Str("server", server).Err(urlErr).Msg("跳过无效的回退服务器")
+			continue
+		}
+		// 其他服务器上不存在的文件不能视为下载成功
+		discardPart(ctx, filePath)
+		provenance, err = d.downloadBundleFileAdaptive(ctx, fallbackURL, bundleFile, filePath, false, onBytes, false)
+		if err == nil {
+			return recovered(ctx, provenance, model.DownloadRecoveryFallbackServer, filePath, cause), nil
+		}
+		if errs.IsCancelled(err) {
+			return provenance, err
+		}
+		logger.Warn().Str("filePath", filePath).Str("server", server).Err(err).Msg("从其他服务器下载文件失败")
+	}
+
+	logger.Error().Str("filePath", filePath).Err(cause).Msg("绕过缓存并切换服务器后文件仍然校验失败")
+	return model.FileProvenance{}, fmt.Errorf("绕过缓存并切换服务器后仍然失败: %w", cause)
+}
+
+// discardPart 删除文件断点续传用的部分文件，删除失败只记录日志.
+func discardPart(ctx context.Context, filePath string) {
+	if err := fsutil.RemovePart(filePath); err != nil {
+		log.FromContext(ctx).Warn().Str("filePath", filePath).Err(err).Msg("删除部分文件失败")
+	}
+}
+
+// fallbackServers 返回校验失败时依次尝试的其他服务器，即 ServerFallback 中除 assetsURL 所在服务器以外的服务器.
+func fallbackServers(assetsURL string) []string {
+	current := config.AssetsURLServer(assetsURL)
+	var servers []string
+	for _, server := range config.Get().ServerFallback {
+		if server != current {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// recovered 在来源信息中记录最终生效的下载方式，并写入包含实际 URL 的日志
+// 文件因允许不存在而被跳过时来源信息为零值，原样返回.
+func recovered(
+	ctx context.Context,
+	provenance model.FileProvenance,
+	recovery model.DownloadRecovery,
+	filePath string,
+	cause error,
+) model.FileProvenance {
+	if provenance.Source == "" {
+		return provenance
+	}
+	provenance.Recovery = recovery
+	log.FromContext(ctx).Warn().
+		Str("filePath", filePath).
+		Str("recovery", string(recovery)).
+		Str("url", provenance.URL).
+		AnErr("cause", cause).
+		Msg("文件校验失败后改用其他方式下载成功")
+	return provenance
+}
@@ -0,0 +1,47 @@
+package downloader
+
+import (
+	"slices"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// ScheduleModels 按调度方式排列批量下载中模型的开始顺序
+// RunBatch 在有空闲位置时按列表顺序开始下一个模型，而批次中的模型在开始前就已全部确定，
+// 因此按文件数从少到多排列等价于每次空出位置时都选择剩余文件数最少的模型；
+// 只调整顺序，同时下载的模型数仍由 MaxConcurrentModels 限制，不引入额外的锁
+// 参数:
+//   - names: 模型名称列表
+//   - buildData: 已获取的构建数据，key 为模型名称
+//   - scheduling: 调度方式
+//
+// 返回:
+//   - []string: 排列后的模型名称列表，文件数相同的模型保持原有顺序，没有构建数据的模型排在最后
+func ScheduleModels(names []string, buildData map[string]*model.BuildData, scheduling model.ModelScheduling) []string {
+	ordered := slices.Clone(names)
+	if scheduling != model.ModelSchedulingFewestFiles {
+		return ordered
+	}
+
+	fileCount := func(name string) int {
+		data, ok := buildData[name]
+		if !ok || data == nil {
+			return -1
+		}
+		return len(buildDataFiles(data))
+	}
+	slices.SortStableFunc(ordered, func(a, b string) int {
+		countA, countB := fileCount(a), fileCount(b)
+		switch {
+		case countA == countB:
+			return 0
+		case countA < 0:
+			return 1
+		case countB < 0:
+			return -1
+		default:
+			return countA - countB
+		}
+	})
+	return ordered
+}
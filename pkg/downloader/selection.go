@@ -0,0 +1,157 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/version"
+)
+
+// SelectionFormatVersion 是当前的选择文件格式版本
+// 格式发生不兼容变化时递增，并在 migrateSelectionFile 中处理旧版本.
+const SelectionFormatVersion = 1
+
+// SelectionEntry 表示选择文件中的单个服装.
+type SelectionEntry struct {
+	Costume string `json:"costume"`         // 服装（Live2D 模型）名称
+	CharaID int    `json:"charaId"`         // 角色ID，由服装名称的前缀得出
+	Chara   string `json:"chara,omitempty"` // 导出时显示的角色名称，仅供阅读
+}
+
+// SelectionFile 表示可以分享给他人的服装选择
+// 与任务文件不同，只记录选了哪些服装，不包含保存路径和下载选项.
+type SelectionFile struct {
+	FormatVersion int              `json:"formatVersion"` // 选择文件格式版本
+	AppVersion    string           `json:"appVersion"`    // 导出时的程序版本
+	CreatedAt     time.Time        `json:"createdAt"`     // 导出时间
+	Server        string           `json:"server"`        // 导出时资源所在的服务器
+	Costumes      []SelectionEntry `json:"costumes"`      // 选中的服装
+}
+
+// NewSelectionFile 创建服装选择
+// 参数:
+//   - server: 资源所在的服务器
+//   - chara: 服装所属的角色名称，服装来自多个角色时为空
+//   - costumes: 选中的服装名称
+//
+// 返回:
+//   - *SelectionFile: 服装选择
+func NewSelectionFile(server, chara string, costumes []string) *SelectionFile {
+	entries := make([]SelectionEntry, 0, len(costumes))
+	for _, costume := range costumes {
+		entries = append(entries, SelectionEntry{Costume: costume, CharaID: costumeCharaID(costume), Chara: chara})
+	}
+	return &SelectionFile{
+		FormatVersion: SelectionFormatVersion,
+		AppVersion:    version.GetVersion(),
+		CreatedAt:     time.Now(),
+		Server:        server,
+		Costumes:      entries,
+	}
+}
+
+// costumeCharaID 从服装名称的数字前缀得出角色ID，无法解析时返回 0.
+func costumeCharaID(costume string) int {
+	id, _ := model.Live2dCharaID(costume)
+	return id
+}
+
+// WriteSelectionFile 将服装选择写入文件
+// 参数:
+//   - path: 选择文件路径
+//   - selection: 服装选择
+//
+// 返回:
+//   - error: 错误信息
+func WriteSelectionFile(path string, selection *SelectionFile) error {
+	data, err := json.MarshalIndent(selection, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化选择文件失败: %w", err)
+	}
+	if writeErr := os.WriteFile(path, data, 0600); writeErr != nil {
+		return fmt.Errorf("写入选择文件失败: %w", writeErr)
+	}
+	return nil
+}
+
+// ReadSelectionFile 读取并校验服装选择
+// 旧版本的选择文件会被迁移为当前格式，服装名称会被规范化
+// 参数:
+//   - path: 选择文件路径
+//
+// 返回:
+//   - *SelectionFile: 服装选择
+//   - error: 错误信息
+func ReadSelectionFile(path string) (*SelectionFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取选择文件失败: %w", err)
+	}
+	var selection SelectionFile
+	if unmarshalErr := json.Unmarshal(data, &selection); unmarshalErr != nil {
+		return nil, fmt.Errorf("解析选择文件失败: %w", unmarshalErr)
+	}
+	if migrateErr := migrateSelectionFile(&selection); migrateErr != nil {
+		return nil, migrateErr
+	}
+
+	if len(selection.Costumes) == 0 {
+		return nil, fmt.Errorf("选择文件中没有服装")
+	}
+	for i, entry := range selection.Costumes {
+		name, normalizeErr := model.NormalizeLive2dName(entry.Costume)
+		if normalizeErr != nil {
+			return nil, fmt.Errorf("第 %d 个服装: %w", i+1, normalizeErr)
+		}
+		selection.Costumes[i].Costume = name
+	}
+	return &selection, nil
+}
+
+// migrateSelectionFile 将旧版本的选择文件迁移为当前格式.
+func migrateSelectionFile(selection *SelectionFile) error {
+	switch {
+	case selection.FormatVersion <= 0:
+		return fmt.Errorf("无效的选择文件格式版本: %d", selection.FormatVersion)
+	case selection.FormatVersion > SelectionFormatVersion:
+		return fmt.Errorf("%w: 选择文件版本 %d，当前程序支持 %d",
+			errs.ErrUnsupportedSelectionVersion, selection.FormatVersion, SelectionFormatVersion)
+	}
+	// 目前只有版本 1，新增版本时在这里逐级迁移
+	return nil
+}
+
+// Resolve 按资源索引校验选择中的服装是否仍然存在
+// 不存在的服装（已下架或已改名）会单独返回，由调用方报告给用户
+// 参数:
+//   - exists: 判断服装是否存在于资源索引中
+//
+// 返回:
+//   - []string: 仍然存在的服装，保持文件中的顺序并去除重复
+//   - []string: 已不存在的服装
+//   - error: 校验过程中的错误
+func (s *SelectionFile) Resolve(exists func(costume string) (bool, error)) ([]string, []string, error) {
+	var found, unknown []string
+	seen := make(map[string]struct{}, len(s.Costumes))
+	for _, entry := range s.Costumes {
+		if _, dup := seen[entry.Costume]; dup {
+			continue
+		}
+		seen[entry.Costume] = struct{}{}
+
+		ok, err := exists(entry.Costume)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			found = append(found, entry.Costume)
+		} else {
+			unknown = append(unknown, entry.Costume)
+		}
+	}
+	return found, unknown, nil
+}
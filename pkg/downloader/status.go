@@ -0,0 +1,208 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+)
+
+// ModelState 表示模型在下载器中的状态.
+type ModelState int
+
+const (
+	ModelQueued      ModelState = iota // 等待空闲的模型并发名额
+	ModelDownloading                   // 正在下载文件
+	ModelVerifying                     // 文件下载完成，正在生成模型数据与下载清单
+	ModelDone                          // 构建完成
+	ModelFailed                        // 构建失败或被取消
+)
+
+// String 返回状态的名称.
+func (s ModelState) String() string {
+	switch s {
+	case ModelQueued:
+		return "queued"
+	case ModelDownloading:
+		return "downloading"
+	case ModelVerifying:
+		return "verifying"
+	case ModelDone:
+		return "done"
+	case ModelFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Finished 判断模型是否已结束构建.
+func (s ModelState) Finished() bool {
+	return s == ModelDone || s == ModelFailed
+}
+
+// ModelStatus 表示模型的构建状态.
+type ModelStatus struct {
+	Name      string     // 模型名称
+	State     ModelState // 当前状态
+	Total     int        // 文件总数，开始下载前为 0
+	Completed int        // 已完成的文件数，包括复用的已存在文件
+	Err       error      // 构建失败时的错误
+}
+
+// modelEntry 表示一次模型构建的跟踪记录.
+type modelEntry struct {
+	seq    int                     // 开始跟踪的顺序，用于按排队顺序返回状态
+	status ModelStatus             // 构建状态
+	cancel context.CancelCauseFunc // 取消本次构建
+}
+
+// statusTracker 记录下载器中所有模型的构建状态
+// 下载器是模型状态的唯一来源，TUI 只展示这里同步过去的进度.
+type statusTracker struct {
+	mu      sync.Mutex
+	entries map[string]*modelEntry // 每个模型最近一次构建的记录
+	seq     int                    // 下一条记录的顺序
+	pending int                    // 尚未结束的构建数
+	idle    chan struct{}          // 没有未结束的构建时关闭
+}
+
+// newStatusTracker 创建状态跟踪器.
+func newStatusTracker() *statusTracker {
+	idle := make(chan struct{})
+	close(idle)
+	return &statusTracker{entries: make(map[string]*modelEntry), idle: idle}
+}
+
+// begin 开始跟踪一次模型构建，同名模型之前的记录会被替换.
+func (t *statusTracker) begin(name string, cancel context.CancelCauseFunc) *modelEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.pending++
+	entry := &modelEntry{seq: t.seq, status: ModelStatus{Name: name, State: ModelQueued}, cancel: cancel}
+	t.seq++
+	t.entries[name] = entry
+	return entry
+}
+
+// update 在持有锁时修改构建状态，entry 为 nil 时（未通过 ConstructWithContext 构建）忽略.
+func (t *statusTracker) update(entry *modelEntry, fn func(status *ModelStatus)) {
+	if entry == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !entry.status.State.Finished() {
+		fn(&entry.status)
+	}
+}
+
+// finish 结束一次模型构建.
+func (t *statusTracker) finish(entry *modelEntry, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry.status.State.Finished() {
+		return
+	}
+	entry.status.State = ModelDone
+	if err != nil {
+		entry.status.State = ModelFailed
+		entry.status.Err = err
+	}
+	t.pending--
+	if t.pending == 0 {
+		close(t.idle)
+	}
+}
+
+// CancelModel 取消正在排队或构建中的模型
+// 参数:
+//   - name: 模型名称
+//
+// 返回:
+//   - bool: 模型正在排队或构建中时返回 true
+func (d *Downloader) CancelModel(name string) bool {
+	d.status.mu.Lock()
+	entry, ok := d.status.entries[name]
+	active := ok && !entry.status.State.Finished()
+	d.status.mu.Unlock()
+
+	if active {
+		entry.cancel(errs.ErrModelCancelled)
+	}
+	return active
+}
+
+// Active 返回下载器跟踪的所有模型的状态，按开始排队的顺序排列
+// 包括已结束的模型，同名模型只保留最近一次构建的状态
+// 返回:
+//   - []ModelStatus: 模型状态列表
+func (d *Downloader) Active() []ModelStatus {
+	d.status.mu.Lock()
+	defer d.status.mu.Unlock()
+
+	entries := make([]*modelEntry, 0, len(d.status.entries))
+	for _, entry := range d.status.entries {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b *modelEntry) int { return a.seq - b.seq })
+
+	statuses := make([]ModelStatus, len(entries))
+	for i, entry := range entries {
+		statuses[i] = entry.status
+	}
+	return statuses
+}
+
+// Wait 等待所有正在排队或构建中的模型结束
+// 参数:
+//   - ctx: 上下文，取消时停止等待
+//
+// 返回:
+//   - error: 上下文被取消时返回上下文的错误
+func (d *Downloader) Wait(ctx context.Context) error {
+	d.status.mu.Lock()
+	idle := d.status.idle
+	d.status.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("等待下载结束已取消: %w", ctx.Err())
+	case <-idle:
+		return nil
+	}
+}
+
+// setState 更新模型的构建状态.
+func (b *Live2dBuilder) setState(state ModelState) {
+	b.downloader.status.update(b.status, func(status *ModelStatus) { status.State = state })
+}
+
+// setTotalFiles 记录模型的文件总数并同步到进度接收者.
+func (b *Live2dBuilder) setTotalFiles(total int) {
+	b.downloader.status.update(b.status, func(status *ModelStatus) { status.Total = total })
+	b.downloader.EnsureItem(b.ModelName, total)
+}
+
+// updateProgress 记录模型已完成的文件数并同步到进度接收者.
+func (b *Live2dBuilder) updateProgress(completed int) {
+	b.downloader.status.update(b.status, func(status *ModelStatus) { status.Completed = completed })
+	b.downloader.reportItemProgress(b.ModelName, completed)
+}
+
+// cancelledError 在构建因 CancelModel 被取消时返回包裹 errs.ErrModelCancelled 的错误
+// 使调用方可以区分单个模型被取消与整个下载被取消.
+func (b *Live2dBuilder) cancelledError(ctx context.Context, err error) error {
+	if err != nil && errs.IsCancelled(err) && errors.Is(context.Cause(ctx), errs.ErrModelCancelled) {
+		return fmt.Errorf("%w: %s", errs.ErrModelCancelled, b.ModelName)
+	}
+	return err
+}
@@ -0,0 +1,30 @@
+package downloader
+
+import (
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/tui"
+)
+
+// Summary 返回构建完成的模型的文件统计
+// 参数:
+//   - elapsed: 构建耗时
+//
+// 返回:
+//   - tui.Summary: 文件统计
+func (b *Live2dBuilder) Summary(elapsed time.Duration) tui.Summary {
+	summary := tui.Summary{
+		HasModel:    b.model.Model != "",
+		HasPhysics:  b.model.Physics != "",
+		Textures:    len(b.model.Textures),
+		Expressions: len(b.model.Expressions),
+		Elapsed:     elapsed,
+	}
+	for _, motions := range b.model.Motions {
+		summary.Motions += len(motions)
+	}
+	for _, file := range b.manifest.Files {
+		summary.Bytes += file.Provenance.Size
+	}
+	return summary
+}
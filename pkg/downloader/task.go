@@ -0,0 +1,148 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/version"
+)
+
+// TaskFormatVersion 是当前的任务文件格式版本
+// 格式发生不兼容变化时递增，并在 migrateTaskFile 中处理旧版本.
+const TaskFormatVersion = 1
+
+// TaskOptions 表示任务文件中的下载选项.
+type TaskOptions struct {
+	FailFast           bool                   `json:"failFast"`                   // 是否在第一个模型失败后中止批次
+	OutputLayout       model.OutputLayout     `json:"outputLayout,omitempty"`     // 模型文件的组织方式
+	LayoutPreset       string                 `json:"layoutPreset,omitempty"`     // 指定使用的布局预设
+	TextureAlphaMode   model.TextureAlphaMode `json:"textureAlphaMode,omitempty"` // 纹理 alpha 通道的修正方式
+	GenerateModelIndex bool                   `json:"generateModelIndex"`         // 是否生成动作与表情索引
+}
+
+// TaskFile 表示可移植的下载任务
+// 包含服务器、模型列表、输出路径及下载选项，可以在另一台机器上原样执行.
+type TaskFile struct {
+	FormatVersion int              `json:"formatVersion"` // 任务文件格式版本
+	AppVersion    string           `json:"appVersion"`    // 导出时的程序版本
+	CreatedAt     time.Time        `json:"createdAt"`     // 导出时间
+	Server        string           `json:"server"`        // 资源所在的服务器
+	Models        []ModelListEntry `json:"models"`        // 模型列表
+	Options       TaskOptions      `json:"options"`       // 下载选项
+}
+
+// NewTaskFile 根据当前配置创建下载任务
+// 参数:
+//   - cfg: 程序配置
+//   - models: 模型列表
+//
+// 返回:
+//   - *TaskFile: 下载任务
+func NewTaskFile(cfg *config.Config, models []ModelListEntry) *TaskFile {
+	return &TaskFile{
+		FormatVersion: TaskFormatVersion,
+		AppVersion:    version.GetVersion(),
+		CreatedAt:     time.Now(),
+		Server:        cfg.AssetsServer(),
+		Models:        models,
+		Options: TaskOptions{
+			FailFast:           cfg.BatchFailFast,
+			OutputLayout:       cfg.OutputLayout,
+			LayoutPreset:       cfg.LayoutPreset,
+			TextureAlphaMode:   cfg.TextureAlphaMode,
+			GenerateModelIndex: cfg.GenerateModelIndex,
+		},
+	}
+}
+
+// WriteTaskFile 将下载任务写入文件
+// 参数:
+//   - path: 任务文件路径
+//   - task: 下载任务
+//
+// 返回:
+//   - error: 错误信息
+func WriteTaskFile(path string, task *TaskFile) error {
+	data, err := json.MarshalIndent(task, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化任务文件失败: %w", err)
+	}
+	if writeErr := os.WriteFile(path, data, 0600); writeErr != nil {
+		return fmt.Errorf("写入任务文件失败: %w", writeErr)
+	}
+	return nil
+}
+
+// ReadTaskFile 读取并校验下载任务
+// 旧版本的任务文件会被迁移为当前格式，模型名称会被规范化
+// 参数:
+//   - path: 任务文件路径
+//
+// 返回:
+//   - *TaskFile: 下载任务
+//   - error: 错误信息
+func ReadTaskFile(path string) (*TaskFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取任务文件失败: %w", err)
+	}
+	var task TaskFile
+	if unmarshalErr := json.Unmarshal(data, &task); unmarshalErr != nil {
+		return nil, fmt.Errorf("解析任务文件失败: %w", unmarshalErr)
+	}
+	if migrateErr := migrateTaskFile(&task); migrateErr != nil {
+		return nil, migrateErr
+	}
+
+	if len(task.Models) == 0 {
+		return nil, fmt.Errorf("任务文件中没有模型")
+	}
+	for i, entry := range task.Models {
+		name, normalizeErr := model.NormalizeLive2dName(entry.Name)
+		if normalizeErr != nil {
+			return nil, fmt.Errorf("第 %d 个模型: %w", i+1, normalizeErr)
+		}
+		task.Models[i].Name = name
+	}
+	return &task, nil
+}
+
+// migrateTaskFile 将旧版本的任务文件迁移为当前格式.
+func migrateTaskFile(task *TaskFile) error {
+	switch {
+	case task.FormatVersion <= 0:
+		return fmt.Errorf("无效的任务文件格式版本: %d", task.FormatVersion)
+	case task.FormatVersion > TaskFormatVersion:
+		return fmt.Errorf("%w: 任务文件版本 %d，当前程序支持 %d",
+			errs.ErrUnsupportedTaskVersion, task.FormatVersion, TaskFormatVersion)
+	}
+	// 目前只有版本 1，新增版本时在这里逐级迁移
+	return nil
+}
+
+// Apply 将下载任务中的服务器与下载选项应用到配置
+// 参数:
+//   - cfg: 程序配置
+//
+// 返回:
+//   - error: 错误信息
+func (t *TaskFile) Apply(cfg *config.Config) error {
+	if t.Server != "" {
+		if err := cfg.SetAssetsServer(t.Server); err != nil {
+			return err //nolint:wrapcheck // 错误信息已包含服务器名称
+		}
+	}
+	cfg.BatchFailFast = t.Options.FailFast
+	if t.Options.OutputLayout != "" {
+		cfg.OutputLayout = t.Options.OutputLayout
+	}
+	cfg.LayoutPreset = t.Options.LayoutPreset
+	cfg.TextureAlphaMode = t.Options.TextureAlphaMode
+	cfg.GenerateModelIndex = t.Options.GenerateModelIndex
+	return nil
+}
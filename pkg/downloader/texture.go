@@ -0,0 +1,135 @@
+package downloader
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// SelectTextureAlphaMode 为模型选择纹理 alpha 通道的修正方式
+// 优先使用按模型指定的方式，否则使用默认方式，无法识别的方式视为不处理
+// 参数:
+//   - cfg: 程序配置
+//   - modelName: 模型名称
+//
+// 返回:
+//   - model.TextureAlphaMode: 修正方式
+func SelectTextureAlphaMode(cfg *config.Config, modelName string) model.TextureAlphaMode {
+	mode := cfg.TextureAlphaMode
+	if override, ok := cfg.TextureAlphaModels[modelName]; ok {
+		mode = override
+	}
+
+	switch mode {
+	case model.TextureAlphaNone, model.TextureAlphaPremultiply, model.TextureAlphaUnpremultiply:
+		return mode
+	default:
+		log.DefaultLogger.Warn().Str("modelName", modelName).Str("mode", string(mode)).Msg("无法识别的纹理 alpha 修正方式，跳过处理")
+		return model.TextureAlphaNone
+	}
+}
+
+// ProcessTextureAlpha 对 png 纹理进行 alpha 通道修正并覆盖原文件
+// 非 png 文件、不处理的模式以及修正后没有变化的纹理会被跳过
+// 参数:
+//   - path: 纹理文件路径
+//   - mode: 修正方式
+//
+// 返回:
+//   - bool: 纹理是否被修改
+//   - error: 错误信息
+func ProcessTextureAlpha(path string, mode model.TextureAlphaMode) (bool, error) {
+	if mode == model.TextureAlphaNone || !strings.EqualFold(filepath.Ext(path), ".png") {
+		return false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("打开纹理失败: %w", err)
+	}
+	src, err := png.Decode(file)
+	file.Close()
+	if err != nil {
+		return false, fmt.Errorf("解码纹理失败: %w", err)
+	}
+
+	img, ok := src.(*image.NRGBA)
+	if !ok {
+		img = image.NewNRGBA(src.Bounds())
+		draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+	}
+	if !applyAlphaMode(img, mode) {
+		return false, nil
+	}
+
+	tmp, err := fsutil.CreateTemp(path)
+	if err != nil {
+		return false, err
+	}
+	if encodeErr := png.Encode(tmp, img); encodeErr != nil {
+		fsutil.DiscardTemp(tmp)
+		return false, fmt.Errorf("编码纹理失败: %w", encodeErr)
+	}
+	if commitErr := fsutil.CommitTemp(tmp, path); commitErr != nil {
+		return false, commitErr
+	}
+	return true, nil
+}
+
+// applyAlphaMode 按修正方式就地修改图像的 RGB 通道
+// 完全不透明与完全透明的像素保持不变，返回是否有像素被修改.
+func applyAlphaMode(img *image.NRGBA, mode model.TextureAlphaMode) bool {
+	changed := false
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		alpha := uint32(img.Pix[i+3])
+		if alpha == 0xff || alpha == 0 {
+			continue
+		}
+		for c := i; c < i+3; c++ {
+			value := uint32(img.Pix[c])
+			switch mode {
+			case model.TextureAlphaPremultiply:
+				value = (value*alpha + 0x7f) / 0xff
+			case model.TextureAlphaUnpremultiply:
+				value = min((value*0xff+alpha/2)/alpha, 0xff)
+			case model.TextureAlphaNone:
+			}
+			if uint8(value) != img.Pix[c] { //nolint:gosec // value 不超过 255
+				img.Pix[c] = uint8(value) //nolint:gosec // value 不超过 255
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// postProcessTexture 对新下载的纹理按模型配置进行 alpha 通道修正
+// 纹理被修改时同步更新来源信息中的文件大小.
+func (b *Live2dBuilder) postProcessTexture(task downloadTask, provenance *model.FileProvenance) error {
+	if task.kind != FileKindTexture {
+		return nil
+	}
+	filePath := task.filePath
+	mode := SelectTextureAlphaMode(config.Get(), b.ModelName)
+	changed, err := ProcessTextureAlpha(filePath, mode)
+	if err != nil {
+		return fmt.Errorf("修正纹理 alpha 通道失败: %w", err)
+	}
+	if !changed {
+		return nil
+	}
+	if info, statErr := os.Stat(filePath); statErr == nil {
+		provenance.Size = info.Size()
+	}
+	b.logger.Debug().Str("modelName", b.ModelName).Str("filePath", filePath).Str("mode", string(mode)).Msg("已修正纹理 alpha 通道")
+	return nil
+}
@@ -0,0 +1,75 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// ReadTextureMeta 读取纹理的尺寸与格式
+// 只解码图片头部，无法打开或解码的纹理标记为未知
+// 参数:
+//   - modelPath: 模型目录
+//   - relPath: 纹理文件路径，相对于模型目录
+//
+// 返回:
+//   - model.TextureMeta: 纹理元信息
+//   - error: 无法读取时的原因，此时元信息标记为未知
+func ReadTextureMeta(modelPath, relPath string) (model.TextureMeta, error) {
+	meta := model.TextureMeta{File: relPath}
+	file, err := os.Open(filepath.Join(modelPath, filepath.FromSlash(relPath)))
+	if err != nil {
+		meta.Unknown = true
+		return meta, fmt.Errorf("打开纹理失败: %w", err)
+	}
+	defer file.Close()
+
+	cfg, format, err := image.DecodeConfig(file)
+	if err != nil {
+		meta.Unknown = true
+		return meta, fmt.Errorf("解码纹理失败: %w", err)
+	}
+	meta.Width, meta.Height, meta.Format = cfg.Width, cfg.Height, format
+	return meta, nil
+}
+
+// writeTexturesMeta 在模型目录生成纹理元信息
+// 未启用时不生成，无法解码的纹理记录为未知，不影响构建结果.
+func (b *Live2dBuilder) writeTexturesMeta() error {
+	if !config.Get().GenerateTextureMeta {
+		return nil
+	}
+
+	meta := model.TexturesMeta{
+		Model:    b.ModelName,
+		Count:    len(b.model.Textures),
+		Textures: make([]model.TextureMeta, 0, len(b.model.Textures)),
+	}
+	for _, texture := range b.model.Textures {
+		textureMeta, err := ReadTextureMeta(b.path, texture)
+		if err != nil {
+			b.logger.Warn().Str("modelName", b.ModelName).Str("texture", texture).Err(err).Msg("无法读取纹理尺寸，记录为未知")
+		}
+		meta.Textures = append(meta.Textures, textureMeta)
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Err(err).Msg("序列化纹理元信息失败")
+		return fmt.Errorf("序列化纹理元信息失败: %w", err)
+	}
+
+	metaPath := filepath.Join(b.path, model.TexturesMetaFileName)
+	if writeErr := os.WriteFile(metaPath, data, 0600); writeErr != nil {
+		b.logger.Error().Str("modelName", b.ModelName).Str("path", metaPath).Err(writeErr).Msg("写入纹理元信息失败")
+		return fmt.Errorf("写入纹理元信息失败: %w", writeErr)
+	}
+
+	b.logger.Info().Str("modelName", b.ModelName).Str("path", metaPath).Msg("纹理元信息写入完成")
+	return nil
+}
@@ -0,0 +1,79 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+)
+
+// warmupTimeout 是连接预热的最长等待时间.
+const warmupTimeout = 5 * time.Second
+
+// WarmupConnections 预先与资源主机建立连接（包括 TLS 握手）
+// 用于避免批量下载开始时大量请求同时建连，预热失败只记录日志，不影响后续下载
+// 参数:
+//   - ctx: 上下文
+func (d *Downloader) WarmupConnections(ctx context.Context) {
+	cfg := config.Get()
+	count := cfg.WarmupConnections
+	if count <= 0 {
+		return
+	}
+
+	target, err := url.Parse(cfg.BaseAssetsURL)
+	if err != nil {
+		log.DefaultLogger.Warn().Str("url", cfg.BaseAssetsURL).Err(err).Msg("解析资源地址失败，跳过连接预热")
+		return
+	}
+	warmupURL := (&url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/"}).String()
+
+	ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for range count {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if warmupErr := d.warmupConnection(ctx, warmupURL); warmupErr != nil {
+				log.DefaultLogger.Debug().Str("url", warmupURL).Err(warmupErr).Msg("预热连接失败")
+				return
+			}
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	log.DefaultLogger.Info().
+		Str("host", target.Host).
+		Int("requested", count).
+		Int("succeeded", succeeded).
+		Dur("elapsed", time.Since(start)).
+		Msg("连接预热完成")
+}
+
+// warmupConnection 发送一个 HEAD 请求以建立连接，响应体读完后连接会回到空闲连接池.
+func (d *Downloader) warmupConnection(ctx context.Context, warmupURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, warmupURL, nil)
+	if err != nil {
+		return fmt.Errorf("创建预热请求失败: %w", err)
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("预热连接失败: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
@@ -0,0 +1,67 @@
+// Package errs 定义了程序中使用的领域错误
+// 调用方应使用 errors.Is 判断错误类型，而不是比较错误文案
+package errs
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrDownloadCancelled 表示下载已取消.
+	ErrDownloadCancelled = errors.New("下载已取消")
+	// ErrModelCancelled 表示单个模型的下载被取消，其他模型的下载不受影响.
+	ErrModelCancelled = errors.New("模型下载已取消")
+	// ErrBatchAborted 表示批量下载因快速失败而中止.
+	ErrBatchAborted = errors.New("批量下载已中止")
+	// ErrSkippedFailFast 表示模型因批次快速失败而被跳过.
+	ErrSkippedFailFast = errors.New("已跳过（快速失败）")
+	// ErrModelNotFound 表示 Live2D 模型不存在.
+	ErrModelNotFound = errors.New("模型不存在")
+	// ErrNotFound 表示请求的资源不存在（HTTP 404）.
+	ErrNotFound = errors.New("资源不存在")
+	// ErrFileUnavailable 表示文件不存在或无法访问（例如返回了 HTML 错误页面）.
+	ErrFileUnavailable = errors.New("文件不存在或无法访问")
+	// ErrFileStalled 表示单个文件下载超过了超时阈值.
+	ErrFileStalled = errors.New("文件下载超时")
+	// ErrStallCancelled 表示停滞的文件下载被用户取消，下载器会重新连接.
+	ErrStallCancelled = errors.New("停滞的文件下载已取消")
+	// ErrHTTPStatus 表示服务器返回了 404 以外的非 2xx 状态码，可能是暂时性的，可以重试.
+	ErrHTTPStatus = errors.New("HTTP状态码异常")
+	// ErrIncompleteDownload 表示写入的字节数与响应的 Content-Length 不一致.
+	ErrIncompleteDownload = errors.New("下载的文件不完整")
+	// ErrOffline 表示离线模式下请求的数据没有可用的缓存.
+	ErrOffline = errors.New("离线且无缓存")
+	// ErrUnsupportedModelSchema 表示模型数据由更新版本的程序生成，格式版本不受支持.
+	ErrUnsupportedModelSchema = errors.New("不支持的模型数据格式版本")
+	// ErrUnsupportedTaskVersion 表示任务文件由更新版本的程序生成，格式版本不受支持.
+	ErrUnsupportedTaskVersion = errors.New("不支持的任务文件版本")
+	// ErrUnsupportedSelectionVersion 表示选择文件由更新版本的程序生成，格式版本不受支持.
+	ErrUnsupportedSelectionVersion = errors.New("不支持的选择文件版本")
+	// ErrUserQuit 表示用户主动退出程序.
+	ErrUserQuit = errors.New("用户退出程序")
+	// ErrServerConflict 表示模型与角色目录中已有模型来自不同服务器且用户选择了中止.
+	ErrServerConflict = errors.New("角色目录服务器冲突")
+	// ErrOtherDirectoryLayout 表示模型已按另一种目录布局下载，为避免重复下载而跳过.
+	ErrOtherDirectoryLayout = errors.New("模型已按另一种目录布局下载")
+	// ErrInvalidLive2dName 表示 Live2D 名称格式无效.
+	ErrInvalidLive2dName = errors.New("无效的Live2D名称格式")
+	// ErrInvalidAssetsIndex 表示资源索引格式无效.
+	ErrInvalidAssetsIndex = errors.New("无效的资源索引格式")
+	// ErrReferenceOutsideModel 表示模型数据引用了模型目录之外的文件.
+	ErrReferenceOutsideModel = errors.New("模型数据引用了模型目录之外的文件")
+	// ErrInvalidModelHash 表示整模型哈希记录格式无效.
+	ErrInvalidModelHash = errors.New("整模型哈希记录格式错误")
+	// ErrInvalidBuildData 表示构建数据格式无效.
+	ErrInvalidBuildData = errors.New("构建数据格式错误")
+	// ErrLayoutChanged 表示大量请求因资源结构不符合预期而失败，Bestdori 的接口或资源路径可能已变化.
+	ErrLayoutChanged = errors.New("Bestdori 资源结构可能已变化，请检查是否有新版本工具")
+	// ErrInvalidCommand 表示 RPC 模式下读取的命令格式无效或参数缺失.
+	ErrInvalidCommand = errors.New("无效的命令")
+)
+
+// IsCancelled 判断错误是否由取消操作引起
+// 包括主动取消下载以及上下文被取消的情况.
+func IsCancelled(err error) bool {
+	return errors.Is(err, ErrDownloadCancelled) || errors.Is(err, context.Canceled)
+}
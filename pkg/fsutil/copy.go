@@ -0,0 +1,39 @@
+package fsutil
+
+import (
+	"io"
+	"sync"
+)
+
+// copyBufferSize 是复制数据时使用的缓冲区大小，与 io.Copy 的默认值一致.
+const copyBufferSize = 32 * 1024
+
+// copyBufferPool 缓存复制数据用的缓冲区
+// 批量下载大量小文件时，避免每个文件都分配一次缓冲区.
+//
+//nolint:gochecknoglobals // 缓冲池需要在所有下载之间共享
+var copyBufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, copyBufferSize)
+		return &buf
+	},
+}
+
+// writerOnly 隐藏目标的 ReadFrom 方法，确保 io.CopyBuffer 使用传入的缓冲区.
+type writerOnly struct {
+	io.Writer
+}
+
+// Copy 使用池化的缓冲区将 src 复制到 dst
+// 参数:
+//   - dst: 写入目标
+//   - src: 数据来源
+//
+// 返回:
+//   - int64: 复制的字节数
+//   - error: 错误信息
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	bufPtr, _ := copyBufferPool.Get().(*[]byte)
+	defer copyBufferPool.Put(bufPtr)
+	return io.CopyBuffer(writerOnly{dst}, src, *bufPtr) //nolint:wrapcheck // 由调用方包装错误
+}
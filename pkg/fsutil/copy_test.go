@@ -0,0 +1,75 @@
+package fsutil_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+// readerOnly 模拟 HTTP 响应体，只实现 io.Reader.
+type readerOnly struct {
+	io.Reader
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "空数据", data: ""},
+		{name: "小于缓冲区", data: "test"},
+		{name: "大于缓冲区", data: strings.Repeat("live2d", 20000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var dst bytes.Buffer
+			written, err := fsutil.Copy(&dst, readerOnly{strings.NewReader(tt.data)})
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.data)), written)
+			assert.Equal(t, tt.data, dst.String())
+		})
+	}
+}
+
+// writerOnly 模拟没有缓冲池的写入目标，只实现 io.Writer.
+type writerOnly struct {
+	io.Writer
+}
+
+// BenchmarkCopy 模拟下载上千个小文件，对比 io.Copy 与池化缓冲区的内存分配.
+func BenchmarkCopy(b *testing.B) {
+	const files = 1000
+	data := bytes.Repeat([]byte("x"), 8*1024)
+
+	benchmarks := []struct {
+		name string
+		copy func(io.Writer, io.Reader) (int64, error)
+	}{
+		{name: "io.Copy", copy: io.Copy},
+		{name: "pooled", copy: fsutil.Copy},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				for range files {
+					if _, err := bm.copy(writerOnly{io.Discard}, readerOnly{bytes.NewReader(data)}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package fsutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// windowsReservedNames 是 Windows 上不能用作文件名的设备名，不区分大小写且忽略扩展名.
+//
+//nolint:gochecknoglobals // 只读的查找表
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeFileName 清洗文件名，使其可以在常见的文件系统上使用
+// 将路径分隔符、Windows 不允许的字符和控制字符替换为下划线，去掉首尾的空白与末尾的点，
+// 并为 Windows 保留的设备名加上下划线前缀
+// 参数:
+//   - name: 文件名
+//
+// 返回:
+//   - string: 清洗后的文件名，只包含点或空白时返回空字符串
+func SanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimRight(strings.TrimSpace(name), ". ")
+
+	base, _, _ := strings.Cut(name, ".")
+	if windowsReservedNames[strings.ToUpper(strings.TrimSpace(base))] {
+		name = "_" + name
+	}
+	return name
+}
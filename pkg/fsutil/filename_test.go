@@ -0,0 +1,37 @@
+package fsutil_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"普通名称", "kasumi", "kasumi"},
+		{"保留空格与非 ASCII 字符", "戸山 香澄", "戸山 香澄"},
+		{"路径分隔符", "a/b\\c", "a_b_c"},
+		{"Windows 不允许的字符", `a<b>c:d"e|f?g*h`, "a_b_c_d_e_f_g_h"},
+		{"控制字符", "a\tb\x00c", "a_b_c"},
+		{"首尾空白与末尾的点", "  kasumi.. ", "kasumi"},
+		{"只有点", "..", ""},
+		{"Windows 保留名称", "con", "_con"},
+		{"带扩展名的保留名称", "Aux.txt", "_Aux.txt"},
+		{"包含保留名称的普通名称", "console", "console"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, fsutil.SanitizeFileName(tt.input))
+		})
+	}
+}
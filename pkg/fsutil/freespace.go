@@ -0,0 +1,58 @@
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrFreeSpaceUnsupported 表示当前平台不支持查询磁盘剩余空间.
+var ErrFreeSpaceUnsupported = errors.New("当前平台不支持查询磁盘剩余空间")
+
+// FreeSpace 返回路径所在卷中当前用户可用的剩余空间
+// 路径不存在时查询其最近的已存在上级目录所在的卷
+// 参数:
+//   - path: 文件或目录路径
+//
+// 返回:
+//   - uint64: 可用的剩余空间（字节）
+//   - error: 错误信息，平台不支持时返回 ErrFreeSpaceUnsupported
+func FreeSpace(path string) (uint64, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return 0, fmt.Errorf("解析路径失败: %w", err)
+	}
+	for {
+		_, statErr := os.Stat(dir)
+		if statErr == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if !errors.Is(statErr, fs.ErrNotExist) || parent == dir {
+			return 0, fmt.Errorf("查询磁盘剩余空间失败: %w", statErr)
+		}
+		dir = parent
+	}
+
+	free, err := freeSpace(dir)
+	if err != nil {
+		return 0, fmt.Errorf("查询磁盘剩余空间失败: %w", err)
+	}
+	return free, nil
+}
+
+// FormatBytes 将字节数格式化为便于阅读的文本，例如 1.5 GiB.
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package fsutil
+
+// freeSpace 在不支持的平台上总是返回 ErrFreeSpaceUnsupported.
+func freeSpace(string) (uint64, error) {
+	return 0, ErrFreeSpaceUnsupported
+}
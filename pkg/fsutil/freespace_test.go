@@ -0,0 +1,50 @@
+package fsutil_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+func TestFreeSpace(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	free, err := fsutil.FreeSpace(dir)
+	if errors.Is(err, fsutil.ErrFreeSpaceUnsupported) {
+		t.Skip("当前平台不支持查询剩余空间")
+	}
+	require.NoError(t, err)
+	assert.Positive(t, free)
+
+	// 不存在的路径按最近的已存在上级目录查询
+	missing, err := fsutil.FreeSpace(filepath.Join(dir, "a", "b"))
+	require.NoError(t, err)
+	assert.Positive(t, missing)
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		n    uint64
+		want string
+	}{
+		{name: "字节", n: 512, want: "512 B"},
+		{name: "KiB", n: 1536, want: "1.5 KiB"},
+		{name: "GiB", n: 2 << 30, want: "2.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, fsutil.FormatBytes(tt.n))
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+
+package fsutil
+
+import "golang.org/x/sys/unix"
+
+// freeSpace 使用 statfs 查询目录所在卷的剩余空间.
+func freeSpace(dir string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(dir, &stat); err != nil {
+		return 0, err //nolint:wrapcheck // 由 FreeSpace 统一包装
+	}
+	// 各平台字段类型不同，Bavail 为非特权用户可用的块数
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil //nolint:gosec,unconvert // 块数与块大小不会为负
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package fsutil
+
+import "golang.org/x/sys/windows"
+
+// freeSpace 使用 GetDiskFreeSpaceEx 查询目录所在卷的剩余空间.
+func freeSpace(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err //nolint:wrapcheck // 由 FreeSpace 统一包装
+	}
+	var available uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &available, nil, nil); err != nil {
+		return 0, err //nolint:wrapcheck // 由 FreeSpace 统一包装
+	}
+	return available, nil
+}
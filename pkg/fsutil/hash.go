@@ -0,0 +1,143 @@
+package fsutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// maxHashWorkers 是默认哈希并发数的上限
+// 哈希主要受磁盘读取速度限制，过多的并发反而会降低机械硬盘的吞吐.
+const maxHashWorkers = 8
+
+// HashResult 表示单个文件的哈希结果.
+type HashResult struct {
+	SHA256 string // 十六进制编码的 SHA-256 值
+	Size   int64  // 文件大小（字节）
+	Err    error  // 错误信息，不为 nil 时其他字段无效
+}
+
+// HashProgressFunc 表示哈希进度回调函数
+// 参数:
+//   - done: 已完成的文件数
+//   - total: 文件总数
+type HashProgressFunc func(done, total int)
+
+// DefaultHashWorkers 返回默认的哈希并发数，即 CPU 核心数且不超过 8.
+func DefaultHashWorkers() int {
+	return min(runtime.NumCPU(), maxHashWorkers)
+}
+
+// HashFiles 并发计算多个文件的 SHA-256
+// 参数:
+//   - ctx: 上下文，取消后尚未处理的文件返回上下文的错误
+//   - paths: 文件路径列表
+//   - workers: 并发数，小于等于 0 时使用 DefaultHashWorkers
+//
+// 返回:
+//   - map[string]HashResult: 哈希结果，key 为文件路径
+func HashFiles(ctx context.Context, paths []string, workers int) map[string]HashResult {
+	return HashFilesWithProgress(ctx, paths, workers, nil)
+}
+
+// HashFilesWithProgress 并发计算多个文件的 SHA-256，并报告进度
+// 参数:
+//   - ctx: 上下文，取消后尚未处理的文件返回上下文的错误
+//   - paths: 文件路径列表
+//   - workers: 并发数，小于等于 0 时使用 DefaultHashWorkers
+//   - onProgress: 进度回调，为 nil 时不报告进度，可能被多个协程调用但不会并发调用
+//
+// 返回:
+//   - map[string]HashResult: 哈希结果，key 为文件路径
+func HashFilesWithProgress(
+	ctx context.Context,
+	paths []string,
+	workers int,
+	onProgress HashProgressFunc,
+) map[string]HashResult {
+	if workers <= 0 {
+		workers = DefaultHashWorkers()
+	}
+
+	results := make(map[string]HashResult, len(paths))
+	var mu sync.Mutex
+	done := 0
+	record := func(path string, result HashResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[path] = result
+		done++
+		if onProgress != nil {
+			onProgress(done, len(paths))
+		}
+	}
+
+	pathChan := make(chan string)
+	var wg sync.WaitGroup
+	for range min(workers, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range pathChan {
+				if ctx.Err() != nil {
+					record(path, HashResult{Err: ctx.Err()})
+					continue
+				}
+				record(path, hashFile(ctx, path))
+			}
+		}()
+	}
+
+	for _, path := range paths {
+		pathChan <- path
+	}
+	close(pathChan)
+	wg.Wait()
+	return results
+}
+
+// hashFile 计算单个文件的 SHA-256.
+func hashFile(ctx context.Context, path string) HashResult {
+	file, err := os.Open(path)
+	if err != nil {
+		return HashResult{Err: fmt.Errorf("打开文件失败: %w", err)}
+	}
+	defer file.Close()
+	return HashReader(ctx, file)
+}
+
+// HashReader 计算读取到的全部内容的 SHA-256
+// 用于计算压缩包条目等不在磁盘上的内容的哈希
+// 参数:
+//   - ctx: 上下文，取消后中止读取
+//   - r: 数据来源
+//
+// 返回:
+//   - HashResult: 哈希结果
+func HashReader(ctx context.Context, r io.Reader) HashResult {
+	h := sha256.New()
+	size, err := io.Copy(h, &ctxReader{ctx: ctx, r: r})
+	if err != nil {
+		return HashResult{Err: fmt.Errorf("读取文件失败: %w", err)}
+	}
+	return HashResult{SHA256: hex.EncodeToString(h.Sum(nil)), Size: size}
+}
+
+// ctxReader 在上下文取消后中止读取，避免大文件哈希无法及时取消.
+type ctxReader struct {
+	ctx context.Context //nolint:containedctx // 仅在单次读取期间使用
+	r   io.Reader
+}
+
+// Read 读取数据，上下文已取消时返回上下文的错误.
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err //nolint:wrapcheck // 透传上下文错误
+	}
+	return c.r.Read(p) //nolint:wrapcheck // 透传底层读取错误
+}
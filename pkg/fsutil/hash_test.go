@@ -0,0 +1,103 @@
+package fsutil_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+// writeSyntheticTree 生成用于测试的文件树，返回文件路径列表.
+func writeSyntheticTree(tb testing.TB, count, size int) []string {
+	tb.Helper()
+
+	root := tb.TempDir()
+	paths := make([]string, 0, count)
+	for i := range count {
+		path := filepath.Join(root, fmt.Sprintf("dir%02d", i%10), fmt.Sprintf("file%03d.bin", i))
+		require.NoError(tb, os.MkdirAll(filepath.Dir(path), 0750))
+		data := make([]byte, size)
+		for j := range data {
+			data[j] = byte(i + j)
+		}
+		require.NoError(tb, os.WriteFile(path, data, 0600))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestHashFiles(t *testing.T) {
+	t.Parallel()
+
+	paths := writeSyntheticTree(t, 20, 4096)
+	missing := filepath.Join(filepath.Dir(paths[0]), "missing.bin")
+
+	var lastDone, lastTotal int
+	results := fsutil.HashFilesWithProgress(context.Background(), append(paths, missing), 4, func(done, total int) {
+		lastDone, lastTotal = done, total
+	})
+	require.Len(t, results, len(paths)+1)
+	assert.Equal(t, len(paths)+1, lastDone)
+	assert.Equal(t, len(paths)+1, lastTotal)
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		sum := sha256.Sum256(data)
+		require.NoError(t, results[path].Err)
+		assert.Equal(t, hex.EncodeToString(sum[:]), results[path].SHA256)
+		assert.Equal(t, int64(len(data)), results[path].Size)
+	}
+	require.ErrorIs(t, results[missing].Err, os.ErrNotExist)
+}
+
+func TestHashFilesCancelled(t *testing.T) {
+	t.Parallel()
+
+	paths := writeSyntheticTree(t, 10, 1024)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := fsutil.HashFiles(ctx, paths, 2)
+	require.Len(t, results, len(paths))
+	for _, result := range results {
+		require.ErrorIs(t, result.Err, context.Canceled)
+	}
+}
+
+func TestDefaultHashWorkers(t *testing.T) {
+	t.Parallel()
+
+	workers := fsutil.DefaultHashWorkers()
+	assert.Positive(t, workers)
+	assert.LessOrEqual(t, workers, 8)
+}
+
+func BenchmarkHashFiles(b *testing.B) {
+	paths := writeSyntheticTree(b, 200, 256*1024)
+
+	benchmarks := []struct {
+		name    string
+		workers int
+	}{
+		{name: "serial", workers: 1},
+		{name: "pooled", workers: fsutil.DefaultHashWorkers()},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(int64(len(paths)) * 256 * 1024)
+			for range b.N {
+				fsutil.HashFiles(context.Background(), paths, bm.workers)
+			}
+		})
+	}
+}
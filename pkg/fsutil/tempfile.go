@@ -0,0 +1,252 @@
+// Package fsutil 提供了文件系统相关的工具函数
+// 包括下载临时文件的创建与清理等功能
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 临时文件命名约定：<原文件名>.<随机串>.bdl.tmp，断点续传用的部分文件为 <原文件名>.part
+// 只有符合该约定的文件才会被 CleanTempFiles 清理，避免误删其他程序的临时文件.
+const (
+	tempMarker = ".bdl"
+	tempSuffix = tempMarker + ".tmp"
+	partSuffix = ".part"
+)
+
+// CreateTemp 在目标文件所在目录创建下载用的临时文件
+// 目录不存在时会自动创建，下载完成后应使用 CommitTemp 将其重命名为目标文件
+// 参数:
+//   - target: 目标文件路径
+//
+// 返回:
+//   - *os.File: 临时文件
+//   - error: 错误信息
+func CreateTemp(target string) (*os.File, error) {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+	file, err := os.CreateTemp(dir, filepath.Base(target)+".*"+tempSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	return file, nil
+}
+
+// CommitTemp 关闭临时文件并将其重命名为目标文件.
+func CommitTemp(file *os.File, target string) error {
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("关闭临时文件失败: %w", err)
+	}
+	if err := os.Rename(file.Name(), target); err != nil {
+		// 目标路径位于其他设备时（例如挂载到目录中的文件）无法重命名，改为复制
+		if errors.Is(err, syscall.EXDEV) {
+			return moveByCopy(file.Name(), target)
+		}
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("重命名临时文件失败: %w", err)
+	}
+	return nil
+}
+
+// PartPath 返回目标文件断点续传用的部分文件路径.
+func PartPath(target string) string {
+	return target + partSuffix
+}
+
+// PartSize 返回目标文件已下载的部分文件大小，部分文件不存在时返回 0.
+func PartSize(target string) int64 {
+	info, err := os.Stat(PartPath(target))
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+	return info.Size()
+}
+
+// OpenPart 打开目标文件断点续传用的部分文件
+// 目录不存在时会自动创建，下载完成后应使用 CommitTemp 将其重命名为目标文件；
+// 下载中断时保留部分文件，下次下载从已写入的位置继续
+// 参数:
+//   - target: 目标文件路径
+//   - resume: 为 true 时在部分文件末尾追加，否则清空后从头写入
+//
+// 返回:
+//   - *os.File: 部分文件
+//   - error: 错误信息
+func OpenPart(target string, resume bool) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if resume {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(PartPath(target), flag, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("打开部分文件失败: %w", err)
+	}
+	return file, nil
+}
+
+// RemovePart 删除目标文件断点续传用的部分文件，文件不存在时不返回错误.
+func RemovePart(target string) error {
+	if err := os.Remove(PartPath(target)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("删除部分文件失败: %w", err)
+	}
+	return nil
+}
+
+// moveByCopy 将 src 的内容复制到 target 后删除 src
+// 复制失败时会删除不完整的目标文件，避免其被当作已下载的文件复用.
+func moveByCopy(src, target string) error {
+	defer os.Remove(src) //nolint:errcheck // 临时文件删除失败不影响结果
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("打开临时文件失败: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("创建目标文件失败: %w", err)
+	}
+	_, copyErr := Copy(out, in)
+	closeErr := out.Close()
+	if err = errors.Join(copyErr, closeErr); err != nil {
+		_ = os.Remove(target)
+		return fmt.Errorf("复制临时文件失败: %w", err)
+	}
+	return nil
+}
+
+// RemoveTemps 删除目标文件遗留的临时文件
+// 下载中断时临时文件可能没有被删除，重新下载前先清理，避免同一文件的临时文件不断累积；
+// 断点续传用的部分文件不会被删除
+// 参数:
+//   - target: 目标文件路径
+//
+// 返回:
+//   - []string: 已删除的文件路径
+//   - error: 错误信息
+func RemoveTemps(target string) ([]string, error) {
+	dir := filepath.Dir(target)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取目录失败: %w", err)
+	}
+
+	prefix := filepath.Base(target) + "."
+	var removed []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !IsTempFile(name) || !isTempOf(name, prefix) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if removeErr := os.Remove(path); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			return removed, fmt.Errorf("删除临时文件失败: %w", removeErr)
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// isTempOf 判断临时文件是否属于 prefix 对应的目标文件
+// 随机串中不包含 "."，以免 a.png 误匹配 a.png.bak 的临时文件.
+func isTempOf(name, prefix string) bool {
+	base, ok := strings.CutSuffix(name, tempSuffix)
+	if !ok {
+		return false
+	}
+	random, ok := strings.CutPrefix(base, prefix)
+	return ok && !strings.Contains(random, ".")
+}
+
+// DiscardTemp 关闭并删除临时文件.
+func DiscardTemp(file *os.File) {
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+}
+
+// IsTempFile 判断文件名是否符合本工具的临时文件命名约定，断点续传用的部分文件也视为临时文件.
+func IsTempFile(name string) bool {
+	_, ok := TempTarget(name)
+	return ok
+}
+
+// TempTarget 返回临时文件或部分文件对应的目标文件名
+// 参数:
+//   - name: 文件名或路径
+//
+// 返回:
+//   - string: 目标文件名，不含目录
+//   - bool: 是否符合本工具的临时文件命名约定
+func TempTarget(name string) (string, bool) {
+	if base, ok := strings.CutSuffix(filepath.Base(name), partSuffix); ok {
+		return base, base != ""
+	}
+	base, ok := strings.CutSuffix(filepath.Base(name), tempSuffix)
+	if !ok {
+		return "", false
+	}
+	// 去掉随机串后仍需保留原文件名
+	idx := strings.LastIndex(base, ".")
+	if idx <= 0 || idx >= len(base)-1 {
+		return "", false
+	}
+	return base[:idx], true
+}
+
+// CleanTempFiles 清理目录中遗留的临时文件
+// 程序崩溃或被强制结束时，下载中的临时文件不会被删除
+// 参数:
+//   - root: 需要扫描的目录，不存在时视为没有临时文件
+//   - minAge: 只清理修改时间早于该时长之前的文件，为 0 时清理全部
+//
+// 返回:
+//   - []string: 已删除的文件路径
+//   - error: 错误信息
+func CleanTempFiles(root string, minAge time.Duration) ([]string, error) {
+	var removed []string
+	cutoff := time.Now().Add(-minAge)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			if errors.Is(walkErr, fs.ErrNotExist) {
+				return nil
+			}
+			return walkErr
+		}
+		if d.IsDir() || !IsTempFile(d.Name()) {
+			return nil
+		}
+		info, infoErr := d.Info()
+		if infoErr != nil {
+			return nil //nolint:nilerr // 文件可能已被其他下载删除
+		}
+		if minAge > 0 && info.ModTime().After(cutoff) {
+			return nil
+		}
+		if removeErr := os.Remove(path); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			return removeErr
+		}
+		removed = append(removed, path)
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("清理临时文件失败: %w", err)
+	}
+	return removed, nil
+}
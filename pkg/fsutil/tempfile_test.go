@@ -0,0 +1,138 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+func TestIsTempFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		file       string
+		want       bool
+		wantTarget string
+	}{
+		{name: "本工具的临时文件", file: "texture_00.png.123456.bdl.tmp", want: true, wantTarget: "texture_00.png"},
+		{name: "带目录的临时文件", file: "data/chara_1.json.123456.bdl.tmp", want: true, wantTarget: "chara_1.json"},
+		{name: "其他程序的临时文件", file: "texture_00.png.tmp", want: false},
+		{name: "缺少原文件名", file: ".123456.bdl.tmp", want: false},
+		{name: "缺少随机串", file: "texture_00.png..bdl.tmp", want: false},
+		{name: "普通文件", file: "model.json", want: false},
+		{name: "断点续传的部分文件", file: "texture_00.png.part", want: true, wantTarget: "texture_00.png"},
+		{name: "缺少原文件名的部分文件", file: ".part", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, fsutil.IsTempFile(tt.file))
+			target, ok := fsutil.TempTarget(tt.file)
+			assert.Equal(t, tt.want, ok)
+			assert.Equal(t, tt.wantTarget, target)
+		})
+	}
+}
+
+func TestCreateAndCommitTemp(t *testing.T) {
+	t.Parallel()
+
+	target := filepath.Join(t.TempDir(), "data", "model.moc")
+	file, err := fsutil.CreateTemp(target)
+	require.NoError(t, err)
+	assert.True(t, fsutil.IsTempFile(file.Name()))
+	_, err = file.WriteString("test")
+	require.NoError(t, err)
+
+	require.NoError(t, fsutil.CommitTemp(file, target))
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "test", string(data))
+	assert.NoFileExists(t, file.Name())
+}
+
+func TestRemoveTemps(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "texture_00.png")
+	files := map[string]bool{
+		"texture_00.png.123.bdl.tmp":     true,
+		"texture_00.png.456.bdl.tmp":     true,
+		"texture_00.png.bak.789.bdl.tmp": false,
+		"texture_01.png.123.bdl.tmp":     false,
+		"texture_00.png":                 false,
+		"texture_00.png.part":            false,
+	}
+	for name := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("test"), 0600))
+	}
+
+	removed, err := fsutil.RemoveTemps(target)
+	require.NoError(t, err)
+	assert.Len(t, removed, 2)
+	for name, wantRemoved := range files {
+		if wantRemoved {
+			assert.NoFileExists(t, filepath.Join(dir, name))
+		} else {
+			assert.FileExists(t, filepath.Join(dir, name))
+		}
+	}
+
+	removed, err = fsutil.RemoveTemps(filepath.Join(dir, "missing", "model.moc"))
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestCleanTempFiles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		minAge    time.Duration
+		wantCount int
+	}{
+		{name: "只清理过期的临时文件", minAge: time.Hour, wantCount: 1},
+		{name: "清理全部临时文件", minAge: 0, wantCount: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			dir := filepath.Join(root, "kasumi", "001_casual", "data")
+			require.NoError(t, os.MkdirAll(dir, 0750))
+			old := filepath.Join(dir, "texture_00.png.111.bdl.tmp")
+			recent := filepath.Join(dir, "model.moc.222.bdl.tmp")
+			other := filepath.Join(dir, "notes.tmp")
+			for _, path := range []string{old, recent, other} {
+				require.NoError(t, os.WriteFile(path, []byte("x"), 0600))
+			}
+			past := time.Now().Add(-2 * time.Hour)
+			require.NoError(t, os.Chtimes(old, past, past))
+
+			removed, err := fsutil.CleanTempFiles(root, tt.minAge)
+			require.NoError(t, err)
+			assert.Len(t, removed, tt.wantCount)
+			assert.NoFileExists(t, old)
+			assert.FileExists(t, other, "其他程序的文件不应被清理")
+		})
+	}
+}
+
+func TestCleanTempFilesMissingRoot(t *testing.T) {
+	t.Parallel()
+
+	removed, err := fsutil.CleanTempFiles(filepath.Join(t.TempDir(), "missing"), 0)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
@@ -0,0 +1,138 @@
+// Package history 提供了下载历史记录功能
+// 记录每次模型下载的时间、来源服务器和结果，用于提示重复下载和回顾下载记录
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+// FileName 是下载历史在角色信息缓存目录中的文件名.
+const FileName = "download_history.json"
+
+// fileMu 保证同一进程内对历史文件的读写不会互相覆盖
+// 批量下载时多个模型可能同时完成.
+//
+//nolint:gochecknoglobals // 历史文件在进程内只有一份，需要全局互斥
+var fileMu sync.Mutex
+
+// Entry 表示一次模型下载的记录.
+type Entry struct {
+	Model   string    `json:"model"`           // 模型名称
+	Server  string    `json:"server"`          // 资源来源的服务器
+	Success bool      `json:"success"`         // 是否下载成功
+	Error   string    `json:"error,omitempty"` // 失败原因
+	Time    time.Time `json:"time"`            // 下载完成时间
+}
+
+// String 返回用于命令行显示的记录文本.
+func (e Entry) String() string {
+	return e.Format(e.Model)
+}
+
+// Format 返回用于命令行显示的记录文本，模型名称显示为 label
+// 参数:
+//   - label: 显示的模型名称，例如带有服装标题的名称
+//
+// 返回:
+//   - string: 记录文本
+func (e Entry) Format(label string) string {
+	result := "成功"
+	if !e.Success {
+		result = "失败: " + e.Error
+	}
+	return fmt.Sprintf("%s  %-3s  %s  %s", e.Time.Local().Format(time.DateTime), e.Server, label, result)
+}
+
+// History 表示下载历史，记录按时间先后排列.
+type History struct {
+	Entries []Entry `json:"entries"` // 下载记录
+}
+
+// LastSuccess 返回指定模型最近一次成功下载的记录
+// 参数:
+//   - model: 模型名称
+//
+// 返回:
+//   - Entry: 下载记录
+//   - bool: 是否下载过
+func (h *History) LastSuccess(model string) (Entry, bool) {
+	for i := len(h.Entries) - 1; i >= 0; i-- {
+		if h.Entries[i].Model == model && h.Entries[i].Success {
+			return h.Entries[i], true
+		}
+	}
+	return Entry{}, false
+}
+
+// Load 读取历史文件，文件不存在时返回空历史
+// 参数:
+//   - path: 历史文件路径
+//
+// 返回:
+//   - *History: 下载历史
+//   - error: 错误信息
+func Load(path string) (*History, error) {
+	history := &History{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取下载历史失败: %w", err)
+	}
+	if unmarshalErr := json.Unmarshal(data, history); unmarshalErr != nil {
+		return nil, fmt.Errorf("解析下载历史失败: %w", unmarshalErr)
+	}
+	return history, nil
+}
+
+// save 将历史写入历史文件，先写入临时文件再替换，避免写入中断损坏历史文件.
+func save(path string, history *History) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化下载历史失败: %w", err)
+	}
+	file, err := fsutil.CreateTemp(path)
+	if err != nil {
+		return fmt.Errorf("写入下载历史失败: %w", err)
+	}
+	if _, writeErr := file.Write(data); writeErr != nil {
+		fsutil.DiscardTemp(file)
+		return fmt.Errorf("写入下载历史失败: %w", writeErr)
+	}
+	if commitErr := fsutil.CommitTemp(file, path); commitErr != nil {
+		return fmt.Errorf("写入下载历史失败: %w", commitErr)
+	}
+	return nil
+}
+
+// Append 追加一条下载记录，超过条数上限时丢弃最早的记录
+// 参数:
+//   - path: 历史文件路径
+//   - entry: 下载记录
+//   - maxEntries: 保留的最大记录条数，小于等于 0 时不限制
+//
+// 返回:
+//   - error: 错误信息
+func Append(path string, entry Entry, maxEntries int) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	history, err := Load(path)
+	if err != nil {
+		return err
+	}
+	history.Entries = append(history.Entries, entry)
+	if maxEntries > 0 && len(history.Entries) > maxEntries {
+		history.Entries = history.Entries[len(history.Entries)-maxEntries:]
+	}
+	return save(path, history)
+}
@@ -0,0 +1,65 @@
+package history_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/history"
+)
+
+func TestAppend(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), history.FileName)
+	base := time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)
+	entries := []history.Entry{
+		{Model: "001_casual", Server: "jp", Success: true, Time: base},
+		{Model: "001_live", Server: "jp", Success: true, Time: base.Add(time.Hour)},
+		{Model: "001_casual", Server: "cn", Success: true, Time: base.Add(2 * time.Hour)},
+		{Model: "001_casual", Server: "jp", Success: false, Error: "下载已取消", Time: base.Add(3 * time.Hour)},
+	}
+	for _, entry := range entries {
+		require.NoError(t, history.Append(path, entry, 3))
+	}
+
+	h, err := history.Load(path)
+	require.NoError(t, err)
+	// 超过上限时丢弃最早的记录
+	require.Len(t, h.Entries, 3)
+	assert.Equal(t, "001_live", h.Entries[0].Model)
+
+	tests := []struct {
+		name   string
+		model  string
+		want   history.Entry
+		wantOK bool
+	}{
+		{name: "忽略失败的记录", model: "001_casual", want: entries[2], wantOK: true},
+		{name: "只有一次成功", model: "001_live", want: entries[1], wantOK: true},
+		{name: "已被滚动丢弃", model: "002_casual", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := h.LastSuccess(tt.model)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want.Server, got.Server)
+				assert.True(t, tt.want.Time.Equal(got.Time))
+			}
+		})
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	t.Parallel()
+
+	h, err := history.Load(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	assert.Empty(t, h.Entries)
+}
@@ -19,6 +19,8 @@ var (
 // Logger 提供日志功能.
 type Logger struct {
 	logger zerolog.Logger
+	writer zerolog.LevelWriter // 日志输出，子日志在此基础上追加输出
+	tail   *Tail               // 最近的 info 及以上级别日志，供界面显示
 }
 
 // New 创建一个新的日志实例.
@@ -30,7 +32,7 @@ func New(logPath string) (*Logger, error) {
 
 	// 创建日志文件
 	logFile, err := os.OpenFile(
-		filepath.Join(logPath, time.Now().Format("2006-01-02")+".log"),
+		filepath.Join(logPath, time.Now().Format(time.DateOnly)+".log"),
 		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
 		0600,
 	)
@@ -38,12 +40,23 @@ func New(logPath string) (*Logger, error) {
 		return nil, fmt.Errorf("创建日志文件失败: %w", err)
 	}
 
-	// 配置日志输出
-	logger := zerolog.New(logFile).With().Timestamp().Logger()
-	DefaultLogger = &Logger{logger: logger}
+	// 配置日志输出，同时在内存中保留最近的日志供界面显示
+	tail := NewTail(TailCapacity)
+	console := zerolog.ConsoleWriter{Out: tail, NoColor: true, TimeFormat: time.TimeOnly}
+	writer := zerolog.MultiLevelWriter(logFile, &zerolog.FilteredLevelWriter{
+		Writer: zerolog.LevelWriterAdapter{Writer: console},
+		Level:  zerolog.InfoLevel,
+	})
+	logger := zerolog.New(writer).With().Timestamp().Logger()
+	DefaultLogger = &Logger{logger: logger, writer: writer, tail: tail}
 	return DefaultLogger, nil
 }
 
+// Tail 返回最近日志行的环形缓冲区.
+func (l *Logger) Tail() *Tail {
+	return l.tail
+}
+
 // Error 记录错误日志.
 func (l *Logger) Error() *zerolog.Event {
 	return l.logger.Error()
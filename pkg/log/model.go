@@ -0,0 +1,94 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ctxKey 是上下文中保存日志实例的键.
+type ctxKey struct{}
+
+// ForModel 创建写入单个模型独立日志文件的子日志
+// 子日志同时写入主日志，文件位于 <日志目录>/<日期>/<模型名>.log，使用完毕后需要关闭
+// 参数:
+//   - logPath: 日志目录
+//   - modelName: 模型名称
+//
+// 返回:
+//   - *Logger: 子日志
+//   - io.Closer: 用于关闭模型日志文件
+//   - error: 错误信息
+func (l *Logger) ForModel(logPath, modelName string) (*Logger, io.Closer, error) {
+	dir := filepath.Join(logPath, time.Now().Format(time.DateOnly))
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, nil, fmt.Errorf("创建模型日志目录失败: %w", err)
+	}
+	file, err := os.OpenFile(
+		filepath.Join(dir, filepath.Base(modelName)+".log"),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
+		0600,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建模型日志文件失败: %w", err)
+	}
+
+	modelLog := &modelFile{file: file}
+	writer := zerolog.MultiLevelWriter(l.writer, modelLog)
+	logger := l.logger.Output(writer).With().Str("model", modelName).Logger()
+	return &Logger{logger: logger, writer: writer, tail: l.tail}, modelLog, nil
+}
+
+// modelFile 表示模型日志文件
+// 关闭后的写入会被忽略，避免取消下载时仍在退出的协程写入已关闭的文件.
+type modelFile struct {
+	mu     sync.Mutex
+	file   *os.File
+	closed bool
+}
+
+// Write 写入模型日志文件.
+func (f *modelFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return len(p), nil
+	}
+	return f.file.Write(p) //nolint:wrapcheck // 透传底层写入错误
+}
+
+// Close 关闭模型日志文件.
+func (f *modelFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	return f.file.Close() //nolint:wrapcheck // 透传底层关闭错误
+}
+
+// WithContext 返回保存了日志实例的上下文
+// 参数:
+//   - ctx: 上下文
+//   - l: 日志实例
+//
+// 返回:
+//   - context.Context: 新的上下文
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext 返回上下文中保存的日志实例，没有时返回 DefaultLogger.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return l
+	}
+	return DefaultLogger
+}
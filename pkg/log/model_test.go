@@ -0,0 +1,48 @@
+package log_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+)
+
+func TestForModel(t *testing.T) {
+	logPath := t.TempDir()
+	main, err := log.New(logPath)
+	require.NoError(t, err)
+
+	logger, closer, err := main.ForModel(logPath, "037_casual-2023")
+	require.NoError(t, err)
+	logger.Info().Msg("模型日志")
+	main.Info().Msg("其他日志")
+	require.NoError(t, closer.Close())
+
+	// 关闭后的写入被忽略
+	logger.Info().Msg("关闭后的日志")
+	require.NoError(t, closer.Close())
+
+	date := time.Now().Format(time.DateOnly)
+	modelLog, err := os.ReadFile(filepath.Join(logPath, date, "037_casual-2023.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(modelLog), "模型日志")
+	assert.Contains(t, string(modelLog), `"model":"037_casual-2023"`)
+	assert.NotContains(t, string(modelLog), "其他日志")
+	assert.NotContains(t, string(modelLog), "关闭后的日志")
+
+	// 模型日志同时写入主日志
+	mainLog, err := os.ReadFile(filepath.Join(logPath, date+".log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(mainLog), "模型日志")
+	assert.Contains(t, string(mainLog), "其他日志")
+
+	// 上下文中没有日志实例时使用 DefaultLogger
+	assert.Same(t, main, log.FromContext(context.Background()))
+	assert.Same(t, logger, log.FromContext(log.WithContext(context.Background(), logger)))
+}
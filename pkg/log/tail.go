@@ -0,0 +1,74 @@
+package log
+
+import (
+	"strings"
+	"sync"
+)
+
+// TailCapacity 是内存中保留的最近日志行数.
+const TailCapacity = 200
+
+// Tail 在内存中保留最近的日志行，供界面实时显示
+// 写满后覆盖最早的日志行.
+type Tail struct {
+	mu     sync.Mutex
+	lines  []string      // 环形缓冲区
+	start  int           // 最早一行在缓冲区中的位置
+	count  int           // 当前保留的行数
+	notify chan struct{} // 有新日志行时发出通知，容量为 1，多次写入合并为一次通知
+}
+
+// NewTail 创建新的日志环形缓冲区
+// 参数:
+//   - capacity: 保留的最大行数
+//
+// 返回:
+//   - *Tail: 日志环形缓冲区
+func NewTail(capacity int) *Tail {
+	return &Tail{
+		lines:  make([]string, capacity),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Write 实现 io.Writer 接口，按行保存写入的日志.
+func (t *Tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if t.count < len(t.lines) {
+			t.lines[(t.start+t.count)%len(t.lines)] = line
+			t.count++
+		} else {
+			t.lines[t.start] = line
+			t.start = (t.start + 1) % len(t.lines)
+		}
+	}
+	t.mu.Unlock()
+
+	// 不阻塞写日志的调用方，界面还没处理上一次通知时合并通知
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Lines 返回当前保留的日志行，按时间先后排列.
+func (t *Tail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := make([]string, t.count)
+	for i := range t.count {
+		lines[i] = t.lines[(t.start+i)%len(t.lines)]
+	}
+	return lines
+}
+
+// Notify 返回新日志行的通知通道.
+func (t *Tail) Notify() <-chan struct{} {
+	return t.notify
+}
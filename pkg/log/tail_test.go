@@ -0,0 +1,39 @@
+package log_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/log"
+)
+
+func TestTail(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{name: "未写满", writes: []string{"a\n", "b\n"}, want: []string{"a", "b"}},
+		{name: "写满后覆盖最早的行", writes: []string{"a\n", "b\n", "c\n", "d\n"}, want: []string{"b", "c", "d"}},
+		{name: "一次写入多行", writes: []string{"a\nb\n\nc\nd\n"}, want: []string{"b", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tail := log.NewTail(3)
+			for _, w := range tt.writes {
+				_, err := tail.Write([]byte(w))
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, tail.Lines())
+
+			// 多次写入合并为一次通知
+			assert.Len(t, tail.Notify(), 1)
+		})
+	}
+}
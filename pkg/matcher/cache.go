@@ -0,0 +1,140 @@
+package matcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedMatch 表示一次角色搜索的匹配结果.
+type CachedMatch struct {
+	ID         string    `json:"id"`         // 最佳匹配的角色ID
+	Name       string    `json:"name"`       // 最佳匹配的角色名称
+	Similarity float64   `json:"similarity"` // 匹配相似度
+	CreatedAt  time.Time `json:"createdAt"`  // 匹配时间
+}
+
+// cacheFile 表示搜索缓存文件的内容.
+type cacheFile struct {
+	Fingerprint string                 `json:"fingerprint"` // 生成缓存时角色列表的指纹
+	Entries     map[string]CachedMatch `json:"entries"`     // 匹配结果，key 为规范化后的搜索名称
+}
+
+// Cache 缓存“搜索名称 -> 匹配角色”的结果，避免重复计算相似度
+// 缓存与角色列表的指纹绑定，角色列表变化或缓存过期后结果失效.
+type Cache struct {
+	mu   sync.Mutex
+	path string        // 缓存文件路径，为空时只缓存在内存中
+	ttl  time.Duration // 缓存有效期，为 0 时不过期
+	data cacheFile
+}
+
+// LoadCache 加载角色搜索缓存
+// 缓存文件不存在或损坏时从空缓存开始
+// 参数:
+//   - path: 缓存文件路径，为空时只缓存在内存中
+//   - ttl: 缓存有效期，通常与角色列表的缓存时间一致
+//
+// 返回:
+//   - *Cache: 角色搜索缓存
+func LoadCache(path string, ttl time.Duration) *Cache {
+	c := &Cache{path: path, ttl: ttl, data: cacheFile{Entries: make(map[string]CachedMatch)}}
+	if path == "" {
+		return c
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	var data cacheFile
+	if json.Unmarshal(raw, &data) == nil && data.Entries != nil {
+		c.data = data
+	}
+	return c
+}
+
+// cacheKey 返回搜索名称在缓存中的 key.
+func cacheKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Lookup 查找搜索名称的缓存结果
+// 参数:
+//   - name: 搜索名称
+//   - fingerprint: 当前角色列表的指纹
+//
+// 返回:
+//   - CachedMatch: 缓存的匹配结果
+//   - bool: 是否命中
+func (c *Cache) Lookup(name, fingerprint string) (CachedMatch, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data.Fingerprint != fingerprint {
+		return CachedMatch{}, false
+	}
+	match, ok := c.data.Entries[cacheKey(name)]
+	if !ok || (c.ttl > 0 && time.Since(match.CreatedAt) >= c.ttl) {
+		return CachedMatch{}, false
+	}
+	return match, true
+}
+
+// Store 保存搜索名称的匹配结果
+// 角色列表的指纹变化时会清空旧的结果，写入缓存文件失败时内存中的结果仍然有效
+// 参数:
+//   - name: 搜索名称
+//   - fingerprint: 当前角色列表的指纹
+//   - match: 匹配结果
+//
+// 返回:
+//   - error: 写入缓存文件失败时的错误信息
+func (c *Cache) Store(name, fingerprint string, match CachedMatch) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data.Fingerprint != fingerprint {
+		c.data = cacheFile{Fingerprint: fingerprint, Entries: make(map[string]CachedMatch)}
+	}
+	c.data.Entries[cacheKey(name)] = match
+	if c.path == "" {
+		return nil
+	}
+
+	raw, err := json.Marshal(c.data)
+	if err != nil {
+		return fmt.Errorf("序列化角色搜索缓存失败: %w", err)
+	}
+	if mkdirErr := os.MkdirAll(filepath.Dir(c.path), 0750); mkdirErr != nil {
+		return fmt.Errorf("创建缓存目录失败: %w", mkdirErr)
+	}
+	if writeErr := os.WriteFile(c.path, raw, 0600); writeErr != nil {
+		return fmt.Errorf("写入角色搜索缓存失败: %w", writeErr)
+	}
+	return nil
+}
+
+// Fingerprint 计算候选名称映射的指纹，用于判断角色列表是否发生变化
+// 参数:
+//   - candidates: 候选名称映射，key 为角色ID，value 为角色名称列表
+//
+// 返回:
+//   - string: 指纹
+func Fingerprint(candidates map[string][]string) string {
+	ids := make([]string, 0, len(candidates))
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	h := sha256.New()
+	for _, id := range ids {
+		fmt.Fprintf(h, "%s\x00%s\x00", id, strings.Join(candidates[id], "\x1f"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,75 @@
+package matcher_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/matcher"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	roster := map[string][]string{"36": {"千早 愛音", "千早", "愛音", "Anon"}}
+	fingerprint := matcher.Fingerprint(roster)
+	match := matcher.CachedMatch{ID: "36", Name: "Anon", Similarity: 1, CreatedAt: time.Now()}
+
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		createdAt   time.Time
+		lookup      string
+		fingerprint string
+		wantHit     bool
+	}{
+		{name: "命中", lookup: "anon", fingerprint: fingerprint, wantHit: true},
+		{name: "忽略大小写与空白", lookup: "  ANON ", fingerprint: fingerprint, wantHit: true},
+		{name: "未搜索过的名称", lookup: "soyo", fingerprint: fingerprint},
+		{
+			name:        "角色列表变化后失效",
+			lookup:      "anon",
+			fingerprint: matcher.Fingerprint(map[string][]string{"36": {"千早 愛音"}, "37": {"要 楽奈"}}),
+		},
+		{
+			name:        "过期后失效",
+			ttl:         time.Hour,
+			createdAt:   time.Now().Add(-2 * time.Hour),
+			lookup:      "anon",
+			fingerprint: fingerprint,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "chara_search.json")
+			entry := match
+			if !tt.createdAt.IsZero() {
+				entry.CreatedAt = tt.createdAt
+			}
+			require.NoError(t, matcher.LoadCache(path, tt.ttl).Store("Anon", fingerprint, entry))
+
+			// 重新加载以验证跨会话的缓存
+			got, ok := matcher.LoadCache(path, tt.ttl).Lookup(tt.lookup, tt.fingerprint)
+			assert.Equal(t, tt.wantHit, ok)
+			if tt.wantHit {
+				assert.Equal(t, "36", got.ID)
+				assert.Equal(t, "Anon", got.Name)
+			}
+		})
+	}
+}
+
+func TestCacheMemoryOnly(t *testing.T) {
+	t.Parallel()
+
+	cache := matcher.LoadCache("", 0)
+	require.NoError(t, cache.Store("anon", "fp", matcher.CachedMatch{ID: "36", CreatedAt: time.Now()}))
+	_, ok := cache.Lookup("anon", "fp")
+	assert.True(t, ok)
+}
@@ -9,6 +9,8 @@ import (
 	"slices"
 
 	"github.com/adrg/strutil/metrics"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
 )
 
 // compareSimilarity 比较两个相似度并决定是否更新最佳匹配
@@ -102,13 +104,14 @@ func calculateSimilarity(
 }
 
 // isValidCandidate 检查候选ID是否有效
+// 超过 MaxCharaID 的角色不参与搜索
 // 参数:
 //   - id: 候选ID
 //
 // 返回:
 //   - bool: ID是否有效
 func isValidCandidate(id string) bool {
-	if idNum, err := strconv.Atoi(id); err != nil || idNum > 1000 {
+	if idNum, err := strconv.Atoi(id); err != nil || !config.Get().CharaIDAllowed(idNum) {
 		return false
 	}
 	return true
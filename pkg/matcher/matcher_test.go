@@ -3,6 +3,7 @@ package matcher_test
 import (
 	"testing"
 
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/config"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/matcher"
 	"github.com/stretchr/testify/assert"
 )
@@ -61,3 +62,31 @@ func TestFindBestMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestFindBestMatchMaxCharaID(t *testing.T) {
+	candidates := map[string][]string{
+		"1001": {"特殊角色"},
+	}
+
+	tests := []struct {
+		name       string
+		maxCharaID int
+		wantID     string
+	}{
+		{name: "默认不搜索特殊角色", maxCharaID: 1000, wantID: ""},
+		{name: "不限制角色ID", maxCharaID: 0, wantID: "1001"},
+		{name: "提高上限", maxCharaID: 2000, wantID: "1001"},
+	}
+
+	cfg := config.Get()
+	oldMax := cfg.MaxCharaID
+	defer func() { cfg.MaxCharaID = oldMax }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.MaxCharaID = tt.maxCharaID
+			gotID, _, _ := matcher.FindBestMatch("特殊角色", candidates)
+			assert.Equal(t, tt.wantID, gotID)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+)
+
+// live2dGeneralSuffix 是各角色共用的 general 资源包名称的后缀，不是可下载的服装.
+const live2dGeneralSuffix = "general"
+
+// AssetsIndex 表示 Bestdori 资源索引 _info.json 中本程序使用的部分.
+type AssetsIndex struct {
+	Live2dCharas []string // live2d/chara 目录下的资源包名称，按名称排序
+}
+
+// ParseAssetsIndex 解析资源索引 JSON
+// 参数:
+//   - data: 资源索引 JSON
+//
+// 返回:
+//   - *AssetsIndex: 资源索引
+//   - error: 格式无效时返回包裹 errs.ErrInvalidAssetsIndex 的错误
+func ParseAssetsIndex(data []byte) (*AssetsIndex, error) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidAssetsIndex, err)
+	}
+	return NewAssetsIndex(value)
+}
+
+// NewAssetsIndex 从已解码的资源索引 JSON 构建资源索引
+// 逐层校验目录结构，错误信息包含缺少的键或实际的类型
+// 参数:
+//   - value: 通过 encoding/json 解码得到的资源索引
+//
+// 返回:
+//   - *AssetsIndex: 资源索引
+//   - error: 格式无效时返回包裹 errs.ErrInvalidAssetsIndex 的错误
+func NewAssetsIndex(value any) (*AssetsIndex, error) {
+	charas, err := indexDir(value, "live2d", "chara")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(charas))
+	for name := range charas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return &AssetsIndex{Live2dCharas: names}, nil
+}
+
+// Costumes 按角色分组返回可下载的服装，排除无法解析角色ID的资源包与 general 资源
+// 返回:
+//   - map[int][]string: 服装列表映射，key 为角色ID，服装按名称排序
+func (idx *AssetsIndex) Costumes() map[int][]string {
+	costumes := make(map[int][]string)
+	for _, name := range idx.Live2dCharas {
+		if charaID, ok := Live2dCharaID(name); ok {
+			costumes[charaID] = append(costumes[charaID], name)
+		}
+	}
+	return costumes
+}
+
+// Live2dCharaID 从服装名称下划线前的数字前缀解析角色ID
+// 参数:
+//   - name: 服装名称，例如 037_casual-2023 或 1001_casual
+//
+// 返回:
+//   - int: 角色ID
+//   - bool: 名称过短、前缀不是数字或是 general 资源时返回 false
+func Live2dCharaID(name string) (int, bool) {
+	if strings.HasSuffix(name, live2dGeneralSuffix) {
+		return 0, false
+	}
+	prefix, _, _ := strings.Cut(name, "_")
+	if !isCharaIDPrefix(prefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(prefix)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// indexDir 沿着键路径逐层取出资源索引中的目录.
+func indexDir(value any, keys ...string) (map[string]any, error) {
+	dir, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: 根节点应为对象，实际类型为 %s", errs.ErrInvalidAssetsIndex, jsonTypeName(value))
+	}
+	for i, key := range keys {
+		path := strings.Join(keys[:i+1], ".")
+		child, exists := dir[key]
+		if !exists {
+			return nil, fmt.Errorf("%w: 缺少 %s", errs.ErrInvalidAssetsIndex, path)
+		}
+		if dir, ok = child.(map[string]any); !ok {
+			return nil, fmt.Errorf("%w: %s 应为对象，实际类型为 %s", errs.ErrInvalidAssetsIndex, path, jsonTypeName(child))
+		}
+	}
+	return dir, nil
+}
+
+// jsonTypeName 返回 encoding/json 解码得到的值对应的 JSON 类型名称.
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "对象"
+	case []any:
+		return "数组"
+	case string:
+		return "字符串"
+	case float64, json.Number:
+		return "数字"
+	case bool:
+		return "布尔值"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,127 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+func TestParseAssetsIndex(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		input     string
+		wantNames []string
+		wantErr   string
+	}{
+		{
+			name:      "有效的索引",
+			input:     `{"live2d":{"chara":{"002_live_event_01":1,"001_casual-2023":2,"001_general":3}},"sound":{}}`,
+			wantNames: []string{"001_casual-2023", "001_general", "002_live_event_01"},
+		},
+		{
+			name:      "空的模型目录",
+			input:     `{"live2d":{"chara":{}}}`,
+			wantNames: []string{},
+		},
+		{
+			name:    "根节点不是对象",
+			input:   `[{"live2d":{}}]`,
+			wantErr: "根节点应为对象，实际类型为 数组",
+		},
+		{
+			name:    "缺少 live2d",
+			input:   `{"sound":{}}`,
+			wantErr: "缺少 live2d",
+		},
+		{
+			name:    "live2d 不是对象",
+			input:   `{"live2d":"chara"}`,
+			wantErr: "live2d 应为对象，实际类型为 字符串",
+		},
+		{
+			name:    "缺少 chara",
+			input:   `{"live2d":{"other":{}}}`,
+			wantErr: "缺少 live2d.chara",
+		},
+		{
+			name:    "chara 为 null",
+			input:   `{"live2d":{"chara":null}}`,
+			wantErr: "live2d.chara 应为对象，实际类型为 null",
+		},
+		{
+			name:    "chara 是数字",
+			input:   `{"live2d":{"chara":12}}`,
+			wantErr: "live2d.chara 应为对象，实际类型为 数字",
+		},
+		{
+			name:    "无效的 JSON",
+			input:   `{"live2d":`,
+			wantErr: "unexpected end of JSON input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			index, err := model.ParseAssetsIndex([]byte(tt.input))
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, errs.ErrInvalidAssetsIndex)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				assert.Nil(t, index)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNames, index.Live2dCharas)
+		})
+	}
+}
+
+func TestAssetsIndexCostumes(t *testing.T) {
+	t.Parallel()
+
+	index := &model.AssetsIndex{
+		Live2dCharas: []string{"", "01", "1a_x", "001_casual-2023", "001_general", "001_school", "037_live_event_01"},
+	}
+	assert.Equal(t, map[int][]string{
+		1:  {"001_casual-2023", "001_school"},
+		37: {"037_live_event_01"},
+	}, index.Costumes())
+}
+
+func TestLive2dCharaID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		input  string
+		wantID int
+		wantOK bool
+	}{
+		{name: "普通服装", input: "037_casual-2023", wantID: 37, wantOK: true},
+		{name: "特殊角色", input: "1001_casual", wantID: 1001, wantOK: true},
+		{name: "补零过多", input: "0037_casual"},
+		{name: "只有编号", input: "001", wantID: 1, wantOK: true},
+		{name: "空字符串", input: ""},
+		{name: "长度不足", input: "01"},
+		{name: "前缀不是数字", input: "ab1_casual"},
+		{name: "带符号的前缀", input: "+12_casual"},
+		{name: "general 资源", input: "001_general"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			id, ok := model.Live2dCharaID(tt.input)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantID, id)
+		})
+	}
+}
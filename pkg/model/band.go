@@ -0,0 +1,85 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// BandInfo 表示 Bestdori 乐队接口返回的乐队信息.
+type BandInfo struct {
+	BandName LocalizedStrings `json:"bandName"` // 各服务器的乐队名称
+}
+
+// BandMember 表示乐队成员.
+type BandMember struct {
+	ID    int        // 角色ID
+	Chara *CharaInfo // 角色信息
+}
+
+// CharaSummary 表示角色浏览列表中的角色.
+type CharaSummary struct {
+	ID       int              // 角色ID
+	Name     LocalizedStrings // 各服务器的角色全名
+	BandID   int              // 所属乐队ID，没有时为 0
+	BandName LocalizedStrings // 各服务器的乐队名称，不属于任何乐队或乐队信息获取失败时为空
+}
+
+// ParseBands 解析乐队信息 JSON
+// 无法解析的乐队会被跳过，不影响其他乐队
+// 参数:
+//   - data: 乐队信息 JSON，key 为乐队ID
+//
+// 返回:
+//   - map[int]*BandInfo: 乐队信息，key 为乐队ID
+//   - error: 错误信息
+func ParseBands(data []byte) (map[int]*BandInfo, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析乐队信息失败: %w", err)
+	}
+
+	bands := make(map[int]*BandInfo, len(raw))
+	for key, value := range raw {
+		id, err := strconv.Atoi(key)
+		if err != nil || id <= 0 {
+			continue
+		}
+		var band BandInfo
+		if json.Unmarshal(value, &band) != nil {
+			continue
+		}
+		bands[id] = &band
+	}
+	return bands, nil
+}
+
+// LocalizedName 返回指定服务器的乐队名称，没有时回退到其他服务器的名称
+// 参数:
+//   - localeIndex: 服务器在多语言数组中的位置，例如 LocaleCN
+//
+// 返回:
+//   - string: 乐队名称，所有服务器都没有时为空字符串
+func (b *BandInfo) LocalizedName(localeIndex int) string {
+	if b == nil {
+		return ""
+	}
+	return b.BandName.Get(localeIndex)
+}
+
+// BandTitle 返回带乐队名称的角色标题，例如 "Poppin'Party — 户山香澄"
+// 参数:
+//   - band: 乐队名称，为空时只返回角色名称
+//   - name: 角色名称
+//
+// 返回:
+//   - string: 角色标题
+func BandTitle(band, name string) string {
+	if band == "" {
+		return name
+	}
+	if name == "" {
+		return band
+	}
+	return band + " — " + name
+}
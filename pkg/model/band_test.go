@@ -0,0 +1,55 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+func TestParseBands(t *testing.T) {
+	t.Parallel()
+
+	input := `{
+		"1":{"bandName":["Poppin'Party","Poppin'Party","Poppin'Party","Poppin'Party","Poppin'Party"]},
+		"2":{"bandName":["Afterglow",null,"Afterglow",null,null]},
+		"3":{"bandName":"Hello, Happy World!"},
+		"abc":{"bandName":["无效的乐队ID"]},
+		"4":[1,2]
+	}`
+	bands, err := model.ParseBands([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, bands, 3)
+	assert.Equal(t, "Poppin'Party", bands[1].LocalizedName(model.LocaleCN))
+	assert.Equal(t, "Afterglow", bands[2].LocalizedName(model.LocaleCN), "缺少国服名称时回退到日服名称")
+	assert.Empty(t, bands[3].LocalizedName(model.LocaleCN), "格式错误的名称按缺失处理")
+	assert.Empty(t, bands[99].LocalizedName(model.LocaleCN), "不存在的乐队返回空字符串")
+
+	_, err = model.ParseBands([]byte(`[`))
+	require.Error(t, err)
+}
+
+func TestBandTitle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		band  string
+		chara string
+		want  string
+	}{
+		{name: "乐队与角色", band: "Poppin'Party", chara: "户山 香澄", want: "Poppin'Party — 户山 香澄"},
+		{name: "没有乐队", chara: "户山 香澄", want: "户山 香澄"},
+		{name: "没有角色", band: "Poppin'Party", want: "Poppin'Party"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, model.BandTitle(tt.band, tt.chara))
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Bestdori 多语言数组中各服务器所在的位置.
+const (
+	LocaleJP = iota // 日服
+	LocaleEN        // 国际服
+	LocaleTW        // 台服
+	LocaleCN        // 国服
+	LocaleKR        // 韩服
+)
+
+// LocalizedStrings 表示 Bestdori 按服务器排列的多语言数组
+// 解析时容忍脏数据：null 与非字符串元素记为空字符串，不是数组时视为空数组.
+type LocalizedStrings []string
+
+// UnmarshalJSON 解析多语言数组，保留每个元素的位置.
+func (l *LocalizedStrings) UnmarshalJSON(data []byte) error {
+	var values []any
+	if err := json.Unmarshal(data, &values); err != nil {
+		*l = nil
+		return nil //nolint:nilerr // 格式错误的字段按缺失处理，不影响其他字段
+	}
+	*l = localizedFromValues(values)
+	return nil
+}
+
+// localizedFromValues 将解码得到的数组转换为多语言数组，非字符串元素记为空字符串.
+func localizedFromValues(values []any) LocalizedStrings {
+	strs := make(LocalizedStrings, len(values))
+	for i, value := range values {
+		strs[i], _ = value.(string)
+	}
+	return strs
+}
+
+// At 返回指定位置的值，位置超出范围时返回空字符串.
+func (l LocalizedStrings) At(locale int) string {
+	if locale < 0 || locale >= len(l) {
+		return ""
+	}
+	return strings.TrimSpace(l[locale])
+}
+
+// Get 返回指定位置的值，没有时依次回退到日服的值和第一个非空值.
+func (l LocalizedStrings) Get(locale int) string {
+	if value := l.At(locale); value != "" {
+		return value
+	}
+	if value := l.At(LocaleJP); value != "" {
+		return value
+	}
+	for i := range l {
+		if value := l.At(i); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// CharaInfo 表示 Bestdori 角色接口返回的角色信息.
+type CharaInfo struct {
+	CharacterType string           `json:"characterType"` // 角色类型
+	CharacterName LocalizedStrings `json:"characterName"` // 各服务器的角色全名
+	FirstName     LocalizedStrings `json:"firstName"`     // 各服务器的名
+	LastName      LocalizedStrings `json:"lastName"`      // 各服务器的姓
+	Nickname      LocalizedStrings `json:"nickname"`      // 各服务器的昵称
+	BandID        int              `json:"bandId"`        // 所属乐队ID，没有时为 0
+	ColorCode     string           `json:"colorCode"`     // 角色代表色
+}
+
+// ParseCharaInfo 解析角色信息 JSON
+// 参数:
+//   - data: 角色信息 JSON
+//
+// 返回:
+//   - *CharaInfo: 角色信息
+//   - error: 错误信息
+func ParseCharaInfo(data []byte) (*CharaInfo, error) {
+	var info CharaInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("解析角色信息失败: %w", err)
+	}
+	return &info, nil
+}
+
+// LocalizedName 返回指定服务器的角色全名，没有时回退到其他服务器的名称
+// 参数:
+//   - localeIndex: 服务器在多语言数组中的位置，例如 LocaleCN
+//
+// 返回:
+//   - string: 角色全名，所有服务器都没有时为空字符串
+func (c *CharaInfo) LocalizedName(localeIndex int) string {
+	return c.CharacterName.Get(localeIndex)
+}
+
+// LocalizedFirstName 返回指定服务器的角色名，没有时回退到其他服务器的名
+// 参数:
+//   - localeIndex: 服务器在多语言数组中的位置，例如 LocaleEN
+//
+// 返回:
+//   - string: 角色名，所有服务器都没有时为空字符串
+func (c *CharaInfo) LocalizedFirstName(localeIndex int) string {
+	return c.FirstName.Get(localeIndex)
+}
@@ -0,0 +1,103 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+func TestParseCharaInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		input         string
+		wantErr       bool
+		wantName      string
+		wantCNName    string
+		wantFirstName string
+		wantBandID    int
+	}{
+		{
+			name: "完整的角色信息",
+			input: `{"characterType":"unique","bandId":1,` +
+				`"characterName":["戸山 香澄","Kasumi Toyama","戶山 香澄","户山 香澄","토야마 카스미"],` +
+				`"firstName":["香澄","Kasumi","香澄","香澄","카스미"]}`,
+			wantName:      "戸山 香澄",
+			wantCNName:    "户山 香澄",
+			wantFirstName: "Kasumi",
+			wantBandID:    1,
+		},
+		{
+			name:          "字段缺失",
+			input:         `{"characterType":"unique"}`,
+			wantName:      "",
+			wantCNName:    "",
+			wantFirstName: "",
+		},
+		{
+			name:          "数组长度不足",
+			input:         `{"characterName":["戸山 香澄"],"firstName":["香澄"]}`,
+			wantName:      "戸山 香澄",
+			wantCNName:    "戸山 香澄",
+			wantFirstName: "香澄",
+		},
+		{
+			name:          "null 元素",
+			input:         `{"characterName":[null,"Kasumi Toyama",null,null],"firstName":[null,null]}`,
+			wantName:      "Kasumi Toyama",
+			wantCNName:    "Kasumi Toyama",
+			wantFirstName: "",
+		},
+		{
+			name:          "非字符串元素与空白名称",
+			input:         `{"characterName":[1,"  ",{},"户山 香澄"],"firstName":"香澄","bandId":null}`,
+			wantName:      "户山 香澄",
+			wantCNName:    "户山 香澄",
+			wantFirstName: "",
+		},
+		{
+			name:    "不是对象",
+			input:   `["戸山 香澄"]`,
+			wantErr: true,
+		},
+		{
+			name:    "无效的 JSON",
+			input:   `{`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := model.ParseCharaInfo([]byte(tt.input))
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Nil(t, info)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, info.LocalizedName(model.LocaleJP))
+			assert.Equal(t, tt.wantCNName, info.LocalizedName(model.LocaleCN))
+			assert.Equal(t, tt.wantFirstName, info.LocalizedFirstName(model.LocaleEN))
+			assert.Equal(t, tt.wantBandID, info.BandID)
+		})
+	}
+}
+
+func TestLocalizedStringsAt(t *testing.T) {
+	t.Parallel()
+
+	names := model.LocalizedStrings{"香澄", "", " Kasumi "}
+	assert.Equal(t, "香澄", names.At(model.LocaleJP))
+	assert.Empty(t, names.At(model.LocaleEN), "At should not fall back")
+	assert.Equal(t, "Kasumi", names.At(model.LocaleTW))
+	assert.Empty(t, names.At(model.LocaleKR))
+	assert.Empty(t, names.At(-1))
+	assert.Equal(t, "香澄", names.Get(model.LocaleEN))
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// CostumeSort 表示服装列表的排序方式.
+type CostumeSort string
+
+const (
+	// CostumeSortID 表示按服装ID排序，活动服装排在最后.
+	CostumeSortID CostumeSort = ""
+	// CostumeSortRelease 表示按发布时间从早到晚排序，发布时间未知的服装排在最后.
+	CostumeSortRelease CostumeSort = "release"
+)
+
+// CostumeInfo 表示服装的展示信息
+// 描述与发布时间来自 Bestdori 的服装接口，查不到时为零值.
+type CostumeInfo struct {
+	Name         string    // Live2D 资源包名称
+	Description  string    // 服装描述
+	PublishedAt  time.Time // 发布时间
+	EventLimited bool      // 是否为活动限定服装
+}
+
+// Label 返回列表中显示的文本，例如 "037_live_event_205 — 〇〇活动服 (2023-05)"
+// 没有描述时只显示资源包名称.
+func (c CostumeInfo) Label() string {
+	if c.Description == "" {
+		return c.Name
+	}
+	label := fmt.Sprintf("%s — %s", c.Name, c.Description)
+	if !c.PublishedAt.IsZero() {
+		label += fmt.Sprintf(" (%s)", c.PublishedAt.Format("2006-01"))
+	}
+	return label
+}
+
+// SortCostumeInfosByRelease 按发布时间从早到晚排序服装
+// 发布时间未知的服装保持原有顺序排在最后.
+func SortCostumeInfosByRelease(infos []CostumeInfo) {
+	slices.SortStableFunc(infos, func(a, b CostumeInfo) int {
+		switch {
+		case a.PublishedAt.IsZero() && b.PublishedAt.IsZero():
+			return 0
+		case a.PublishedAt.IsZero():
+			return 1
+		case b.PublishedAt.IsZero():
+			return -1
+		}
+		return a.PublishedAt.Compare(b.PublishedAt)
+	})
+}
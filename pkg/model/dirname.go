@@ -0,0 +1,71 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+// DirNameStyle 表示角色目录名的大小写风格.
+type DirNameStyle string
+
+const (
+	// DirNameStyleLower 表示全部小写，例如 kasumi.
+	DirNameStyleLower DirNameStyle = "lower"
+	// DirNameStyleUpper 表示全部大写，例如 KASUMI.
+	DirNameStyleUpper DirNameStyle = "upper"
+	// DirNameStyleOriginal 表示保留角色名的原始大小写.
+	DirNameStyleOriginal DirNameStyle = "original"
+	// DirNameStylePascal 表示去掉空格、连字符与下划线后每个单词首字母大写，例如 Kasumi.
+	DirNameStylePascal DirNameStyle = "pascal"
+)
+
+// CharaDirName 按风格生成角色目录名，生成的目录名经过文件名清洗
+// 参数:
+//   - name: 角色名
+//   - charaID: 角色ID
+//   - style: 大小写风格
+//   - appendID: 是否以 "<角色名>-<三位角色ID>" 的形式附加角色ID，避免不同角色重名
+//
+// 返回:
+//   - string: 角色目录名，清洗后为空时返回 chara_<三位角色ID>
+func CharaDirName(name string, charaID int, style DirNameStyle, appendID bool) string {
+	switch style {
+	case DirNameStyleOriginal:
+	case DirNameStyleUpper:
+		name = strings.ToUpper(name)
+	case DirNameStylePascal:
+		name = pascalCase(name)
+	case DirNameStyleLower:
+		name = strings.ToLower(name)
+	default:
+		// 未知的风格与 lower 相同，保持原有的目录名
+		name = strings.ToLower(name)
+	}
+
+	name = fsutil.SanitizeFileName(name)
+	if name == "" {
+		return fmt.Sprintf("chara_%03d", charaID)
+	}
+	if appendID {
+		name = fmt.Sprintf("%s-%03d", name, charaID)
+	}
+	return name
+}
+
+// pascalCase 将以空格、连字符或下划线分隔的单词转换为首字母大写并连接在一起.
+func pascalCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == '_'
+	})
+	var b strings.Builder
+	for _, word := range words {
+		first, size := utf8.DecodeRuneInString(word)
+		b.WriteRune(unicode.ToUpper(first))
+		b.WriteString(strings.ToLower(word[size:]))
+	}
+	return b.String()
+}
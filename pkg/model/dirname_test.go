@@ -0,0 +1,38 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+func TestCharaDirName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		style    model.DirNameStyle
+		appendID bool
+		want     string
+	}{
+		{name: "默认小写", input: "Kasumi", style: model.DirNameStyleLower, want: "kasumi"},
+		{name: "未知风格按小写处理", input: "Kasumi", style: "", want: "kasumi"},
+		{name: "全部大写", input: "Kasumi", style: model.DirNameStyleUpper, want: "KASUMI"},
+		{name: "保留原始大小写", input: "Kasumi", style: model.DirNameStyleOriginal, want: "Kasumi"},
+		{name: "PascalCase", input: "rimi-ushigome kitazawa_HAGUMI", style: model.DirNameStylePascal, want: "RimiUshigomeKitazawaHagumi"},
+		{name: "附加角色ID", input: "Kasumi", style: model.DirNameStyleLower, appendID: true, want: "kasumi-001"},
+		{name: "清洗非法字符", input: "Ka/su:mi", style: model.DirNameStyleOriginal, want: "Ka_su_mi"},
+		{name: "PascalCase 同样清洗", input: "ka?sumi", style: model.DirNameStylePascal, want: "Ka_sumi"},
+		{name: "清洗后为空时使用角色ID", input: " .. ", style: model.DirNameStyleOriginal, appendID: true, want: "chara_001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, model.CharaDirName(tt.input, 1, tt.style, tt.appendID))
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+// ModelDataFileName 是生成的模型数据文件名.
+const ModelDataFileName = "model.json"
+
+// 模型数据扩展信息常量.
+const (
+	// ExtensionKey 是模型数据中扩展信息的键名.
+	ExtensionKey = "bestdori_dl"
+
+	// ExtensionSchemaLegacy 是不包含扩展信息的旧版模型数据的格式版本.
+	ExtensionSchemaLegacy = 1
+	// ExtensionSchemaVersion 是当前生成的模型数据的格式版本
+	// 扩展信息或模型数据发生不兼容变化时递增.
+	ExtensionSchemaVersion = 2
+
+	// FeatureProvenance 表示模型目录中包含记录文件来源的下载清单.
+	FeatureProvenance = "provenance"
+)
+
+// Extension 表示本工具写入模型数据的扩展信息
+// 下游工具可以据此识别由本工具生成的文件及其包含的功能.
+type Extension struct {
+	Schema       int      `json:"schema"`                  // 模型数据格式版本
+	ToolVersion  string   `json:"tool_version"`            // 生成时的程序版本
+	Profile      string   `json:"profile,omitempty"`       // 生成时使用的输出配置
+	LayoutPreset string   `json:"layout_preset,omitempty"` // 生成时使用的布局预设
+	Features     []string `json:"features,omitempty"`      // 包含的功能
+}
@@ -0,0 +1,24 @@
+package model
+
+// IndexFileName 是模型目录下的动作与表情索引文件名.
+const IndexFileName = "index.json"
+
+// IndexMotionGroup 表示索引中的一个动作组.
+type IndexMotionGroup struct {
+	Name  string   `json:"name"`  // 动作组名称
+	Files []string `json:"files"` // 动作文件路径，相对于模型目录
+}
+
+// IndexExpression 表示索引中的一个表情.
+type IndexExpression struct {
+	Name string `json:"name"` // 表情名称
+	File string `json:"file"` // 表情文件路径，相对于模型目录
+}
+
+// ModelIndex 表示模型的动作与表情索引
+// 从模型数据中提取，便于渲染器或用户快速查找可用的动作和表情.
+type ModelIndex struct {
+	Model       string             `json:"model"`       // 模型名称
+	Motions     []IndexMotionGroup `json:"motions"`     // 动作组列表，按名称排序
+	Expressions []IndexExpression  `json:"expressions"` // 表情列表，按名称排序
+}
@@ -0,0 +1,55 @@
+package model
+
+// valueAt 沿着键路径逐层取出 encoding/json 解码结果中的值
+// 中间节点不是对象或键不存在时返回 false.
+func valueAt(value any, keys ...string) (any, bool) {
+	for _, key := range keys {
+		dir, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = dir[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// StringAt 沿着键路径取出 encoding/json 解码结果中的字符串
+// 用于读取结构不固定的 Bestdori 数据，路径不存在或类型不符时返回空字符串，不会 panic
+// 参数:
+//   - value: encoding/json 解码得到的值
+//   - keys: 键路径，为空时直接转换 value
+//
+// 返回:
+//   - string: 取出的字符串
+//   - bool: 路径是否存在且值为字符串
+func StringAt(value any, keys ...string) (string, bool) {
+	value, ok := valueAt(value, keys...)
+	if !ok {
+		return "", false
+	}
+	s, ok := value.(string)
+	return s, ok
+}
+
+// StringsAt 沿着键路径取出 encoding/json 解码结果中的多语言数组
+// 与解析 LocalizedStrings 时相同，null 与非字符串元素记为空字符串并保留位置
+// 参数:
+//   - value: encoding/json 解码得到的值
+//   - keys: 键路径，为空时直接转换 value
+//
+// 返回:
+//   - LocalizedStrings: 取出的数组，路径不存在或不是数组时为 nil
+//   - bool: 路径是否存在且值为数组
+func StringsAt(value any, keys ...string) (LocalizedStrings, bool) {
+	value, ok := valueAt(value, keys...)
+	if !ok {
+		return nil, false
+	}
+	values, ok := value.([]any)
+	if !ok {
+		return nil, false
+	}
+	return localizedFromValues(values), true
+}
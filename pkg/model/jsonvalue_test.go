@@ -0,0 +1,98 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// decodeJSON 将 JSON 文本解码为 encoding/json 的通用值.
+func decodeJSON(t *testing.T, raw string) any {
+	t.Helper()
+	var value any
+	require.NoError(t, json.Unmarshal([]byte(raw), &value))
+	return value
+}
+
+func TestStringAt(t *testing.T) {
+	t.Parallel()
+
+	const raw = `{"assetBundleName": "037_casual-2023", "id": 1, "nested": {"name": "kasumi", "list": ["a"]}, "empty": null}`
+
+	tests := []struct {
+		name   string
+		input  string
+		keys   []string
+		want   string
+		wantOK bool
+	}{
+		{name: "顶层字段", input: raw, keys: []string{"assetBundleName"}, want: "037_casual-2023", wantOK: true},
+		{name: "嵌套字段", input: raw, keys: []string{"nested", "name"}, want: "kasumi", wantOK: true},
+		{name: "没有键路径时直接转换", input: `"kasumi"`, want: "kasumi", wantOK: true},
+		{name: "字段不存在", input: raw, keys: []string{"missing"}},
+		{name: "值为数字", input: raw, keys: []string{"id"}},
+		{name: "值为 null", input: raw, keys: []string{"empty"}},
+		{name: "值为数组", input: raw, keys: []string{"nested", "list"}},
+		{name: "中间节点不是对象", input: raw, keys: []string{"assetBundleName", "name"}},
+		{name: "根节点不是对象", input: `["037_casual-2023"]`, keys: []string{"assetBundleName"}},
+		{name: "根节点为 null", input: `null`, keys: []string{"assetBundleName"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := model.StringAt(decodeJSON(t, tt.input), tt.keys...)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+
+	// 未经解码的 nil 与其他类型同样不会 panic
+	got, ok := model.StringAt(nil, "a", "b")
+	assert.Empty(t, got)
+	assert.False(t, ok)
+	got, ok = model.StringAt(map[string]string{"a": "b"}, "a")
+	assert.Empty(t, got)
+	assert.False(t, ok)
+}
+
+func TestStringsAt(t *testing.T) {
+	t.Parallel()
+
+	const raw = `{"description": ["活动服", null, 1, "活动服（国服）"], "name": "kasumi", "detail": {"names": []}}`
+
+	tests := []struct {
+		name   string
+		keys   []string
+		want   model.LocalizedStrings
+		wantOK bool
+	}{
+		{name: "保留元素位置", keys: []string{"description"}, want: model.LocalizedStrings{"活动服", "", "", "活动服（国服）"}, wantOK: true},
+		{name: "空数组", keys: []string{"detail", "names"}, want: model.LocalizedStrings{}, wantOK: true},
+		{name: "值不是数组", keys: []string{"name"}},
+		{name: "字段不存在", keys: []string{"publishedAt"}},
+		{name: "中间节点不是对象", keys: []string{"name", "names"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := model.StringsAt(decodeJSON(t, raw), tt.keys...)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+
+	// 取值失败时得到的 nil 数组仍可安全使用
+	missing, _ := model.StringsAt(nil, "description")
+	assert.Empty(t, missing.Get(model.LocaleCN))
+	values, _ := model.StringsAt(decodeJSON(t, raw), "description")
+	assert.Equal(t, "活动服（国服）", values.Get(model.LocaleCN))
+	assert.Equal(t, "活动服", values.Get(model.LocaleEN))
+}
@@ -0,0 +1,21 @@
+package model
+
+// OutputLayout 表示模型文件在模型目录中的组织方式.
+type OutputLayout string
+
+const (
+	// OutputLayoutRestructured 表示按文件类型整理到 data/{textures,motions,expressions} 目录.
+	OutputLayoutRestructured OutputLayout = "restructured"
+	// OutputLayoutOriginal 表示保留 Bestdori 资源包的原始路径，并保存 buildData.asset.
+	OutputLayoutOriginal OutputLayout = "original"
+)
+
+// DirectoryLayout 表示模型目录在保存路径下的组织方式.
+type DirectoryLayout string
+
+const (
+	// DirectoryLayoutByCharacter 表示按角色分目录保存，例如 <保存路径>/<角色名>/casual-2023.
+	DirectoryLayoutByCharacter DirectoryLayout = "by-character"
+	// DirectoryLayoutFlat 表示所有模型并列保存在保存路径下，例如 <保存路径>/037_casual-2023.
+	DirectoryLayoutFlat DirectoryLayout = "flat"
+)
@@ -0,0 +1,91 @@
+package model
+
+import "time"
+
+const (
+	// ManifestFileName 是模型目录下的下载清单文件名.
+	ManifestFileName = "download_manifest.json"
+	// FolderMarkerFileName 是角色目录下记录目录信息的标记文件名.
+	FolderMarkerFileName = ".bestdori_folder.json"
+	// ModelHashFileName 是模型目录下记录整模型哈希的文件名.
+	ModelHashFileName = ".model_hash"
+	// ModelHashVersion 是整模型哈希记录的格式版本.
+	ModelHashVersion = 1
+)
+
+// FileSource 表示文件的获取来源.
+type FileSource string
+
+const (
+	// FileSourceNetwork 表示文件本次从网络下载.
+	FileSourceNetwork FileSource = "network"
+	// FileSourceCache 表示文件复用了本地已有的缓存副本.
+	FileSourceCache FileSource = "cache"
+)
+
+// DownloadRecovery 表示文件多次校验失败后最终下载成功所用的方式.
+type DownloadRecovery string
+
+const (
+	// DownloadRecoveryCacheBust 表示附加时间戳查询参数与 Cache-Control: no-cache 请求头绕过中间缓存后下载成功.
+	DownloadRecoveryCacheBust DownloadRecovery = "cache-bust"
+	// DownloadRecoveryFallbackServer 表示切换到 ServerFallback 中的其他服务器后下载成功.
+	DownloadRecoveryFallbackServer DownloadRecovery = "fallback-server"
+)
+
+// FileProvenance 表示单个文件的来源信息
+// 用于排查文件内容异常时追溯其下载来源.
+type FileProvenance struct {
+	Source       FileSource `json:"source"`                 // 文件来源
+	Host         string     `json:"host,omitempty"`         // 下载所用的主机
+	URL          string     `json:"url,omitempty"`          // 下载所用的完整 URL
+	DownloadedAt time.Time  `json:"downloadedAt"`           // 原始下载时间
+	Size         int64      `json:"size"`                   // 文件大小（字节）
+	ExpectedSize int64      `json:"expectedSize,omitempty"` // 响应 Content-Length 声明的大小（字节），未知时为 0
+	// 文件多次校验失败后最终下载成功所用的方式，正常下载时为空
+	Recovery DownloadRecovery `json:"recovery,omitempty"`
+}
+
+// ManifestFile 表示下载清单中的单个文件记录.
+type ManifestFile struct {
+	Provenance FileProvenance `json:"provenance"`       // 文件来源信息
+	SHA256     string         `json:"sha256,omitempty"` // 文件内容的 SHA-256
+}
+
+// DownloadManifest 表示模型的下载清单
+// 记录模型目录中每个文件的相关信息，key 为相对于模型目录的路径.
+type DownloadManifest struct {
+	Model        string                  `json:"model"`                 // 模型名称
+	Server       string                  `json:"server,omitempty"`      // 模型资源来源的服务器
+	DownloadedAt time.Time               `json:"downloadedAt"`          // 本次构建完成的时间，格式为 ISO 8601
+	ToolVersion  string                  `json:"toolVersion,omitempty"` // 生成清单的工具版本
+	Files        map[string]ManifestFile `json:"files"`                 // 文件记录映射
+}
+
+// ModelHash 表示模型目录的整模型哈希记录
+// 下载同名模型时，构建数据的哈希相同且本地文件的聚合哈希仍与记录一致则跳过整个模型.
+type ModelHash struct {
+	Version   int    `json:"version"`   // 格式版本
+	BuildData string `json:"buildData"` // 构建数据及其来源服务器的 SHA-256，用于判断远端模型是否更新
+	Content   string `json:"content"`   // 所有文件内容的聚合 SHA-256
+}
+
+// FolderMarker 表示角色目录的标记信息
+// 用于避免同一角色目录中混入来自不同服务器的模型.
+type FolderMarker struct {
+	Server string `json:"server"` // 目录中模型的来源服务器
+}
+
+// ServerConflictDecision 表示角色目录服务器冲突时的处理方式.
+type ServerConflictDecision string
+
+const (
+	// ServerConflictAsk 表示每次冲突时询问用户.
+	ServerConflictAsk ServerConflictDecision = ""
+	// ServerConflictProceed 表示仍然保存到原角色目录.
+	ServerConflictProceed ServerConflictDecision = "proceed"
+	// ServerConflictSuffix 表示保存到带服务器后缀的角色目录，例如 anon-cn.
+	ServerConflictSuffix ServerConflictDecision = "suffix"
+	// ServerConflictAbort 表示中止该模型的下载.
+	ServerConflictAbort ServerConflictDecision = "abort"
+)
@@ -0,0 +1,52 @@
+package model
+
+// OutputFormat 表示生成的模型数据格式.
+type OutputFormat string
+
+const (
+	// OutputFormatCubism2 表示只生成 Cubism 2 的 model.json.
+	OutputFormatCubism2 OutputFormat = "cubism2"
+	// OutputFormatCubism3 表示在 model.json 之外生成 Cubism 3 的 <模型名>.model3.json.
+	OutputFormatCubism3 OutputFormat = "cubism3"
+)
+
+// Model3FileSuffix 是 Cubism 3 模型数据文件名的后缀，文件名为模型名称加上该后缀.
+const Model3FileSuffix = ".model3.json"
+
+// Model3Version 是生成的 Cubism 3 模型数据的版本.
+const Model3Version = 3
+
+// Model3Data 表示 Cubism 3 的模型数据.
+type Model3Data struct {
+	Version        int                  `json:"Version"`          // 模型数据版本
+	FileReferences Model3FileReferences `json:"FileReferences"`   // 模型引用的文件
+	Groups         []Model3Group        `json:"Groups"`           // 参数分组，例如眨眼与口型同步
+	Layout         map[string]float64   `json:"Layout,omitempty"` // 模型的显示位置与大小
+}
+
+// Model3FileReferences 表示 Cubism 3 模型数据引用的文件.
+type Model3FileReferences struct {
+	Moc         string                    `json:"Moc"`                   // 模型文件路径
+	Textures    []string                  `json:"Textures"`              // 纹理文件路径列表
+	Physics     string                    `json:"Physics,omitempty"`     // 物理文件路径
+	Motions     map[string][]Model3Motion `json:"Motions,omitempty"`     // 动作文件映射，key 为动作分组
+	Expressions []Model3Expression        `json:"Expressions,omitempty"` // 表情文件列表
+}
+
+// Model3Motion 表示 Cubism 3 模型数据中的动作文件.
+type Model3Motion struct {
+	File string `json:"File"` // 动作文件路径
+}
+
+// Model3Expression 表示 Cubism 3 模型数据中的表情文件.
+type Model3Expression struct {
+	Name string `json:"Name"` // 表情名称
+	File string `json:"File"` // 表情文件路径
+}
+
+// Model3Group 表示 Cubism 3 模型数据中的参数分组.
+type Model3Group struct {
+	Target string   `json:"Target"` // 分组对象，通常为 Parameter
+	Name   string   `json:"Name"`   // 分组名称，例如 EyeBlink、LipSync
+	Ids    []string `json:"Ids"`    // 分组包含的参数ID
+}
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+)
+
+// Live2D 名称的组成约定：<三位角色编号>_<服装名称>，例如 037_casual-2023
+// 1000 以上的特殊角色编号不补零，例如 1001_casual.
+const (
+	live2dCharaIDLength = 3               // 角色编号补零后的位数
+	live2dRipSuffix     = "_rip"          // 资源目录名的后缀
+	live2dCharaPrefix   = "live2d/chara/" // 资源路径中模型目录的前缀
+)
+
+// 全角字符与半角字符的编码差值.
+const (
+	fullWidthStart  = '！'
+	fullWidthEnd    = '～'
+	fullWidthOffset = fullWidthStart - '!'
+	fullWidthSpace  = '　'
+)
+
+// foldWidth 将全角 ASCII 字符和全角空格转换为对应的半角字符.
+func foldWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == fullWidthSpace:
+			return ' '
+		case r >= fullWidthStart && r <= fullWidthEnd:
+			return r - fullWidthOffset
+		default:
+			return r
+		}
+	}, s)
+}
+
+// stripLive2dAffixes 去掉名称中常见的多余部分
+// 包括资源路径或 URL 中的 live2d/chara/ 前缀、末尾的斜杠以及 _rip 后缀.
+func stripLive2dAffixes(name string) string {
+	if idx := strings.LastIndex(name, live2dCharaPrefix); idx >= 0 {
+		name = name[idx+len(live2dCharaPrefix):]
+	}
+	name = strings.TrimRight(name, "/")
+	return strings.TrimSuffix(name, live2dRipSuffix)
+}
+
+// CanonicalLive2dName 返回服务器资源包名称对应的统一名称
+// 部分服务器的资源包名称带有服务器后缀，例如国际服的 037_casual-2023_en，去掉后缀后与日服的名称一致，
+// 合并各服务器的资源索引时视为同一服装；去掉后缀后不再是有效的 Live2D 名称时保持原样
+// 参数:
+//   - name: 资源索引中的资源包名称
+//   - server: 资源索引所属的服务器
+//
+// 返回:
+//   - string: 统一名称，没有服务器后缀时为 name 本身
+func CanonicalLive2dName(name, server string) string {
+	if server == "" {
+		return name
+	}
+	for _, separator := range []string{"_", "-"} {
+		base, found := strings.CutSuffix(name, separator+server)
+		if !found {
+			continue
+		}
+		if id, costume, ok := strings.Cut(base, "_"); ok && costume != "" && isCharaIDPrefix(id) {
+			return base
+		}
+	}
+	return name
+}
+
+// LooksLikeLive2dName 判断输入是否像是 Live2D 名称而不是角色名称
+// 以数字加下划线开头或包含资源路径前缀的输入视为 Live2D 名称，不要求格式完全正确.
+func LooksLikeLive2dName(input string) bool {
+	name := strings.ToLower(strings.TrimSpace(foldWidth(input)))
+	if strings.Contains(name, live2dCharaPrefix) {
+		return true
+	}
+	id, _, found := strings.Cut(name, "_")
+	return found && isDigits(id)
+}
+
+// isDigits 判断字符串是否非空且只包含 ASCII 数字.
+func isDigits(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) < 0
+}
+
+// isCharaIDPrefix 判断字符串是否为 Live2D 名称中的角色编号
+// 1000 以下的编号补零为三位，更大的编号不补零.
+func isCharaIDPrefix(id string) bool {
+	if !isDigits(id) {
+		return false
+	}
+	return len(id) == live2dCharaIDLength || (len(id) > live2dCharaIDLength && id[0] != '0')
+}
+
+// isCostumeRune 判断字符是否可以出现在服装名称中.
+func isCostumeRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-'
+}
+
+// NormalizeLive2dName 规范化用户输入的 Live2D 名称
+// 会去掉首尾空白、转换全角字符、转为小写，并去掉资源路径前缀、末尾斜杠和 _rip 后缀
+// 参数:
+//   - input: 用户输入的名称
+//
+// 返回:
+//   - string: 规范化后的名称
+//   - error: 名称格式无效时返回包装了 errs.ErrInvalidLive2dName 的错误，说明具体原因
+func NormalizeLive2dName(input string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(foldWidth(input)))
+	name = stripLive2dAffixes(name)
+
+	if name == "" {
+		return "", fmt.Errorf("%w: 名称为空", errs.ErrInvalidLive2dName)
+	}
+	if strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return "", fmt.Errorf("%w: %q 包含空白字符，多个模型请用逗号分隔", errs.ErrInvalidLive2dName, input)
+	}
+	if strings.Contains(name, "/") {
+		return "", fmt.Errorf("%w: %q 包含多余的路径，只需要模型目录名", errs.ErrInvalidLive2dName, input)
+	}
+
+	id, costume, found := strings.Cut(name, "_")
+	switch {
+	case !found:
+		return "", fmt.Errorf("%w: %q 缺少角色编号，格式应为 037_casual-2023", errs.ErrInvalidLive2dName, input)
+	case !isDigits(id):
+		return "", fmt.Errorf("%w: 角色编号 %q 不是数字", errs.ErrInvalidLive2dName, id)
+	case !isCharaIDPrefix(id):
+		return "", fmt.Errorf("%w: 角色编号 %q 应为 %d 位数字，例如 037，1000 以上的编号不补零", errs.ErrInvalidLive2dName, id, live2dCharaIDLength)
+	case costume == "":
+		return "", fmt.Errorf("%w: %q 缺少服装名称", errs.ErrInvalidLive2dName, input)
+	}
+
+	for _, r := range costume {
+		if !isCostumeRune(r) {
+			return "", fmt.Errorf("%w: 服装名称 %q 包含无效字符 %q", errs.ErrInvalidLive2dName, costume, r)
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,212 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+func TestNormalizeLive2dName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "规范名称", input: "037_casual-2023", want: "037_casual-2023"},
+		{name: "首尾空白", input: "  037_casual-2023\t\n", want: "037_casual-2023"},
+		{name: "大写字母", input: "037_Casual-2023", want: "037_casual-2023"},
+		{name: "全角数字", input: "０３７_casual", want: "037_casual"},
+		{name: "全角字母与下划线", input: "０３７＿ｃａｓｕａｌ", want: "037_casual"},
+		{name: "全角空格", input: "　037_casual　", want: "037_casual"},
+		{name: "末尾斜杠", input: "037_casual/", want: "037_casual"},
+		{name: "多个末尾斜杠", input: "037_casual//", want: "037_casual"},
+		{name: "rip 后缀", input: "037_casual_rip", want: "037_casual"},
+		{name: "rip 后缀与末尾斜杠", input: "037_casual_rip/", want: "037_casual"},
+		{name: "资源路径前缀", input: "live2d/chara/037_casual", want: "037_casual"},
+		{name: "完整 URL", input: "https://bestdori.com/assets/jp/live2d/chara/037_casual_rip/", want: "037_casual"},
+		{name: "服装名称包含下划线", input: "001_2019_dream_festival", want: "001_2019_dream_festival"},
+		{name: "空输入", input: "", wantErr: "名称为空"},
+		{name: "只有空白", input: "   ", wantErr: "名称为空"},
+		{name: "只有前缀", input: "live2d/chara/", wantErr: "名称为空"},
+		{name: "包含空格", input: "037_casual 038_live", wantErr: "包含空白字符"},
+		{name: "多余的路径", input: "037_casual_rip/buildData.asset", wantErr: "包含多余的路径"},
+		{name: "缺少下划线", input: "037casual", wantErr: "缺少角色编号"},
+		{name: "角色编号不是数字", input: "abc_casual", wantErr: "不是数字"},
+		{name: "角色编号为空", input: "_casual", wantErr: "不是数字"},
+		{name: "角色编号位数不足", input: "37_casual", wantErr: "应为 3 位数字"},
+		{name: "角色编号补零过多", input: "0037_casual", wantErr: "应为 3 位数字"},
+		{name: "特殊角色编号", input: "1001_casual", want: "1001_casual"},
+		{name: "缺少服装名称", input: "037_", wantErr: "缺少服装名称"},
+		{name: "服装名称包含无效字符", input: "037_casual.2023", wantErr: "包含无效字符"},
+		{name: "服装名称包含中文", input: "037_私服", wantErr: "包含无效字符"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := model.NormalizeLive2dName(tt.input)
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, errs.ErrInvalidLive2dName)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLooksLikeLive2dName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "模型名称", input: "037_casual", want: true},
+		{name: "全角数字", input: "０３７_casual", want: true},
+		{name: "资源路径", input: "live2d/chara/037_casual", want: true},
+		{name: "格式有误的模型名称", input: "37_casual.2023", want: true},
+		{name: "角色名称", input: "anon", want: false},
+		{name: "角色编号", input: "37", want: false},
+		{name: "包含下划线的角色名称", input: "anon_chihaya", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, model.LooksLikeLive2dName(tt.input))
+		})
+	}
+}
+
+func TestCanonicalLive2dName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		bundle string
+		server string
+		want   string
+	}{
+		{name: "下划线服务器后缀", bundle: "037_casual-2023_en", server: "en", want: "037_casual-2023"},
+		{name: "连字符服务器后缀", bundle: "037_casual-en", server: "en", want: "037_casual"},
+		{name: "特殊角色", bundle: "1001_casual_en", server: "en", want: "1001_casual"},
+		{name: "general 资源", bundle: "037_general_en", server: "en", want: "037_general"},
+		{name: "没有服务器后缀", bundle: "037_casual-2023", server: "en", want: "037_casual-2023"},
+		{name: "其他服务器的后缀", bundle: "037_casual_en", server: "jp", want: "037_casual_en"},
+		{name: "后缀是服装名称本身", bundle: "037_en", server: "en", want: "037_en"},
+		{name: "后缀只是名称的一部分", bundle: "037_casual_green", server: "en", want: "037_casual_green"},
+		{name: "前缀不是角色编号", bundle: "live_casual_en", server: "en", want: "live_casual_en"},
+		{name: "未知服务器", bundle: "037_casual_en", want: "037_casual_en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, model.CanonicalLive2dName(tt.bundle, tt.server))
+		})
+	}
+}
+
+func TestMatchLive2dNames(t *testing.T) {
+	t.Parallel()
+
+	names := []string{
+		"037_casual-2023",
+		"037_casual-2022",
+		"037_school_summer",
+		"036_casual-2023",
+		"037_live_event_307_ssr",
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "星号匹配", input: "037_casual-*", want: []string{"037_casual-2022", "037_casual-2023"}},
+		{name: "问号匹配", input: "03?_casual-2023", want: []string{"036_casual-2023", "037_casual-2023"}},
+		{name: "字符集合", input: "037_[ls]*", want: []string{"037_live_event_307_ssr", "037_school_summer"}},
+		{name: "全角与大写", input: "０３７_CASUAL-２０２２＊", want: []string{"037_casual-2022"}},
+		{name: "资源路径", input: "live2d/chara/036_*_rip", want: []string{"036_casual-2023"}},
+		{name: "没有匹配", input: "099_*", want: nil},
+		{name: "语法错误", input: "037_[casual", wantErr: true},
+		{name: "包含路径", input: "037/*", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.True(t, model.IsLive2dPattern(tt.input))
+			pattern, err := model.NormalizeLive2dPattern(tt.input)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errs.ErrInvalidLive2dName)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, model.MatchLive2dNames(names, pattern))
+		})
+	}
+
+	assert.False(t, model.IsLive2dPattern("037_casual-2023"))
+}
+
+func TestLive2dPatterns(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"037_casual-2023", "037_placeholder", "036_live_event_1", "036_casual-2023"}
+
+	tests := []struct {
+		name        string
+		inputs      []string
+		wantKept    []string
+		wantMatched []string
+		wantErr     bool
+	}{
+		{
+			name:        "精确名称只匹配自身",
+			inputs:      []string{"037_placeholder"},
+			wantKept:    []string{"037_casual-2023", "036_live_event_1", "036_casual-2023"},
+			wantMatched: []string{"037_placeholder"},
+		},
+		{
+			name:        "多个通配符模式",
+			inputs:      []string{"*_live_*", "LIVE2D/CHARA/036_CASUAL-*_RIP", ""},
+			wantKept:    []string{"037_casual-2023", "037_placeholder"},
+			wantMatched: []string{"036_live_event_1", "036_casual-2023"},
+		},
+		{name: "没有模式", wantKept: names},
+		{name: "语法错误", inputs: []string{"037_[casual"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			patterns, err := model.ParseLive2dPatterns(tt.inputs)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errs.ErrInvalidLive2dName)
+				return
+			}
+			require.NoError(t, err)
+			kept, matched := patterns.Split(names)
+			assert.Equal(t, tt.wantKept, kept)
+			assert.Equal(t, tt.wantMatched, matched)
+			for _, name := range matched {
+				assert.True(t, patterns.Match(name))
+			}
+		})
+	}
+}
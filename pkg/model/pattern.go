@@ -0,0 +1,113 @@
+package model
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+)
+
+// live2dPatternChars 是通配符模式中的特殊字符.
+const live2dPatternChars = "*?["
+
+// IsLive2dPattern 判断输入是否为包含通配符的 Live2D 名称模式，例如 037_casual-*.
+func IsLive2dPattern(input string) bool {
+	return strings.ContainsAny(foldWidth(input), live2dPatternChars)
+}
+
+// NormalizeLive2dPattern 规范化用户输入的 Live2D 名称模式
+// 与 NormalizeLive2dName 一样转换全角字符、转为小写并去掉资源路径前缀和 _rip 后缀
+// 参数:
+//   - input: 用户输入的模式
+//
+// 返回:
+//   - string: 规范化后的模式
+//   - error: 模式语法无效时返回包装了 errs.ErrInvalidLive2dName 的错误
+func NormalizeLive2dPattern(input string) (string, error) {
+	pattern := stripLive2dAffixes(strings.ToLower(strings.TrimSpace(foldWidth(input))))
+	if pattern == "" {
+		return "", fmt.Errorf("%w: 模式为空", errs.ErrInvalidLive2dName)
+	}
+	if strings.Contains(pattern, "/") {
+		return "", fmt.Errorf("%w: 模式 %q 包含多余的路径，只需要模型目录名", errs.ErrInvalidLive2dName, input)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", fmt.Errorf("%w: 模式 %q 语法错误", errs.ErrInvalidLive2dName, input)
+	}
+	return pattern, nil
+}
+
+// MatchLive2dNames 返回名称列表中与模式匹配的名称
+// 参数:
+//   - names: 名称列表
+//   - pattern: 规范化后的模式，* 匹配任意字符，? 匹配单个字符，[...] 匹配字符集合
+//
+// 返回:
+//   - []string: 按名称排序的匹配结果
+func MatchLive2dNames(names []string, pattern string) []string {
+	var matches []string
+	for _, name := range names {
+		// 模式已经过校验，不会返回错误
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+	slices.Sort(matches)
+	return matches
+}
+
+// Live2dPatterns 表示一组规范化后的 Live2D 名称模式
+// 屏蔽列表与命令行的排除模式共用同一套匹配规则.
+type Live2dPatterns []string
+
+// ParseLive2dPatterns 规范化并校验一组 Live2D 名称模式，不含通配符的名称只匹配自身
+// 参数:
+//   - inputs: 用户输入的模式，空字符串会被忽略
+//
+// 返回:
+//   - Live2dPatterns: 规范化后的模式
+//   - error: 任一模式语法无效时返回包装了 errs.ErrInvalidLive2dName 的错误
+func ParseLive2dPatterns(inputs []string) (Live2dPatterns, error) {
+	patterns := make(Live2dPatterns, 0, len(inputs))
+	for _, input := range inputs {
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+		pattern, err := NormalizeLive2dPattern(input)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// Match 判断名称是否与任一模式匹配.
+func (p Live2dPatterns) Match(name string) bool {
+	return slices.ContainsFunc(p, func(pattern string) bool {
+		// 模式已经过校验，不会返回错误
+		ok, _ := path.Match(pattern, name)
+		return ok
+	})
+}
+
+// Split 按是否与任一模式匹配拆分名称列表，两部分均保持原有顺序
+// 参数:
+//   - names: 名称列表
+//
+// 返回:
+//   - []string: 不匹配的名称
+//   - []string: 匹配的名称
+func (p Live2dPatterns) Split(names []string) ([]string, []string) {
+	var kept, matched []string
+	for _, name := range names {
+		if p.Match(name) {
+			matched = append(matched, name)
+		} else {
+			kept = append(kept, name)
+		}
+	}
+	return kept, matched
+}
@@ -0,0 +1,16 @@
+package model
+
+// 内置布局预设名称.
+const (
+	LayoutPresetStandard = "standard" // 标准全身模型
+	LayoutPresetChibi    = "chibi"    // Q 版模型
+	LayoutPresetHalf     = "half"     // 半身模型
+)
+
+// LayoutPreset 表示 model.json 中的布局参数预设
+// 不同体型的模型需要不同的 layout 与 hit_areas_custom 才能正确缩放和响应点击.
+type LayoutPreset struct {
+	Match          []string             `json:"match" yaml:"match"`                       // 模型名称中包含任一关键字时自动应用
+	Layout         map[string]float64   `json:"layout" yaml:"layout"`                     // 布局参数
+	HitAreasCustom map[string][]float64 `json:"hit_areas_custom" yaml:"hit_areas_custom"` // 自定义点击区域
+}
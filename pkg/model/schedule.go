@@ -0,0 +1,11 @@
+package model
+
+// ModelScheduling 表示批量下载时模型的开始顺序.
+type ModelScheduling string
+
+const (
+	// ModelSchedulingFIFO 表示按选择的顺序开始下载.
+	ModelSchedulingFIFO ModelScheduling = "fifo"
+	// ModelSchedulingFewestFiles 表示优先开始文件数少的模型，避免小模型排在大模型之后长时间等待.
+	ModelSchedulingFewestFiles ModelScheduling = "fewest-files"
+)
@@ -0,0 +1,33 @@
+package model
+
+// TextureAlphaMode 表示纹理 alpha 通道的修正方式.
+type TextureAlphaMode string
+
+const (
+	// TextureAlphaNone 表示不处理纹理.
+	TextureAlphaNone TextureAlphaMode = ""
+	// TextureAlphaPremultiply 表示将 RGB 通道乘以 alpha，转换为预乘 alpha 纹理.
+	TextureAlphaPremultiply TextureAlphaMode = "premultiply"
+	// TextureAlphaUnpremultiply 表示将 RGB 通道除以 alpha，还原预乘 alpha 纹理.
+	TextureAlphaUnpremultiply TextureAlphaMode = "unpremultiply"
+)
+
+// TexturesMetaFileName 是模型目录下的纹理元信息文件名.
+const TexturesMetaFileName = "textures.json"
+
+// TextureMeta 表示一张纹理的元信息.
+type TextureMeta struct {
+	File    string `json:"file"`              // 纹理文件路径，相对于模型目录
+	Width   int    `json:"width"`             // 宽度（像素），无法解码时为 0
+	Height  int    `json:"height"`            // 高度（像素），无法解码时为 0
+	Format  string `json:"format,omitempty"`  // 图片格式，例如 png，无法解码时为空
+	Unknown bool   `json:"unknown,omitempty"` // 纹理是否无法解码，尺寸未知
+}
+
+// TexturesMeta 表示模型全部纹理的元信息
+// 便于渲染器在加载纹理前预分配显存或图集空间.
+type TexturesMeta struct {
+	Model    string        `json:"model"`    // 模型名称
+	Count    int           `json:"count"`    // 纹理数量
+	Textures []TextureMeta `json:"textures"` // 纹理元信息，顺序与模型数据中的纹理一致
+}
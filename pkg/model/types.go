@@ -35,6 +35,8 @@ type BuildData struct {
 	Transition  BundleFile   `json:"transition"`  // 过渡文件
 	Motions     []BundleFile `json:"motions"`     // 动作文件列表
 	Expressions []BundleFile `json:"expressions"` // 表情文件列表
+
+	Server string `json:"-"` // 实际获取到构建数据的服务器，后续文件从同一服务器下载
 }
 
 // MotionFile 表示动作文件
@@ -55,6 +57,7 @@ type ExpressionFile struct {
 type Live2dModel struct {
 	Model       string                  `json:"model,omitempty"`       // 模型文件路径
 	Physics     string                  `json:"physics,omitempty"`     // 物理文件路径
+	Transition  string                  `json:"transition,omitempty"`  // 过渡文件路径
 	Textures    []string                `json:"textures,omitempty"`    // 纹理文件路径列表
 	Motions     map[string][]MotionFile `json:"motions,omitempty"`     // 动作文件映射
 	Expressions []ExpressionFile        `json:"expressions,omitempty"` // 表情文件列表
@@ -67,9 +70,11 @@ type Data struct {
 	HitAreasCustom map[string][]float64    `json:"hit_areas_custom"`
 	Model          string                  `json:"model"`
 	Physics        string                  `json:"physics"`
+	Transition     string                  `json:"transition,omitempty"` // 过渡文件，只有部分模型有
 	Textures       []string                `json:"textures"`
 	Motions        map[string][]MotionFile `json:"motions"`
 	Expressions    []ExpressionFile        `json:"expressions"`
+	Extension      *Extension              `json:"bestdori_dl,omitempty"` // 本工具的扩展信息，旧版模型数据中不存在
 }
 
 // MatchChara 表示匹配的角色信息
@@ -79,3 +84,11 @@ type MatchChara struct {
 	Name  string   `json:"name"`  // 角色名称
 	Names []string `json:"names"` // 角色所有可能的名称列表
 }
+
+// CharaRecord 表示角色与 Live2D 模型的映射记录
+// 用于导出可离线使用的角色-模型数据库.
+type CharaRecord struct {
+	CharaID    int      `json:"charaId"`    // 角色ID
+	CharaNames []string `json:"charaNames"` // 角色在各服务器的名称
+	Costumes   []string `json:"costumes"`   // Live2D 服装列表
+}
@@ -0,0 +1,432 @@
+// Package pack 提供了 Live2D 模型分发包的生成、校验与解包功能
+// 分发包（.l2dpack）是固定结构的 zip，包含 model.json、数据文件、meta.json 与 manifest.json
+package pack
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/downloader"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+const (
+	// FormatVersion 是当前的分发包格式版本
+	// 格式发生不兼容变化时递增，并在 migrateMeta 中处理旧版本.
+	FormatVersion = 1
+
+	// Extension 是分发包的文件扩展名.
+	Extension = ".l2dpack"
+
+	// metaName 是分发包中描述文件的名称.
+	metaName = "meta.json"
+	// manifestName 是分发包中下载清单的名称，解包后还原为模型目录中的下载清单.
+	manifestName = "manifest.json"
+)
+
+var (
+	// ErrUnsupportedVersion 表示分发包的格式版本比当前程序更新.
+	ErrUnsupportedVersion = errors.New("不支持的模型包版本")
+	// ErrInvalidPack 表示分发包格式无效.
+	ErrInvalidPack = errors.New("无效的模型包")
+	// ErrCorrupted 表示文件内容与记录的 SHA-256 不一致.
+	ErrCorrupted = errors.New("模型包内容已损坏")
+)
+
+// Meta 表示分发包的描述信息.
+type Meta struct {
+	FormatVersion int               `json:"formatVersion"`          // 分发包格式版本
+	AppVersion    string            `json:"appVersion"`             // 打包时的程序版本
+	CreatedAt     time.Time         `json:"createdAt"`              // 打包时间
+	Model         string            `json:"model"`                  // 模型名称
+	Server        string            `json:"server,omitempty"`       // 模型资源来源的服务器
+	Schema        int               `json:"schema"`                 // model.json 的格式版本
+	ToolVersion   string            `json:"toolVersion,omitempty"`  // 生成 model.json 的程序版本
+	LayoutPreset  string            `json:"layoutPreset,omitempty"` // 生成 model.json 时使用的布局预设
+	Generated     map[string]string `json:"generated"`              // 不在下载清单中的生成文件的 SHA-256，key 为包内路径
+}
+
+// Report 表示分发包的校验结果.
+type Report struct {
+	Meta       *Meta    // 分发包描述信息
+	Files      int      // 校验通过的文件数
+	Missing    []string // 记录了但包内不存在的文件
+	Mismatched []string // 内容与记录的 SHA-256 不一致的文件
+	Unexpected []string // 包内存在但没有记录的文件
+}
+
+// OK 返回分发包是否完整.
+func (r *Report) OK() bool {
+	return len(r.Missing) == 0 && len(r.Mismatched) == 0 && len(r.Unexpected) == 0
+}
+
+// Create 将模型目录打包为分发包
+// 打包前会按下载清单校验文件，内容与清单不一致的模型不会被打包
+// 参数:
+//   - ctx: 上下文
+//   - modelPath: 模型目录
+//   - packPath: 分发包路径
+//   - appVersion: 程序版本
+//
+// 返回:
+//   - *Meta: 分发包描述信息
+//   - error: 错误信息
+func Create(ctx context.Context, modelPath, packPath, appVersion string) (*Meta, error) {
+	data, err := downloader.LoadModelData(modelPath)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // 错误信息已包含上下文
+	}
+	manifest, err := downloader.LoadManifest(modelPath)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // 错误信息已包含上下文
+	}
+
+	meta := &Meta{
+		FormatVersion: FormatVersion,
+		AppVersion:    appVersion,
+		CreatedAt:     time.Now(),
+		Model:         manifest.Model,
+		Server:        manifest.Server,
+		Schema:        data.Extension.Schema,
+		ToolVersion:   data.Extension.ToolVersion,
+		LayoutPreset:  data.Extension.LayoutPreset,
+		Generated:     make(map[string]string),
+	}
+	if hashErr := hashModelFiles(ctx, modelPath, meta, manifest); hashErr != nil {
+		return nil, hashErr
+	}
+
+	names := make([]string, 0, len(meta.Generated)+len(manifest.Files))
+	for name := range meta.Generated {
+		names = append(names, name)
+	}
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	if writeErr := writePack(modelPath, packPath, meta, manifest, names); writeErr != nil {
+		return nil, writeErr
+	}
+	return meta, nil
+}
+
+// hashModelFiles 计算模型目录中需要打包的文件的 SHA-256
+// 下载清单中的文件与清单记录比对并补全缺失的哈希，生成文件的哈希记录到描述信息中.
+func hashModelFiles(ctx context.Context, modelPath string, meta *Meta, manifest *model.DownloadManifest) error {
+	generated := []string{model.ModelDataFileName}
+	for _, name := range []string{model.IndexFileName, model.TexturesMetaFileName} {
+		if _, err := os.Stat(filepath.Join(modelPath, name)); err == nil {
+			generated = append(generated, name)
+		}
+	}
+	model3Files, _ := filepath.Glob(filepath.Join(modelPath, "*"+model.Model3FileSuffix)) // 模式固定，不会返回错误
+	for _, path := range model3Files {
+		generated = append(generated, filepath.Base(path))
+	}
+
+	names := make(map[string]string, len(generated)+len(manifest.Files))
+	paths := make([]string, 0, len(names))
+	for _, name := range generated {
+		path := filepath.Join(modelPath, name)
+		names[path] = name
+		paths = append(paths, path)
+	}
+	for name := range manifest.Files {
+		path := filepath.Join(modelPath, filepath.FromSlash(name))
+		names[path] = name
+		paths = append(paths, path)
+	}
+
+	for path, result := range fsutil.HashFiles(ctx, paths, 0) {
+		name := names[path]
+		if result.Err != nil {
+			return fmt.Errorf("计算 %s 的哈希失败: %w", name, result.Err)
+		}
+		entry, ok := manifest.Files[name]
+		if !ok {
+			meta.Generated[name] = result.SHA256
+			continue
+		}
+		if entry.SHA256 != "" && entry.SHA256 != result.SHA256 {
+			return fmt.Errorf("%w: %s 与下载清单不一致，请重新下载模型后再打包", ErrCorrupted, name)
+		}
+		entry.SHA256 = result.SHA256
+		manifest.Files[name] = entry
+	}
+	return nil
+}
+
+// writePack 将描述信息、下载清单与模型文件写入分发包
+// 先写入临时文件，完成后再重命名，避免留下不完整的分发包.
+func writePack(modelPath, packPath string, meta *Meta, manifest *model.DownloadManifest, names []string) error {
+	file, err := fsutil.CreateTemp(packPath)
+	if err != nil {
+		return fmt.Errorf("创建模型包失败: %w", err)
+	}
+
+	zw := zip.NewWriter(file)
+	writeErr := writeJSON(zw, metaName, meta)
+	if writeErr == nil {
+		writeErr = writeJSON(zw, manifestName, manifest)
+	}
+	for _, name := range names {
+		if writeErr != nil {
+			break
+		}
+		writeErr = addFile(zw, name, filepath.Join(modelPath, filepath.FromSlash(name)))
+	}
+	if writeErr == nil {
+		writeErr = zw.Close()
+	}
+	if writeErr != nil {
+		fsutil.DiscardTemp(file)
+		return fmt.Errorf("写入模型包失败: %w", writeErr)
+	}
+
+	if commitErr := fsutil.CommitTemp(file, packPath); commitErr != nil {
+		return fmt.Errorf("保存模型包失败: %w", commitErr)
+	}
+	return nil
+}
+
+// writeJSON 将数据序列化为 JSON 写入分发包.
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 %s 失败: %w", name, err)
+	}
+	w, err := zw.Create(name)
+	if err != nil {
+		return err //nolint:wrapcheck // 由 writePack 统一包装
+	}
+	_, err = w.Write(data)
+	return err //nolint:wrapcheck // 由 writePack 统一包装
+}
+
+// addFile 将本地文件写入分发包.
+func addFile(zw *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err //nolint:wrapcheck // 由 writePack 统一包装
+	}
+	defer src.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err //nolint:wrapcheck // 由 writePack 统一包装
+	}
+	_, err = io.Copy(w, src)
+	return err //nolint:wrapcheck // 由 writePack 统一包装
+}
+
+// Verify 校验分发包的完整性
+// 包内每个文件都会与 manifest.json 和 meta.json 中记录的 SHA-256 比对
+// 参数:
+//   - ctx: 上下文
+//   - packPath: 分发包路径
+//
+// 返回:
+//   - *Report: 校验结果
+//   - error: 分发包无法读取或格式无效时返回错误
+func Verify(ctx context.Context, packPath string) (*Report, error) {
+	zr, err := zip.OpenReader(packPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPack, err)
+	}
+	defer zr.Close()
+
+	meta, manifest, err := readPack(&zr.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make(map[string]string, len(meta.Generated)+len(manifest.Files))
+	for name, sha := range meta.Generated {
+		expected[name] = sha
+	}
+	for name, entry := range manifest.Files {
+		expected[name] = entry.SHA256
+	}
+
+	report := &Report{Meta: meta}
+	for _, f := range zr.File {
+		if f.Name == metaName || f.Name == manifestName || f.FileInfo().IsDir() {
+			continue
+		}
+		sha, ok := expected[f.Name]
+		if !ok {
+			report.Unexpected = append(report.Unexpected, f.Name)
+			continue
+		}
+		delete(expected, f.Name)
+
+		actual, hashErr := hashEntry(ctx, f)
+		if hashErr != nil {
+			return nil, hashErr
+		}
+		if actual != sha {
+			report.Mismatched = append(report.Mismatched, f.Name)
+			continue
+		}
+		report.Files++
+	}
+	for name := range expected {
+		report.Missing = append(report.Missing, name)
+	}
+	slices.Sort(report.Missing)
+	return report, nil
+}
+
+// hashEntry 计算分发包条目内容的 SHA-256.
+func hashEntry(ctx context.Context, f *zip.File) (string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("%w: 读取 %s 失败: %w", ErrInvalidPack, f.Name, err)
+	}
+	defer r.Close()
+
+	result := fsutil.HashReader(ctx, r)
+	if result.Err != nil {
+		return "", fmt.Errorf("%w: 读取 %s 失败: %w", ErrInvalidPack, f.Name, result.Err)
+	}
+	return result.SHA256, nil
+}
+
+// readPack 读取并校验分发包的描述信息与下载清单
+// 同时检查所有条目的路径，拒绝指向包外的条目.
+func readPack(zr *zip.Reader) (*Meta, *model.DownloadManifest, error) {
+	var meta *Meta
+	var manifest *model.DownloadManifest
+	for _, f := range zr.File {
+		if !filepath.IsLocal(filepath.FromSlash(f.Name)) {
+			return nil, nil, fmt.Errorf("%w: 非法的条目路径 %s", ErrInvalidPack, f.Name)
+		}
+		switch f.Name {
+		case metaName:
+			meta = &Meta{}
+			if err := decodeEntry(f, meta); err != nil {
+				return nil, nil, err
+			}
+		case manifestName:
+			manifest = &model.DownloadManifest{}
+			if err := decodeEntry(f, manifest); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if meta == nil {
+		return nil, nil, fmt.Errorf("%w: 缺少 %s", ErrInvalidPack, metaName)
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("%w: 缺少 %s", ErrInvalidPack, manifestName)
+	}
+
+	meta, err := migrateMeta(meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	return meta, manifest, nil
+}
+
+// decodeEntry 将分发包中的 JSON 条目解析到 v.
+func decodeEntry(f *zip.File, v any) error {
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: 读取 %s 失败: %w", ErrInvalidPack, f.Name, err)
+	}
+	defer r.Close()
+
+	if decodeErr := json.NewDecoder(r).Decode(v); decodeErr != nil {
+		return fmt.Errorf("%w: 解析 %s 失败: %w", ErrInvalidPack, f.Name, decodeErr)
+	}
+	return nil
+}
+
+// migrateMeta 将旧版本的分发包描述迁移为当前格式.
+func migrateMeta(meta *Meta) (*Meta, error) {
+	switch {
+	case meta.FormatVersion <= 0:
+		return nil, fmt.Errorf("%w: 格式版本 %d", ErrInvalidPack, meta.FormatVersion)
+	case meta.FormatVersion > FormatVersion:
+		return nil, fmt.Errorf("%w: 模型包版本 %d，当前程序支持 %d", ErrUnsupportedVersion, meta.FormatVersion, FormatVersion)
+	}
+	// 目前只有版本 1，新增版本时在这里逐级迁移
+	return meta, nil
+}
+
+// Unpack 校验分发包并解包到模型目录
+// 分发包不完整时不会解包，manifest.json 会还原为模型目录中的下载清单，以便之后更新模型时复用已有文件
+// 参数:
+//   - ctx: 上下文
+//   - packPath: 分发包路径
+//   - destDir: 解包的目标目录，必须不存在或为空目录
+//
+// 返回:
+//   - *Meta: 分发包描述信息
+//   - error: 错误信息
+func Unpack(ctx context.Context, packPath, destDir string) (*Meta, error) {
+	report, err := Verify(ctx, packPath)
+	if err != nil {
+		return nil, err
+	}
+	if !report.OK() {
+		return nil, fmt.Errorf("%w: %d 个文件缺失，%d 个文件不一致，%d 个文件没有记录",
+			ErrCorrupted, len(report.Missing), len(report.Mismatched), len(report.Unexpected))
+	}
+
+	if entries, readErr := os.ReadDir(destDir); readErr == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("解包目录 %s 不为空", destDir)
+	}
+
+	zr, err := zip.OpenReader(packPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPack, err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name == metaName || f.FileInfo().IsDir() {
+			continue
+		}
+		name := f.Name
+		if name == manifestName {
+			name = model.ManifestFileName
+		}
+		if extractErr := extractFile(f, filepath.Join(destDir, filepath.FromSlash(name))); extractErr != nil {
+			return nil, extractErr
+		}
+	}
+	return report.Meta, nil
+}
+
+// extractFile 将分发包条目解压到目标路径.
+func extractFile(f *zip.File, target string) error {
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: 读取 %s 失败: %w", ErrInvalidPack, f.Name, err)
+	}
+	defer r.Close()
+
+	file, err := fsutil.CreateTemp(target)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %w", err)
+	}
+	if _, copyErr := io.Copy(file, r); copyErr != nil {
+		fsutil.DiscardTemp(file)
+		return fmt.Errorf("写入 %s 失败: %w", target, copyErr)
+	}
+	if commitErr := fsutil.CommitTemp(file, target); commitErr != nil {
+		return fmt.Errorf("写入 %s 失败: %w", target, commitErr)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package pack_test
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/pack"
+)
+
+// sha 返回内容的十六进制 SHA-256.
+func sha(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeModel 构造一个带下载清单的模型目录.
+func writeModel(t *testing.T, dir string) {
+	t.Helper()
+
+	files := map[string]string{
+		model.ModelDataFileName: `{"version":"1.0.0","bestdori_dl":{"schema":2,"tool_version":"v1.0.0"}}`,
+		"data/model.moc":        "moc",
+		"data/texture_00.png":   "png",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	manifest := model.DownloadManifest{
+		Model:  "001_test",
+		Server: "jp",
+		Files: map[string]model.ManifestFile{
+			"data/model.moc": {Provenance: model.FileProvenance{Source: model.FileSourceNetwork, Size: 3}, SHA256: sha("moc")},
+			// 旧版清单可能没有记录哈希，打包时补全
+			"data/texture_00.png": {Provenance: model.FileProvenance{Source: model.FileSourceNetwork, Size: 3}},
+		},
+	}
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, model.ManifestFileName), data, 0600))
+}
+
+// rewritePack 读取分发包中的全部条目，经 modify 修改后写回.
+func rewritePack(t *testing.T, path string, modify func(entries map[string][]byte)) {
+	t.Helper()
+
+	zr, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	entries := make(map[string][]byte)
+	for _, f := range zr.File {
+		r, openErr := f.Open()
+		require.NoError(t, openErr)
+		data, readErr := io.ReadAll(r)
+		require.NoError(t, readErr)
+		r.Close()
+		entries[f.Name] = data
+	}
+	require.NoError(t, zr.Close())
+
+	modify(entries)
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	zw := zip.NewWriter(file)
+	for name, data := range entries {
+		w, createErr := zw.Create(name)
+		require.NoError(t, createErr)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+}
+
+func TestPackRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	modelDir := filepath.Join(t.TempDir(), "001_test")
+	writeModel(t, modelDir)
+	packPath := modelDir + pack.Extension
+
+	meta, err := pack.Create(ctx, modelDir, packPath, "v1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "001_test", meta.Model)
+	assert.Equal(t, "jp", meta.Server)
+	assert.Equal(t, model.ExtensionSchemaVersion, meta.Schema)
+	assert.Equal(t, map[string]string{model.ModelDataFileName: sha(
+		`{"version":"1.0.0","bestdori_dl":{"schema":2,"tool_version":"v1.0.0"}}`,
+	)}, meta.Generated)
+
+	report, err := pack.Verify(ctx, packPath)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, 3, report.Files)
+	assert.Equal(t, "v1.2.3", report.Meta.AppVersion)
+
+	destDir := filepath.Join(t.TempDir(), "unpacked")
+	_, err = pack.Unpack(ctx, packPath, destDir)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(destDir, "data", "texture_00.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "png", string(content))
+	_, err = os.Stat(filepath.Join(destDir, "meta.json"))
+	assert.True(t, os.IsNotExist(err), "meta.json should not be extracted")
+
+	var manifest model.DownloadManifest
+	data, err := os.ReadFile(filepath.Join(destDir, model.ManifestFileName))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	assert.Equal(t, sha("png"), manifest.Files["data/texture_00.png"].SHA256)
+
+	_, err = pack.Unpack(ctx, packPath, destDir)
+	require.Error(t, err, "should not unpack into a non-empty directory")
+}
+
+func TestCreateCorruptedModel(t *testing.T) {
+	modelDir := t.TempDir()
+	writeModel(t, modelDir)
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "data", "model.moc"), []byte("bad"), 0600))
+
+	_, err := pack.Create(context.Background(), modelDir, filepath.Join(t.TempDir(), "001_test.l2dpack"), "v1.2.3")
+	require.ErrorIs(t, err, pack.ErrCorrupted)
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name           string
+		modify         func(entries map[string][]byte)
+		wantErr        error
+		wantMissing    []string
+		wantMismatched []string
+		wantUnexpected []string
+	}{
+		{
+			name:           "文件被修改",
+			modify:         func(entries map[string][]byte) { entries["data/model.moc"] = []byte("bad") },
+			wantMismatched: []string{"data/model.moc"},
+		},
+		{
+			name:        "文件缺失",
+			modify:      func(entries map[string][]byte) { delete(entries, "data/texture_00.png") },
+			wantMissing: []string{"data/texture_00.png"},
+		},
+		{
+			name:           "没有记录的文件",
+			modify:         func(entries map[string][]byte) { entries["extra.txt"] = []byte("extra") },
+			wantUnexpected: []string{"extra.txt"},
+		},
+		{
+			name:           "生成文件被修改",
+			modify:         func(entries map[string][]byte) { entries[model.ModelDataFileName] = []byte("{}") },
+			wantMismatched: []string{model.ModelDataFileName},
+		},
+		{
+			name:    "条目路径越界",
+			modify:  func(entries map[string][]byte) { entries["../evil.txt"] = []byte("evil") },
+			wantErr: pack.ErrInvalidPack,
+		},
+		{
+			name:    "缺少描述文件",
+			modify:  func(entries map[string][]byte) { delete(entries, "meta.json") },
+			wantErr: pack.ErrInvalidPack,
+		},
+		{
+			name: "更新版本的模型包",
+			modify: func(entries map[string][]byte) {
+				entries["meta.json"] = []byte(`{"formatVersion":99}`)
+			},
+			wantErr: pack.ErrUnsupportedVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			modelDir := t.TempDir()
+			writeModel(t, modelDir)
+			packPath := filepath.Join(t.TempDir(), "001_test.l2dpack")
+			_, err := pack.Create(ctx, modelDir, packPath, "v1.2.3")
+			require.NoError(t, err)
+			rewritePack(t, packPath, tt.modify)
+
+			report, err := pack.Verify(ctx, packPath)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.False(t, report.OK())
+			assert.Equal(t, tt.wantMissing, report.Missing)
+			assert.Equal(t, tt.wantMismatched, report.Mismatched)
+			assert.Equal(t, tt.wantUnexpected, report.Unexpected)
+
+			_, err = pack.Unpack(ctx, packPath, filepath.Join(t.TempDir(), "unpacked"))
+			require.ErrorIs(t, err, pack.ErrCorrupted)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Package rpc 实现了通过标准输入输出驱动下载的 JSON 行协议
+// 每行一个 JSON 命令（download、search、cancel），每行输出一个 JSON 事件（progress、done、error 等），
+// 便于 GUI 应用将本工具作为子进程嵌入，而不需要解析日志
+package rpc
+
+// ProtocolVersion 是协议版本，在 ready 事件中输出，不兼容的修改会增加版本号.
+const ProtocolVersion = 1
+
+// 命令的方法名称.
+const (
+	// MethodDownload 下载 models 中的模型.
+	MethodDownload = "download"
+	// MethodSearch 按名称搜索角色并列出其服装.
+	MethodSearch = "search"
+	// MethodCancel 取消 target 指定的正在执行的命令.
+	MethodCancel = "cancel"
+)
+
+// 事件类型
+// 带有 model 的 done 与 error 表示单个模型下载成功或失败，命令继续执行；
+// 不带 model 的 done 与 error 表示命令结束，每个命令恰好结束一次.
+const (
+	// EventReady 表示已开始接收命令，启动后输出一次.
+	EventReady = "ready"
+	// EventProgress 表示模型已完成的文件数变化.
+	EventProgress = "progress"
+	// EventDone 表示模型下载成功或命令执行成功.
+	EventDone = "done"
+	// EventError 表示模型下载失败、命令执行失败或命令无效.
+	EventError = "error"
+	// EventMessage 表示与命令无关的提示信息，例如跳过的文件与所需空间估算.
+	EventMessage = "message"
+	// EventDisk 表示保存路径的剩余空间不足.
+	EventDisk = "disk"
+)
+
+// Command 表示从标准输入读取的一条命令.
+type Command struct {
+	ID     string   `json:"id"`               // 命令 id，由调用方指定，事件中原样返回，同一时间不能重复
+	Method string   `json:"method"`           // 方法名称
+	Models []string `json:"models,omitempty"` // download 要下载的模型名称，不支持通配符
+	Query  string   `json:"query,omitempty"`  // search 要搜索的角色名称
+	Target string   `json:"target,omitempty"` // cancel 要取消的命令 id
+}
+
+// Event 表示写入标准输出的一条事件.
+type Event struct {
+	Event     string         `json:"event"`               // 事件类型
+	ID        string         `json:"id,omitempty"`        // 对应的命令 id，与命令无关的事件为空
+	Model     string         `json:"model,omitempty"`     // 对应的模型名称，命令级事件为空
+	Version   int            `json:"version,omitempty"`   // 协议版本，只用于 ready
+	Progress  *Progress      `json:"progress,omitempty"`  // 模型的文件进度，只用于 progress
+	Summary   *Summary       `json:"summary,omitempty"`   // 模型的文件统计，只用于模型级 done
+	Disk      *Disk          `json:"disk,omitempty"`      // 剩余空间，只用于 disk
+	Completed []string       `json:"completed,omitempty"` // download 命令中下载成功的模型
+	Failed    []string       `json:"failed,omitempty"`    // download 命令中下载失败的模型
+	Results   []SearchResult `json:"results,omitempty"`   // search 命令找到的角色
+	Message   string         `json:"message,omitempty"`   // 提示信息，只用于 message
+	Error     string         `json:"error,omitempty"`     // 错误信息，只用于 error
+}
+
+// Progress 表示模型的文件进度.
+type Progress struct {
+	Completed int `json:"completed"` // 已完成的文件数
+	Total     int `json:"total"`     // 文件总数，尚未知道时为 0
+}
+
+// Summary 表示下载完成的模型的文件统计.
+type Summary struct {
+	Title       string `json:"title,omitempty"` // 服装标题，没有记录时为空
+	Textures    int    `json:"textures"`        // 纹理数量
+	Motions     int    `json:"motions"`         // 动作数量
+	Expressions int    `json:"expressions"`     // 表情数量
+	Bytes       int64  `json:"bytes"`           // 模型文件总大小（字节）
+	ElapsedMS   int64  `json:"elapsed_ms"`      // 构建耗时（毫秒）
+}
+
+// Disk 表示保存路径的剩余空间.
+type Disk struct {
+	Free     uint64 `json:"free"`     // 剩余空间（字节）
+	Required uint64 `json:"required"` // 继续下载所需的空间（字节）
+	Paused   bool   `json:"paused"`   // 下载是否已因空间不足暂停
+}
+
+// SearchResult 表示 search 命令找到的角色.
+type SearchResult struct {
+	CharaID  int       `json:"chara_id"` // 角色ID
+	Name     string    `json:"name"`     // 角色名称
+	Costumes []Costume `json:"costumes"` // 角色的服装
+}
+
+// Costume 表示角色的一件服装.
+type Costume struct {
+	Name  string `json:"name"`            // 服装资源名称，可直接用于 download
+	Title string `json:"title,omitempty"` // 服装标题，没有记录时为空
+}
@@ -0,0 +1,177 @@
+package rpc_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/rpc"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/tui"
+)
+
+// fakeHandler 按模型名称模拟下载结果，名称以 bad 开头的模型下载失败.
+type fakeHandler struct {
+	sink    *rpc.Sink
+	started chan struct{} // 不为 nil 时，download 开始后关闭并等待取消
+}
+
+func (h *fakeHandler) Download(ctx context.Context, models []string, report func(string, error)) error {
+	if h.started != nil {
+		close(h.started)
+		<-ctx.Done()
+		return errs.ErrDownloadCancelled
+	}
+	for _, name := range models {
+		if strings.HasPrefix(name, "bad") {
+			report(name, errs.ErrModelNotFound)
+			continue
+		}
+		h.sink.AddDownloadItem(name, 2)
+		h.sink.UpdateProgress(name, 2)
+		h.sink.SetItemTitle(name, "制服")
+		h.sink.SendSummary(name, tui.Summary{Textures: 1, Motions: 1, Bytes: 10, Elapsed: time.Second})
+		report(name, nil)
+	}
+	return nil
+}
+
+func (h *fakeHandler) Search(_ context.Context, query string) ([]rpc.SearchResult, error) {
+	if query == "无人" {
+		return nil, errors.New("未找到角色")
+	}
+	return []rpc.SearchResult{{CharaID: 37, Name: "爱音", Costumes: []rpc.Costume{{Name: "037_casual-2023", Title: "私服"}}}}, nil
+}
+
+// serve 执行输入中的命令，返回输出的全部事件.
+func serve(t *testing.T, in io.Reader, handler *fakeHandler) []rpc.Event {
+	t.Helper()
+
+	var out bytes.Buffer
+	server := rpc.NewServer(context.Background(), &out)
+	handler.sink = server.Sink()
+	require.NoError(t, server.Serve(context.Background(), in, handler))
+
+	var events []rpc.Event
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var event rpc.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event), scanner.Text())
+		events = append(events, event)
+	}
+	require.NotEmpty(t, events)
+	assert.Equal(t, rpc.Event{Event: rpc.EventReady, Version: rpc.ProtocolVersion}, events[0])
+	return events[1:]
+}
+
+func TestInvalidCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		wantID string
+	}{
+		{"不是 JSON", `download 037_casual-2023`, ""},
+		{"缺少 id", `{"method":"search","query":"爱音"}`, ""},
+		{"未知的方法", `{"id":"1","method":"pause"}`, "1"},
+		{"download 缺少 models", `{"id":"2","method":"download"}`, "2"},
+		{"download 使用通配符", `{"id":"3","method":"download","models":["037_*"]}`, "3"},
+		{"search 缺少 query", `{"id":"4","method":"search"}`, "4"},
+		{"cancel 缺少 target", `{"id":"5","method":"cancel"}`, "5"},
+		{"cancel 没有正在执行的命令", `{"id":"6","method":"cancel","target":"1"}`, "6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := serve(t, strings.NewReader(tt.line+"\n"), &fakeHandler{})
+
+			require.Len(t, events, 1)
+			assert.Equal(t, rpc.EventError, events[0].Event)
+			assert.Equal(t, tt.wantID, events[0].ID)
+			assert.Contains(t, events[0].Error, errs.ErrInvalidCommand.Error())
+		})
+	}
+}
+
+func TestDownloadCommand(t *testing.T) {
+	events := serve(t, strings.NewReader(`{"id":"d1","method":"download","models":["037_casual-2023","bad_model"]}`+"\n"), &fakeHandler{})
+
+	assert.Equal(t, []rpc.Event{
+		{Event: rpc.EventProgress, ID: "d1", Model: "037_casual-2023", Progress: &rpc.Progress{Completed: 0, Total: 2}},
+		{Event: rpc.EventProgress, ID: "d1", Model: "037_casual-2023", Progress: &rpc.Progress{Completed: 2, Total: 2}},
+		{Event: rpc.EventDone, ID: "d1", Model: "037_casual-2023", Summary: &rpc.Summary{
+			Title: "制服", Textures: 1, Motions: 1, Bytes: 10, ElapsedMS: 1000,
+		}},
+		{Event: rpc.EventError, ID: "d1", Model: "bad_model", Error: errs.ErrModelNotFound.Error()},
+		{Event: rpc.EventDone, ID: "d1", Completed: []string{"037_casual-2023"}, Failed: []string{"bad_model"}},
+	}, events)
+}
+
+func TestSearchCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want rpc.Event
+	}{
+		{"找到角色", `{"id":"s1","method":"search","query":"爱音"}`, rpc.Event{
+			Event:   rpc.EventDone,
+			ID:      "s1",
+			Results: []rpc.SearchResult{{CharaID: 37, Name: "爱音", Costumes: []rpc.Costume{{Name: "037_casual-2023", Title: "私服"}}}},
+		}},
+		{"搜索失败", `{"id":"s2","method":"search","query":"无人"}`, rpc.Event{Event: rpc.EventError, ID: "s2", Error: "未找到角色"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := serve(t, strings.NewReader(tt.line+"\n"), &fakeHandler{})
+			assert.Equal(t, []rpc.Event{tt.want}, events)
+		})
+	}
+}
+
+func TestCancelCommand(t *testing.T) {
+	handler := &fakeHandler{started: make(chan struct{})}
+	in, w := io.Pipe()
+	go func() {
+		_, _ = io.WriteString(w, `{"id":"d1","method":"download","models":["037_casual-2023"]}`+"\n")
+		<-handler.started
+		// 正在执行的命令 id 不能重复使用
+		_, _ = io.WriteString(w, `{"id":"d1","method":"search","query":"爱音"}`+"\n")
+		_, _ = io.WriteString(w, `{"id":"c1","method":"cancel","target":"d1"}`+"\n")
+		_ = w.Close()
+	}()
+
+	events := serve(t, in, handler)
+
+	require.Len(t, events, 3)
+	assert.Equal(t, rpc.EventError, events[0].Event)
+	assert.Equal(t, "d1", events[0].ID)
+	assert.Contains(t, events[0].Error, errs.ErrInvalidCommand.Error())
+	assert.Contains(t, events, rpc.Event{Event: rpc.EventDone, ID: "c1"})
+	assert.Contains(t, events, rpc.Event{Event: rpc.EventError, ID: "d1", Error: errs.ErrDownloadCancelled.Error()})
+}
+
+func TestServeContextCancelled(t *testing.T) {
+	handler := &fakeHandler{started: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	in, w := io.Pipe()
+	defer w.Close()
+	go func() {
+		_, _ = io.WriteString(w, `{"id":"d1","method":"download","models":["037_casual-2023"]}`+"\n")
+		<-handler.started
+		cancel()
+	}()
+
+	var out bytes.Buffer
+	server := rpc.NewServer(ctx, &out)
+	handler.sink = server.Sink()
+	// 输入没有结束，上下文被取消后仍然等待正在执行的命令结束再返回
+	require.NoError(t, server.Serve(ctx, in, handler))
+	assert.Contains(t, out.String(), `"event":"error","id":"d1"`)
+}
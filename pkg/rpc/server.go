@@ -0,0 +1,252 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// maxCommandSize 是单行命令的最大字节数.
+const maxCommandSize = 1 << 20
+
+// Handler 执行 download 与 search 命令
+// 不同命令会并发调用，命令被取消时 ctx 随之取消.
+type Handler interface {
+	// Download 下载模型，每个模型结束时调用一次 report，err 为 nil 表示下载成功
+	// 返回的错误表示整个命令失败，例如被取消.
+	Download(ctx context.Context, models []string, report func(name string, err error)) error
+	// Search 按名称搜索角色并列出其服装.
+	Search(ctx context.Context, query string) ([]SearchResult, error)
+}
+
+// Server 从输入逐行读取命令并将事件写入输出
+// download 与 search 命令在各自的 goroutine 中执行，可以同时进行，cancel 命令立即执行.
+type Server struct {
+	out  *writer
+	sink *Sink
+
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc // 正在执行的命令，key 为命令 id
+	wg      sync.WaitGroup
+}
+
+// NewServer 创建将事件写入 out 的服务
+// 参数:
+//   - ctx: 上下文，被取消时中止所有命令
+//   - out: 事件的输出目标，通常为标准输出
+//
+// 返回:
+//   - *Server: 服务实例
+func NewServer(ctx context.Context, out io.Writer) *Server {
+	w := newWriter(out)
+	return &Server{
+		out:     w,
+		sink:    newSink(ctx, w),
+		running: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// Sink 返回将下载进度转发给命令的进度接收者，创建下载器时使用.
+func (s *Server) Sink() *Sink {
+	return s.sink
+}
+
+// Serve 输出 ready 事件后逐行读取并执行命令
+// 输入结束后等待正在执行的命令完成再返回；ctx 被取消时取消所有命令，等待其结束后返回
+// 参数:
+//   - ctx: 上下文
+//   - in: 命令的输入来源，通常为标准输入
+//   - handler: 命令的执行者
+//
+// 返回:
+//   - error: 读取输入失败时返回错误
+func (s *Server) Serve(ctx context.Context, in io.Reader, handler Handler) error {
+	s.out.emit(Event{Event: EventReady, Version: ProtocolVersion})
+
+	// 读取输入会阻塞，在单独的 goroutine 中读取，使 ctx 被取消时能立即返回
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxCommandSize)
+		for scanner.Scan() {
+			line := bytes.Clone(scanner.Bytes())
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				s.wg.Wait()
+				if err := <-readErr; err != nil {
+					return fmt.Errorf("读取命令失败: %w", err)
+				}
+				return nil
+			}
+			if len(bytes.TrimSpace(line)) > 0 {
+				s.dispatch(ctx, line, handler)
+			}
+		}
+	}
+}
+
+// dispatch 解析并执行一条命令，无效的命令输出 error 事件.
+func (s *Server) dispatch(ctx context.Context, line []byte, handler Handler) {
+	var cmd Command
+	if err := json.Unmarshal(line, &cmd); err != nil {
+		s.fail(cmd.ID, fmt.Errorf("%w: %w", errs.ErrInvalidCommand, err))
+		return
+	}
+	if err := validate(cmd); err != nil {
+		s.fail(cmd.ID, err)
+		return
+	}
+
+	if cmd.Method == MethodCancel {
+		s.cancel(cmd)
+		return
+	}
+
+	cmdCtx, cancel := context.WithCancelCause(ctx)
+	if !s.register(cmd.ID, cancel) {
+		cancel(nil)
+		s.fail(cmd.ID, fmt.Errorf("%w: 命令 id 正在使用: %s", errs.ErrInvalidCommand, cmd.ID))
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.unregister(cmd.ID)
+		defer cancel(nil)
+		switch cmd.Method {
+		case MethodDownload:
+			s.download(cmdCtx, cmd, handler)
+		case MethodSearch:
+			s.search(cmdCtx, cmd, handler)
+		}
+	}()
+}
+
+// validate 检查命令的方法与参数.
+func validate(cmd Command) error {
+	if cmd.ID == "" {
+		return fmt.Errorf("%w: 缺少 id", errs.ErrInvalidCommand)
+	}
+	switch cmd.Method {
+	case MethodDownload:
+		if len(cmd.Models) == 0 {
+			return fmt.Errorf("%w: download 缺少 models", errs.ErrInvalidCommand)
+		}
+		for _, name := range cmd.Models {
+			if name == "" || model.IsLive2dPattern(name) {
+				return fmt.Errorf("%w: 无效的模型名称: %q", errs.ErrInvalidCommand, name)
+			}
+		}
+	case MethodSearch:
+		if cmd.Query == "" {
+			return fmt.Errorf("%w: search 缺少 query", errs.ErrInvalidCommand)
+		}
+	case MethodCancel:
+		if cmd.Target == "" {
+			return fmt.Errorf("%w: cancel 缺少 target", errs.ErrInvalidCommand)
+		}
+	default:
+		return fmt.Errorf("%w: 未知的方法: %q", errs.ErrInvalidCommand, cmd.Method)
+	}
+	return nil
+}
+
+// register 登记正在执行的命令，id 已被使用时返回 false.
+func (s *Server) register(id string, cancel context.CancelCauseFunc) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.running[id]; ok {
+		return false
+	}
+	s.running[id] = cancel
+	return true
+}
+
+// unregister 取消命令的登记.
+func (s *Server) unregister(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, id)
+}
+
+// cancel 取消 target 指定的命令，被取消的命令以 error 事件结束.
+func (s *Server) cancel(cmd Command) {
+	s.mu.Lock()
+	cancel, ok := s.running[cmd.Target]
+	s.mu.Unlock()
+	if !ok {
+		s.fail(cmd.ID, fmt.Errorf("%w: 没有正在执行的命令: %s", errs.ErrInvalidCommand, cmd.Target))
+		return
+	}
+	cancel(errs.ErrDownloadCancelled)
+	s.out.emit(Event{Event: EventDone, ID: cmd.ID})
+}
+
+// download 执行 download 命令，逐个报告模型的结果后报告命令的结果.
+func (s *Server) download(ctx context.Context, cmd Command, handler Handler) {
+	s.sink.bind(cmd.ID, cmd.Models)
+	defer s.sink.unbind(cmd.ID, cmd.Models)
+
+	var mu sync.Mutex
+	var completed, failed []string
+	err := handler.Download(ctx, cmd.Models, func(name string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			failed = append(failed, name)
+			s.out.emit(Event{Event: EventError, ID: cmd.ID, Model: name, Error: err.Error()})
+			return
+		}
+		completed = append(completed, name)
+		s.out.emit(Event{Event: EventDone, ID: cmd.ID, Model: name, Summary: s.sink.summary(name)})
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil {
+		if cause := context.Cause(ctx); errs.IsCancelled(err) && cause != nil {
+			err = cause
+		}
+		s.out.emit(Event{Event: EventError, ID: cmd.ID, Completed: completed, Failed: failed, Error: err.Error()})
+		return
+	}
+	s.out.emit(Event{Event: EventDone, ID: cmd.ID, Completed: completed, Failed: failed})
+}
+
+// search 执行 search 命令.
+func (s *Server) search(ctx context.Context, cmd Command, handler Handler) {
+	results, err := handler.Search(ctx, cmd.Query)
+	if err != nil {
+		s.fail(cmd.ID, err)
+		return
+	}
+	s.out.emit(Event{Event: EventDone, ID: cmd.ID, Results: results})
+}
+
+// fail 输出命令失败的 error 事件.
+func (s *Server) fail(id string, err error) {
+	s.out.emit(Event{Event: EventError, ID: id, Error: err.Error()})
+}
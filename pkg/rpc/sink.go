@@ -0,0 +1,204 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/tui"
+)
+
+// writer 将事件逐行写入输出，保证并发写入的事件不会交错.
+type writer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// newWriter 创建写入 out 的事件输出.
+func newWriter(out io.Writer) *writer {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	return &writer{enc: enc}
+}
+
+// emit 写入一条事件，写入失败时忽略，调用方已退出时无法再报告.
+func (w *writer) emit(event Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.enc.Encode(event)
+}
+
+// Sink 是 RPC 模式下的进度接收者
+// 将模型的文件进度转发给正在下载该模型的命令，模型的下载结果由 Server 在命令中报告.
+type Sink struct {
+	mu        sync.Mutex
+	out       *writer
+	ctx       context.Context
+	owners    map[string][]string    // 正在下载模型的命令 id，key 为模型名称
+	totals    map[string]int         // 每个模型的文件总数
+	titles    map[string]string      // 每个模型的服装标题
+	summaries map[string]tui.Summary // 每个模型最近一次下载完成的文件统计
+	diskLevel tui.DiskLevel          // 上一次报告的剩余空间状态
+}
+
+// newSink 创建进度接收者.
+func newSink(ctx context.Context, out *writer) *Sink {
+	return &Sink{
+		out:       out,
+		ctx:       ctx,
+		owners:    make(map[string][]string),
+		totals:    make(map[string]int),
+		titles:    make(map[string]string),
+		summaries: make(map[string]tui.Summary),
+	}
+}
+
+// bind 登记命令正在下载的模型，同一模型可以同时属于多个命令.
+func (s *Sink) bind(id string, models []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range models {
+		s.owners[name] = append(s.owners[name], id)
+	}
+}
+
+// unbind 取消命令对模型的登记.
+func (s *Sink) unbind(id string, models []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range models {
+		owners := s.owners[name]
+		for i, owner := range owners {
+			if owner == id {
+				owners = append(owners[:i], owners[i+1:]...)
+				break
+			}
+		}
+		if len(owners) == 0 {
+			delete(s.owners, name)
+		} else {
+			s.owners[name] = owners
+		}
+	}
+}
+
+// summary 返回模型最近一次下载完成的文件统计，没有记录时返回 nil.
+func (s *Sink) summary(name string) *Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary, ok := s.summaries[name]
+	if !ok {
+		return nil
+	}
+	return &Summary{
+		Title:       s.titles[name],
+		Textures:    summary.Textures,
+		Motions:     summary.Motions,
+		Expressions: summary.Expressions,
+		Bytes:       summary.Bytes,
+		ElapsedMS:   summary.Elapsed.Milliseconds(),
+	}
+}
+
+// AddDownloadItem 记录模型的文件总数，文件总数已知时报告一次进度.
+func (s *Sink) AddDownloadItem(name string, totalFiles int) {
+	if totalFiles <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals[name] = totalFiles
+	s.progress(name, 0)
+}
+
+// UpdateProgress 向正在下载模型的命令报告已完成的文件数.
+func (s *Sink) UpdateProgress(name string, current int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress(name, current)
+}
+
+// progress 为每个正在下载模型的命令输出一条 progress 事件，调用方需持有锁.
+func (s *Sink) progress(name string, current int) {
+	for _, id := range s.owners[name] {
+		s.out.emit(Event{
+			Event:    EventProgress,
+			ID:       id,
+			Model:    name,
+			Progress: &Progress{Completed: current, Total: s.totals[name]},
+		})
+	}
+}
+
+// SetError 输出一条提示信息.
+func (s *Sink) SetError(message string) {
+	if message == "" {
+		return
+	}
+	s.out.emit(Event{Event: EventMessage, Message: message})
+}
+
+// SendError 忽略构建过程中的失败，失败原因由 Server 在命令中报告.
+func (s *Sink) SendError(string, error) {}
+
+// SendSummary 记录下载完成的模型的文件统计，在模型级 done 事件中输出.
+func (s *Sink) SendSummary(itemName string, summary tui.Summary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summaries[itemName] = summary
+}
+
+// SendDiskSpace 在剩余空间状态变为不足时输出剩余空间.
+func (s *Sink) SendDiskSpace(free, required uint64, level tui.DiskLevel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if level == s.diskLevel {
+		return
+	}
+	s.diskLevel = level
+	if level == tui.DiskLow || level == tui.DiskCritical {
+		s.out.emit(Event{Event: EventDisk, Disk: &Disk{Free: free, Required: required, Paused: level == tui.DiskCritical}})
+	}
+}
+
+// TrackFile 不跟踪文件停滞，返回空的回调.
+func (s *Sink) TrackFile(string, context.CancelCauseFunc) (func(int64), func()) {
+	return nil, func() {}
+}
+
+// Context 返回 Server 的上下文.
+func (s *Sink) Context() context.Context {
+	return s.ctx
+}
+
+// SetTotalModels 不需要批量进度，每个命令结束时报告成功与失败的模型.
+func (s *Sink) SetTotalModels(int) {}
+
+// UpdateTotalProgress 不需要批量进度，忽略.
+func (s *Sink) UpdateTotalProgress() {}
+
+// SetItemGroup 不需要分组显示，忽略.
+func (s *Sink) SetItemGroup(string, string, string) {}
+
+// SetItemTitle 记录模型的服装标题，在模型级 done 事件中输出.
+func (s *Sink) SetItemTitle(name, title string) {
+	if title == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.titles[name] = title
+}
+
+// SendSpaceEstimate 输出所需空间的估算结果.
+func (s *Sink) SendSpaceEstimate(text string, insufficient bool) {
+	if text == "" {
+		return
+	}
+	message := text
+	if insufficient {
+		message += "，剩余空间不足"
+	}
+	s.out.emit(Event{Event: EventMessage, Message: message})
+}
@@ -0,0 +1,322 @@
+// Package settings 提供了程序配置与状态的导出和导入功能
+// 用于在不同机器之间迁移设置，不包含已下载的模型
+package settings
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatVersion 是当前的设置包格式版本
+	// 格式发生不兼容变化时递增，并在 migrateManifest 中处理旧版本.
+	FormatVersion = 1
+
+	// manifestName 是设置包中描述文件的名称.
+	manifestName = "bundle.json"
+)
+
+var (
+	// ErrUnsupportedVersion 表示设置包的格式版本比当前程序更新.
+	ErrUnsupportedVersion = errors.New("不支持的设置包版本")
+	// ErrInvalidBundle 表示设置包格式无效.
+	ErrInvalidBundle = errors.New("无效的设置包")
+)
+
+// Item 表示设置包中的一项内容.
+type Item struct {
+	Name string // 设置包中的名称
+	Path string // 本地路径，可以是文件或目录
+}
+
+// Manifest 表示设置包的描述信息.
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"` // 设置包格式版本
+	AppVersion    string    `json:"appVersion"`    // 导出时的程序版本
+	CreatedAt     time.Time `json:"createdAt"`     // 导出时间
+	Items         []string  `json:"items"`         // 包含的内容名称
+}
+
+// Export 将设置导出为 tar.gz 设置包
+// 本地不存在的内容会被跳过
+// 参数:
+//   - bundlePath: 设置包路径
+//   - items: 需要导出的内容
+//   - appVersion: 程序版本
+//
+// 返回:
+//   - *Manifest: 设置包描述信息
+//   - error: 错误信息
+func Export(bundlePath string, items []Item, appVersion string) (*Manifest, error) {
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		AppVersion:    appVersion,
+		CreatedAt:     time.Now(),
+		Items:         []string{},
+	}
+	var existing []Item
+	for _, item := range items {
+		if _, err := os.Stat(item.Path); err == nil {
+			existing = append(existing, item)
+			manifest.Items = append(manifest.Items, item.Name)
+		}
+	}
+
+	file, err := os.Create(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("创建设置包失败: %w", err)
+	}
+	defer file.Close()
+
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化设置包描述失败: %w", err)
+	}
+	if writeErr := writeTarFile(tw, manifestName, manifestData, 0600); writeErr != nil {
+		return nil, writeErr
+	}
+
+	for _, item := range existing {
+		if addErr := addItem(tw, item); addErr != nil {
+			return nil, addErr
+		}
+	}
+
+	if closeErr := tw.Close(); closeErr != nil {
+		return nil, fmt.Errorf("写入设置包失败: %w", closeErr)
+	}
+	if closeErr := gw.Close(); closeErr != nil {
+		return nil, fmt.Errorf("写入设置包失败: %w", closeErr)
+	}
+	return manifest, nil
+}
+
+// writeTarFile 向 tar 中写入一个文件.
+func writeTarFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	header := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("写入设置包失败: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("写入设置包失败: %w", err)
+	}
+	return nil
+}
+
+// addItem 将一项内容写入 tar，目录会递归写入.
+func addItem(tw *tar.Writer, item Item) error {
+	walkErr := filepath.WalkDir(item.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(item.Path, p)
+		if relErr != nil {
+			return relErr
+		}
+		name := item.Name
+		if rel != "." {
+			name = path.Join(item.Name, filepath.ToSlash(rel))
+		}
+		data, readErr := os.ReadFile(p)
+		if readErr != nil {
+			return readErr
+		}
+		return writeTarFile(tw, name, data, 0600)
+	})
+	if walkErr != nil {
+		return fmt.Errorf("导出 %s 失败: %w", item.Name, walkErr)
+	}
+	return nil
+}
+
+// ReadManifest 读取并校验设置包的描述信息
+// 旧版本的设置包会被迁移为当前格式
+// 参数:
+//   - bundlePath: 设置包路径
+//
+// 返回:
+//   - *Manifest: 设置包描述信息
+//   - error: 错误信息
+func ReadManifest(bundlePath string) (*Manifest, error) {
+	var manifest *Manifest
+	err := walkBundle(bundlePath, func(header *tar.Header, r io.Reader) error {
+		if header.Name != manifestName {
+			return nil
+		}
+		manifest = &Manifest{}
+		if decodeErr := json.NewDecoder(r).Decode(manifest); decodeErr != nil {
+			return fmt.Errorf("%w: 解析描述文件失败: %w", ErrInvalidBundle, decodeErr)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: 缺少 %s", ErrInvalidBundle, manifestName)
+	}
+	return migrateManifest(manifest)
+}
+
+// migrateManifest 将旧版本的设置包描述迁移为当前格式.
+func migrateManifest(manifest *Manifest) (*Manifest, error) {
+	switch {
+	case manifest.FormatVersion <= 0:
+		return nil, fmt.Errorf("%w: 格式版本 %d", ErrInvalidBundle, manifest.FormatVersion)
+	case manifest.FormatVersion > FormatVersion:
+		return nil, fmt.Errorf("%w: 设置包版本 %d，当前程序支持 %d", ErrUnsupportedVersion, manifest.FormatVersion, FormatVersion)
+	}
+	// 目前只有版本 1，新增版本时在这里逐级迁移
+	return manifest, nil
+}
+
+// Conflicts 返回导入时会被覆盖的本地内容
+// 参数:
+//   - manifest: 设置包描述信息
+//   - items: 本地内容
+//
+// 返回:
+//   - []Item: 本地已存在、导入时会被覆盖的内容
+func Conflicts(manifest *Manifest, items []Item) []Item {
+	var conflicts []Item
+	for _, item := range items {
+		if !slices.Contains(manifest.Items, item.Name) {
+			continue
+		}
+		if _, err := os.Stat(item.Path); err == nil {
+			conflicts = append(conflicts, item)
+		}
+	}
+	return conflicts
+}
+
+// Import 导入设置包
+// 会被覆盖的本地内容先重命名为带时间戳的备份
+// 参数:
+//   - bundlePath: 设置包路径
+//   - items: 本地内容，决定设置包中各项内容的解压位置
+//
+// 返回:
+//   - []string: 备份文件路径
+//   - error: 错误信息
+func Import(bundlePath string, items []Item) ([]string, error) {
+	manifest, err := ReadManifest(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]Item, len(items))
+	for _, item := range items {
+		if slices.Contains(manifest.Items, item.Name) {
+			targets[item.Name] = item
+		}
+	}
+
+	// 先校验所有条目，避免解压到一半才发现设置包有问题
+	if validateErr := walkBundle(bundlePath, func(header *tar.Header, _ io.Reader) error {
+		_, _, resolveErr := resolveEntry(header, targets)
+		return resolveErr
+	}); validateErr != nil {
+		return nil, validateErr
+	}
+
+	// 备份会被覆盖的内容
+	var backups []string
+	suffix := ".bak-" + time.Now().Format("20060102150405")
+	for _, item := range Conflicts(manifest, items) {
+		backup := item.Path + suffix
+		if renameErr := os.Rename(item.Path, backup); renameErr != nil {
+			return backups, fmt.Errorf("备份 %s 失败: %w", item.Path, renameErr)
+		}
+		backups = append(backups, backup)
+	}
+
+	extractErr := walkBundle(bundlePath, func(header *tar.Header, r io.Reader) error {
+		target, ok, resolveErr := resolveEntry(header, targets)
+		if resolveErr != nil || !ok {
+			return resolveErr
+		}
+		if mkdirErr := os.MkdirAll(filepath.Dir(target), 0750); mkdirErr != nil {
+			return fmt.Errorf("创建目录失败: %w", mkdirErr)
+		}
+		data, readErr := io.ReadAll(r)
+		if readErr != nil {
+			return fmt.Errorf("读取设置包失败: %w", readErr)
+		}
+		if writeErr := os.WriteFile(target, data, 0600); writeErr != nil {
+			return fmt.Errorf("写入 %s 失败: %w", target, writeErr)
+		}
+		return nil
+	})
+	return backups, extractErr
+}
+
+// resolveEntry 返回设置包条目在本地的解压路径
+// 条目不属于任何本地内容时返回 false.
+func resolveEntry(header *tar.Header, targets map[string]Item) (string, bool, error) {
+	if header.Name == manifestName || header.Typeflag == tar.TypeDir {
+		return "", false, nil
+	}
+	if header.Typeflag != tar.TypeReg {
+		return "", false, fmt.Errorf("%w: 不支持的条目类型 %s", ErrInvalidBundle, header.Name)
+	}
+
+	name, rest, _ := strings.Cut(header.Name, "/")
+	item, ok := targets[name]
+	if !ok {
+		return "", false, nil
+	}
+	if rest == "" {
+		return item.Path, true, nil
+	}
+	if !filepath.IsLocal(rest) {
+		return "", false, fmt.Errorf("%w: 非法的条目路径 %s", ErrInvalidBundle, header.Name)
+	}
+	return filepath.Join(item.Path, filepath.FromSlash(rest)), true, nil
+}
+
+// walkBundle 依次遍历设置包中的所有条目.
+func walkBundle(bundlePath string, fn func(header *tar.Header, r io.Reader) error) error {
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return fmt.Errorf("打开设置包失败: %w", err)
+	}
+	defer file.Close()
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, nextErr := tr.Next()
+		if errors.Is(nextErr, io.EOF) {
+			return nil
+		}
+		if nextErr != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidBundle, nextErr)
+		}
+		if fnErr := fn(header, tr); fnErr != nil {
+			return fnErr
+		}
+	}
+}
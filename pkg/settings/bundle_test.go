@@ -0,0 +1,222 @@
+package settings_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/settings"
+)
+
+// writeBundle 手动构造设置包，用于测试异常输入.
+func writeBundle(t *testing.T, path string, manifest settings.Manifest, files map[string]string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	entries := map[string]string{"bundle.json": string(data)}
+	for name, content := range files {
+		entries[name] = content
+	}
+	for name, content := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content))}))
+		_, err = tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+}
+
+func TestExportImport(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	cacheDir := filepath.Join(src, "chara_cache")
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "sub"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "001.json"), []byte(`{"a":1}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "sub", "002.json"), []byte(`{"b":2}`), 0600))
+
+	bundlePath := filepath.Join(t.TempDir(), "settings.tar.gz")
+	manifest, err := settings.Export(bundlePath, []settings.Item{
+		{Name: "chara_cache", Path: cacheDir},
+		{Name: "missing", Path: filepath.Join(src, "missing.json")},
+	}, "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chara_cache"}, manifest.Items)
+
+	read, err := settings.ReadManifest(bundlePath)
+	require.NoError(t, err)
+	assert.Equal(t, settings.FormatVersion, read.FormatVersion)
+	assert.Equal(t, "v1.0.0", read.AppVersion)
+
+	tests := []struct {
+		name     string
+		existing bool
+		backups  int
+	}{
+		{name: "导入到新机器", existing: false, backups: 0},
+		{name: "覆盖前备份本地内容", existing: true, backups: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dst := filepath.Join(t.TempDir(), "chara_cache")
+			if tt.existing {
+				require.NoError(t, os.MkdirAll(dst, 0750))
+				require.NoError(t, os.WriteFile(filepath.Join(dst, "old.json"), []byte(`{}`), 0600))
+			}
+
+			items := []settings.Item{{Name: "chara_cache", Path: dst}}
+			assert.Len(t, settings.Conflicts(read, items), tt.backups)
+
+			backups, err := settings.Import(bundlePath, items)
+			require.NoError(t, err)
+			assert.Len(t, backups, tt.backups)
+			for _, backup := range backups {
+				assert.FileExists(t, filepath.Join(backup, "old.json"))
+			}
+
+			data, err := os.ReadFile(filepath.Join(dst, "sub", "002.json"))
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"b":2}`, string(data))
+			assert.NoFileExists(t, filepath.Join(dst, "old.json"))
+		})
+	}
+}
+
+func TestExportImportConfig(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	configPath := filepath.Join(src, "bestdori.yaml")
+	statePath := filepath.Join(src, "state.json")
+	require.NoError(t, os.WriteFile(configPath, []byte("live2d_save_path: ./live2d\nchara_cache_path: ./chara_cache\n"), 0600))
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"history":[]}`), 0600))
+
+	bundlePath := filepath.Join(t.TempDir(), "settings.tar.gz")
+	manifest, err := settings.Export(bundlePath, []settings.Item{
+		{Name: "config", Path: configPath},
+		{Name: "state", Path: statePath},
+	}, "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config", "state"}, manifest.Items)
+
+	dst := t.TempDir()
+	items := []settings.Item{
+		{Name: "config", Path: filepath.Join(dst, "bestdori.yaml")},
+		{Name: "state", Path: filepath.Join(dst, "state.json")},
+	}
+	backups, err := settings.Import(bundlePath, items)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	for i, want := range []string{configPath, statePath} {
+		wantData, err := os.ReadFile(want)
+		require.NoError(t, err)
+		gotData, err := os.ReadFile(items[i].Path)
+		require.NoError(t, err)
+		assert.Equal(t, string(wantData), string(gotData), items[i].Name)
+	}
+}
+
+func TestImportNestedItem(t *testing.T) {
+	t.Parallel()
+
+	src := filepath.Join(t.TempDir(), "chara_cache")
+	require.NoError(t, os.MkdirAll(src, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "history.json"), []byte(`{"new":true}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "001.json"), []byte(`{}`), 0600))
+
+	itemsAt := func(dir string) []settings.Item {
+		return []settings.Item{
+			{Name: "history", Path: filepath.Join(dir, "history.json")},
+			{Name: "chara_cache", Path: dir},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		items []settings.Item
+	}{
+		{name: "只导出下载历史", items: itemsAt(src)[:1]},
+		{name: "同时导出缓存目录", items: itemsAt(src)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			bundlePath := filepath.Join(t.TempDir(), "settings.tar.gz")
+			_, err := settings.Export(bundlePath, tt.items, "v1.0.0")
+			require.NoError(t, err)
+
+			// 本地已有缓存目录与下载历史，导入时两者都需要备份
+			dst := filepath.Join(t.TempDir(), "chara_cache")
+			require.NoError(t, os.MkdirAll(dst, 0750))
+			require.NoError(t, os.WriteFile(filepath.Join(dst, "history.json"), []byte(`{"old":true}`), 0600))
+
+			_, err = settings.Import(bundlePath, itemsAt(dst))
+			require.NoError(t, err)
+
+			data, err := os.ReadFile(filepath.Join(dst, "history.json"))
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"new":true}`, string(data))
+		})
+	}
+}
+
+func TestImportInvalidBundle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		manifest settings.Manifest
+		files    map[string]string
+		wantErr  error
+	}{
+		{
+			name:     "更新的格式版本",
+			manifest: settings.Manifest{FormatVersion: settings.FormatVersion + 1, Items: []string{"chara_cache"}},
+			wantErr:  settings.ErrUnsupportedVersion,
+		},
+		{
+			name:     "路径穿越",
+			manifest: settings.Manifest{FormatVersion: settings.FormatVersion, Items: []string{"chara_cache"}},
+			files:    map[string]string{"chara_cache/../../evil.json": "{}"},
+			wantErr:  settings.ErrInvalidBundle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			bundlePath := filepath.Join(dir, "settings.tar.gz")
+			writeBundle(t, bundlePath, tt.manifest, tt.files)
+
+			dst := filepath.Join(dir, "cache", "chara_cache")
+			require.NoError(t, os.MkdirAll(dst, 0750))
+
+			_, err := settings.Import(bundlePath, []settings.Item{{Name: "chara_cache", Path: dst}})
+			require.ErrorIs(t, err, tt.wantErr)
+			// 校验失败时不应备份或修改本地内容
+			assert.DirExists(t, dst)
+			assert.NoFileExists(t, filepath.Join(dir, "evil.json"))
+		})
+	}
+}
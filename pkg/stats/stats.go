@@ -0,0 +1,164 @@
+// Package stats 提供了本地使用统计功能
+// 记录每个服装的下载次数和最近使用时间，统计数据只保存在本地
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+// fileMu 保证同一进程内对状态文件的读写不会互相覆盖
+// 批量下载时多个模型可能同时完成.
+//
+//nolint:gochecknoglobals // 状态文件在进程内只有一份，需要全局互斥
+var fileMu sync.Mutex
+
+// CostumeUsage 表示单个服装的使用统计.
+type CostumeUsage struct {
+	Chara     string    `json:"chara"`     // 所属角色目录名
+	Path      string    `json:"path"`      // 模型保存路径，用于清理已删除模型的记录
+	Downloads int       `json:"downloads"` // 首次下载的次数
+	Repairs   int       `json:"repairs"`   // 在已有目录上重新下载（修复）的次数
+	LastUsed  time.Time `json:"lastUsed"`  // 最近一次下载或修复的时间
+}
+
+// Label 返回用于界面显示的统计文本，例如 "已下载 3 次，最近 2024-11-02".
+func (u CostumeUsage) Label() string {
+	return fmt.Sprintf("已下载 %d 次，最近 %s", u.Downloads+u.Repairs, u.LastUsed.Format(time.DateOnly))
+}
+
+// State 表示保存在状态文件中的本地状态.
+type State struct {
+	Usage map[string]CostumeUsage `json:"usage"` // 服装使用统计，key 为服装名称
+}
+
+// CharaUsage 汇总指定角色所有服装的使用统计.
+func (s *State) CharaUsage(chara string) CostumeUsage {
+	total := CostumeUsage{Chara: chara}
+	for _, usage := range s.Usage {
+		if usage.Chara != chara {
+			continue
+		}
+		total.Downloads += usage.Downloads
+		total.Repairs += usage.Repairs
+		if usage.LastUsed.After(total.LastUsed) {
+			total.LastUsed = usage.LastUsed
+		}
+	}
+	return total
+}
+
+// Load 读取状态文件，文件不存在时返回空状态
+// 参数:
+//   - path: 状态文件路径
+//
+// 返回:
+//   - *State: 本地状态
+//   - error: 错误信息
+func Load(path string) (*State, error) {
+	state := &State{Usage: make(map[string]CostumeUsage)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取状态文件失败: %w", err)
+	}
+	if unmarshalErr := json.Unmarshal(data, state); unmarshalErr != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %w", unmarshalErr)
+	}
+	if state.Usage == nil {
+		state.Usage = make(map[string]CostumeUsage)
+	}
+	return state, nil
+}
+
+// save 将状态写入状态文件，先写入临时文件再替换，避免写入中断损坏状态文件.
+func save(path string, state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %w", err)
+	}
+	file, err := fsutil.CreateTemp(path)
+	if err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	if _, writeErr := file.Write(data); writeErr != nil {
+		fsutil.DiscardTemp(file)
+		return fmt.Errorf("写入状态文件失败: %w", writeErr)
+	}
+	if commitErr := fsutil.CommitTemp(file, path); commitErr != nil {
+		return fmt.Errorf("写入状态文件失败: %w", commitErr)
+	}
+	return nil
+}
+
+// update 读取状态文件，调用 fn 修改，fn 返回 true 时写回.
+func update(path string, fn func(state *State) bool) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	state, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if !fn(state) {
+		return nil
+	}
+	return save(path, state)
+}
+
+// RecordUsage 记录一次服装的下载或修复
+// 参数:
+//   - path: 状态文件路径
+//   - costume: 服装名称
+//   - chara: 所属角色目录名
+//   - modelPath: 模型保存路径
+//   - repair: 是否为在已有目录上的重新下载
+//   - now: 记录时间
+//
+// 返回:
+//   - error: 错误信息
+func RecordUsage(path, costume, chara, modelPath string, repair bool, now time.Time) error {
+	return update(path, func(state *State) bool {
+		usage := state.Usage[costume]
+		usage.Chara = chara
+		usage.Path = modelPath
+		if repair {
+			usage.Repairs++
+		} else {
+			usage.Downloads++
+		}
+		usage.LastUsed = now
+		state.Usage[costume] = usage
+		return true
+	})
+}
+
+// Prune 删除模型目录已不存在的服装记录，避免状态文件越来越大
+// 参数:
+//   - path: 状态文件路径
+//
+// 返回:
+//   - []string: 被删除记录的服装名称
+//   - error: 错误信息
+func Prune(path string) ([]string, error) {
+	var pruned []string
+	err := update(path, func(state *State) bool {
+		for costume, usage := range state.Usage {
+			if _, statErr := os.Stat(usage.Path); errors.Is(statErr, fs.ErrNotExist) {
+				delete(state.Usage, costume)
+				pruned = append(pruned, costume)
+			}
+		}
+		return len(pruned) > 0
+	})
+	return pruned, err
+}
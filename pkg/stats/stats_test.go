@@ -0,0 +1,67 @@
+package stats_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/stats"
+)
+
+func TestRecordUsage(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	modelPath := filepath.Join(dir, "kasumi", "001_casual")
+	first := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 11, 2, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, stats.RecordUsage(statePath, "001_casual", "kasumi", modelPath, false, first))
+	require.NoError(t, stats.RecordUsage(statePath, "001_casual", "kasumi", modelPath, true, first))
+	require.NoError(t, stats.RecordUsage(statePath, "001_casual", "kasumi", modelPath, true, last))
+	require.NoError(t, stats.RecordUsage(statePath, "001_live", "kasumi", modelPath, false, first))
+
+	state, err := stats.Load(statePath)
+	require.NoError(t, err)
+	usage := state.Usage["001_casual"]
+	assert.Equal(t, 1, usage.Downloads)
+	assert.Equal(t, 2, usage.Repairs)
+	assert.Equal(t, "已下载 3 次，最近 2024-11-02", usage.Label())
+	assert.Equal(t, "已下载 4 次，最近 2024-11-02", state.CharaUsage("kasumi").Label())
+}
+
+func TestLoadMissing(t *testing.T) {
+	t.Parallel()
+
+	state, err := stats.Load(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	assert.Empty(t, state.Usage)
+}
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	kept := filepath.Join(dir, "kasumi", "001_casual")
+	deleted := filepath.Join(dir, "kasumi", "001_live")
+	require.NoError(t, os.MkdirAll(kept, 0750))
+
+	now := time.Now()
+	require.NoError(t, stats.RecordUsage(statePath, "001_casual", "kasumi", kept, false, now))
+	require.NoError(t, stats.RecordUsage(statePath, "001_live", "kasumi", deleted, false, now))
+
+	pruned, err := stats.Prune(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"001_live"}, pruned)
+
+	state, err := stats.Load(statePath)
+	require.NoError(t, err)
+	assert.Contains(t, state.Usage, "001_casual")
+	assert.NotContains(t, state.Usage, "001_live")
+}
@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// StateBandMembers 表示选择乐队成员的状态.
+const StateBandMembers = "bandMembers"
+
+// BandMember 表示乐队成员列表中的角色.
+type BandMember struct {
+	ID   int    // 角色ID
+	Name string // 显示的角色名称
+}
+
+// UpdateBandMembersMsg 表示显示乐队成员列表的消息.
+type UpdateBandMembersMsg struct {
+	Band    string       // 乐队名称
+	Members []BandMember // 乐队成员，按显示顺序排列
+}
+
+// handleUpdateBandMembersMsg 显示乐队成员列表，等待用户选择角色.
+func (m *Model) handleUpdateBandMembersMsg(msg UpdateBandMembersMsg) (tea.Model, tea.Cmd) {
+	m.bandMembers = msg
+	m.memberCursor = 0
+	m.State = StateBandMembers
+	m.ClearError()
+	return m, nil
+}
+
+// handleBandMembersState 处理乐队成员列表中的按键
+// 选择角色后按角色编号搜索，进入该角色的服装列表.
+func (m *Model) handleBandMembersState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	members := m.bandMembers.Members
+	switch msg.String() {
+	case "up", "k":
+		if len(members) > 0 {
+			m.memberCursor = (m.memberCursor - 1 + len(members)) % len(members)
+		}
+	case "down", "j":
+		if len(members) > 0 {
+			m.memberCursor = (m.memberCursor + 1) % len(members)
+		}
+	case "enter":
+		if m.memberCursor >= len(members) {
+			return m, nil
+		}
+		m.State = StateLoading
+		m.loadingProgress = loadingProgressMsg{}
+		select {
+		case m.SearchChan <- strconv.Itoa(members[m.memberCursor].ID):
+		default:
+		}
+		return m, m.Spinner.Tick
+	case KeyEsc:
+		m.State = StateInput
+		m.TextInput.Reset()
+	}
+	return m, nil
+}
+
+// bandMembersView 渲染乐队成员列表.
+func (m *Model) bandMembersView() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("乐队成员 - " + m.bandMembers.Band))
+	s.WriteString("\n\n")
+	for i, member := range m.bandMembers.Members {
+		line := fmt.Sprintf("  %s", member.Name)
+		if i == m.memberCursor {
+			line = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF69B4")).Render("> " + member.Name)
+		}
+		s.WriteString(line)
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+	s.WriteString(helpStyle("↑/↓ 选择角色，Enter 查看服装，Esc 返回，Ctrl+C 退出"))
+	return s.String()
+}
@@ -0,0 +1,11 @@
+package tui
+
+// toggleBlocked 切换服装列表中是否显示屏蔽的列表项，已选中的列表项保持选中.
+func (m *Model) toggleBlocked() {
+	if len(m.listMsg.Blocked) == 0 {
+		return
+	}
+	selected := m.GetSelectedItems()
+	m.ShowBlocked = !m.ShowBlocked
+	m.setListItems(selected)
+}
@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// StateCharaBrowser 表示浏览全部角色的状态.
+const StateCharaBrowser = "charaBrowser"
+
+// CharaGroup 表示角色浏览列表中的一个乐队分组.
+type CharaGroup struct {
+	Band   string       // 乐队名称
+	Charas []BandMember // 乐队中的角色，按显示顺序排列
+}
+
+// UpdateCharaBrowserMsg 表示显示全部角色列表的消息.
+type UpdateCharaBrowserMsg struct {
+	Groups []CharaGroup // 按乐队分组的角色，按显示顺序排列
+}
+
+// charaHeaderItem 表示角色浏览列表中的乐队标题.
+type charaHeaderItem struct {
+	band string // 乐队名称
+}
+
+// FilterValue 返回用于过滤的值，按乐队名称过滤时保留标题.
+func (i charaHeaderItem) FilterValue() string { return i.band }
+
+// charaItem 表示角色浏览列表中的角色.
+type charaItem struct {
+	member BandMember // 角色
+	band   string     // 所属乐队名称
+}
+
+// FilterValue 返回用于过滤的值，同时支持按乐队名称过滤.
+func (i charaItem) FilterValue() string { return i.member.Name + " " + i.band }
+
+// charaBrowserDelegate 渲染角色浏览列表，每个列表项只占一行.
+type charaBrowserDelegate struct{}
+
+// Height 返回列表项的高度.
+func (d charaBrowserDelegate) Height() int { return 1 }
+
+// Spacing 返回列表项之间的间距.
+func (d charaBrowserDelegate) Spacing() int { return 0 }
+
+// Update 处理列表项的更新.
+func (d charaBrowserDelegate) Update(tea.Msg, *list.Model) tea.Cmd { return nil }
+
+// Render 渲染列表项.
+func (d charaBrowserDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	switch i := item.(type) {
+	case charaHeaderItem:
+		fmt.Fprint(w, titleStyle.Render(i.band))
+	case charaItem:
+		line := fmt.Sprintf("%3d  %s", i.member.ID, i.member.Name)
+		if index == m.Index() {
+			fmt.Fprint(w, lipgloss.NewStyle().Foreground(lipgloss.Color("#FF69B4")).Render("> "+line))
+			return
+		}
+		fmt.Fprint(w, "  "+line)
+	}
+}
+
+// newCharaBrowser 创建角色浏览列表.
+func newCharaBrowser() list.Model {
+	l := list.New([]list.Item{}, charaBrowserDelegate{}, 0, 0)
+	l.Title = "选择角色"
+	l.SetShowHelp(true)
+	l.DisableQuitKeybindings()
+	return l
+}
+
+// handleUpdateCharaBrowserMsg 显示全部角色列表，等待用户选择角色.
+func (m *Model) handleUpdateCharaBrowserMsg(msg UpdateCharaBrowserMsg) (tea.Model, tea.Cmd) {
+	var items []list.Item
+	first := -1
+	for _, group := range msg.Groups {
+		items = append(items, charaHeaderItem{band: group.Band})
+		for _, member := range group.Charas {
+			if first < 0 {
+				first = len(items)
+			}
+			items = append(items, charaItem{member: member, band: group.Band})
+		}
+	}
+	m.charaBrowser.ResetFilter()
+	cmd := m.charaBrowser.SetItems(items)
+	m.charaBrowser.Select(max(first, 0))
+	m.State = StateCharaBrowser
+	m.ClearError()
+	return m, cmd
+}
+
+// handleCharaBrowserState 处理角色浏览列表中的按键
+// 正在输入过滤条件时按键交给列表处理；选择角色后按角色编号搜索，进入该角色的服装列表.
+func (m *Model) handleCharaBrowserState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.charaBrowser.FilterState() == list.Filtering {
+		var cmd tea.Cmd
+		m.charaBrowser, cmd = m.charaBrowser.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "enter":
+		chara, ok := m.charaBrowser.SelectedItem().(charaItem)
+		if !ok {
+			return m, nil
+		}
+		id := strconv.Itoa(chara.member.ID)
+		m.TextInput.SetValue(id)
+		m.State = StateLoading
+		m.loadingProgress = loadingProgressMsg{}
+		select {
+		case m.SearchChan <- id:
+		default:
+		}
+		return m, m.Spinner.Tick
+	case KeyEsc:
+		if m.charaBrowser.FilterState() == list.FilterApplied {
+			m.charaBrowser.ResetFilter()
+			return m, nil
+		}
+		m.State = StateInput
+		m.TextInput.Reset()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.charaBrowser, cmd = m.charaBrowser.Update(msg)
+	return m, cmd
+}
+
+// charaBrowserView 渲染角色浏览列表.
+func (m *Model) charaBrowserView() string {
+	return m.charaBrowser.View() + "\n\n" +
+		helpStyle("↑/↓ 选择角色，/ 过滤，Enter 查看服装，Esc 返回，Ctrl+C 退出")
+}
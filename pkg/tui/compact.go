@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// compactBarWidth 是紧凑视图中进度条的宽度.
+	compactBarWidth = 20
+	// narrowBarWidth 是窄屏视图中进度条的宽度.
+	narrowBarWidth = 10
+	// narrowMinNameWidth 是窄屏视图中模型名称至少保留的宽度.
+	narrowMinNameWidth = 8
+)
+
+// statusIcon 返回下载列表项的状态图标.
+func (i DownloadListItem) statusIcon() string {
+	switch {
+	case i.Err != nil:
+		return "❌"
+	case i.Current == i.Total:
+		return "✅"
+	default:
+		return "⏳"
+	}
+}
+
+// compactLine 返回下载列表项在紧凑视图中的单行表示
+// 格式为：状态图标 名称 进度条 百分比.
+func (i DownloadListItem) compactLine() string {
+	ratio := float64(i.Current) / float64(i.Total)
+	bar := i.Progress
+	bar.Width = compactBarWidth
+	bar.ShowPercentage = false
+	line := fmt.Sprintf("%s %s %s %5.1f%%", i.statusIcon(), i.label(), bar.ViewAs(bar.Percent()), ratio*100)
+	line += stallBadge(i.StalledFor)
+	if i.Err != nil {
+		line = fmt.Sprintf("%s - 错误: %v", line, i.Err)
+	}
+	return line
+}
+
+// narrowLine 返回下载列表项在窄屏视图中的单行表示
+// 格式为：缩短的名称 进度条 百分比 状态图标，超出 width 的部分被截断，不会折行.
+func (i DownloadListItem) narrowLine(width int) string {
+	bar := i.Progress
+	bar.Width = narrowBarWidth
+	bar.ShowPercentage = false
+	suffix := fmt.Sprintf(" %s %3.0f%% %s", bar.ViewAs(bar.Percent()), float64(i.Current)/float64(i.Total)*100, i.statusIcon())
+	line := shortenName(i.Name, max(width-lipgloss.Width(suffix), narrowMinNameWidth)) + suffix
+	line += stallBadge(i.StalledFor)
+	if i.Err != nil {
+		line = fmt.Sprintf("%s %v", line, i.Err)
+	}
+	return lipgloss.NewStyle().MaxWidth(width).Render(line)
+}
+
+// shortenName 将名称截断到 width 列以内，截断时以省略号结尾.
+func shortenName(name string, width int) string {
+	if lipgloss.Width(name) <= width {
+		return name
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range name {
+		w := lipgloss.Width(string(r))
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	return b.String() + "…"
+}
+
+// renderCompact 以紧凑视图或窄屏视图渲染列表项.
+func (d DownloadDelegate) renderCompact(w io.Writer, m list.Model, index int, item list.Item) {
+	switch dl := item.(type) {
+	case DownloadListItem:
+		if d.Narrow {
+			fmt.Fprintf(w, "  %s", dl.narrowLine(m.Width()-2))
+			return
+		}
+		fmt.Fprintf(w, "  %s", dl.compactLine())
+	case DownloadGroupItem:
+		title := dl.Title()
+		if index == m.Index() {
+			title = "> " + title
+		}
+		fmt.Fprint(w, titleStyle.Render(title))
+	}
+}
+
+// SetCompactView 切换下载列表的紧凑视图与详细视图
+// 终端宽度小于 NarrowWidth 时始终使用窄屏视图，恢复宽度后按该设置显示.
+func (m *Model) SetCompactView(compact bool) {
+	m.CompactView = compact
+	m.updateDownloadDelegate()
+}
+
+// Narrow 返回下载列表当前是否因终端过窄使用窄屏视图.
+func (m *Model) Narrow() bool {
+	return m.narrow
+}
+
+// updateDownloadDelegate 根据紧凑视图设置与终端宽度更新下载列表的渲染方式.
+func (m *Model) updateDownloadDelegate() {
+	m.DownloadList.SetDelegate(DownloadDelegate{Compact: m.CompactView || m.narrow, Narrow: m.narrow})
+}
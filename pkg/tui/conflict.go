@@ -0,0 +1,123 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
+)
+
+// StateServerConflict 表示询问角色目录服务器冲突的状态.
+const StateServerConflict = "serverConflict"
+
+// ServerConflictAnswer 表示用户对服务器冲突的选择.
+type ServerConflictAnswer struct {
+	Decision model.ServerConflictDecision // 处理方式
+	Remember bool                         // 是否记住该角色的选择
+}
+
+// serverConflictMsg 表示角色目录服务器冲突的询问消息.
+type serverConflictMsg struct {
+	chara        string                    // 角色目录名
+	folderServer string                    // 角色目录已记录的服务器
+	server       string                    // 本次下载使用的服务器
+	answer       chan ServerConflictAnswer // 回答通道
+}
+
+// AskServerConflict 询问用户如何处理角色目录的服务器冲突
+// 会阻塞直到用户作出选择或上下文被取消，没有 TUI 程序时默认继续使用原目录
+// 参数:
+//   - ctx: 上下文
+//   - chara: 角色目录名
+//   - folderServer: 角色目录已记录的服务器
+//   - server: 本次下载使用的服务器
+//
+// 返回:
+//   - ServerConflictAnswer: 用户的选择
+//   - error: 错误信息
+func (m *Model) AskServerConflict(ctx context.Context, chara, folderServer, server string) (ServerConflictAnswer, error) {
+	if m.program == nil {
+		return ServerConflictAnswer{Decision: model.ServerConflictProceed}, nil
+	}
+
+	answer := make(chan ServerConflictAnswer, 1)
+	m.program.Send(serverConflictMsg{chara: chara, folderServer: folderServer, server: server, answer: answer})
+	select {
+	case <-ctx.Done():
+		return ServerConflictAnswer{}, errs.ErrDownloadCancelled
+	case a := <-answer:
+		return a, nil
+	}
+}
+
+// handleServerConflictMsg 将冲突询问加入队列，并切换到询问状态.
+func (m *Model) handleServerConflictMsg(msg serverConflictMsg) (tea.Model, tea.Cmd) {
+	m.pendingConflicts = append(m.pendingConflicts, msg)
+	if m.State != StateServerConflict {
+		m.conflictReturnState = m.State
+		m.State = StateServerConflict
+	}
+	return m, nil
+}
+
+// handleServerConflictState 处理询问状态下的按键
+// 小写字母仅对本次生效，大写字母会记住该角色的选择.
+func (m *Model) handleServerConflictState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if len(m.pendingConflicts) == 0 {
+		m.State = m.conflictReturnState
+		return m, nil
+	}
+
+	key := msg.String()
+	var decision model.ServerConflictDecision
+	switch strings.ToLower(key) {
+	case "p":
+		decision = model.ServerConflictProceed
+	case "s":
+		decision = model.ServerConflictSuffix
+	case "a", KeyEsc:
+		decision = model.ServerConflictAbort
+	default:
+		return m, nil
+	}
+
+	current := m.pendingConflicts[0]
+	m.pendingConflicts = m.pendingConflicts[1:]
+	current.answer <- ServerConflictAnswer{
+		Decision: decision,
+		Remember: key != strings.ToLower(key),
+	}
+
+	if len(m.pendingConflicts) == 0 {
+		m.State = m.conflictReturnState
+	}
+	return m, nil
+}
+
+// serverConflictView 渲染服务器冲突询问界面.
+func (m *Model) serverConflictView() string {
+	if len(m.pendingConflicts) == 0 {
+		return ""
+	}
+	current := m.pendingConflicts[0]
+
+	var s strings.Builder
+	s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500")).Render(
+		fmt.Sprintf("角色目录 %s 中的模型来自 %s 服，本次下载来自 %s 服", current.chara, current.folderServer, current.server),
+	))
+	s.WriteString("\n\n")
+	s.WriteString(fmt.Sprintf("  P 仍然保存到 %s\n", current.chara))
+	s.WriteString(fmt.Sprintf("  S 保存到 %s-%s\n", current.chara, current.server))
+	s.WriteString("  A 中止该模型的下载\n\n")
+	if len(m.pendingConflicts) > 1 {
+		s.WriteString(helpStyle(fmt.Sprintf("还有 %d 个冲突等待处理", len(m.pendingConflicts)-1)))
+		s.WriteString("\n")
+	}
+	s.WriteString(helpStyle("按 Shift 加字母可记住该角色的选择（写入 bestdori.yaml），Ctrl+C 退出"))
+	return s.String()
+}
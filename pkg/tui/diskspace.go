@@ -0,0 +1,125 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+// DiskLevel 表示保存路径剩余空间的状态.
+type DiskLevel int
+
+const (
+	// DiskUnknown 表示尚未检查剩余空间.
+	DiskUnknown DiskLevel = iota
+	// DiskOK 表示剩余空间充足.
+	DiskOK
+	// DiskLow 表示剩余空间低于警告阈值.
+	DiskLow
+	// DiskCritical 表示剩余空间不足以完成进行中的文件，批量下载已暂停.
+	DiskCritical
+)
+
+// diskSpaceMsg 表示剩余空间的检查结果.
+type diskSpaceMsg struct {
+	free     uint64    // 剩余空间（字节）
+	required uint64    // 进行中的文件预估需要的空间加上安全余量（字节）
+	level    DiskLevel // 剩余空间状态
+}
+
+// SendDiskSpace 更新状态栏中显示的剩余空间
+// 参数:
+//   - free: 剩余空间（字节）
+//   - required: 进行中的文件预估需要的空间加上安全余量（字节）
+//   - level: 剩余空间状态
+func (m *Model) SendDiskSpace(free, required uint64, level DiskLevel) {
+	msg := diskSpaceMsg{free: free, required: required, level: level}
+	if m.program != nil {
+		m.program.Send(msg)
+		return
+	}
+	m.handleDiskSpaceMsg(msg)
+}
+
+// handleDiskSpaceMsg 处理剩余空间的检查结果.
+func (m *Model) handleDiskSpaceMsg(msg diskSpaceMsg) (tea.Model, tea.Cmd) {
+	m.disk = msg
+	return m, nil
+}
+
+// DiskPaused 返回批量下载是否因剩余空间不足而暂停.
+func (m *Model) DiskPaused() bool {
+	return m.disk.level == DiskCritical
+}
+
+// diskStatusView 渲染状态栏中的剩余空间
+// 空间不足时以黄色警告，下载暂停时以红色提示.
+func (m *Model) diskStatusView() string {
+	text := "剩余空间: " + fsutil.FormatBytes(m.disk.free)
+	switch m.disk.level {
+	case DiskOK:
+		return helpStyle(text)
+	case DiskLow:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500")).Render("⚠ " + text + "，即将不足")
+	case DiskCritical:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Render("✖ " + text + "，下载已暂停")
+	case DiskUnknown:
+	}
+	return ""
+}
+
+// spaceEstimateMsg 表示下载前估算的所需空间.
+type spaceEstimateMsg struct {
+	text         string // 估算结果
+	insufficient bool   // 剩余空间是否不足
+}
+
+// SendSpaceEstimate 更新下载前估算的所需空间
+// 参数:
+//   - text: 估算结果，例如 "预计占用 1.2 GiB，可用 50.0 GiB"，为空时不显示
+//   - insufficient: 剩余空间是否不足以完成下载
+func (m *Model) SendSpaceEstimate(text string, insufficient bool) {
+	msg := spaceEstimateMsg{text: text, insufficient: insufficient}
+	if m.program != nil {
+		m.program.Send(msg)
+		return
+	}
+	m.handleSpaceEstimateMsg(msg)
+}
+
+// handleSpaceEstimateMsg 处理下载前估算的所需空间.
+func (m *Model) handleSpaceEstimateMsg(msg spaceEstimateMsg) (tea.Model, tea.Cmd) {
+	m.estimate = msg
+	return m, nil
+}
+
+// spaceEstimateView 渲染下载前估算的所需空间，空间不足时以红色警告.
+func (m *Model) spaceEstimateView() string {
+	if m.estimate.text == "" {
+		return ""
+	}
+	if m.estimate.insufficient {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).
+			Render("⚠ " + m.estimate.text + "，剩余空间可能不足，下载可能中途暂停")
+	}
+	return helpStyle(m.estimate.text)
+}
+
+// diskPausedView 渲染剩余空间不足时的暂停提示.
+func (m *Model) diskPausedView() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("保存路径的剩余空间只有 %s，进行中的文件预计还需要 %s（含安全余量）\n",
+		fsutil.FormatBytes(m.disk.free), fsutil.FormatBytes(m.disk.required)))
+	s.WriteString("已暂停开始新的文件下载，进行中的文件会继续完成\n")
+	s.WriteString("请清理磁盘空间，空间足够后会自动继续下载")
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#FF0000")).
+		Foreground(lipgloss.Color("#FF0000")).
+		Padding(0, 1).
+		Render(s.String())
+}
@@ -0,0 +1,224 @@
+package tui
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ungroupedName 是尚未确定所属角色的下载项的分组名称.
+const ungroupedName = "其他"
+
+// itemGroupMsg 表示下载项所属角色更新消息.
+type itemGroupMsg struct {
+	itemName string // 项目名称
+	group    string // 所属角色名称
+	dir      string // 角色目录
+}
+
+// DownloadGroupItem 表示下载列表中的角色分组标题.
+type DownloadGroupItem struct {
+	Name      string // 角色名称
+	Dir       string // 角色目录
+	Collapsed bool   // 是否已折叠
+	Completed int    // 已完成的模型数量
+	Failed    int    // 失败的模型数量
+	Total     int    // 模型总数
+}
+
+// Title 返回分组标题.
+func (g DownloadGroupItem) Title() string {
+	arrow := "▼"
+	if g.Collapsed {
+		arrow = "▶"
+	}
+	title := fmt.Sprintf("%s %s (%d/%d)", arrow, g.Name, g.Completed, g.Total)
+	if g.Failed > 0 {
+		title = fmt.Sprintf("%s - %d 个失败", title, g.Failed)
+	}
+	return title
+}
+
+// Description 返回分组描述.
+func (g DownloadGroupItem) Description() string {
+	if g.Dir == "" {
+		return ""
+	}
+	return helpStyle("目录: " + g.Dir)
+}
+
+// FilterValue 返回用于过滤的值.
+func (g DownloadGroupItem) FilterValue() string { return g.Name }
+
+// SetItemGroup 设置下载项所属的角色及角色目录
+// 用于在下载列表中按角色分组显示.
+func (m *Model) SetItemGroup(name, group, dir string) {
+	if m.program != nil {
+		m.program.Send(itemGroupMsg{itemName: name, group: group, dir: dir})
+		return
+	}
+	m.applyItemGroup(itemGroupMsg{itemName: name, group: group, dir: dir})
+}
+
+// applyItemGroup 更新下载项的分组信息.
+func (m *Model) applyItemGroup(msg itemGroupMsg) {
+	item, exists := m.Items[msg.itemName]
+	if !exists {
+		return
+	}
+	if msg.group != "" {
+		item.Group = msg.group
+	}
+	if msg.dir != "" {
+		item.Dir = msg.dir
+	}
+	m.updateDownloadList()
+}
+
+// handleItemGroupMsg 处理下载项分组消息.
+func (m *Model) handleItemGroupMsg(msg itemGroupMsg) (tea.Model, tea.Cmd) {
+	m.applyItemGroup(msg)
+	return m, nil
+}
+
+// hasGroups 判断是否有下载项已经确定所属角色.
+func (m *Model) hasGroups() bool {
+	for _, item := range m.Items {
+		if item.Group != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// groupName 返回下载项所属的分组名称.
+func groupName(item *DownloadItem) string {
+	if item.Group == "" {
+		return ungroupedName
+	}
+	return item.Group
+}
+
+// groupedDownloadItems 按角色分组生成下载列表项
+// 分组顺序与组内顺序均与 ItemOrder 保持一致.
+func (m *Model) groupedDownloadItems() []list.Item {
+	var order []string
+	members := make(map[string][]*DownloadItem)
+	for _, name := range m.ItemOrder {
+		item, exists := m.Items[name]
+		if !exists {
+			continue
+		}
+		group := groupName(item)
+		if _, seen := members[group]; !seen {
+			order = append(order, group)
+		}
+		members[group] = append(members[group], item)
+	}
+
+	items := make([]list.Item, 0, len(m.Items)+len(order))
+	for _, group := range order {
+		header := DownloadGroupItem{
+			Name:      group,
+			Collapsed: m.collapsedGroups[group],
+			Total:     len(members[group]),
+		}
+		for _, item := range members[group] {
+			if header.Dir == "" {
+				header.Dir = item.Dir
+			}
+			switch {
+			case item.Err != nil:
+				header.Failed++
+			case item.Current == item.Total:
+				header.Completed++
+			}
+		}
+		items = append(items, header)
+		if header.Collapsed {
+			continue
+		}
+		for _, item := range members[group] {
+			items = append(items, newDownloadListItem(item))
+		}
+	}
+	return items
+}
+
+// selectedGroup 返回下载列表当前选中项所属的分组.
+func (m *Model) selectedGroup() (DownloadGroupItem, bool) {
+	var name string
+	switch selected := m.DownloadList.SelectedItem().(type) {
+	case DownloadGroupItem:
+		return selected, true
+	case DownloadListItem:
+		item, exists := m.Items[selected.Name]
+		if !exists {
+			return DownloadGroupItem{}, false
+		}
+		name = groupName(item)
+	default:
+		return DownloadGroupItem{}, false
+	}
+
+	for _, listItem := range m.DownloadList.Items() {
+		if header, ok := listItem.(DownloadGroupItem); ok && header.Name == name {
+			return header, true
+		}
+	}
+	return DownloadGroupItem{}, false
+}
+
+// setGroupCollapsed 折叠或展开当前选中项所属的分组
+// 完成后光标移动到该分组的标题上.
+func (m *Model) setGroupCollapsed(collapsed bool) {
+	header, ok := m.selectedGroup()
+	if !ok {
+		return
+	}
+	m.collapsedGroups[header.Name] = collapsed
+	m.updateDownloadList()
+
+	for i, listItem := range m.DownloadList.Items() {
+		if group, isHeader := listItem.(DownloadGroupItem); isHeader && group.Name == header.Name {
+			m.DownloadList.Select(i)
+			return
+		}
+	}
+}
+
+// openSelectedGroupDir 在文件管理器中打开当前选中分组的角色目录.
+func (m *Model) openSelectedGroupDir() {
+	header, ok := m.selectedGroup()
+	if !ok {
+		return
+	}
+	if header.Dir == "" {
+		m.SetError(fmt.Sprintf("%s 的目录尚未确定", header.Name))
+		return
+	}
+	if err := openDir(header.Dir); err != nil {
+		m.SetError(fmt.Sprintf("打开目录失败: %v", err))
+	}
+}
+
+// openDir 使用系统默认的文件管理器打开目录.
+func openDir(dir string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("explorer", dir) //nolint:gosec // 目录由程序自身生成
+	case "darwin":
+		cmd = exec.Command("open", dir) //nolint:gosec // 目录由程序自身生成
+	default:
+		cmd = exec.Command("xdg-open", dir) //nolint:gosec // 目录由程序自身生成
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动文件管理器失败: %w", err)
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
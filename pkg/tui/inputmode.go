@@ -0,0 +1,43 @@
+package tui
+
+// InputMode 表示输入框的输入模式.
+type InputMode int
+
+const (
+	// InputModeSearch 表示按角色名称或编号搜索，也可以直接输入模型名称.
+	InputModeSearch InputMode = iota
+	// InputModeModel 表示只按 Live2D 模型名称直接下载，跳过角色搜索.
+	InputModeModel
+)
+
+// placeholder 返回输入模式对应的输入框提示.
+func (mode InputMode) placeholder() string {
+	if mode == InputModeModel {
+		return "输入 Live2D 模型名称，多个模型用空格或逗号分隔，支持 * ? 通配符，例如 037_casual-*"
+	}
+	return "输入角色名称、乐队名称或 Live2D 模型名称，留空按 Enter 浏览全部角色"
+}
+
+// label 返回输入模式的名称.
+func (mode InputMode) label() string {
+	if mode == InputModeModel {
+		return "直接模型名"
+	}
+	return "角色搜索"
+}
+
+// SetInputMode 切换输入框的输入模式.
+func (m *Model) SetInputMode(mode InputMode) {
+	m.InputMode = mode
+	m.TextInput.Placeholder = mode.placeholder()
+}
+
+// inputModeView 返回输入模式的提示行.
+func (m *Model) inputModeView() string {
+	return "模式: " + titleStyle.Render(m.InputMode.label()) + "  " + helpStyle("按 Tab 切换")
+}
+
+// GetModelChan 返回直接模型名通道.
+func (m *Model) GetModelChan() <-chan string {
+	return m.ModelChan
+}
@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bytesPerMB 是每 MB 的字节数.
+const bytesPerMB = 1024 * 1024
+
+// loadingProgressMsg 表示加载状态下的数据获取进度消息.
+type loadingProgressMsg struct {
+	endpoint string // 正在获取的接口名称
+	read     int64  // 已读取的字节数
+	total    int64  // 总字节数，未知时为 -1
+}
+
+// UpdateLoadingProgress 更新加载状态下的数据获取进度
+// 参数:
+//   - endpoint: 正在获取的接口名称
+//   - read: 已读取的字节数
+//   - total: 总字节数，未知时为 -1
+func (m *Model) UpdateLoadingProgress(endpoint string, read, total int64) {
+	msg := loadingProgressMsg{endpoint: endpoint, read: read, total: total}
+	if m.program != nil {
+		m.program.Send(msg)
+		return
+	}
+	m.loadingProgress = msg
+}
+
+// handleLoadingProgressMsg 处理数据获取进度消息.
+func (m *Model) handleLoadingProgressMsg(msg loadingProgressMsg) (tea.Model, tea.Cmd) {
+	m.loadingProgress = msg
+	return m, nil
+}
+
+// loadingProgressView 渲染加载状态下的进度行.
+func (m *Model) loadingProgressView() string {
+	p := m.loadingProgress
+	if p.endpoint == "" {
+		return ""
+	}
+	if p.total > 0 {
+		return fmt.Sprintf("正在获取%s… %.1f/%.1f MB", p.endpoint, float64(p.read)/bytesPerMB, float64(p.total)/bytesPerMB)
+	}
+	return fmt.Sprintf("正在获取%s… %.1f MB", p.endpoint, float64(p.read)/bytesPerMB)
+}
@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// logPaneHeight 是日志面板显示的日志行数.
+const logPaneHeight = 8
+
+// logPaneStyle 是日志面板的边框样式.
+//
+//nolint:gochecknoglobals // 使用全局样式常量是必要的，因为需要在不同的 UI 组件中保持一致的样式
+var logPaneStyle = lipgloss.NewStyle().
+	Border(lipgloss.NormalBorder(), true, false, false, false).
+	BorderForeground(lipgloss.Color("#626262"))
+
+// logLinesMsg 表示最近日志行的更新.
+type logLinesMsg struct {
+	lines []string // 最近的日志行，按时间先后排列
+}
+
+// newLogPane 创建日志面板.
+func newLogPane() viewport.Model {
+	pane := viewport.New(0, logPaneHeight)
+	// 面板打开时只响应翻页键，方向键仍用于列表
+	pane.KeyMap = viewport.KeyMap{
+		PageDown: pane.KeyMap.PageDown,
+		PageUp:   pane.KeyMap.PageUp,
+	}
+	return pane
+}
+
+// SendLogLines 更新日志面板中的日志行
+// 参数:
+//   - lines: 最近的日志行，按时间先后排列
+func (m *Model) SendLogLines(lines []string) {
+	msg := logLinesMsg{lines: lines}
+	if m.program != nil {
+		m.program.Send(msg)
+		return
+	}
+	m.handleLogLinesMsg(msg)
+}
+
+// handleLogLinesMsg 处理最近日志行的更新
+// 面板停留在底部时跟随最新的日志，翻页查看历史时保持位置.
+func (m *Model) handleLogLinesMsg(msg logLinesMsg) (tea.Model, tea.Cmd) {
+	follow := m.logPane.AtBottom()
+	m.logPane.SetContent(strings.Join(msg.lines, "\n"))
+	if follow {
+		m.logPane.GotoBottom()
+	}
+	return m, nil
+}
+
+// logPaneAvailable 返回当前状态下是否可以显示日志面板.
+func (m *Model) logPaneAvailable() bool {
+	return m.State == StateList || m.State == StateDownloading
+}
+
+// toggleLogPane 打开或关闭日志面板.
+func (m *Model) toggleLogPane() {
+	m.LogPaneOpen = !m.LogPaneOpen
+	m.resizeLists()
+	if m.LogPaneOpen {
+		m.logPane.GotoBottom()
+	}
+}
+
+// handleLogPaneKey 处理日志面板打开时的按键
+// 返回 true 表示按键已被日志面板处理.
+func (m *Model) handleLogPaneKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if !m.logPaneAvailable() {
+		return nil, false
+	}
+	if msg.String() == "`" {
+		m.toggleLogPane()
+		return nil, true
+	}
+	if !m.LogPaneOpen || (msg.String() != "pgup" && msg.String() != "pgdown") {
+		return nil, false
+	}
+	var cmd tea.Cmd
+	m.logPane, cmd = m.logPane.Update(msg)
+	return cmd, true
+}
+
+// logPaneView 渲染日志面板.
+func (m *Model) logPaneView() string {
+	if !m.LogPaneOpen || !m.logPaneAvailable() {
+		return ""
+	}
+	return logPaneStyle.Render(m.logPane.View()) + "\n" + helpStyle("` 关闭日志，PgUp/PgDn 翻页")
+}
@@ -6,10 +6,11 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"sort"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/model"
 	"github.com/A-kirami/bestdori-live2d-downloader/pkg/version"
 
 	"slices"
@@ -19,6 +20,7 @@ import (
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -63,6 +65,16 @@ type DownloadItem struct {
 	Total    int            // 总文件数
 	Current  int            // 当前完成数
 	Err      error          // 错误信息
+	Group    string         // 所属角色名称
+	Dir      string         // 所属角色目录
+	Title    string         // 服装标题，未知时为空
+	Summary  *Summary       // 下载完成后的文件统计
+
+	Bytes       int64         // 已接收的字节数
+	StalledFor  time.Duration // 停滞时长，为 0 时表示未停滞
+	lastAdvance time.Time     // 上一次有明显进展的时间
+	lastBytes   int64         // 上一次有明显进展时的字节数
+	lastCurrent int           // 上一次有明显进展时的完成数
 }
 
 // DownloadListItem 表示下载列表项.
@@ -72,6 +84,11 @@ type DownloadListItem struct {
 	Total    int            // 总文件数
 	Current  int            // 当前完成数
 	Err      error          // 错误信息
+	Summary  *Summary       // 下载完成后的文件统计
+	Width    int            // 可用宽度，用于省略文件统计
+
+	ItemTitle  string        // 服装标题，未知时为空（Title 为列表接口的方法名）
+	StalledFor time.Duration // 停滞时长
 }
 
 // Title 返回下载列表项的标题.
@@ -79,12 +96,12 @@ func (i DownloadListItem) Title() string {
 	progress := float64(i.Current) / float64(i.Total)
 	progressStr := fmt.Sprintf("%.1f%%", progress*100)
 	if i.Err != nil {
-		return fmt.Sprintf("❌ %s (%s) - 错误: %v", i.Name, progressStr, i.Err)
+		return fmt.Sprintf("❌ %s (%s) - 错误: %v", i.label(), progressStr, i.Err)
 	}
 	if i.Current == i.Total {
-		return fmt.Sprintf("✅ %s (%s)", i.Name, progressStr)
+		return i.completedTitle()
 	}
-	return fmt.Sprintf("⏳ %s (%s)", i.Name, progressStr)
+	return fmt.Sprintf("⏳ %s (%s)%s", i.label(), progressStr, stallBadge(i.StalledFor))
 }
 
 // Description 返回下载列表项的描述.
@@ -92,85 +109,163 @@ func (i DownloadListItem) Description() string {
 	return i.Progress.ViewAs(i.Progress.Percent())
 }
 
-// FilterValue 返回用于过滤的值.
-func (i DownloadListItem) FilterValue() string { return i.Name }
+// FilterValue 返回用于过滤的值，同时支持按服装标题过滤.
+func (i DownloadListItem) FilterValue() string { return i.label() }
+
+// newDownloadListItem 根据下载项创建下载列表项.
+func newDownloadListItem(item *DownloadItem) DownloadListItem {
+	return DownloadListItem{
+		Name:     item.Name,
+		Progress: item.Progress,
+		Total:    item.Total,
+		Current:  item.Current,
+		Err:      item.Err,
+		Summary:  item.Summary,
+		Width:    item.Progress.Width,
+
+		ItemTitle:  item.Title,
+		StalledFor: item.StalledFor,
+	}
+}
 
 // listItem 表示列表项.
 type listItem struct {
 	title    string // 标题
+	label    string // 显示文本，例如带服装描述的名称，为空时显示标题
 	selected bool   // 是否选中
+	note     string // 附加说明，例如使用统计
+	blocked  bool   // 是否在屏蔽列表中
 }
 
 // Title 返回列表项的标题.
 func (i listItem) Title() string {
+	text := i.title
+	if i.label != "" {
+		text = i.label
+	}
+	title := "  " + text
 	if i.selected {
-		return lipgloss.NewStyle().Foreground(lipgloss.Color("#FF69B4")).Render("✓ " + i.title)
+		title = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF69B4")).Render("✓ " + text)
+	}
+	if i.blocked {
+		title += "  " + lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Render("已屏蔽")
 	}
-	return "  " + i.title
+	if i.note != "" {
+		title += "  " + helpStyle(i.note)
+	}
+	return title
 }
 
 // Description 返回列表项的描述.
 func (i listItem) Description() string { return "" }
 
-// FilterValue 返回用于过滤的值.
-func (i listItem) FilterValue() string { return i.title }
+// FilterValue 返回用于过滤的值，同时支持按服装描述过滤.
+func (i listItem) FilterValue() string { return i.title + " " + i.label }
 
 // Model 表示 TUI 模型
 // 包含所有 UI 组件和状态.
 type Model struct {
-	Items            map[string]*DownloadItem // 下载项映射，key 为项目名称，value 为下载项
-	ItemOrder        []string                 // 下载项顺序列表
-	Width            int                      // 界面宽度
-	Quitting         bool                     // 是否正在退出程序
-	TextInput        textinput.Model          // 文本输入框组件
-	Live2dList       list.Model               // Live2D 列表组件
-	DownloadList     list.Model               // 下载列表组件
-	SelectedIDs      []int                    // 选中的项目 ID 列表
-	State            string                   // 当前状态
-	SearchChan       chan string              // 搜索通道，用于处理搜索请求
-	SelectChan       chan []string            // 选择通道，用于处理选择请求
-	Spinner          spinner.Model            // 加载动画组件
-	CurrentCharaName string                   // 当前角色名称
-	ExtraCharaName   string                   // 额外角色名称
-	program          *tea.Program             // TUI 程序实例
-	cancelChan       chan struct{}            // 取消通道，用于取消操作
-	Ctx              context.Context          // 上下文，用于控制操作的生命周期
-	Cancel           context.CancelFunc       // 取消函数，用于取消上下文
-	ErrorMessage     string                   // 错误消息
-	TotalModels      int                      // 总模型数量
-	CompletedModels  int                      // 已完成的模型数量
+	Items               map[string]*DownloadItem // 下载项映射，key 为项目名称，value 为下载项
+	ItemOrder           []string                 // 下载项顺序列表
+	Width               int                      // 界面宽度
+	Quitting            bool                     // 是否正在退出程序
+	TextInput           textinput.Model          // 文本输入框组件
+	Live2dList          list.Model               // Live2D 列表组件
+	DownloadList        list.Model               // 下载列表组件
+	SelectedIDs         []int                    // 选中的项目 ID 列表
+	State               string                   // 当前状态
+	SearchChan          chan string              // 搜索通道，用于处理搜索请求
+	ModelChan           chan string              // 直接模型名通道，用于跳过角色搜索直接下载
+	InputMode           InputMode                // 输入框的输入模式
+	SelectChan          chan []string            // 选择通道，用于处理选择请求
+	ExportChan          chan []string            // 导出通道，用于将选中的模型导出为任务文件
+	ShareChan           chan []string            // 分享通道，用于将选中的服装导出为选择文件
+	Spinner             spinner.Model            // 加载动画组件
+	CurrentCharaName    string                   // 当前角色名称
+	ExtraCharaName      string                   // 额外角色名称
+	CurrentBandName     string                   // 当前角色所属乐队名称，获取失败时为空
+	program             *tea.Program             // TUI 程序实例
+	cancelChan          chan struct{}            // 取消通道，用于取消操作
+	Ctx                 context.Context          // 上下文，用于控制操作的生命周期
+	Cancel              context.CancelFunc       // 取消函数，用于取消上下文
+	cancel              context.CancelCauseFunc  // 带原因的取消函数，可通过 context.Cause 获取取消原因
+	quitOnce            *sync.Once               // 保证退出流程只执行一次
+	quitReturnState     string                   // 取消退出后返回的状态
+	ErrorMessage        string                   // 错误消息
+	TotalModels         int                      // 总模型数量
+	CompletedModels     int                      // 已完成的模型数量
+	collapsedGroups     map[string]bool          // 已折叠的角色分组
+	FailFast            bool                     // 批量下载时是否在第一个失败后中止
+	Server              string                   // 当前使用的服务器，all 表示合并查询所有服务器，为空时不显示
+	ShowBlocked         bool                     // 服装列表中是否显示屏蔽的列表项
+	listMsg             UpdateListMsg            // 最近一次的列表内容，切换屏蔽项显示时用于重建列表
+	pendingConflicts    []serverConflictMsg      // 等待用户处理的服务器冲突
+	conflictReturnState string                   // 处理完冲突后返回的状态
+	bandMembers         UpdateBandMembersMsg     // 当前显示的乐队成员列表
+	memberCursor        int                      // 乐队成员列表中选中的位置
+	charaBrowser        list.Model               // 全部角色的浏览列表
+	loadingProgress     loadingProgressMsg       // 加载状态下的数据获取进度
+	CompactView         bool                     // 下载列表是否使用紧凑视图
+	NarrowWidth         int                      // 终端宽度小于该列数时下载列表自动使用窄屏视图，为 0 时不自动切换
+	narrow              bool                     // 下载列表是否因终端过窄使用窄屏视图
+	activity            *activityTracker         // 各下载项的字节级下载进度
+	disk                diskSpaceMsg             // 保存路径剩余空间的最近一次检查结果
+	estimate            spaceEstimateMsg         // 下载前估算的所需空间
+	LogPaneOpen         bool                     // 是否显示日志面板
+	logPane             viewport.Model           // 显示最近日志的面板
+	windowHeight        int                      // 终端窗口高度
 }
 
 // DownloadDelegate 用于下载进度列表的代理
 // 自定义列表项的渲染方式.
-type DownloadDelegate struct{}
+type DownloadDelegate struct {
+	Compact bool // 是否使用紧凑视图，每项只占一行
+	Narrow  bool // 是否使用窄屏视图，在紧凑视图的基础上缩短名称与进度条，每项不超过一行
+}
 
 // Height 返回列表项的高度.
-func (d DownloadDelegate) Height() int { return 2 }
+func (d DownloadDelegate) Height() int {
+	if d.Compact {
+		return 1
+	}
+	return 2
+}
 
 // Spacing 返回列表项的间距.
-func (d DownloadDelegate) Spacing() int { return 1 }
+func (d DownloadDelegate) Spacing() int {
+	if d.Compact {
+		return 0
+	}
+	return 1
+}
 
 // Update 处理列表项的更新.
 func (d DownloadDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
 
 // Render 渲染列表项.
-func (d DownloadDelegate) Render(w io.Writer, _ list.Model, _ int, item list.Item) {
-	dl, ok := item.(DownloadListItem)
-	if !ok {
+func (d DownloadDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if d.Compact {
+		d.renderCompact(w, m, index, item)
 		return
 	}
-	title := dl.Title()
-	desc := dl.Description()
-	fmt.Fprintf(w, "  %s\n  %s", title, desc)
+	switch dl := item.(type) {
+	case DownloadListItem:
+		fmt.Fprintf(w, "  %s\n  %s", dl.Title(), dl.Description())
+	case DownloadGroupItem:
+		title := titleStyle.Render(dl.Title())
+		if index == m.Index() {
+			title = titleStyle.Render("> " + dl.Title())
+		}
+		fmt.Fprintf(w, "%s\n  %s", title, dl.Description())
+	}
 }
 
 // NewModel 创建新的 TUI 模型实例.
 func NewModel() Model {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancelCause(context.Background())
 
 	ti := textinput.New()
-	ti.Placeholder = "输入角色名称或 Live2D 模型名称"
+	ti.Placeholder = InputModeSearch.placeholder()
 	ti.Focus()
 	ti.CharLimit = 156
 	ti.Width = 50
@@ -193,6 +288,10 @@ func NewModel() Model {
 				key.WithKeys("a"),
 				key.WithHelp("a", "全选/取消全选"),
 			),
+			key.NewBinding(
+				key.WithKeys("f"),
+				key.WithHelp("f", "切换快速失败"),
+			),
 		}
 	}
 
@@ -215,24 +314,38 @@ func NewModel() Model {
 		DownloadList:    downloadList,
 		State:           StateInput,
 		SearchChan:      make(chan string, 1),
+		ModelChan:       make(chan string, 1),
 		SelectChan:      make(chan []string, 1),
+		ExportChan:      make(chan []string, 1),
+		ShareChan:       make(chan []string, 1),
 		Spinner:         s,
 		cancelChan:      make(chan struct{}), // 初始化取消通道
 		Ctx:             ctx,
-		Cancel:          cancel,
+		Cancel:          func() { cancel(nil) },
+		cancel:          cancel,
+		quitOnce:        &sync.Once{},
 		TotalModels:     0,
 		CompletedModels: 0,
+		collapsedGroups: make(map[string]bool),
+		activity:        newActivityTracker(),
+		logPane:         newLogPane(),
+		charaBrowser:    newCharaBrowser(),
 	}
 }
 
 // Init 初始化 TUI 模型.
 func (m *Model) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, m.Spinner.Tick)
+	return tea.Batch(textinput.Blink, m.Spinner.Tick, stallTick())
 }
 
 // UpdateListMsg 表示更新列表消息.
 type UpdateListMsg struct {
-	Items []string // 列表项
+	Items    []string          // 列表项
+	Notes    map[string]string // 列表项的附加说明，key 为列表项
+	Summary  string            // 显示在列表标题后的附加说明
+	Selected []string          // 预先选中的列表项
+	Labels   map[string]string // 列表项的显示文本，key 为列表项，没有时显示列表项本身
+	Blocked  []string          // 在屏蔽列表中的列表项，默认隐藏且不参与全选
 }
 
 // UpdateDownloadListMsg 表示更新下载列表消息.
@@ -242,15 +355,25 @@ type UpdateDownloadListMsg struct {
 
 // handleInputState 处理输入状态下的消息.
 func (m *Model) handleInputState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	if msg.String() == "enter" {
+	switch msg.String() {
+	case "tab":
+		m.SetInputMode((m.InputMode + 1) % (InputModeModel + 1))
+		return m, nil
+	case "enter":
 		value := strings.TrimSpace(m.TextInput.Value())
-		if value == "" {
-			m.SetError("请输入角色名称或 Live2D 模型名称")
+		if value == "" && m.InputMode == InputModeModel {
+			m.SetError("请输入 Live2D 模型名称")
 			return m, nil
 		}
+		// 角色搜索模式下输入为空时浏览全部角色
 		m.State = StateLoading
+		m.loadingProgress = loadingProgressMsg{}
+		target := m.SearchChan
+		if m.InputMode == InputModeModel {
+			target = m.ModelChan
+		}
 		select {
-		case m.SearchChan <- value:
+		case target <- value:
 		default:
 		}
 		return m, m.Spinner.Tick
@@ -289,6 +412,28 @@ func (m *Model) handleListState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	case "a":
 		m.handleSelectAll()
+	case "f":
+		m.FailFast = !m.FailFast
+		return m, nil
+	case "b":
+		m.toggleBlocked()
+		return m, nil
+	case "e":
+		if selected := m.GetSelectedItems(); len(selected) > 0 {
+			select {
+			case m.ShareChan <- selected:
+			default:
+			}
+		}
+		return m, nil
+	case "E":
+		if selected := m.GetSelectedItems(); len(selected) > 0 {
+			select {
+			case m.ExportChan <- selected:
+			default:
+			}
+		}
+		return m, nil
 	case "up":
 		if m.Live2dList.Index() == 0 && len(m.Live2dList.Items()) > 0 {
 			m.Live2dList.Select(len(m.Live2dList.Items()) - 1)
@@ -317,12 +462,13 @@ func (m *Model) handleListState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-// handleSelectAll 处理全选/取消全选.
+// handleSelectAll 处理全选/取消全选
+// 屏蔽的列表项不参与全选，只能单独选择.
 func (m *Model) handleSelectAll() {
 	allSelected := true
 	for _, i := range m.Live2dList.Items() {
 		item, ok := i.(listItem)
-		if !ok {
+		if !ok || item.blocked {
 			continue
 		}
 		if !item.selected {
@@ -330,21 +476,19 @@ func (m *Model) handleSelectAll() {
 			break
 		}
 	}
+	m.SelectedIDs = nil
 	for i, item := range m.Live2dList.Items() {
 		it, ok := item.(listItem)
 		if !ok {
 			continue
 		}
-		it.selected = !allSelected
-		m.Live2dList.SetItem(i, it)
-	}
-	if !allSelected {
-		m.SelectedIDs = make([]int, len(m.Live2dList.Items()))
-		for i := range m.Live2dList.Items() {
-			m.SelectedIDs[i] = i
+		if !it.blocked {
+			it.selected = !allSelected
+			m.Live2dList.SetItem(i, it)
+		}
+		if it.selected {
+			m.SelectedIDs = append(m.SelectedIDs, i)
 		}
-	} else {
-		m.SelectedIDs = nil
 	}
 }
 
@@ -352,9 +496,7 @@ func (m *Model) handleSelectAll() {
 func (m *Model) handleListEnter() (tea.Model, tea.Cmd) {
 	selected := m.GetSelectedItems()
 	if len(selected) > 0 {
-		for _, name := range selected {
-			m.AddDownloadItem(name, 1)
-		}
+		// 下载项由下载器统一登记，这里只切换到下载状态
 		m.State = StateDownloading
 		// 设置总体进度并立即更新标题
 		m.SetTotalModels(len(selected))
@@ -370,6 +512,21 @@ func (m *Model) handleListEnter() (tea.Model, tea.Cmd) {
 // handleDownloadingState 处理下载状态下的消息.
 func (m *Model) handleDownloadingState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
+	case "left":
+		m.setGroupCollapsed(true)
+		return m, nil
+	case "right":
+		m.setGroupCollapsed(false)
+		return m, nil
+	case "o":
+		m.openSelectedGroupDir()
+		return m, nil
+	case "v":
+		m.SetCompactView(!m.CompactView)
+		return m, nil
+	case "x":
+		m.cancelSelectedStalled()
+		return m, nil
 	case "up":
 		if m.DownloadList.Index() == 0 && len(m.DownloadList.Items()) > 0 {
 			m.DownloadList.Select(len(m.DownloadList.Items()) - 1)
@@ -385,6 +542,7 @@ func (m *Model) handleDownloadingState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// 清空下载项
 		m.Items = make(map[string]*DownloadItem)
 		m.ItemOrder = []string{}
+		m.collapsedGroups = make(map[string]bool)
 		m.updateDownloadList()
 		// 重置输入框和列表光标
 		m.TextInput.Reset()
@@ -398,26 +556,48 @@ func (m *Model) handleDownloadingState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // handleUpdateListMsg 处理更新列表消息.
 func (m *Model) handleUpdateListMsg(msg UpdateListMsg) (tea.Model, tea.Cmd) {
-	listItems := make([]list.Item, len(msg.Items))
-	for i, item := range msg.Items {
-		listItems[i] = listItem{
-			title:    item,
-			selected: false,
+	m.listMsg = msg
+	m.setListItems(msg.Selected)
+	m.State = StateList
+	return m, nil
+}
+
+// setListItems 按是否显示屏蔽项重建列表，selected 中的列表项保持选中.
+func (m *Model) setListItems(selected []string) {
+	msg := m.listMsg
+	listItems := make([]list.Item, 0, len(msg.Items))
+	m.SelectedIDs = nil
+	hidden := 0
+	for _, item := range msg.Items {
+		blocked := slices.Contains(msg.Blocked, item)
+		if blocked && !m.ShowBlocked {
+			hidden++
+			continue
 		}
+		isSelected := slices.Contains(selected, item)
+		if isSelected {
+			m.SelectedIDs = append(m.SelectedIDs, len(listItems))
+		}
+		listItems = append(listItems, listItem{
+			title:    item,
+			label:    msg.Labels[item],
+			selected: isSelected,
+			note:     msg.Notes[item],
+			blocked:  blocked,
+		})
 	}
 	m.Live2dList.SetItems(listItems)
-	m.SelectedIDs = nil
-	m.State = StateList
 	if m.CurrentCharaName != "" {
-		title := fmt.Sprintf("选择要下载的 Live2D 模型 - %s", m.CurrentCharaName)
-		if m.ExtraCharaName != "" {
-			title = fmt.Sprintf("%s (%s)", title, m.ExtraCharaName)
-		}
-		m.Live2dList.Title = title
+		m.Live2dList.Title = fmt.Sprintf("选择要下载的 Live2D 模型 - %s", m.charaTitle())
 	} else {
 		m.Live2dList.Title = "选择要下载的 Live2D 模型"
 	}
-	return m, nil
+	if msg.Summary != "" {
+		m.Live2dList.Title = fmt.Sprintf("%s | %s", m.Live2dList.Title, msg.Summary)
+	}
+	if hidden > 0 {
+		m.Live2dList.Title = fmt.Sprintf("%s | 已隐藏 %d 个屏蔽的服装", m.Live2dList.Title, hidden)
+	}
 }
 
 // handleUpdateDownloadListMsg 处理更新下载列表消息.
@@ -433,10 +613,10 @@ func (m *Model) handleUpdateDownloadListMsg(msg UpdateDownloadListMsg) (tea.Mode
 // handleKeyMsg 处理键盘消息.
 func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if msg.String() == "ctrl+c" || (msg.String() == KeyEsc && m.State == StateInput) {
-		close(m.cancelChan)
-		m.Cancel()
-		m.Quitting = true
-		return m, tea.Quit
+		return m.handleQuitKey()
+	}
+	if cmd, handled := m.handleLogPaneKey(msg); handled {
+		return m, cmd
 	}
 
 	switch m.State {
@@ -448,6 +628,14 @@ func (m *Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleListState(msg)
 	case StateDownloading:
 		return m.handleDownloadingState(msg)
+	case StateServerConflict:
+		return m.handleServerConflictState(msg)
+	case StateBandMembers:
+		return m.handleBandMembersState(msg)
+	case StateCharaBrowser:
+		return m.handleCharaBrowserState(msg)
+	case StateQuitConfirm:
+		return m.handleQuitConfirmState(msg)
 	}
 
 	return m, nil
@@ -462,14 +650,31 @@ func (m *Model) handleWindowSizeMsg(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd)
 	for _, item := range m.Items {
 		item.Progress.Width = m.Width
 	}
-	availableHeight := msg.Height - padding*2 - 6
+	m.windowHeight = msg.Height
+	if narrow := m.NarrowWidth > 0 && msg.Width < m.NarrowWidth; narrow != m.narrow {
+		m.narrow = narrow
+		m.updateDownloadDelegate()
+	}
 	m.Live2dList.SetWidth(msg.Width - padding*2)
-	m.Live2dList.SetHeight(availableHeight)
 	m.DownloadList.SetWidth(msg.Width - padding*2)
-	m.DownloadList.SetHeight(availableHeight)
+	m.charaBrowser.SetWidth(msg.Width - padding*2)
+	m.logPane.Width = msg.Width - padding*2
+	m.resizeLists()
 	return m, nil
 }
 
+// resizeLists 根据窗口高度和日志面板是否打开调整列表高度.
+func (m *Model) resizeLists() {
+	availableHeight := m.windowHeight - padding*2 - 6
+	if m.LogPaneOpen {
+		// 日志行、上边框和帮助行
+		availableHeight -= logPaneHeight + 2
+	}
+	m.Live2dList.SetHeight(availableHeight)
+	m.DownloadList.SetHeight(availableHeight)
+	m.charaBrowser.SetHeight(availableHeight)
+}
+
 // handleProgressMsg 处理进度消息.
 func (m *Model) handleProgressMsg(msg progressMsg) (tea.Model, tea.Cmd) {
 	item, exists := m.Items[msg.itemName]
@@ -490,13 +695,35 @@ func (m *Model) handleProgressMsg(msg progressMsg) (tea.Model, tea.Cmd) {
 
 // handleProgressErrMsg 处理进度错误消息.
 func (m *Model) handleProgressErrMsg(msg progressErrMsg) (tea.Model, tea.Cmd) {
-	if item, exists := m.Items[msg.itemName]; exists {
-		item.Err = msg.err
-		m.updateDownloadList()
+	// 尚未开始下载的模型（例如被快速失败跳过）也需要显示错误
+	if _, exists := m.Items[msg.itemName]; !exists {
+		m.AddDownloadItem(msg.itemName, 1)
 	}
+	m.Items[msg.itemName].Err = msg.err
+	m.updateDownloadList()
 	return m, nil
 }
 
+// failFastStatus 返回快速失败开关的状态文本.
+func (m *Model) failFastStatus() string {
+	if m.FailFast {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#FF69B4")).Render("快速失败: 开（任一模型失败即中止批量下载）")
+	}
+	return helpStyle("快速失败: 关（失败后继续下载其余模型）")
+}
+
+// serverStatus 返回当前服务器的状态行，未设置服务器时返回空字符串.
+func (m *Model) serverStatus() string {
+	switch m.Server {
+	case "":
+		return ""
+	case "all":
+		return helpStyle("服务器: 全部（合并查询各服务器的服装）")
+	default:
+		return helpStyle("服务器: " + strings.ToUpper(m.Server))
+	}
+}
+
 // handleProgressFrameMsg 处理进度帧消息.
 func (m *Model) handleProgressFrameMsg(msg progress.FrameMsg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
@@ -510,6 +737,18 @@ func (m *Model) handleProgressFrameMsg(msg progress.FrameMsg) (tea.Model, tea.Cm
 	return m, tea.Batch(cmds...)
 }
 
+// handleFilterMatchesMsg 将列表异步过滤的结果交给当前显示的列表.
+func (m *Model) handleFilterMatchesMsg(msg list.FilterMatchesMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch m.State {
+	case StateList:
+		m.Live2dList, cmd = m.Live2dList.Update(msg)
+	case StateCharaBrowser:
+		m.charaBrowser, cmd = m.charaBrowser.Update(msg)
+	}
+	return m, cmd
+}
+
 // Update 处理 TUI 模型的更新.
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
@@ -519,6 +758,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleUpdateListMsg(msg)
 	case UpdateDownloadListMsg:
 		return m.handleUpdateDownloadListMsg(msg)
+	case UpdateBandMembersMsg:
+		return m.handleUpdateBandMembersMsg(msg)
+	case UpdateCharaBrowserMsg:
+		return m.handleUpdateCharaBrowserMsg(msg)
+	case list.FilterMatchesMsg:
+		return m.handleFilterMatchesMsg(msg)
 	case tea.KeyMsg:
 		return m.handleKeyMsg(msg)
 	case tea.WindowSizeMsg:
@@ -527,8 +772,26 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleProgressMsg(msg)
 	case progressErrMsg:
 		return m.handleProgressErrMsg(msg)
+	case StallTickMsg:
+		return m.handleStallTickMsg(msg)
 	case progress.FrameMsg:
 		return m.handleProgressFrameMsg(msg)
+	case itemGroupMsg:
+		return m.handleItemGroupMsg(msg)
+	case itemTitleMsg:
+		return m.handleItemTitleMsg(msg)
+	case serverConflictMsg:
+		return m.handleServerConflictMsg(msg)
+	case loadingProgressMsg:
+		return m.handleLoadingProgressMsg(msg)
+	case diskSpaceMsg:
+		return m.handleDiskSpaceMsg(msg)
+	case spaceEstimateMsg:
+		return m.handleSpaceEstimateMsg(msg)
+	case logLinesMsg:
+		return m.handleLogLinesMsg(msg)
+	case summaryMsg:
+		return m.handleSummaryMsg(msg)
 	}
 
 	if m.State == StateLoading {
@@ -549,54 +812,112 @@ func (m *Model) View() string {
 	s.WriteString("\n")
 	s.WriteString(titleStyle.Render("Bestdori Live2D 下载器"))
 	s.WriteString("\n")
-	s.WriteString(helpStyle(fmt.Sprintf("版本: %s | 作者: Akirami", version.GetVersionInfo())))
-	s.WriteString("\n\n")
+	s.WriteString(helpStyle(fmt.Sprintf("版本: %s | 作者: Akirami", version.GetVersion())))
+	s.WriteString("\n")
+	if status := m.serverStatus(); status != "" {
+		s.WriteString(status)
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
 
 	switch m.State {
 	case StateInput:
+		s.WriteString(m.inputModeView())
+		s.WriteString("\n")
 		s.WriteString(m.TextInput.View())
 		s.WriteString("\n\n")
 		if m.ErrorMessage != "" {
 			s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Render(m.ErrorMessage))
 			s.WriteString("\n\n")
 		}
-		s.WriteString(helpStyle("按 Enter 确认，按 Esc 或 Ctrl+C 退出"))
+		s.WriteString(helpStyle("按 Enter 确认，按 Tab 在角色搜索与直接模型名之间切换，按 Esc 或 Ctrl+C 退出"))
 
 	case StateLoading:
 		s.WriteString(m.TextInput.View())
 		s.WriteString("\n\n")
-		s.WriteString(fmt.Sprintf("%s 正在搜索角色...", m.Spinner.View()))
-		s.WriteString("\n\n")
+		action := "正在搜索角色..."
+		if m.TextInput.Value() == "" {
+			action = "正在获取角色列表..."
+		} else if m.InputMode == InputModeModel {
+			action = "正在验证模型..."
+		}
+		s.WriteString(fmt.Sprintf("%s %s", m.Spinner.View(), action))
+		s.WriteString("\n")
+		if progressLine := m.loadingProgressView(); progressLine != "" {
+			s.WriteString(helpStyle("  " + progressLine))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
 		s.WriteString(helpStyle("按 Esc 或 Ctrl+C 退出"))
 
 	case StateList:
 		s.WriteString(m.Live2dList.View())
 		s.WriteString("\n\n")
-		s.WriteString(helpStyle("使用空格选择/取消选择，A 全选/取消全选，Enter 确认，Esc 返回，Ctrl+C 退出"))
+		s.WriteString(m.failFastStatus())
+		s.WriteString("\n\n")
+		help := "使用空格选择/取消选择，A 全选/取消全选，F 切换快速失败，E 导出选择，Shift+E 导出为任务文件，` 日志，Enter 确认，Esc 返回，Ctrl+C 退出"
+		if len(m.listMsg.Blocked) > 0 {
+			help += "，B 显示/隐藏屏蔽的服装"
+		}
+		s.WriteString(helpStyle(help))
 
 	case StateDownloading:
+		if m.DiskPaused() {
+			s.WriteString(m.diskPausedView())
+			s.WriteString("\n\n")
+		}
 		s.WriteString(m.DownloadList.View())
 		s.WriteString("\n\n")
-		s.WriteString(helpStyle("按 Esc 返回主菜单，Ctrl+C 退出"))
+		if estimate := m.spaceEstimateView(); estimate != "" {
+			s.WriteString(estimate)
+			s.WriteString("\n\n")
+		}
+		if status := m.diskStatusView(); status != "" {
+			s.WriteString(status)
+			s.WriteString("\n\n")
+		}
+		if m.ErrorMessage != "" {
+			s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Render(m.ErrorMessage))
+			s.WriteString("\n\n")
+		}
+		s.WriteString(helpStyle("←/→ 折叠/展开角色分组，O 打开角色目录，V 切换紧凑视图，X 取消停滞的文件，` 日志，Esc 返回主菜单，Ctrl+C 退出"))
+
+	case StateServerConflict:
+		s.WriteString(m.serverConflictView())
+
+	case StateBandMembers:
+		s.WriteString(m.bandMembersView())
+
+	case StateCharaBrowser:
+		s.WriteString(m.charaBrowserView())
+
+	case StateQuitConfirm:
+		s.WriteString(m.quitConfirmView())
+	}
+
+	if pane := m.logPaneView(); pane != "" {
+		s.WriteString("\n\n")
+		s.WriteString(pane)
 	}
 
 	return s.String()
 }
 
+// AddDownloadItem 登记一个下载项及其文件总数，文件总数为 0 表示尚未知道
+// 已存在的下载项只更新文件总数，不重置已完成的文件数.
 func (m *Model) AddDownloadItem(name string, totalFiles int) {
-	// 检查是否已存在相同名称的下载项
 	if item, exists := m.Items[name]; exists {
-		// 如果已存在，更新总数和重置进度
-		item.Total = totalFiles
-		item.Current = 0 // 重置当前进度
-		m.updateDownloadList()
+		if totalFiles > 0 {
+			item.Total = totalFiles
+			m.updateDownloadList()
+		}
 		return
 	}
 
 	item := &DownloadItem{
 		Name:     name,
 		Progress: progress.New(progress.WithDefaultGradient()),
-		Total:    totalFiles,
+		Total:    max(totalFiles, 1),
 		Current:  0,
 	}
 	if m.Width > 0 {
@@ -634,17 +955,17 @@ func (m *Model) ClearError() {
 }
 
 func (m *Model) updateDownloadList() {
+	// 存在角色信息时按角色分组显示
+	if m.hasGroups() {
+		m.DownloadList.SetItems(m.groupedDownloadItems())
+		return
+	}
+
 	items := make([]list.Item, 0, len(m.Items))
 	// 按照 ItemOrder 的顺序添加下载项
 	for _, name := range m.ItemOrder {
 		if item, exists := m.Items[name]; exists {
-			items = append(items, DownloadListItem{
-				Name:     item.Name,
-				Progress: item.Progress,
-				Total:    item.Total,
-				Current:  item.Current,
-				Err:      item.Err,
-			})
+			items = append(items, newDownloadListItem(item))
 		}
 	}
 	m.DownloadList.SetItems(items)
@@ -664,49 +985,30 @@ func (m *Model) SetLive2DList(items []string) {
 	m.State = StateList
 }
 
+// GetSelectedItems 按列表显示顺序返回选中的项目.
 func (m *Model) GetSelectedItems() []string {
-	// 使用 map 来确保唯一性
-	uniqueItems := make(map[string]struct{})
+	selectedIDs := make(map[int]struct{}, len(m.SelectedIDs))
 	for _, id := range m.SelectedIDs {
-		if id < len(m.Live2dList.Items()) {
-			if item, ok := m.Live2dList.Items()[id].(listItem); ok {
-				uniqueItems[item.title] = struct{}{}
-			}
-		}
-	}
-
-	// 将 map 转换回切片
-	selected := make([]string, 0, len(uniqueItems))
-	for item := range uniqueItems {
-		selected = append(selected, item)
+		selectedIDs[id] = struct{}{}
 	}
 
-	// 对选中的项目进行排序
-	sort.Slice(selected, func(i, j int) bool {
-		// 提取服装ID（模型名称中的数字部分）
-		iParts := strings.Split(selected[i], "_")
-		jParts := strings.Split(selected[j], "_")
-
-		// 如果包含"live_event"，将其排在后面
-		iHasEvent := strings.Contains(selected[i], "live_event")
-		jHasEvent := strings.Contains(selected[j], "live_event")
-
-		if iHasEvent != jHasEvent {
-			return !iHasEvent
+	// 按照列表的显示顺序收集选中的项目，保证下载顺序与用户看到的一致
+	seen := make(map[string]struct{}, len(selectedIDs))
+	selected := make([]string, 0, len(selectedIDs))
+	for i, listEntry := range m.Live2dList.Items() {
+		if _, ok := selectedIDs[i]; !ok {
+			continue
 		}
-
-		// 比较服装ID
-		if len(iParts) > 1 && len(jParts) > 1 {
-			iID, iErr := strconv.Atoi(iParts[1])
-			jID, jErr := strconv.Atoi(jParts[1])
-			if iErr == nil && jErr == nil {
-				return iID < jID
-			}
+		item, ok := listEntry.(listItem)
+		if !ok {
+			continue
 		}
-
-		// 如果无法比较ID，则按字符串排序
-		return selected[i] < selected[j]
-	})
+		if _, dup := seen[item.title]; dup {
+			continue
+		}
+		seen[item.title] = struct{}{}
+		selected = append(selected, item.title)
+	}
 
 	return selected
 }
@@ -719,11 +1021,26 @@ func (m *Model) GetSelectChan() <-chan []string {
 	return m.SelectChan
 }
 
+// GetExportChan 返回导出通道.
+func (m *Model) GetExportChan() <-chan []string {
+	return m.ExportChan
+}
+
+// GetShareChan 返回分享通道.
+func (m *Model) GetShareChan() <-chan []string {
+	return m.ShareChan
+}
+
 // GetCancelChan 返回取消通道.
 func (m *Model) GetCancelChan() <-chan struct{} {
 	return m.cancelChan
 }
 
+// Context 返回模型的上下文，用户退出时被取消.
+func (m *Model) Context() context.Context {
+	return m.Ctx
+}
+
 // SetProgram 设置程序实例.
 func (m *Model) SetProgram(p *tea.Program) {
 	m.program = p
@@ -760,13 +1077,19 @@ func (m *Model) GetTotalProgress() string {
 	return fmt.Sprintf("总进度: %d/%d", m.CompletedModels, m.TotalModels)
 }
 
+// charaTitle 返回标题中显示的角色名称，包含所属乐队与额外角色名称.
+func (m *Model) charaTitle() string {
+	title := model.BandTitle(m.CurrentBandName, m.CurrentCharaName)
+	if m.ExtraCharaName != "" {
+		title = fmt.Sprintf("%s (%s)", title, m.ExtraCharaName)
+	}
+	return title
+}
+
 // UpdateDownloadListTitle 更新下载列表标题，包含总体进度.
 func (m *Model) UpdateDownloadListTitle() {
 	if m.CurrentCharaName != "" {
-		title := fmt.Sprintf("下载列表 - %s", m.CurrentCharaName)
-		if m.ExtraCharaName != "" {
-			title = fmt.Sprintf("%s (%s)", title, m.ExtraCharaName)
-		}
+		title := fmt.Sprintf("下载列表 - %s", m.charaTitle())
 		// 添加总体进度到标题
 		if progressStr := m.GetTotalProgress(); progressStr != "" {
 			title = fmt.Sprintf("%s - %s", title, progressStr)
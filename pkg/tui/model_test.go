@@ -0,0 +1,822 @@
+package tui_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/tui"
+)
+
+// newDownloadingModel 创建处于下载状态且包含分组下载项的模型.
+func newDownloadingModel() *tui.Model {
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	m.State = tui.StateDownloading
+	m.AddDownloadItem("001_casual", 1)
+	m.AddDownloadItem("002_casual", 1)
+	m.AddDownloadItem("001_live", 1)
+	m.SetItemGroup("001_casual", "kasumi", "live2d/kasumi")
+	m.SetItemGroup("002_casual", "tae", "live2d/tae")
+	m.SetItemGroup("001_live", "kasumi", "live2d/kasumi")
+	return &m
+}
+
+func TestDownloadListGroups(t *testing.T) {
+	t.Parallel()
+
+	m := newDownloadingModel()
+	view := m.View()
+	assert.Contains(t, view, "▼ kasumi (0/2)")
+	assert.Contains(t, view, "▼ tae (0/1)")
+	assert.Contains(t, view, "live2d/kasumi")
+	assert.Contains(t, view, "001_live")
+	// 同一角色的模型应排列在一起
+	assert.Len(t, m.DownloadList.Items(), 5)
+}
+
+func TestDownloadListCollapse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		keys     []tea.KeyType
+		contains string
+		excludes string
+		items    int
+	}{
+		{
+			name:     "折叠角色分组",
+			keys:     []tea.KeyType{tea.KeyLeft},
+			contains: "▶ kasumi (0/2)",
+			excludes: "001_live",
+			items:    3,
+		},
+		{
+			name:     "展开角色分组",
+			keys:     []tea.KeyType{tea.KeyLeft, tea.KeyRight},
+			contains: "▼ kasumi (0/2)",
+			items:    5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := newDownloadingModel()
+			for _, k := range tt.keys {
+				m.Update(tea.KeyMsg{Type: k})
+			}
+			view := m.View()
+			assert.Contains(t, view, tt.contains)
+			if tt.excludes != "" {
+				assert.NotContains(t, view, tt.excludes)
+			}
+			assert.Len(t, m.DownloadList.Items(), tt.items)
+		})
+	}
+}
+
+func TestFailFastToggle(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	m.Update(tui.UpdateListMsg{Items: []string{"001_casual", "001_live"}})
+	assert.False(t, m.FailFast)
+	assert.Contains(t, m.View(), "快速失败: 关")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	assert.True(t, m.FailFast)
+	assert.Contains(t, m.View(), "快速失败: 开")
+}
+
+func TestServerStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		server string
+		want   string
+	}{
+		{name: "单个服务器", server: "cn", want: "服务器: CN"},
+		{name: "所有服务器", server: "all", want: "服务器: 全部"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tui.NewModel()
+			assert.NotContains(t, m.View(), "服务器:")
+			m.Server = tt.server
+			assert.Contains(t, m.View(), tt.want)
+		})
+	}
+}
+
+func TestLoadingProgress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		read  int64
+		total int64
+		want  string
+	}{
+		{name: "已知总大小", read: 2 * 1024 * 1024, total: 4 * 1024 * 1024, want: "正在获取资源索引… 2.0/4.0 MB"},
+		{name: "未知总大小", read: 1024 * 1024, total: -1, want: "正在获取资源索引… 1.0 MB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tui.NewModel()
+			m.State = tui.StateLoading
+			m.UpdateLoadingProgress("资源索引", tt.read, tt.total)
+			assert.Contains(t, m.View(), tt.want)
+		})
+	}
+}
+
+func TestGetSelectedItemsOrder(t *testing.T) {
+	t.Parallel()
+
+	// 自定义的显示顺序，与服装ID排序不同
+	items := []string{"001_live_event_10", "001_2023_summer", "001_casual", "001_2019_school"}
+
+	tests := []struct {
+		name string
+		keys []tea.KeyMsg
+		want []string
+	}{
+		{
+			name: "全选时按显示顺序下载",
+			keys: []tea.KeyMsg{{Type: tea.KeyRunes, Runes: []rune("a")}},
+			want: items,
+		},
+		{
+			name: "逆序选择时仍按显示顺序下载",
+			keys: []tea.KeyMsg{
+				{Type: tea.KeyDown}, {Type: tea.KeyDown}, {Type: tea.KeyDown},
+				{Type: tea.KeySpace, Runes: []rune(" ")},
+				{Type: tea.KeyUp}, {Type: tea.KeyUp}, {Type: tea.KeyUp},
+				{Type: tea.KeySpace, Runes: []rune(" ")},
+			},
+			want: []string{"001_live_event_10", "001_2019_school"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tui.NewModel()
+			m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+			m.Update(tui.UpdateListMsg{Items: items})
+			for _, k := range tt.keys {
+				m.Update(k)
+			}
+			assert.Equal(t, tt.want, m.GetSelectedItems())
+
+			// 批量下载的派发顺序与显示顺序一致
+			m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			assert.Equal(t, tt.want, <-m.GetSelectChan())
+		})
+	}
+}
+
+func TestPreselectedList(t *testing.T) {
+	t.Parallel()
+
+	items := []string{"001_casual", "001_2019_school", "001_live_event_10"}
+
+	tests := []struct {
+		name     string
+		key      string
+		wantChan func(m *tui.Model) <-chan []string
+	}{
+		{name: "e 导出选择文件", key: "e", wantChan: (*tui.Model).GetShareChan},
+		{name: "Shift+E 导出任务文件", key: "E", wantChan: (*tui.Model).GetExportChan},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tui.NewModel()
+			m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+			m.Update(tui.UpdateListMsg{Items: items, Selected: []string{"001_live_event_10", "001_casual"}})
+			assert.Equal(t, []string{"001_casual", "001_live_event_10"}, m.GetSelectedItems())
+
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.key)})
+			assert.Equal(t, []string{"001_casual", "001_live_event_10"}, <-tt.wantChan(&m))
+		})
+	}
+}
+
+func TestBlockedItems(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	m.Update(tui.UpdateListMsg{
+		Items:   []string{"001_casual", "001_placeholder", "001_live_event_10"},
+		Blocked: []string{"001_placeholder"},
+	})
+	assert.Contains(t, m.View(), "已隐藏 1 个屏蔽的服装")
+	assert.NotContains(t, m.View(), "001_placeholder")
+
+	// 显示屏蔽项后全选也不会选中屏蔽项
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	assert.True(t, m.ShowBlocked)
+	assert.Contains(t, m.View(), "001_placeholder")
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	assert.Equal(t, []string{"001_casual", "001_live_event_10"}, m.GetSelectedItems())
+
+	// 屏蔽项可以单独选择，再次隐藏时已选中的其他列表项保持选中
+	m.Live2dList.Select(1)
+	m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	assert.Equal(t, []string{"001_casual", "001_placeholder", "001_live_event_10"}, m.GetSelectedItems())
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	assert.Equal(t, []string{"001_casual", "001_live_event_10"}, m.GetSelectedItems())
+}
+
+func TestAddDownloadItem(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.AddDownloadItem("001_casual", 0)
+	assert.Equal(t, 1, m.Items["001_casual"].Total, "unknown totals are shown as a single file")
+
+	m.AddDownloadItem("001_casual", 4)
+	m.Items["001_casual"].Current = 2
+
+	// 重复登记只更新文件总数，不重置进度
+	m.AddDownloadItem("001_casual", 0)
+	m.AddDownloadItem("001_casual", 4)
+	assert.Equal(t, 4, m.Items["001_casual"].Total)
+	assert.Equal(t, 2, m.Items["001_casual"].Current)
+	assert.Equal(t, []string{"001_casual"}, m.ItemOrder)
+}
+
+func TestItemTitle(t *testing.T) {
+	t.Parallel()
+
+	m := newDownloadingModel()
+	m.SetItemTitle("001_live", "ライブ衣装")
+	m.SetItemTitle("002_casual", "")
+	assert.Equal(t, "ライブ衣装", m.Items["001_live"].Title)
+	assert.Empty(t, m.Items["002_casual"].Title)
+
+	view := m.View()
+	assert.Contains(t, view, "001_live — ライブ衣装")
+	assert.NotContains(t, view, "002_casual —")
+
+	// 下载完成后与紧凑视图中同样显示服装标题
+	m.Items["001_live"].Current = 1
+	m.SetItemTitle("001_live", "ライブ衣装")
+	assert.Contains(t, m.View(), "✅ 001_live — ライブ衣装")
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v")})
+	assert.Contains(t, m.View(), "001_live — ライブ衣装")
+}
+
+func TestCompactDownloadView(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	m.State = tui.StateDownloading
+	m.AddDownloadItem("001_casual", 4)
+	m.AddDownloadItem("001_live", 4)
+	assert.False(t, m.CompactView)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v")})
+	assert.True(t, m.CompactView)
+	view := m.View()
+	assert.Contains(t, view, "⏳ 001_casual")
+	assert.Contains(t, view, "0.0%")
+	// 紧凑视图中相邻的模型占据相邻的行
+	lines := strings.Split(view, "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "001_casual") {
+			assert.Contains(t, lines[i+1], "001_live")
+		}
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("v")})
+	assert.False(t, m.CompactView)
+}
+
+func TestStallDetection(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	m.State = tui.StateDownloading
+	m.AddDownloadItem("001_casual", 4)
+
+	var cancelled error
+	addBytes, done := m.TrackFile("001_casual", func(cause error) { cancelled = cause })
+	defer done()
+
+	start := time.Now()
+	m.Update(tui.StallTickMsg(start))
+	addBytes(1024)
+	m.Update(tui.StallTickMsg(start.Add(10 * time.Second)))
+	assert.NotContains(t, m.View(), "⚠ 停滞")
+
+	// 30 秒内只收到少量数据，视为停滞
+	addBytes(1024)
+	m.Update(tui.StallTickMsg(start.Add(45 * time.Second)))
+	assert.Contains(t, m.View(), "⚠ 停滞 45s")
+
+	// 取消停滞的文件
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	require.ErrorIs(t, cancelled, errs.ErrStallCancelled)
+	assert.NotContains(t, m.View(), "⚠ 停滞")
+
+	// 有明显进展后不再视为停滞
+	m.Update(tui.StallTickMsg(start.Add(50 * time.Second)))
+	addBytes(1024 * 1024)
+	m.Update(tui.StallTickMsg(start.Add(90 * time.Second)))
+	assert.NotContains(t, m.View(), "⚠ 停滞")
+}
+
+func TestQuitConfirm(t *testing.T) {
+	t.Parallel()
+
+	ctrlC := tea.KeyMsg{Type: tea.KeyCtrlC}
+	tests := []struct {
+		name      string
+		downloads bool
+		keys      []tea.KeyMsg
+		wantQuit  bool
+		wantState string
+	}{
+		{
+			name:     "空闲时立即退出",
+			keys:     []tea.KeyMsg{ctrlC},
+			wantQuit: true,
+		},
+		{
+			name:      "下载中请求确认",
+			downloads: true,
+			keys:      []tea.KeyMsg{ctrlC},
+			wantState: tui.StateQuitConfirm,
+		},
+		{
+			name:      "取消退出后返回下载界面",
+			downloads: true,
+			keys:      []tea.KeyMsg{ctrlC, {Type: tea.KeyRunes, Runes: []rune("n")}},
+			wantState: tui.StateDownloading,
+		},
+		{
+			name:      "确认退出",
+			downloads: true,
+			keys:      []tea.KeyMsg{ctrlC, {Type: tea.KeyRunes, Runes: []rune("y")}},
+			wantQuit:  true,
+		},
+		{
+			name:      "连续按下 Ctrl+C",
+			downloads: true,
+			keys:      []tea.KeyMsg{ctrlC, ctrlC, ctrlC},
+			wantQuit:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tui.NewModel()
+			m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+			if tt.downloads {
+				m.State = tui.StateDownloading
+				m.AddDownloadItem("001_casual", 4)
+			}
+			for _, k := range tt.keys {
+				m.Update(k)
+			}
+
+			assert.Equal(t, tt.wantQuit, m.Quitting)
+			if tt.wantQuit {
+				require.ErrorIs(t, context.Cause(m.Ctx), errs.ErrUserQuit)
+				return
+			}
+			assert.Equal(t, tt.wantState, m.State)
+			require.NoError(t, m.Ctx.Err())
+		})
+	}
+}
+
+func TestListUsageNotes(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 60})
+	m.Update(tui.UpdateListMsg{
+		Items:   []string{"001_casual", "001_live"},
+		Notes:   map[string]string{"001_casual": "已下载 3 次，最近 2024-11-02"},
+		Summary: "已下载 3 次，最近 2024-11-02",
+	})
+	view := m.View()
+	assert.Contains(t, view, "001_casual  已下载 3 次，最近 2024-11-02")
+	assert.Contains(t, view, "| 已下载 3 次")
+}
+
+func TestDiskSpaceStatus(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	m.State = tui.StateDownloading
+	assert.NotContains(t, m.View(), "剩余空间")
+
+	m.SendDiskSpace(10<<30, 1<<30, tui.DiskOK)
+	assert.Contains(t, m.View(), "剩余空间: 10.0 GiB")
+	assert.False(t, m.DiskPaused())
+
+	m.SendDiskSpace(1<<30, 512<<20, tui.DiskLow)
+	assert.Contains(t, m.View(), "即将不足")
+
+	m.SendDiskSpace(256<<20, 512<<20, tui.DiskCritical)
+	assert.True(t, m.DiskPaused())
+	assert.Contains(t, m.View(), "下载已暂停")
+	assert.Contains(t, m.View(), "空间足够后会自动继续下载")
+
+	// 空间恢复后自动继续
+	m.SendDiskSpace(4<<30, 512<<20, tui.DiskOK)
+	assert.False(t, m.DiskPaused())
+	assert.NotContains(t, m.View(), "下载已暂停")
+}
+
+func TestSpaceEstimate(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	m.State = tui.StateDownloading
+	assert.NotContains(t, m.View(), "预计占用")
+
+	m.SendSpaceEstimate("预计占用 1.0 GiB，可用 10.0 GiB", false)
+	assert.Contains(t, m.View(), "预计占用 1.0 GiB，可用 10.0 GiB")
+	assert.NotContains(t, m.View(), "剩余空间可能不足")
+
+	m.SendSpaceEstimate("预计占用 10.0 GiB，可用 1.0 GiB", true)
+	assert.Contains(t, m.View(), "剩余空间可能不足")
+
+	m.SendSpaceEstimate("", false)
+	assert.NotContains(t, m.View(), "预计占用")
+}
+
+func TestInputMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		tabs     int
+		wantMode tui.InputMode
+		wantView string
+	}{
+		{name: "默认角色搜索", tabs: 0, wantMode: tui.InputModeSearch, wantView: "角色搜索"},
+		{name: "切换到直接模型名", tabs: 1, wantMode: tui.InputModeModel, wantView: "直接模型名"},
+		{name: "再次切换回角色搜索", tabs: 2, wantMode: tui.InputModeSearch, wantView: "角色搜索"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tui.NewModel()
+			m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+			for range tt.tabs {
+				m.Update(tea.KeyMsg{Type: tea.KeyTab})
+			}
+			assert.Equal(t, tt.wantMode, m.InputMode)
+			assert.Contains(t, m.View(), tt.wantView)
+
+			// 输入内容按模式发送到对应的通道
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("001_casual")})
+			m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			assert.Equal(t, tui.StateLoading, m.State)
+			if tt.wantMode == tui.InputModeModel {
+				assert.Equal(t, "001_casual", <-m.GetModelChan())
+				assert.Empty(t, m.GetSearchChan())
+			} else {
+				assert.Equal(t, "001_casual", <-m.GetSearchChan())
+				assert.Empty(t, m.GetModelChan())
+			}
+		})
+	}
+}
+
+func TestLogPane(t *testing.T) {
+	t.Parallel()
+
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+	m.SendLogLines([]string{"12:00:00 INF 开始下载文件", "12:00:01 WRN 可选文件下载超时"})
+
+	// 输入状态下反引号是普通输入
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("`")})
+	assert.False(t, m.LogPaneOpen)
+	assert.Equal(t, "`", m.TextInput.Value())
+
+	m.Update(tui.UpdateListMsg{Items: []string{"001_casual", "001_live"}})
+	m.State = tui.StateList
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("`")})
+	assert.True(t, m.LogPaneOpen)
+	assert.Contains(t, m.View(), "可选文件下载超时")
+
+	// 面板打开时其他按键仍由列表处理
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" ")})
+	assert.Equal(t, []string{"001_casual"}, m.GetSelectedItems())
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("`")})
+	assert.False(t, m.LogPaneOpen)
+	assert.NotContains(t, m.View(), "可选文件下载超时")
+}
+
+func TestSummaryLine(t *testing.T) {
+	t.Parallel()
+
+	summary := tui.Summary{
+		HasModel:    true,
+		HasPhysics:  true,
+		Textures:    4,
+		Motions:     38,
+		Expressions: 12,
+		Bytes:       14_900_000,
+		Elapsed:     21400 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name  string
+		width int
+		want  string
+	}{
+		{name: "不限制宽度", width: 0, want: "moc ✓, 4 textures, 38 motions, 12 expressions, physics ✓ (14.2 MiB, 21s)"},
+		{name: "宽度充足", width: 100, want: "moc ✓, 4 textures, 38 motions, 12 expressions, physics ✓ (14.2 MiB, 21s)"},
+		{name: "省略为简写", width: 30, want: "4T 38M 12E (14.2 MiB, 21s)"},
+		{name: "只保留数量", width: 12, want: "4T 38M 12E"},
+		{name: "完全省略", width: 5, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, summary.Line(tt.width))
+		})
+	}
+
+	// 下载完成的列表项显示文件统计
+	m := tui.NewModel()
+	m.Update(tea.WindowSizeMsg{Width: 200, Height: 60})
+	m.State = tui.StateDownloading
+	m.AddDownloadItem("037_casual-2023", 1)
+	m.Items["037_casual-2023"].Current = 1
+	m.SendSummary("037_casual-2023", summary)
+	// 列表宽度有限时省略为简写
+	assert.Contains(t, m.View(), "✅ 037_casual-2023 — 4T 38M 12E (14.2 MiB, 21s)")
+}
+
+func TestBandMembers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		keys       []tea.KeyMsg
+		wantState  string
+		wantSearch string
+	}{
+		{
+			name:       "选择第一个成员",
+			keys:       []tea.KeyMsg{{Type: tea.KeyEnter}},
+			wantState:  tui.StateLoading,
+			wantSearch: "36",
+		},
+		{
+			name:       "向下选择成员",
+			keys:       []tea.KeyMsg{{Type: tea.KeyDown}, {Type: tea.KeyEnter}},
+			wantState:  tui.StateLoading,
+			wantSearch: "37",
+		},
+		{
+			name:       "向上选择时回到最后一个成员",
+			keys:       []tea.KeyMsg{{Type: tea.KeyUp}, {Type: tea.KeyEnter}},
+			wantState:  tui.StateLoading,
+			wantSearch: "40",
+		},
+		{
+			name:      "返回输入状态",
+			keys:      []tea.KeyMsg{{Type: tea.KeyEsc}},
+			wantState: tui.StateInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tui.NewModel()
+			m.Update(tui.UpdateBandMembersMsg{
+				Band: "MyGO!!!!!",
+				Members: []tui.BandMember{
+					{ID: 36, Name: "高松 灯"},
+					{ID: 37, Name: "千早 爱音"},
+					{ID: 40, Name: "椎名 立希"},
+				},
+			})
+			require.Equal(t, tui.StateBandMembers, m.State)
+			view := m.View()
+			assert.Contains(t, view, "乐队成员 - MyGO!!!!!")
+			assert.Contains(t, view, "千早 爱音")
+
+			for _, key := range tt.keys {
+				m.Update(key)
+			}
+			assert.Equal(t, tt.wantState, m.State)
+			if tt.wantSearch == "" {
+				assert.Empty(t, m.GetSearchChan())
+				return
+			}
+			assert.Equal(t, tt.wantSearch, <-m.GetSearchChan())
+		})
+	}
+}
+
+// runFilterCmd 执行按键返回的命令，将列表异步过滤的结果交回模型
+// 光标闪烁等定时命令不会在短时间内返回，直接忽略.
+func runFilterCmd(m *tui.Model, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	var msg tea.Msg
+	select {
+	case msg = <-done:
+	case <-time.After(50 * time.Millisecond):
+		return
+	}
+	switch msg := msg.(type) {
+	case tea.BatchMsg:
+		for _, sub := range msg {
+			runFilterCmd(m, sub)
+		}
+	case list.FilterMatchesMsg:
+		m.Update(msg)
+	}
+}
+
+func TestCharaBrowser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		keys       []tea.KeyMsg
+		wantState  string
+		wantSearch string
+	}{
+		{
+			name:       "默认选中第一个角色",
+			keys:       []tea.KeyMsg{{Type: tea.KeyEnter}},
+			wantState:  tui.StateLoading,
+			wantSearch: "1",
+		},
+		{
+			name:       "跨过乐队标题选择下一个乐队的角色",
+			keys:       []tea.KeyMsg{{Type: tea.KeyDown}, {Type: tea.KeyDown}, {Type: tea.KeyDown}, {Type: tea.KeyEnter}},
+			wantState:  tui.StateLoading,
+			wantSearch: "36",
+		},
+		{
+			name:      "乐队标题不能选择",
+			keys:      []tea.KeyMsg{{Type: tea.KeyDown}, {Type: tea.KeyDown}, {Type: tea.KeyEnter}},
+			wantState: tui.StateCharaBrowser,
+		},
+		{
+			name: "按名称过滤后选择",
+			keys: []tea.KeyMsg{
+				{Type: tea.KeyRunes, Runes: []rune("/")},
+				{Type: tea.KeyRunes, Runes: []rune("爱音")},
+				{Type: tea.KeyEnter},
+				{Type: tea.KeyEnter},
+			},
+			wantState:  tui.StateLoading,
+			wantSearch: "37",
+		},
+		{
+			name: "Esc 先清除过滤",
+			keys: []tea.KeyMsg{
+				{Type: tea.KeyRunes, Runes: []rune("/")},
+				{Type: tea.KeyRunes, Runes: []rune("爱音")},
+				{Type: tea.KeyEnter},
+				{Type: tea.KeyEsc},
+			},
+			wantState: tui.StateCharaBrowser,
+		},
+		{
+			name:      "返回输入状态",
+			keys:      []tea.KeyMsg{{Type: tea.KeyEsc}},
+			wantState: tui.StateInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tui.NewModel()
+			m.Update(tea.WindowSizeMsg{Width: 100, Height: 60})
+			// 角色搜索模式下输入为空时请求浏览全部角色
+			m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			require.Equal(t, tui.StateLoading, m.State)
+			require.Empty(t, <-m.GetSearchChan())
+
+			m.Update(tui.UpdateCharaBrowserMsg{Groups: []tui.CharaGroup{
+				{Band: "Poppin'Party", Charas: []tui.BandMember{{ID: 1, Name: "户山 香澄"}, {ID: 2, Name: "花园 多惠"}}},
+				{Band: "MyGO!!!!!", Charas: []tui.BandMember{{ID: 36, Name: "高松 灯"}, {ID: 37, Name: "千早 爱音"}}},
+			}})
+			require.Equal(t, tui.StateCharaBrowser, m.State)
+			view := m.View()
+			assert.Contains(t, view, "MyGO!!!!!")
+			assert.Contains(t, view, "花园 多惠")
+
+			for _, key := range tt.keys {
+				_, cmd := m.Update(key)
+				runFilterCmd(&m, cmd)
+			}
+			assert.Equal(t, tt.wantState, m.State)
+			if tt.wantSearch == "" {
+				assert.Empty(t, m.GetSearchChan())
+				return
+			}
+			assert.Equal(t, tt.wantSearch, <-m.GetSearchChan())
+		})
+	}
+}
+
+func TestNarrowDownloadView(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		compact bool
+	}{
+		{name: "详细视图", compact: false},
+		{name: "紧凑视图", compact: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := tui.NewModel()
+			m.NarrowWidth = 70
+			m.SetCompactView(tt.compact)
+			m.State = tui.StateDownloading
+			m.AddDownloadItem("001_casual", 4)
+			m.Items["001_casual"].Current = 2
+			m.AddDownloadItem("037_live_event_123_ssr", 4)
+
+			// 窄屏时每个模型只占一行且不超过终端宽度
+			m.Update(tea.WindowSizeMsg{Width: 40, Height: 40})
+			require.True(t, m.Narrow())
+			view := m.View()
+			assert.Contains(t, view, "037_live_event…")
+			assert.Contains(t, view, " 50% ⏳")
+			lines := strings.Split(view, "\n")
+			for i, line := range lines {
+				if strings.Contains(line, "001_casual") {
+					assert.LessOrEqual(t, lipgloss.Width(line), 40, line)
+					assert.Contains(t, lines[i+1], "037_live")
+					assert.LessOrEqual(t, lipgloss.Width(lines[i+1]), 40, lines[i+1])
+				}
+			}
+
+			// 恢复宽度后回到原来的视图，来回调整多次结果相同
+			for range 2 {
+				m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+				require.False(t, m.Narrow())
+				assert.Equal(t, tt.compact, m.CompactView)
+				assert.Contains(t, m.View(), "037_live_event_123_ssr")
+
+				m.Update(tea.WindowSizeMsg{Width: 40, Height: 40})
+				require.True(t, m.Narrow())
+				assert.Contains(t, m.View(), "037_live_event…")
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+)
+
+// StateQuitConfirm 表示确认是否退出并中止下载的状态.
+const StateQuitConfirm = "quitConfirm"
+
+// activeDownloads 返回尚未完成且没有出错的下载项数量.
+func (m *Model) activeDownloads() int {
+	count := 0
+	for _, item := range m.Items {
+		if item.Err == nil && item.Current < item.Total {
+			count++
+		}
+	}
+	return count
+}
+
+// requestQuit 退出程序，并以 reason 作为原因取消上下文
+// 多次调用时只有第一次生效.
+func (m *Model) requestQuit(reason error) tea.Cmd {
+	m.quitOnce.Do(func() {
+		close(m.cancelChan)
+		m.cancel(reason)
+		m.Quitting = true
+	})
+	return tea.Quit
+}
+
+// handleQuitKey 处理退出按键
+// 有下载正在进行时先请求确认，否则立即退出.
+func (m *Model) handleQuitKey() (tea.Model, tea.Cmd) {
+	if m.State == StateQuitConfirm {
+		// 确认界面中再次按 Ctrl+C 视为确认退出
+		return m, m.requestQuit(errs.ErrUserQuit)
+	}
+	if m.activeDownloads() == 0 {
+		return m, m.requestQuit(errs.ErrUserQuit)
+	}
+	m.quitReturnState = m.State
+	m.State = StateQuitConfirm
+	return m, nil
+}
+
+// handleQuitConfirmState 处理退出确认状态下的按键.
+func (m *Model) handleQuitConfirmState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if strings.ToLower(msg.String()) == "y" {
+		return m, m.requestQuit(errs.ErrUserQuit)
+	}
+	m.State = m.quitReturnState
+	return m, nil
+}
+
+// quitConfirmView 渲染退出确认界面.
+func (m *Model) quitConfirmView() string {
+	var s strings.Builder
+	s.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500")).Render(
+		fmt.Sprintf("还有 %d 个下载正在进行，确定要退出并中止这些下载吗？(y/N)", m.activeDownloads()),
+	))
+	s.WriteString("\n\n")
+	s.WriteString(helpStyle("按 Y 退出，按其他键返回"))
+	return s.String()
+}
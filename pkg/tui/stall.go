@@ -0,0 +1,176 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/errs"
+)
+
+// 停滞检测常量.
+const (
+	stallCheckInterval = time.Second      // 停滞检测的间隔
+	stallThreshold     = 30 * time.Second // 超过该时长没有明显进展即视为停滞
+	stallMinBytes      = 256 * 1024       // 视为有明显进展所需的最少字节数
+)
+
+// StallTickMsg 表示停滞检测的定时消息
+// 携带检测时的时间.
+type StallTickMsg time.Time
+
+// stallTick 返回下一次停滞检测的定时命令.
+func stallTick() tea.Cmd {
+	return tea.Tick(stallCheckInterval, func(t time.Time) tea.Msg {
+		return StallTickMsg(t)
+	})
+}
+
+// itemActivity 记录下载项正在下载的文件及已接收的字节数.
+type itemActivity struct {
+	bytes   int64                           // 已接收的字节数
+	nextID  int                             // 下一个文件的编号
+	cancels map[int]context.CancelCauseFunc // 正在下载的文件的取消函数
+}
+
+// activityTracker 记录各下载项的字节级下载进度
+// 由下载协程写入，由 TUI 在停滞检测时读取，因此需要加锁.
+type activityTracker struct {
+	mu    sync.Mutex
+	items map[string]*itemActivity
+}
+
+// newActivityTracker 创建新的下载进度记录器.
+func newActivityTracker() *activityTracker {
+	return &activityTracker{items: make(map[string]*itemActivity)}
+}
+
+// get 返回下载项的进度记录，不存在时创建.
+func (t *activityTracker) get(name string) *itemActivity {
+	activity, exists := t.items[name]
+	if !exists {
+		activity = &itemActivity{cancels: make(map[int]context.CancelCauseFunc)}
+		t.items[name] = activity
+	}
+	return activity
+}
+
+// snapshot 返回下载项已接收的字节数及正在下载的文件数.
+func (t *activityTracker) snapshot(name string) (int64, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	activity, exists := t.items[name]
+	if !exists {
+		return 0, 0
+	}
+	return activity.bytes, len(activity.cancels)
+}
+
+// cancelFiles 取消下载项正在下载的所有文件.
+func (t *activityTracker) cancelFiles(name string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	activity, exists := t.items[name]
+	if !exists {
+		return 0
+	}
+	for _, cancel := range activity.cancels {
+		cancel(errs.ErrStallCancelled)
+	}
+	return len(activity.cancels)
+}
+
+// TrackFile 登记一个正在下载的文件，用于停滞检测
+// 参数:
+//   - name: 下载项名称
+//   - cancel: 取消该文件下载的函数，用户取消停滞的文件时以 errs.ErrStallCancelled 调用
+//
+// 返回:
+//   - func(int64): 接收到数据时调用，参数为本次接收的字节数
+//   - func(): 文件下载结束时调用
+func (m *Model) TrackFile(name string, cancel context.CancelCauseFunc) (func(int64), func()) {
+	m.activity.mu.Lock()
+	activity := m.activity.get(name)
+	id := activity.nextID
+	activity.nextID++
+	activity.cancels[id] = cancel
+	m.activity.mu.Unlock()
+
+	addBytes := func(n int64) {
+		m.activity.mu.Lock()
+		activity.bytes += n
+		m.activity.mu.Unlock()
+	}
+	done := func() {
+		m.activity.mu.Lock()
+		delete(activity.cancels, id)
+		m.activity.mu.Unlock()
+	}
+	return addBytes, done
+}
+
+// checkStalls 根据字节级进度更新各下载项的停滞状态
+// 已完成文件数或已接收字节数有明显增长时，重置停滞计时.
+func (m *Model) checkStalls(now time.Time) {
+	changed := false
+	for _, item := range m.Items {
+		bytes, active := m.activity.snapshot(item.Name)
+		previous := item.StalledFor
+
+		switch {
+		case item.Err != nil || item.Current >= item.Total || active == 0:
+			// 已结束或尚未开始下载文件的项目不参与检测
+			item.lastAdvance = time.Time{}
+			item.StalledFor = 0
+		case item.lastAdvance.IsZero() || item.Current != item.lastCurrent || bytes-item.lastBytes >= stallMinBytes:
+			item.lastAdvance = now
+			item.lastBytes = bytes
+			item.lastCurrent = item.Current
+			item.StalledFor = 0
+		default:
+			if idle := now.Sub(item.lastAdvance); idle >= stallThreshold {
+				item.StalledFor = idle.Truncate(time.Second)
+			}
+		}
+		item.Bytes = bytes
+		changed = changed || previous != item.StalledFor
+	}
+	if changed {
+		m.updateDownloadList()
+	}
+}
+
+// handleStallTickMsg 处理停滞检测的定时消息.
+func (m *Model) handleStallTickMsg(msg StallTickMsg) (tea.Model, tea.Cmd) {
+	m.checkStalls(time.Time(msg))
+	return m, stallTick()
+}
+
+// cancelSelectedStalled 取消当前选中的停滞下载项中正在下载的文件
+// 被取消的文件会由下载器重新连接下载.
+func (m *Model) cancelSelectedStalled() {
+	selected, ok := m.DownloadList.SelectedItem().(DownloadListItem)
+	if !ok {
+		return
+	}
+	item, exists := m.Items[selected.Name]
+	if !exists || item.StalledFor == 0 {
+		return
+	}
+	count := m.activity.cancelFiles(item.Name)
+	item.lastAdvance = time.Time{}
+	item.StalledFor = 0
+	m.SetError(fmt.Sprintf("%s: 已取消 %d 个停滞的文件，正在重新连接", item.Name, count))
+	m.updateDownloadList()
+}
+
+// stallBadge 返回停滞状态的提示文本.
+func stallBadge(stalledFor time.Duration) string {
+	if stalledFor == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" ⚠ 停滞 %s", stalledFor)
+}
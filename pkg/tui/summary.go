@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/fsutil"
+)
+
+// Summary 表示下载完成的模型的文件统计.
+type Summary struct {
+	HasModel    bool          // 是否包含模型文件
+	HasPhysics  bool          // 是否包含物理文件
+	Textures    int           // 纹理数量
+	Motions     int           // 动作数量
+	Expressions int           // 表情数量
+	Bytes       int64         // 模型文件总大小（字节）
+	Elapsed     time.Duration // 构建耗时
+}
+
+// summaryMsg 表示下载项的完成统计消息.
+type summaryMsg struct {
+	itemName string  // 项目名称
+	summary  Summary // 完成统计
+}
+
+// SendSummary 设置下载完成的模型的文件统计
+// 参数:
+//   - itemName: 项目名称
+//   - summary: 文件统计
+func (m *Model) SendSummary(itemName string, summary Summary) {
+	msg := summaryMsg{itemName: itemName, summary: summary}
+	if m.program != nil {
+		m.program.Send(msg)
+		return
+	}
+	m.handleSummaryMsg(msg)
+}
+
+// handleSummaryMsg 处理下载项的完成统计消息.
+func (m *Model) handleSummaryMsg(msg summaryMsg) (tea.Model, tea.Cmd) {
+	if item, exists := m.Items[msg.itemName]; exists {
+		item.Summary = &msg.summary
+		m.updateDownloadList()
+	}
+	return m, nil
+}
+
+// check 返回文件是否存在的标记.
+func check(ok bool) string {
+	if ok {
+		return "✓"
+	}
+	return "✗"
+}
+
+// variants 返回从详细到简略的多种文件统计文本.
+func (s Summary) variants() []string {
+	stats := fmt.Sprintf("(%s, %s)", fsutil.FormatBytes(uint64(max(s.Bytes, 0))), s.Elapsed.Round(time.Second))
+	full := fmt.Sprintf("moc %s, %d textures, %d motions, %d expressions, physics %s",
+		check(s.HasModel), s.Textures, s.Motions, s.Expressions, check(s.HasPhysics))
+	short := fmt.Sprintf("%dT %dM %dE", s.Textures, s.Motions, s.Expressions)
+	return []string{
+		full + " " + stats,
+		short + " " + stats,
+		short,
+		"",
+	}
+}
+
+// Line 返回不超过指定宽度的文件统计文本
+// 宽度不足时依次省略为简写的数量、只保留数量，最后完全省略
+// 参数:
+//   - width: 可用宽度，小于等于 0 时不限制
+//
+// 返回:
+//   - string: 文件统计文本
+func (s Summary) Line(width int) string {
+	variants := s.variants()
+	if width <= 0 {
+		return variants[0]
+	}
+	for _, variant := range variants {
+		if lipgloss.Width(variant) <= width {
+			return variant
+		}
+	}
+	return ""
+}
+
+// completedTitle 返回下载完成的列表项标题，有文件统计时在名称后显示.
+func (i DownloadListItem) completedTitle() string {
+	title := "✅ " + i.label()
+	if i.Summary == nil {
+		return fmt.Sprintf("%s (100.0%%)", title)
+	}
+	const separator = " — "
+	width := 0
+	if i.Width > 0 {
+		width = i.Width - lipgloss.Width(title+separator)
+		if width <= 0 {
+			return title
+		}
+	}
+	if line := i.Summary.Line(width); line != "" {
+		return title + separator + line
+	}
+	return title
+}
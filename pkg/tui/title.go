@@ -0,0 +1,49 @@
+package tui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// titleSeparator 是下载列表中名称与服装标题之间的分隔符，与服装列表保持一致.
+const titleSeparator = " — "
+
+// itemTitleMsg 表示下载项服装标题更新消息.
+type itemTitleMsg struct {
+	itemName string // 项目名称
+	title    string // 服装标题
+}
+
+// SetItemTitle 设置下载项的服装标题
+// 下载列表在资源包名称后显示服装标题，标题为空时不更新.
+func (m *Model) SetItemTitle(name, title string) {
+	if title == "" {
+		return
+	}
+	if m.program != nil {
+		m.program.Send(itemTitleMsg{itemName: name, title: title})
+		return
+	}
+	m.applyItemTitle(itemTitleMsg{itemName: name, title: title})
+}
+
+// applyItemTitle 更新下载项的服装标题.
+func (m *Model) applyItemTitle(msg itemTitleMsg) {
+	item, exists := m.Items[msg.itemName]
+	if !exists {
+		return
+	}
+	item.Title = msg.title
+	m.updateDownloadList()
+}
+
+// handleItemTitleMsg 处理下载项服装标题消息.
+func (m *Model) handleItemTitleMsg(msg itemTitleMsg) (tea.Model, tea.Cmd) {
+	m.applyItemTitle(msg)
+	return m, nil
+}
+
+// label 返回下载列表项显示的名称，有服装标题时显示在资源包名称后.
+func (i DownloadListItem) label() string {
+	if i.ItemTitle == "" {
+		return i.Name
+	}
+	return i.Name + titleSeparator + i.ItemTitle
+}
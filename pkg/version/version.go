@@ -1,19 +1,142 @@
 // Package version 提供了版本信息
 package version
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
 
 //nolint:gochecknoglobals // 这些变量用于版本信息，是 GoReleaser 的标准做法
 var (
-	Version = "dev"
+	Version = devVersion
 	Commit  = "none"
 	BuiltBy = "unknown"
 )
 
-// GetVersionInfo 返回版本信息.
-func GetVersionInfo() string {
-	if BuiltBy != "goreleaser" {
+// devVersion 是未注入版本号时的默认版本号，也用作本地构建的后缀.
+const devVersion = "dev"
+
+// releaseWorkflow 是发布版本的 GitHub Actions 工作流名称.
+const releaseWorkflow = "Release"
+
+// shortSHALength 是构建版本号中提交哈希的长度.
+const shortSHALength = 7
+
+// Env 提供计算版本号所需的环境信息
+// 测试时可以替换为固定的环境.
+type Env interface {
+	// Getenv 返回环境变量的值，不存在时返回空字符串.
+	Getenv(key string) string
+	// ReadFile 读取文件内容.
+	ReadFile(path string) ([]byte, error)
+}
+
+// osEnv 从当前进程读取环境信息.
+type osEnv struct{}
+
+// Getenv 返回环境变量的值.
+func (osEnv) Getenv(key string) string {
+	return os.Getenv(key)
+}
+
+// ReadFile 读取文件内容.
+func (osEnv) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path) //nolint:wrapcheck // 错误只用于判断是否能读取事件文件
+}
+
+// currentVersion 缓存当前进程的版本号，环境在进程运行期间不会变化.
+//
+//nolint:gochecknoglobals // 版本号在进程内只需计算一次
+var currentVersion = sync.OnceValue(func() string {
+	return ResolveVersion(osEnv{})
+})
+
+// GetVersion 返回当前程序的版本号
+// 界面标题、-version 输出及导出文件中记录的版本号都使用该函数.
+func GetVersion() string {
+	return currentVersion()
+}
+
+// ResolveVersion 根据构建方式和环境计算版本号
+// 规则如下:
+//   - GoReleaser 构建或 Release 工作流中返回原始版本号
+//   - 不在 CI 中时添加 -dev 后缀
+//   - 其他 CI 构建添加 -build.<短提交哈希> 后缀
+//   - pull_request 事件中追加 (pr#N)
+//
+// 参数:
+//   - env: 环境信息
+//
+// 返回:
+//   - string: 版本号
+func ResolveVersion(env Env) string {
+	if BuiltBy == "goreleaser" {
+		return Version
+	}
+	if env.Getenv("CI") != "true" && env.Getenv("GITHUB_ACTIONS") != "true" {
+		// 未注入版本号时本身就是开发版本，不重复添加后缀
+		if Version == devVersion {
+			return Version
+		}
+		return Version + "-" + devVersion
+	}
+	if env.Getenv("GITHUB_WORKFLOW") == releaseWorkflow {
 		return Version
 	}
-	return fmt.Sprintf("%s-%s", Version, Commit)
+
+	result := Version + "-build"
+	if sha := buildSHA(env); sha != "" {
+		result += "." + sha
+	}
+	if number := pullRequestNumber(env); number > 0 {
+		result += fmt.Sprintf(" (pr#%d)", number)
+	}
+	return result
+}
+
+// buildSHA 返回构建所用提交的短哈希
+// 拉取请求中优先使用 GITHUB_BUILD_SHA 记录的源分支提交，而不是合并提交.
+func buildSHA(env Env) string {
+	sha := env.Getenv("GITHUB_BUILD_SHA")
+	if sha == "" {
+		sha = env.Getenv("GITHUB_SHA")
+	}
+	if sha == "" && Commit != "none" {
+		sha = Commit
+	}
+	if len(sha) > shortSHALength {
+		sha = sha[:shortSHALength]
+	}
+	return sha
+}
+
+// pullRequestNumber 从 GitHub 事件文件中解析拉取请求编号，不是拉取请求事件时返回 0.
+func pullRequestNumber(env Env) int {
+	if !strings.HasPrefix(env.Getenv("GITHUB_EVENT_NAME"), "pull_request") {
+		return 0
+	}
+	path := env.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return 0
+	}
+	data, err := env.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	var event struct {
+		Number      int `json:"number"`
+		PullRequest struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+	}
+	if json.Unmarshal(data, &event) != nil {
+		return 0
+	}
+	if event.PullRequest.Number > 0 {
+		return event.PullRequest.Number
+	}
+	return event.Number
 }
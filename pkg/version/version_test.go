@@ -0,0 +1,102 @@
+package version_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/A-kirami/bestdori-live2d-downloader/pkg/version"
+)
+
+// fakeEnv 是用于测试的固定环境.
+type fakeEnv struct {
+	vars  map[string]string
+	files map[string]string
+}
+
+func (e fakeEnv) Getenv(key string) string {
+	return e.vars[key]
+}
+
+func (e fakeEnv) ReadFile(path string) ([]byte, error) {
+	content, ok := e.files[path]
+	if !ok {
+		return nil, errors.New("file not found")
+	}
+	return []byte(content), nil
+}
+
+func TestResolveVersion(t *testing.T) {
+	oldVersion, oldBuiltBy := version.Version, version.BuiltBy
+	version.Version, version.BuiltBy = "1.2.0", "unknown"
+	defer func() { version.Version, version.BuiltBy = oldVersion, oldBuiltBy }()
+
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		name  string
+		vars  map[string]string
+		files map[string]string
+		want  string
+	}{
+		{name: "本地构建", want: "1.2.0-dev"},
+		{
+			name: "发布工作流",
+			vars: map[string]string{"GITHUB_ACTIONS": "true", "GITHUB_WORKFLOW": "Release", "GITHUB_SHA": sha},
+			want: "1.2.0",
+		},
+		{
+			name: "推送构建",
+			vars: map[string]string{"CI": "true", "GITHUB_EVENT_NAME": "push", "GITHUB_SHA": sha},
+			want: "1.2.0-build.0123456",
+		},
+		{
+			name: "优先使用源分支提交",
+			vars: map[string]string{"CI": "true", "GITHUB_SHA": sha, "GITHUB_BUILD_SHA": "fedcba9876543210"},
+			want: "1.2.0-build.fedcba9",
+		},
+		{
+			name: "拉取请求",
+			vars: map[string]string{
+				"CI":                "true",
+				"GITHUB_SHA":        sha,
+				"GITHUB_EVENT_NAME": "pull_request",
+				"GITHUB_EVENT_PATH": "/event.json",
+			},
+			files: map[string]string{"/event.json": `{"number": 42, "pull_request": {"number": 42}}`},
+			want:  "1.2.0-build.0123456 (pr#42)",
+		},
+		{
+			name: "事件文件无法读取",
+			vars: map[string]string{
+				"CI":                "true",
+				"GITHUB_SHA":        sha,
+				"GITHUB_EVENT_NAME": "pull_request",
+				"GITHUB_EVENT_PATH": "/missing.json",
+			},
+			want: "1.2.0-build.0123456",
+		},
+		{
+			name:  "非拉取请求事件忽略事件文件",
+			vars:  map[string]string{"CI": "true", "GITHUB_SHA": sha, "GITHUB_EVENT_PATH": "/event.json"},
+			files: map[string]string{"/event.json": `{"number": 42}`},
+			want:  "1.2.0-build.0123456",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := fakeEnv{vars: tt.vars, files: tt.files}
+			assert.Equal(t, tt.want, version.ResolveVersion(env))
+		})
+	}
+
+	// 未注入版本号时不重复添加后缀
+	version.Version = "dev"
+	assert.Equal(t, "dev", version.ResolveVersion(fakeEnv{}))
+
+	// GoReleaser 构建的发布版本不受运行环境影响
+	version.Version = "1.2.0"
+	version.BuiltBy = "goreleaser"
+	assert.Equal(t, "1.2.0", version.ResolveVersion(fakeEnv{}))
+}